@@ -0,0 +1,106 @@
+package orderer
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Aliases maps short names, such as big-storage-box, to the configuration of
+// an order. They are read from an alias file:
+//
+//	version: 1
+//	aliases:
+//	  big-storage-box:
+//	    plan: 24rise01-us
+//	    configuration:
+//	      - label: dedicated_datacenter
+//	        value: hil
+//	    options:
+//	      - softraid-2x512nvme-24rise-us
+//	      - ram-32g-ecc-3200-24rise-us
+//
+// The version of the file applies to every alias, which is written as a
+// configuration file without its version.
+type Aliases map[string]*Config
+
+// aliasFile is the content of an alias file
+type aliasFile struct {
+	Version int                `yaml:"version"`
+	Aliases map[string]*Config `yaml:"aliases"`
+}
+
+// LoadAliases reads and validates the alias file at path of fsys.
+func LoadAliases(fsys FS, path string) (Aliases, error) {
+	data, err := fsOrDisk(fsys).ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading aliases: %w", err)
+	}
+	aliases, err := ParseAliases(data)
+	if err != nil {
+		return nil, fmt.Errorf("aliases %s: %w", path, err)
+	}
+	return aliases, nil
+}
+
+// ParseAliases parses and validates an alias file content. Every alias is
+// validated as a configuration file, so that a broken alias is reported when
+// the file is loaded rather than when it is ordered.
+func ParseAliases(data []byte) (Aliases, error) {
+	var file aliasFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid aliases: %w", err)
+	}
+	version := &Config{Version: file.Version}
+	if err := version.checkVersion(); err != nil {
+		return nil, err
+	}
+
+	aliases := make(Aliases, len(file.Aliases))
+	for name, config := range file.Aliases {
+		if name == "" || strings.ContainsAny(name, " \t=,") {
+			return nil, fmt.Errorf("invalid alias name %q: spaces, = and , are not allowed", name)
+		}
+		if config == nil {
+			return nil, fmt.Errorf("alias %s: invalid config: plan is required", name)
+		}
+		if config.Version != 0 && config.Version != file.Version {
+			return nil, fmt.Errorf("alias %s: version %d differs from the version %d of the file", name, config.Version, file.Version)
+		}
+		config.Version = file.Version
+		if err := config.validate(); err != nil {
+			return nil, fmt.Errorf("alias %s: %w", name, err)
+		}
+		aliases[name] = config
+	}
+	return aliases, nil
+}
+
+// Names returns the names of the aliases, sorted.
+func (a Aliases) Names() []string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns a copy of the configuration of the alias name, so that it
+// can be adjusted with Set without altering the alias. The error lists the
+// known aliases when there is no such alias.
+func (a Aliases) Lookup(name string) (*Config, error) {
+	config, ok := a[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown alias %q, known aliases: %s", name, strings.Join(a.Names(), ", "))
+	}
+	copied := *config
+	copied.Configuration = append([]ConfigLabel(nil), config.Configuration...)
+	copied.Options = append([]ConfigOption(nil), config.Options...)
+	return &copied, nil
+}
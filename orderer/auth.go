@@ -0,0 +1,151 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// Account is the authenticated OVH account, as returned by /me.
+type Account struct {
+	Nichandle  string `json:"nichandle"`
+	Email      string `json:"email"`
+	Subsidiary string `json:"ovhSubsidiary"`
+}
+
+// Me returns the account the client is authenticated as.
+func (o *Orderer) Me(ctx context.Context) (*Account, error) {
+	var account Account
+	if err := o.client.GetWithContext(ctx, "/me", &account); err != nil {
+		return nil, fmt.Errorf("error fetching account: %w", err)
+	}
+	return &account, nil
+}
+
+// ErrBillingAccountMismatch is returned, wrapped, when
+// OrderRequest.BillingAccount is not the account the client acts on.
+var ErrBillingAccountMismatch = errors.New("the consumer key cannot order for this billing account")
+
+// checkBillingAccount verifies that the client acts on billingAccount (a
+// nichandle). Orders are billed to the account of the consumer key, the
+// cart API taking no other account, so ordering for another account needs
+// a consumer key of that account.
+func (o *Orderer) checkBillingAccount(ctx context.Context, billingAccount string) error {
+	account, err := o.Me(ctx)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(account.Nichandle, billingAccount) {
+		return fmt.Errorf("%w: the consumer key acts on %s, not %s", ErrBillingAccountMismatch, account.Nichandle, billingAccount)
+	}
+	return nil
+}
+
+// AccessRule is an API access granted to a consumer key. Path may contain
+// "*" wildcards.
+type AccessRule struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+func (r AccessRule) String() string {
+	return r.Method + " " + r.Path
+}
+
+// Credential is the consumer key in use, as returned by /auth/currentCredential.
+type Credential struct {
+	CredentialID int64        `json:"credentialId"`
+	Status       string       `json:"status"`
+	Creation     *time.Time   `json:"creation"`
+	Expiration   *time.Time   `json:"expiration"`
+	Rules        []AccessRule `json:"rules"`
+}
+
+// CurrentCredential returns the consumer key the client uses.
+func (o *Orderer) CurrentCredential(ctx context.Context) (*Credential, error) {
+	var credential Credential
+	if err := o.client.GetWithContext(ctx, "/auth/currentCredential", &credential); err != nil {
+		return nil, fmt.Errorf("error fetching current credential: %w", err)
+	}
+	return &credential, nil
+}
+
+// ErrCredentialInvalid is returned, wrapped, by CheckCredential when the
+// consumer key is expired, revoked or not validated.
+var ErrCredentialInvalid = errors.New("the consumer key is not valid")
+
+// CheckCredential returns the consumer key the client uses, and fails with
+// ErrCredentialInvalid when it cannot be used: the API rejects it, it is not
+// validated, or it has expired. It is a cheap preflight, so that a dead key
+// is noticed before an order starts rather than in the middle of it.
+func (o *Orderer) CheckCredential(ctx context.Context) (*Credential, error) {
+	credential, err := o.CurrentCredential(ctx)
+	var apiErr *ovh.APIError
+	if errors.As(err, &apiErr) && (apiErr.Code == http.StatusUnauthorized || apiErr.Code == http.StatusForbidden) {
+		return nil, fmt.Errorf("%w: %w", ErrCredentialInvalid, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case credential.Status != "validated":
+		return credential, fmt.Errorf("%w: its status is %s", ErrCredentialInvalid, credential.Status)
+	case credential.Expiration != nil && !o.clock.Now().Before(*credential.Expiration):
+		return credential, fmt.Errorf("%w: it expired on %s", ErrCredentialInvalid, credential.Expiration.Format(time.RFC3339))
+	}
+	return credential, nil
+}
+
+// RequiredAccessRules are the calls the order flow makes, as access rules.
+var RequiredAccessRules = []AccessRule{
+	{Method: "GET", Path: "/me"},
+	{Method: "POST", Path: "/order/cart"},
+	{Method: "GET", Path: "/order/cart/*"},
+	{Method: "POST", Path: "/order/cart/*"},
+	{Method: "DELETE", Path: "/order/cart/*"},
+	{Method: "GET", Path: "/me/order/*"},
+	{Method: "POST", Path: "/me/order/*"},
+}
+
+// MissingRules returns the rules of required which are not covered by granted.
+func MissingRules(granted, required []AccessRule) []AccessRule {
+	var missing []AccessRule
+	for _, rule := range required {
+		covered := false
+		for _, grant := range granted {
+			if grant.Method == rule.Method && matchRulePath(grant.Path, rule.Path) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			missing = append(missing, rule)
+		}
+	}
+	return missing
+}
+
+// matchRulePath reports whether the granted path pattern covers path. A "*"
+// in the pattern matches any sequence of characters, including "/". A "*" in
+// path only matches a "*" in the pattern at that position or earlier.
+func matchRulePath(pattern, path string) bool {
+	star := strings.Index(pattern, "*")
+	if star < 0 {
+		return pattern == path
+	}
+	if !strings.HasPrefix(path, pattern[:star]) {
+		return false
+	}
+	rest := pattern[star+1:]
+	for i := star; i <= len(path); i++ {
+		if matchRulePath(rest, path[i:]) {
+			return true
+		}
+	}
+	return false
+}
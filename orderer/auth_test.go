@@ -0,0 +1,60 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCheckCredential(t *testing.T) {
+	// The fake clock is at 2026-01-02T03:04:05Z
+	tests := []struct {
+		name     string
+		code     int
+		body     string
+		wantErr  error
+		wantCred bool
+	}{
+		{name: "valid", body: `{"credentialId":1,"status":"validated","expiration":"2026-02-01T00:00:00Z"}`, wantCred: true},
+		{name: "no expiration", body: `{"credentialId":1,"status":"validated"}`, wantCred: true},
+		{name: "expired", body: `{"credentialId":1,"status":"validated","expiration":"2026-01-01T00:00:00Z"}`, wantErr: ErrCredentialInvalid, wantCred: true},
+		{name: "pending", body: `{"credentialId":1,"status":"pendingValidation"}`, wantErr: ErrCredentialInvalid, wantCred: true},
+		{name: "revoked", code: http.StatusUnauthorized, body: "This credential is not valid", wantErr: ErrCredentialInvalid},
+		{name: "forbidden", code: http.StatusForbidden, body: "This call has not been granted", wantErr: ErrCredentialInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestServer(t, Options{}, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/auth/currentCredential" {
+					writeAPIError(w, http.StatusNotFound, "unexpected call "+r.URL.Path)
+					return
+				}
+				if tt.code != 0 {
+					writeAPIError(w, tt.code, tt.body)
+					return
+				}
+				fmt.Fprint(w, tt.body)
+			})
+
+			credential, err := o.CheckCredential(context.Background())
+			if tt.wantErr == nil && err != nil || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if (credential != nil) != tt.wantCred {
+				t.Errorf("credential = %+v, want one: %t", credential, tt.wantCred)
+			}
+		})
+	}
+}
+
+// Other errors are not mistaken for an invalid key
+func TestCheckCredentialOtherError(t *testing.T) {
+	o := newTestServer(t, Options{}, func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusBadRequest, "Bad request")
+	})
+	if _, err := o.CheckCredential(context.Background()); err == nil || errors.Is(err, ErrCredentialInvalid) {
+		t.Errorf("err = %v, want another error than %v", err, ErrCredentialInvalid)
+	}
+}
@@ -0,0 +1,245 @@
+package orderer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// DatacenterAvailability is the stock of a server configuration in a datacenter.
+type DatacenterAvailability struct {
+	Datacenter string `json:"datacenter"`
+
+	// Availability is a delivery delay such as "1H-high" or "72H", or
+	// "unavailable", "comingSoon" or "unknown".
+	Availability string `json:"availability"`
+}
+
+// InStock reports whether the server can currently be delivered from the datacenter.
+func (a DatacenterAvailability) InStock() bool {
+	switch a.Availability {
+	case "unavailable", "comingSoon", "unknown", "":
+		return false
+	}
+	return true
+}
+
+// Availability is the stock of a server configuration (plan, memory and
+// storage) in each datacenter.
+type Availability struct {
+	FQN           string                   `json:"fqn"`
+	PlanCode      string                   `json:"planCode"`
+	Server        string                   `json:"server"`
+	Memory        string                   `json:"memory"`
+	Storage       string                   `json:"storage"`
+	SystemStorage string                   `json:"systemStorage"`
+	Datacenters   []DatacenterAvailability `json:"datacenters"`
+}
+
+// Availabilities returns the stock of the configurations of planCode, from
+// Options.CatalogCache when it has a fresh copy.
+func (o *Orderer) Availabilities(ctx context.Context, planCode string) ([]Availability, error) {
+	var availabilities []Availability
+	err := o.cached("availabilities-"+planCode, &availabilities, func() (err error) {
+		availabilities, err = o.availabilities(ctx, planCode)
+		return err
+	})
+	return availabilities, err
+}
+
+// availabilities fetches the current stock of the configurations of planCode
+func (o *Orderer) availabilities(ctx context.Context, planCode string) ([]Availability, error) {
+	var availabilities []Availability
+	path := "/dedicated/server/datacenter/availabilities?planCode=" + url.QueryEscape(planCode)
+	if err := o.client.GetWithContext(ctx, path, &availabilities); err != nil {
+		return nil, fmt.Errorf("error fetching availabilities of plan %s: %w", planCode, err)
+	}
+	return availabilities, nil
+}
+
+// matchesOptions reports whether the configuration is the one ordered with
+// options: its memory and storage must be among them, as the plan codes of
+// options extend those of the availabilities (ram-32g-ecc-3200 is sold as
+// ram-32g-ecc-3200-24rise-us). Any configuration matches when no option is
+// given.
+func (a Availability) matchesOptions(options []Option) bool {
+	if len(options) == 0 {
+		return true
+	}
+	for _, component := range []string{a.Memory, a.Storage} {
+		if component == "" {
+			continue
+		}
+		found := false
+		for _, option := range options {
+			if strings.HasPrefix(option.PlanCode, component) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// WaitForAvailability polls the availabilities of the plan of req every
+// interval until the configuration it orders is in stock in one of
+// datacenters (any datacenter when empty), and returns that datacenter.
+// A zero timeout waits until ctx is done.
+//
+// The check is advisory: when the availabilities cannot be read, e.g. the
+// endpoint is down or rate limited, it logs a warning and returns "" so that
+// the order is attempted anyway, unless Options.StrictAvailability is set.
+func (o *Orderer) WaitForAvailability(ctx context.Context, req OrderRequest, datacenters []string, interval, timeout time.Duration) (string, error) {
+	return o.waitForAvailability(ctx, req, datacenters, interval, timeout, nil)
+}
+
+// waitForAvailability implements WaitForAvailability, calling beforeCheck,
+// when set, before each check of the stock
+func (o *Orderer) waitForAvailability(ctx context.Context, req OrderRequest, datacenters []string, interval, timeout time.Duration, beforeCheck func() error) (string, error) {
+	var found string
+	err := o.pollUntil(ctx, poll{Interval: interval, Timeout: timeout}, func() (bool, error) {
+		if beforeCheck != nil {
+			if err := beforeCheck(); err != nil {
+				return false, err
+			}
+		}
+		availabilities, err := o.availabilities(ctx, req.PlanCode)
+		if err != nil && !o.opts.StrictAvailability && ctx.Err() == nil {
+			o.logger.Printf("Warning: cannot check the stock of plan %s, ordering anyway: %v", req.PlanCode, err)
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		for _, availability := range availabilities {
+			if !availability.matchesOptions(req.Options) {
+				continue
+			}
+			for _, dc := range availability.Datacenters {
+				if dc.InStock() && (len(datacenters) == 0 || contains(datacenters, dc.Datacenter)) {
+					found = dc.Datacenter
+					o.logger.Printf("Plan %s is in stock in %s (%s)", req.PlanCode, dc.Datacenter, dc.Availability)
+					return true, nil
+				}
+			}
+		}
+		o.logger.Printf("Plan %s is not in stock yet in %s", req.PlanCode, describeDatacenters(datacenters))
+		return false, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error waiting for plan %s to be in stock: %w", req.PlanCode, err)
+	}
+	return found, nil
+}
+
+// describeDatacenters names a list of datacenters in log messages
+func describeDatacenters(datacenters []string) string {
+	if len(datacenters) == 0 {
+		return "any datacenter"
+	}
+	return strings.Join(datacenters, ", ")
+}
+
+// WaitInCart waits like WaitForAvailability, holding an empty cart for the
+// order of req while it waits, and returns the cart along with the
+// datacenter in stock. The cart is then ordered by setting
+// OrderRequest.CartID.
+//
+// The cart is created once, when the wait starts, and its expiry is
+// extended during the wait rather than creating a cart when stock appears.
+// It is tagged with a key of the order (CartMetadata.Wait), so that when the
+// process dies, or the wait fails, the next wait for the same order finds
+// and resumes it instead of leaking a cart; clean-carts deletes the carts
+// nobody resumes. Carts that expired or are older than Options.MaxStateAge
+// are not resumed. The ID of the cart is returned even when err is set.
+func (o *Orderer) WaitInCart(ctx context.Context, req OrderRequest, datacenters []string, interval, timeout time.Duration) (cartID, datacenter string, err error) {
+	req, err = o.prepare(req)
+	if err != nil {
+		return "", "", err
+	}
+	cartID, err = o.waitingCart(ctx, req)
+	if err != nil {
+		return "", "", err
+	}
+	datacenter, err = o.waitForAvailability(ctx, req, datacenters, interval, timeout, func() error {
+		return o.refreshCartIfExpiring(ctx, cartID)
+	})
+	return cartID, datacenter, err
+}
+
+// waitingCart returns the cart of an earlier wait for req, if one is left
+// and still empty, or creates one
+func (o *Orderer) waitingCart(ctx context.Context, req OrderRequest) (string, error) {
+	key := waitKey(req)
+	carts, err := o.ListCarts(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, cart := range carts {
+		if cart.Metadata == nil || cart.Metadata.Wait != key || cart.ReadOnly {
+			continue
+		}
+		if err := o.checkResumable(cart); errors.Is(err, ErrStaleCart) {
+			o.logger.Printf("Deleting cart %s of an earlier wait: %v", cart.CartID, err)
+			if err := o.deleteCart(ctx, cart.CartID); err != nil {
+				return "", err
+			}
+			continue
+		} else if err != nil {
+			o.logger.Printf("Not resuming cart %s of an earlier wait: %v", cart.CartID, err)
+			continue
+		}
+		// A cart the previous run started to fill cannot be trusted to
+		// hold what req orders
+		var itemIDs []int64
+		err := o.client.GetWithContext(ctx, "/order/cart/"+cart.CartID+"/item", &itemIDs)
+		var apiErr *ovh.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			o.logger.Printf("Not resuming cart %s of an earlier wait: %v", cart.CartID, ErrCartGone)
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("error listing items of cart %s: %w", cart.CartID, err)
+		}
+		if len(itemIDs) > 0 {
+			o.logger.Printf("Deleting cart %s of an earlier wait, which already has items", cart.CartID)
+			if err := o.deleteCart(ctx, cart.CartID); err != nil {
+				return "", err
+			}
+			continue
+		}
+		o.logger.Printf("Resuming cart %s, waiting for stock since %s", cart.CartID, cart.Metadata.Created.Format(time.RFC3339))
+		return cart.CartID, nil
+	}
+	return o.createCart(ctx, req, key)
+}
+
+// waitKey identifies the order of req among waiting carts: a hash of what
+// it orders, so that a wait for another plan, configuration or subsidiary
+// does not resume the cart
+func waitKey(req OrderRequest) string {
+	data, _ := json.Marshal(struct {
+		Subsidiary    string
+		PlanCode      string
+		Duration      string
+		PricingMode   string
+		Quantity      int
+		OS            string
+		Configuration []Configuration
+		Options       []Option
+	}{req.Subsidiary, req.PlanCode, req.Duration, req.PricingMode, req.Quantity, req.OS, req.Configuration, req.Options})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,336 @@
+package orderer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BaselineVersion is the current version of the price baseline format.
+const BaselineVersion = 1
+
+// ErrPriceDrift is returned, wrapped in a *PriceDriftError, when the prices
+// of a cart differ from its price baseline beyond the tolerance. The cart
+// is deleted.
+var ErrPriceDrift = errors.New("cart prices drifted from the baseline")
+
+// PriceBaseline is the approved prices of an order, written from the cart
+// built for the approval (see Orderer.PriceBaseline) and checked against
+// the cart at checkout (see OrderRequest.PriceBaseline), so that a price
+// OVH changed in between is not bought silently:
+//
+//	version: 1
+//	currency: USD
+//	total: "139.99"
+//	items:
+//	  - planCode: 24rise01-us
+//	    detailType: DURATION
+//	    price: "129.99"
+//	  - planCode: ram-64g-ecc-3200-24rise-us
+//	    detailType: DURATION
+//	    price: "10.00"
+//
+// Total is the price of the cart without tax, and each item the price of
+// the lines of the cart summary for a plan code and detail type, summed.
+// Amounts are decimal strings, compared exactly.
+type PriceBaseline struct {
+	Version  int            `yaml:"version" json:"version"`
+	Currency string         `yaml:"currency" json:"currency"`
+	Total    string         `yaml:"total" json:"total"`
+	Items    []BaselineItem `yaml:"items" json:"items"`
+}
+
+// BaselineItem is the price of the lines of a cart summary for PlanCode and
+// DetailType, e.g. DURATION for a period of the service or INSTALLATION for
+// its setup fees.
+type BaselineItem struct {
+	PlanCode   string `yaml:"planCode" json:"planCode"`
+	DetailType string `yaml:"detailType,omitempty" json:"detailType,omitempty"`
+	Price      string `yaml:"price" json:"price"`
+}
+
+// key identifies the item in a baseline
+func (item BaselineItem) key() string {
+	if item.DetailType == "" {
+		return item.PlanCode
+	}
+	return item.PlanCode + " " + item.DetailType
+}
+
+// LoadPriceBaseline reads and validates the price baseline at path of fsys.
+func LoadPriceBaseline(fsys FS, path string) (*PriceBaseline, error) {
+	data, err := fsOrDisk(fsys).ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading price baseline: %w", err)
+	}
+	baseline, err := ParsePriceBaseline(data)
+	if err != nil {
+		return nil, fmt.Errorf("price baseline %s: %w", path, err)
+	}
+	return baseline, nil
+}
+
+// ParsePriceBaseline parses and validates a price baseline, in YAML or JSON.
+func ParsePriceBaseline(data []byte) (*PriceBaseline, error) {
+	var baseline PriceBaseline
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&baseline); err != nil {
+		return nil, fmt.Errorf("invalid price baseline: %w", err)
+	}
+	if baseline.Version != BaselineVersion {
+		return nil, fmt.Errorf("unsupported price baseline version %d, expected %d", baseline.Version, BaselineVersion)
+	}
+	if baseline.Currency == "" {
+		return nil, fmt.Errorf("invalid price baseline: missing currency")
+	}
+	if _, err := ParseAmount(baseline.Total); err != nil {
+		return nil, fmt.Errorf("invalid price baseline total: %w", err)
+	}
+	if len(baseline.Items) == 0 {
+		return nil, fmt.Errorf("invalid price baseline: no items")
+	}
+	seen := make(map[string]bool, len(baseline.Items))
+	for _, item := range baseline.Items {
+		if item.PlanCode == "" {
+			return nil, fmt.Errorf("invalid price baseline: item without plan code")
+		}
+		if seen[item.key()] {
+			return nil, fmt.Errorf("invalid price baseline: duplicate item %s", item.key())
+		}
+		seen[item.key()] = true
+		if _, err := ParseAmount(item.Price); err != nil {
+			return nil, fmt.Errorf("invalid price of item %s: %w", item.key(), err)
+		}
+	}
+	return &baseline, nil
+}
+
+// PriceBaseline returns the price baseline of the cart cartID, e.g. built by
+// BuildCart for its approval.
+func (o *Orderer) PriceBaseline(ctx context.Context, cartID string) (*PriceBaseline, error) {
+	summary, err := o.summary(ctx, cartID)
+	if err != nil {
+		return nil, err
+	}
+	planCodes, err := o.itemPlanCodes(ctx, cartID)
+	if err != nil {
+		return nil, err
+	}
+	return baselineFromSummary(summary, planCodes)
+}
+
+// itemPlanCodes returns the plan codes of the items of the cart cartID, by
+// item ID
+func (o *Orderer) itemPlanCodes(ctx context.Context, cartID string) (map[int64]string, error) {
+	var itemIDs []int64
+	if err := o.client.GetWithContext(ctx, "/order/cart/"+cartID+"/item", &itemIDs); err != nil {
+		return nil, fmt.Errorf("error listing items of cart %s: %w", cartID, err)
+	}
+	planCodes := make(map[int64]string, len(itemIDs))
+	for _, itemID := range itemIDs {
+		var item cartItem
+		if err := o.client.GetWithContext(ctx, fmt.Sprintf("/order/cart/%s/item/%d", cartID, itemID), &item); err != nil {
+			return nil, fmt.Errorf("error fetching item %d of cart %s: %w", itemID, cartID, err)
+		}
+		planCodes[itemID] = item.Settings.PlanCode
+	}
+	return planCodes, nil
+}
+
+// baselineFromSummary returns the baseline of summary, whose lines are
+// matched to their plan code with planCodes. Items are in the order of
+// their first line.
+func baselineFromSummary(summary *CartSummary, planCodes map[int64]string) (*PriceBaseline, error) {
+	baseline := &PriceBaseline{
+		Version:  BaselineVersion,
+		Currency: summary.Prices.WithoutTax.CurrencyCode,
+		Total:    summary.Prices.WithoutTax.Value.String(),
+	}
+	var items []BaselineItem
+	sums := make(map[string]*big.Rat)
+	precisions := make(map[string]int)
+	for _, detail := range summary.Details {
+		planCode, ok := planCodes[detail.CartItemID]
+		if !ok || planCode == "" {
+			return nil, fmt.Errorf("line %q of the cart summary has no known item", detail.Description)
+		}
+		if detail.TotalPrice.CurrencyCode != baseline.Currency {
+			return nil, fmt.Errorf("line %q of the cart summary is in %s, the cart in %s", detail.Description, detail.TotalPrice.CurrencyCode, baseline.Currency)
+		}
+		amount, err := detail.TotalPrice.Amount()
+		if err != nil {
+			return nil, fmt.Errorf("invalid price of line %q: %w", detail.Description, err)
+		}
+		item := BaselineItem{PlanCode: planCode, DetailType: detail.DetailType}
+		if precision := decimals(detail.TotalPrice.Value.String()); precision > precisions[item.key()] {
+			precisions[item.key()] = precision
+		}
+		if sum, ok := sums[item.key()]; ok {
+			sum.Add(sum, amount)
+			continue
+		}
+		sums[item.key()] = amount
+		items = append(items, item)
+	}
+	for _, item := range items {
+		item.Price = sums[item.key()].FloatString(precisions[item.key()])
+		baseline.Items = append(baseline.Items, item)
+	}
+	return baseline, nil
+}
+
+// decimals returns the number of decimals of the amount s, at least 2 so
+// that sums are written as prices, and enough for an exponent to be
+// written exactly
+func decimals(s string) int {
+	n := 2
+	mantissa, exponent, _ := strings.Cut(strings.ToLower(s), "e")
+	if _, fraction, ok := strings.Cut(mantissa, "."); ok && len(fraction) > n {
+		n = len(fraction)
+	}
+	if e, err := strconv.Atoi(exponent); err == nil && e < 0 {
+		n -= e
+	}
+	return n
+}
+
+// priceTolerance is how far a price may drift from its baseline
+type priceTolerance struct {
+	amount  *big.Rat
+	percent bool
+}
+
+// parsePriceTolerance parses a tolerance: an amount (e.g. 0.50), or a
+// percentage of the baseline price (e.g. 2%). Empty means no drift at all.
+func parsePriceTolerance(s string) (priceTolerance, error) {
+	if s == "" {
+		return priceTolerance{amount: new(big.Rat)}, nil
+	}
+	value, percent := strings.CutSuffix(s, "%")
+	amount, err := ParseAmount(value)
+	if err != nil || amount.Sign() < 0 {
+		return priceTolerance{}, fmt.Errorf("invalid price tolerance %q: expected an amount (e.g. 0.50) or a percentage (e.g. 2%%)", s)
+	}
+	return priceTolerance{amount: amount, percent: percent}, nil
+}
+
+// ValidatePriceTolerance checks a price tolerance, see
+// OrderRequest.PriceTolerance.
+func ValidatePriceTolerance(s string) error {
+	_, err := parsePriceTolerance(s)
+	return err
+}
+
+// allows reports whether the tolerance allows current for baseline
+func (t priceTolerance) allows(baseline, current *big.Rat) bool {
+	diff := new(big.Rat).Sub(current, baseline)
+	limit := t.amount
+	if t.percent {
+		limit = new(big.Rat).Mul(new(big.Rat).Abs(baseline), t.amount)
+		limit.Quo(limit, big.NewRat(100, 1))
+	}
+	return diff.Abs(diff).Cmp(limit) <= 0
+}
+
+// PriceDrift is a price of a cart that drifted from its baseline. Baseline
+// is empty for an item missing from the baseline, and Current for an item
+// missing from the cart.
+type PriceDrift struct {
+	// Item is the plan code and detail type of the item, or "total".
+	Item     string
+	Baseline string
+	Current  string
+}
+
+func (d PriceDrift) String() string {
+	switch {
+	case d.Baseline == "":
+		return fmt.Sprintf("%s: %s, not in the baseline", d.Item, d.Current)
+	case d.Current == "":
+		return fmt.Sprintf("%s: %s in the baseline, not in the cart", d.Item, d.Baseline)
+	}
+	baseline, _ := ParseAmount(d.Baseline)
+	current, _ := ParseAmount(d.Current)
+	diff := new(big.Rat).Sub(current, baseline)
+	sign := ""
+	if diff.Sign() >= 0 {
+		sign = "+"
+	}
+	return fmt.Sprintf("%s: %s, baseline %s (%s%s)", d.Item, d.Current, d.Baseline, sign, diff.FloatString(2))
+}
+
+// PriceDriftError lists the prices of a cart that drifted from its
+// baseline beyond the tolerance.
+type PriceDriftError struct {
+	Currency  string
+	Tolerance string
+	Drifts    []PriceDrift
+}
+
+func (e *PriceDriftError) Error() string {
+	drifts := make([]string, len(e.Drifts))
+	for i, drift := range e.Drifts {
+		drifts[i] = drift.String()
+	}
+	tolerance := e.Tolerance
+	if tolerance == "" {
+		tolerance = "0"
+	}
+	return fmt.Sprintf("%v beyond a tolerance of %s (%s): %s", ErrPriceDrift, tolerance, e.Currency, strings.Join(drifts, "; "))
+}
+
+// Unwrap makes errors.Is(err, ErrPriceDrift) match.
+func (e *PriceDriftError) Unwrap() error {
+	return ErrPriceDrift
+}
+
+// checkBaseline compares the current prices of a cart with its baseline,
+// item by item and in total, and returns a *PriceDriftError listing those
+// differing beyond tolerance
+func checkBaseline(baseline, current *PriceBaseline, tolerance string) error {
+	t, err := parsePriceTolerance(tolerance)
+	if err != nil {
+		return err
+	}
+	if current.Currency != baseline.Currency {
+		return fmt.Errorf("%w: the cart is priced in %s, the baseline in %s", ErrPriceDrift, current.Currency, baseline.Currency)
+	}
+	var drifts []PriceDrift
+	compare := func(item, baselinePrice, currentPrice string) {
+		b, errB := ParseAmount(baselinePrice)
+		c, errC := ParseAmount(currentPrice)
+		if errB != nil || errC != nil || !t.allows(b, c) {
+			drifts = append(drifts, PriceDrift{Item: item, Baseline: baselinePrice, Current: currentPrice})
+		}
+	}
+	prices := make(map[string]string, len(current.Items))
+	for _, item := range current.Items {
+		prices[item.key()] = item.Price
+	}
+	for _, item := range baseline.Items {
+		price, ok := prices[item.key()]
+		if !ok {
+			drifts = append(drifts, PriceDrift{Item: item.key(), Baseline: item.Price})
+			continue
+		}
+		delete(prices, item.key())
+		compare(item.key(), item.Price, price)
+	}
+	for _, item := range current.Items {
+		if price, ok := prices[item.key()]; ok {
+			drifts = append(drifts, PriceDrift{Item: item.key(), Current: price})
+		}
+	}
+	compare("total", baseline.Total, current.Total)
+	if len(drifts) > 0 {
+		return &PriceDriftError{Currency: baseline.Currency, Tolerance: tolerance, Drifts: drifts}
+	}
+	return nil
+}
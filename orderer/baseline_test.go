@@ -0,0 +1,164 @@
+package orderer
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePriceBaseline(t *testing.T) {
+	const valid = `version: 1
+currency: USD
+total: "139.99"
+items:
+  - planCode: 24rise01-us
+    detailType: DURATION
+    price: "129.99"
+  - planCode: ram-64g-ecc-3200-24rise-us
+    detailType: DURATION
+    price: "10.00"
+`
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "valid", data: valid},
+		{name: "JSON", data: `{"version":1,"currency":"EUR","total":"10","items":[{"planCode":"24ska01","price":"10"}]}`},
+		{name: "version", data: strings.Replace(valid, "version: 1", "version: 2", 1), wantErr: "unsupported price baseline version 2"},
+		{name: "no currency", data: strings.Replace(valid, "currency: USD\n", "", 1), wantErr: "missing currency"},
+		{name: "total", data: strings.Replace(valid, `total: "139.99"`, `total: "139,99"`, 1), wantErr: "invalid price baseline total"},
+		{name: "no items", data: "version: 1\ncurrency: USD\ntotal: \"0\"\n", wantErr: "no items"},
+		{name: "duplicate item", data: strings.Replace(valid, "ram-64g-ecc-3200-24rise-us", "24rise01-us", 1), wantErr: "duplicate item 24rise01-us DURATION"},
+		{name: "price", data: strings.Replace(valid, `"10.00"`, `"ten"`, 1), wantErr: "invalid price of item ram-64g-ecc-3200-24rise-us DURATION"},
+		{name: "unknown field", data: valid + "approvedBy: alice\n", wantErr: "invalid price baseline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePriceBaseline([]byte(tt.data))
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// The lines of an item and detail type are summed, at their precision
+func TestBaselineFromSummary(t *testing.T) {
+	price := func(value string) Price {
+		return Price{Value: json.Number(value), CurrencyCode: "EUR"}
+	}
+	summary := &CartSummary{
+		Prices: SummaryPrices{WithoutTax: price("110.005")},
+		Details: []SummaryDetail{
+			{CartItemID: 1, Description: "server", DetailType: "DURATION", TotalPrice: price("99.99")},
+			{CartItemID: 1, Description: "server setup", DetailType: "INSTALLATION", TotalPrice: price("0")},
+			{CartItemID: 2, Description: "RAM", DetailType: "DURATION", TotalPrice: price("5.005")},
+			{CartItemID: 2, Description: "RAM, prorata", DetailType: "DURATION", TotalPrice: price("5")},
+		},
+	}
+	planCodes := map[int64]string{1: "24ska01", 2: "ram-32g"}
+
+	baseline, err := baselineFromSummary(summary, planCodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &PriceBaseline{
+		Version:  BaselineVersion,
+		Currency: "EUR",
+		Total:    "110.005",
+		Items: []BaselineItem{
+			{PlanCode: "24ska01", DetailType: "DURATION", Price: "99.99"},
+			{PlanCode: "24ska01", DetailType: "INSTALLATION", Price: "0.00"},
+			{PlanCode: "ram-32g", DetailType: "DURATION", Price: "10.005"},
+		},
+	}
+	if !reflect.DeepEqual(baseline, want) {
+		t.Errorf("baseline = %+v, want %+v", baseline, want)
+	}
+
+	delete(planCodes, 2)
+	if _, err := baselineFromSummary(summary, planCodes); err == nil {
+		t.Error("a line of an unknown item was priced")
+	}
+}
+
+func TestCheckBaseline(t *testing.T) {
+	baseline := &PriceBaseline{
+		Currency: "USD",
+		Total:    "139.99",
+		Items: []BaselineItem{
+			{PlanCode: "24rise01-us", DetailType: "DURATION", Price: "129.99"},
+			{PlanCode: "ram-64g", DetailType: "DURATION", Price: "10.00"},
+		},
+	}
+	current := func(server, total string, extra ...BaselineItem) *PriceBaseline {
+		return &PriceBaseline{
+			Currency: "USD",
+			Total:    total,
+			Items: append([]BaselineItem{
+				{PlanCode: "24rise01-us", DetailType: "DURATION", Price: server},
+				{PlanCode: "ram-64g", DetailType: "DURATION", Price: "10"},
+			}, extra...),
+		}
+	}
+	tests := []struct {
+		name      string
+		current   *PriceBaseline
+		tolerance string
+
+		wantErr    error
+		wantDrifts []string
+	}{
+		{name: "same", current: current("129.99", "139.99")},
+		{name: "drift", current: current("130.49", "140.49"), wantErr: ErrPriceDrift, wantDrifts: []string{"24rise01-us DURATION", "total"}},
+		{name: "within an amount", current: current("130.49", "140.49"), tolerance: "0.50"},
+		{name: "beyond an amount", current: current("130.50", "140.50"), tolerance: "0.50", wantErr: ErrPriceDrift, wantDrifts: []string{"24rise01-us DURATION", "total"}},
+		{name: "within a percentage", current: current("132.58", "142.58"), tolerance: "2%"},
+		{name: "cheaper beyond a percentage", current: current("127", "137"), tolerance: "2%", wantErr: ErrPriceDrift, wantDrifts: []string{"24rise01-us DURATION", "total"}},
+		{name: "item added", current: current("129.99", "139.99", BaselineItem{PlanCode: "24rise01-us", DetailType: "INSTALLATION", Price: "0"}), tolerance: "1", wantErr: ErrPriceDrift, wantDrifts: []string{"24rise01-us INSTALLATION"}},
+		{name: "item removed", current: &PriceBaseline{Currency: "USD", Total: "139.99"}, tolerance: "1", wantErr: ErrPriceDrift, wantDrifts: []string{"24rise01-us DURATION", "ram-64g DURATION"}},
+		{name: "currency", current: &PriceBaseline{Currency: "EUR", Total: "139.99", Items: baseline.Items}, wantErr: ErrPriceDrift},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBaseline(baseline, tt.current, tt.tolerance)
+			if tt.wantErr == nil && err != nil || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			var driftErr *PriceDriftError
+			var drifts []string
+			if errors.As(err, &driftErr) {
+				for _, drift := range driftErr.Drifts {
+					drifts = append(drifts, drift.Item)
+				}
+			}
+			if !reflect.DeepEqual(drifts, tt.wantDrifts) {
+				t.Errorf("drifts = %v, want %v", drifts, tt.wantDrifts)
+			}
+		})
+	}
+
+	if err := checkBaseline(baseline, baseline, "-1"); err == nil || errors.Is(err, ErrPriceDrift) {
+		t.Errorf("err = %v, want an invalid tolerance", err)
+	}
+}
+
+func TestPriceDriftString(t *testing.T) {
+	tests := []struct {
+		drift PriceDrift
+		want  string
+	}{
+		{drift: PriceDrift{Item: "total", Baseline: "139.99", Current: "140.49"}, want: "total: 140.49, baseline 139.99 (+0.50)"},
+		{drift: PriceDrift{Item: "total", Baseline: "139.99", Current: "137"}, want: "total: 137, baseline 139.99 (-2.99)"},
+		{drift: PriceDrift{Item: "ram-64g DURATION", Current: "10"}, want: "ram-64g DURATION: 10, not in the baseline"},
+		{drift: PriceDrift{Item: "ram-64g DURATION", Baseline: "10"}, want: "ram-64g DURATION: 10 in the baseline, not in the cart"},
+	}
+	for _, tt := range tests {
+		if got := tt.drift.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package orderer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BatchEntry is one order of a batch file, identified by the id the file
+// gives it so that its outcome can be matched back to the file.
+type BatchEntry struct {
+	ID      string
+	Request OrderRequest
+}
+
+// batchFile is the content of a YAML or JSON batch file
+type batchFile struct {
+	Version int          `yaml:"version"`
+	Orders  []batchOrder `yaml:"orders"`
+}
+
+// batchOrder is an order of a batch file: a configuration file without its
+// version, and an id
+type batchOrder struct {
+	ID     string `yaml:"id"`
+	Config `yaml:",inline"`
+}
+
+// LoadBatch reads and validates the batch file at path of fsys: a CSV file
+// when its name ends with .csv, a YAML or JSON file otherwise, see
+// ParseBatch and ParseBatchCSV.
+func LoadBatch(fsys FS, path string) ([]BatchEntry, error) {
+	data, err := fsOrDisk(fsys).ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading batch: %w", err)
+	}
+	var entries []BatchEntry
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		entries, err = ParseBatchCSV(data)
+	} else {
+		entries, err = ParseBatch(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("batch %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// ParseBatch parses and validates a YAML or JSON batch file, a list of
+// distinct orders each written as a configuration file without its version:
+//
+//	version: 1
+//	orders:
+//	  - id: storage-hil
+//	    plan: 24rise01-us
+//	    configuration:
+//	      - label: dedicated_datacenter
+//	        value: hil
+//	  - id: compute-vin
+//	    plan: 24adv01-us
+//	    options: [ram-64g-ecc-3200-24adv-us]
+//
+// Entries without an id are given their position in the file, from 1.
+func ParseBatch(data []byte) ([]BatchEntry, error) {
+	var file batchFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid batch: %w", err)
+	}
+	version := &Config{Version: file.Version}
+	if err := version.checkVersion(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]BatchEntry, len(file.Orders))
+	for i, order := range file.Orders {
+		id := batchID(order.ID, i)
+		if order.Version != 0 && order.Version != file.Version {
+			return nil, fmt.Errorf("order %s: version %d differs from the version %d of the file", id, order.Version, file.Version)
+		}
+		config := order.Config
+		config.Version = file.Version
+		if err := config.validate(); err != nil {
+			return nil, fmt.Errorf("order %s: %w", id, err)
+		}
+		entries[i] = BatchEntry{ID: id, Request: config.OrderRequest()}
+	}
+	return checkBatch(entries)
+}
+
+// ParseBatchCSV parses and validates a CSV batch file, one order per row.
+// The header row names the columns: id, and the keys of the -set overrides
+// of a configuration (plan, duration, os, datacenter, configuration.<label>,
+// ...). Options are separated by ; in their column, and empty cells are left
+// to the defaults:
+//
+//	id,plan,datacenter,options
+//	storage-hil,24rise01-us,hil,softraid-2x512nvme-24rise-us;ram-32g-ecc-3200-24rise-us
+//	compute-vin,24adv01-us,vin,
+//
+// Rows without an id are given their position in the file, from 1.
+func ParseBatchCSV(data []byte) ([]BatchEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch: missing header row: %w", err)
+	}
+	idColumn := -1
+	for i, column := range header {
+		header[i] = strings.TrimSpace(column)
+		if header[i] == "id" {
+			idColumn = i
+		}
+	}
+
+	var entries []BatchEntry
+	for row := 0; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid batch: %w", err)
+		}
+		id := ""
+		if idColumn >= 0 {
+			id = strings.TrimSpace(record[idColumn])
+		}
+		id = batchID(id, row)
+		config := &Config{Version: ConfigVersion}
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if i == idColumn || value == "" {
+				continue
+			}
+			if header[i] == "options" {
+				for _, option := range strings.Split(value, ";") {
+					if option = strings.TrimSpace(option); option != "" {
+						config.Options = append(config.Options, ConfigOption{PlanCode: option})
+					}
+				}
+				continue
+			}
+			if err := config.Set(header[i] + "=" + value); err != nil {
+				return nil, fmt.Errorf("order %s: %w", id, err)
+			}
+		}
+		if err := config.validate(); err != nil {
+			return nil, fmt.Errorf("order %s: %w", id, err)
+		}
+		entries = append(entries, BatchEntry{ID: id, Request: config.OrderRequest()})
+	}
+	return checkBatch(entries)
+}
+
+// batchID returns id, or the position i of its entry, from 1, when it is empty
+func batchID(id string, i int) string {
+	if id == "" {
+		return strconv.Itoa(i + 1)
+	}
+	return id
+}
+
+// checkBatch rejects empty batches and duplicate ids, which would make the
+// report ambiguous
+func checkBatch(entries []BatchEntry) ([]BatchEntry, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("invalid batch: no orders")
+	}
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if seen[entry.ID] {
+			return nil, fmt.Errorf("invalid batch: duplicate id %q", entry.ID)
+		}
+		seen[entry.ID] = true
+	}
+	return entries, nil
+}
+
+// ValidateBatch checks every entry as Order would before creating anything,
+// and reports all the invalid entries at once by id.
+func (o *Orderer) ValidateBatch(entries []BatchEntry) error {
+	var errs []error
+	for _, entry := range entries {
+		if _, err := o.prepare(entry.Request); err != nil {
+			errs = append(errs, fmt.Errorf("order %s: %w", entry.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// OrderBatch validates every entry with ValidateBatch, then orders them with
+// OrderBulk, so that a mistake in one entry is found before any order of the
+// batch is placed. Results are in the order of entries: the id of a result
+// is entries[result.Index].ID.
+func (o *Orderer) OrderBatch(ctx context.Context, entries []BatchEntry, opts BulkOptions) ([]BulkResult, error) {
+	if err := o.ValidateBatch(entries); err != nil {
+		return nil, err
+	}
+	reqs := make([]OrderRequest, len(entries))
+	for i, entry := range entries {
+		reqs[i] = entry.Request
+	}
+	return o.OrderBulk(ctx, reqs, opts), nil
+}
@@ -0,0 +1,106 @@
+package orderer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseBatch(t *testing.T) {
+	entries, err := ParseBatch([]byte(`version: 1
+orders:
+  - id: storage-hil
+    plan: 24rise01-us
+    configuration:
+      - label: dedicated_datacenter
+        value: hil
+  - plan: 24adv01-us
+    options: [ram-64g-ecc-3200-24adv-us]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("%d entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.ID != "storage-hil" || e.Request.PlanCode != "24rise01-us" || len(e.Request.Configuration) != 1 {
+		t.Errorf("entry 0 = %+v", e)
+	}
+	// Entries without an id are numbered from 1
+	if e := entries[1]; e.ID != "2" || len(e.Request.Options) != 1 {
+		t.Errorf("entry 1 = %+v", e)
+	}
+
+	invalid := []struct {
+		name string
+		data string
+	}{
+		{name: "no orders", data: "version: 1\norders: []\n"},
+		{name: "duplicate id", data: "version: 1\norders:\n  - {id: a, plan: 24rise01}\n  - {id: a, plan: 24rise02}\n"},
+		{name: "id of a position", data: "version: 1\norders:\n  - {plan: 24rise01}\n  - {id: \"1\", plan: 24rise02}\n"},
+		{name: "unknown field", data: "version: 1\norders:\n  - {plan: 24rise01, datacentre: gra}\n"},
+		{name: "version of an order", data: "version: 1\norders:\n  - {version: 2, plan: 24rise01}\n"},
+		{name: "unsupported version", data: "version: 99\norders:\n  - {plan: 24rise01}\n"},
+		{name: "invalid order", data: "version: 1\norders:\n  - {id: a}\n"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseBatch([]byte(tt.data)); err == nil {
+				t.Error("batch accepted")
+			}
+		})
+	}
+}
+
+func TestParseBatchCSV(t *testing.T) {
+	entries, err := ParseBatchCSV([]byte(`id,plan,datacenter,options
+storage-hil,24rise01-us,hil,softraid-2x512nvme-24rise-us; ram-32g-ecc-3200-24rise-us
+,24adv01-us,vin,
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("%d entries, want 2", len(entries))
+	}
+	first := entries[0]
+	if first.ID != "storage-hil" || first.Request.PlanCode != "24rise01-us" || len(first.Request.Options) != 2 {
+		t.Errorf("entry 0 = %+v", first)
+	}
+	if datacenter, _ := labelValue(first.Request.Configuration, labelDatacenter); datacenter != "hil" {
+		t.Errorf("entry 0 datacenter = %q, want hil", datacenter)
+	}
+	if second := entries[1]; second.ID != "2" || len(second.Request.Options) != 0 {
+		t.Errorf("entry 1 = %+v", second)
+	}
+
+	if _, err := ParseBatchCSV([]byte("id,plan,colour\na,24rise01,blue\n")); err == nil {
+		t.Error("unknown column accepted")
+	}
+}
+
+// A batch with an invalid entry places no order at all, and every invalid
+// entry is reported
+func TestOrderBatchValidatesFirst(t *testing.T) {
+	o := New(&stubClient{answer: func(method, path string, body interface{}) (interface{}, error) {
+		t.Errorf("call %s %s made", method, path)
+		return nil, fmt.Errorf("unexpected call %s %s", method, path)
+	}}, Options{Clock: newFakeClock()})
+	entries := []BatchEntry{
+		{ID: "valid", Request: OrderRequest{Subsidiary: "FR", PlanCode: "24ska01"}},
+		{ID: "bad-duration", Request: OrderRequest{Subsidiary: "FR", PlanCode: "24ska01", Duration: "one month"}},
+		{ID: "bad-subsidiary", Request: OrderRequest{Subsidiary: "XX", PlanCode: "24ska01"}},
+	}
+	results, err := o.OrderBatch(t.Context(), entries, BulkOptions{Workers: 2})
+	if err == nil {
+		t.Fatalf("batch ordered: %+v", results)
+	}
+	for _, id := range []string{"bad-duration", "bad-subsidiary"} {
+		if !strings.Contains(err.Error(), "order "+id+":") {
+			t.Errorf("err = %v, want entry %s reported", err, id)
+		}
+	}
+	if strings.Contains(err.Error(), "order valid:") {
+		t.Errorf("err = %v, reports the valid entry", err)
+	}
+}
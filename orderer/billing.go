@@ -0,0 +1,88 @@
+package orderer
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// oneTimeDetails are the detail types of the cart summary lines billed once,
+// with the first invoice only: installation and setup fees, deposits,
+// transfers and the discounts of the first period. Lines of other types,
+// such as DURATION and RENEW, are billed again at each renewal.
+var oneTimeDetails = map[string]bool{
+	"INSTALLATION": true,
+	"CREATION":     true,
+	"DELIVERY":     true,
+	"CAUTION":      true,
+	"TRANSFER":     true,
+	"SWITCH":       true,
+	"GIFT":         true,
+	"VOUCHER":      true,
+	"REFUND":       true,
+}
+
+// BillingSplit separates what a cart costs at checkout from what it costs
+// once renewed.
+type BillingSplit struct {
+	// FirstInvoice is what the checkout charges, tax included.
+	FirstInvoice Price
+
+	// OneTime is the part of FirstInvoice billed once, such as installation
+	// fees, and Recurring the part billed again every Duration. Summary lines
+	// are priced without tax, so both have the tax rate of the whole cart
+	// applied.
+	OneTime   Price
+	Recurring Price
+	Duration  string
+
+	// Monthly is Recurring spread over the Months of Duration. Both are
+	// empty when Duration is not a whole number of months.
+	Monthly Price
+	Months  int
+}
+
+// SplitBilling splits the price of summary, a cart whose server is billed
+// for duration (e.g. P1M), between its one-time fees and its recurring
+// charges, from the detail type of its lines. A first period OVH prorates is
+// part of the first invoice only, so Recurring is what the following
+// invoices charge.
+func SplitBilling(summary *CartSummary, duration string) (*BillingSplit, error) {
+	withTax, err := summary.Prices.WithTax.Amount()
+	if err != nil {
+		return nil, fmt.Errorf("invalid cart price: %w", err)
+	}
+	withoutTax, err := summary.Prices.WithoutTax.Amount()
+	if err != nil {
+		return nil, fmt.Errorf("invalid cart price: %w", err)
+	}
+	oneTime, recurring := new(big.Rat), new(big.Rat)
+	for _, detail := range summary.Details {
+		amount, err := detail.TotalPrice.Amount()
+		if err != nil {
+			return nil, fmt.Errorf("invalid price of %s: %w", detail.Description, err)
+		}
+		if oneTimeDetails[detail.DetailType] {
+			oneTime.Add(oneTime, amount)
+		} else {
+			recurring.Add(recurring, amount)
+		}
+	}
+	if withoutTax.Sign() != 0 {
+		rate := new(big.Rat).Quo(withTax, withoutTax)
+		oneTime.Mul(oneTime, rate)
+		recurring.Mul(recurring, rate)
+	}
+
+	currency := summary.Prices.WithTax.CurrencyCode
+	split := &BillingSplit{
+		FirstInvoice: summary.Prices.WithTax,
+		OneTime:      ratPrice(oneTime, currency),
+		Recurring:    ratPrice(recurring, currency),
+		Duration:     duration,
+	}
+	if months, ok := durationMonths(duration); ok {
+		split.Months = months
+		split.Monthly = ratPrice(new(big.Rat).Quo(recurring, big.NewRat(int64(months), 1)), currency)
+	}
+	return split, nil
+}
@@ -0,0 +1,167 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// BulkResult is the outcome of one of the orders of OrderBulk.
+type BulkResult struct {
+	// Index is the position of the request in the requests of OrderBulk.
+	Index   int
+	Request OrderRequest
+
+	// Result is set as soon as the cart is created, even when Err is set.
+	Result *OrderResult
+	Err    error
+
+	// Abandoned is set when the order did not complete within
+	// BulkOptions.OrderTimeout. Its cart, if not checked out, is deleted.
+	Abandoned bool
+}
+
+// BulkOptions configures OrderBulk.
+type BulkOptions struct {
+	// Workers is the number of orders run at a time. Defaults to 1.
+	Workers int
+
+	// MaxPerDatacenter, when set, is the number of orders run at a time for
+	// the same dedicated_datacenter, so that concurrent orders do not all
+	// compete for the scarce stock of one datacenter. Orders without a
+	// datacenter are not limited.
+	MaxPerDatacenter int
+
+	// OrderTimeout, when set, bounds each order, so that a stuck order is
+	// abandoned while the others proceed. The context of OrderBulk still
+	// bounds the whole run.
+	OrderTimeout time.Duration
+}
+
+// bulkQueue hands out the requests of a bulk run in order, skipping those
+// whose datacenter already runs MaxPerDatacenter orders
+type bulkQueue struct {
+	reqs []OrderRequest
+	max  int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []int
+	running map[string]int
+}
+
+func newBulkQueue(reqs []OrderRequest, max int) *bulkQueue {
+	q := &bulkQueue{reqs: reqs, max: max, running: make(map[string]int)}
+	q.cond = sync.NewCond(&q.mu)
+	for i := range reqs {
+		q.pending = append(q.pending, i)
+	}
+	return q
+}
+
+// datacenter returns the datacenter the request at index i is limited by
+func (q *bulkQueue) datacenter(i int) string {
+	datacenter, _ := labelValue(q.reqs[i].Configuration, labelDatacenter)
+	return datacenter
+}
+
+// next waits for a pending request whose datacenter has a free slot and
+// returns its index, or false once all requests have been handed out
+func (q *bulkQueue) next() (int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.pending) > 0 {
+		for n, i := range q.pending {
+			datacenter := q.datacenter(i)
+			if q.max > 0 && datacenter != "" && q.running[datacenter] >= q.max {
+				continue
+			}
+			q.pending = append(q.pending[:n], q.pending[n+1:]...)
+			q.running[datacenter]++
+			return i, true
+		}
+		q.cond.Wait()
+	}
+	return 0, false
+}
+
+// done releases the slot of the request at index i
+func (q *bulkQueue) done(i int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.running[q.datacenter(i)]--
+	q.cond.Broadcast()
+}
+
+// bulkOrder places the order of the request at index i of a bulk run,
+// abandoning it after timeout when set
+func (o *Orderer) bulkOrder(ctx context.Context, i int, req OrderRequest, timeout time.Duration) BulkResult {
+	orderCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		orderCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	result, err := o.Order(orderCtx, req)
+	bulkResult := BulkResult{Index: i, Request: req, Result: result, Err: err}
+	// Only the per-order deadline abandons an order, not the overall one
+	if timeout <= 0 || !errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+		return bulkResult
+	}
+
+	bulkResult.Abandoned = true
+	o.logger.Printf("Order %d abandoned after %s", i+1, timeout)
+	if result != nil && result.CartID != "" && result.OrderID == "" {
+		// The cart was not checked out, nothing was bought
+		if err := o.deleteCart(ctx, result.CartID); err != nil {
+			o.logger.Printf("Error deleting the cart of abandoned order %d: %v", i+1, err)
+		}
+	}
+	return bulkResult
+}
+
+// OrderBulk places an order for each of reqs, running at most opts.Workers
+// of them at a time. It returns the outcome of every request, in the order
+// of reqs whatever the order in which they complete; an order failing does
+// not stop the others. Options.OnEvent is called from several goroutines
+// and must be safe for concurrent use.
+func (o *Orderer) OrderBulk(ctx context.Context, reqs []OrderRequest, opts BulkOptions) []BulkResult {
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(reqs) {
+		workers = len(reqs)
+	}
+
+	queue := newBulkQueue(reqs, opts.MaxPerDatacenter)
+	done := make(chan BulkResult)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i, ok := queue.next()
+				if !ok {
+					return
+				}
+				result := o.bulkOrder(ctx, i, reqs[i], opts.OrderTimeout)
+				queue.done(i)
+				done <- result
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Only this goroutine writes the results, each at the index of its request
+	results := make([]BulkResult, len(reqs))
+	for result := range done {
+		results[result.Index] = result
+	}
+	return results
+}
@@ -0,0 +1,146 @@
+package orderer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// Run with -race: the orders complete in the reverse of their order, from
+// several workers, and each result must land at the index of its request
+func TestOrderBulkResultsByIndex(t *testing.T) {
+	const count = 8
+	client := &stubClient{answer: func(method, path string, body interface{}) (interface{}, error) {
+		if method+" "+path != "POST /order/cart" {
+			// Every order fails once its cart is created
+			return nil, &ovh.APIError{Code: http.StatusBadRequest, Message: "rejected " + path}
+		}
+		description := body.(map[string]interface{})["description"].(string)
+		i, err := strconv.Atoi(strings.TrimPrefix(strings.Fields(description)[0], "order-"))
+		if err != nil {
+			return nil, err
+		}
+		time.Sleep(time.Duration(count-i) * time.Millisecond)
+		return map[string]interface{}{"cartId": fmt.Sprintf("cart-%d", i)}, nil
+	}}
+	o := New(client, Options{Clock: newFakeClock()})
+
+	var reqs []OrderRequest
+	for i := 0; i < count; i++ {
+		reqs = append(reqs, OrderRequest{Subsidiary: "FR", PlanCode: "24ska01", Description: fmt.Sprintf("order-%d", i)})
+	}
+	results := o.OrderBulk(context.Background(), reqs, BulkOptions{Workers: 4})
+	if len(results) != count {
+		t.Fatalf("%d results, want %d", len(results), count)
+	}
+	for i, result := range results {
+		if result.Index != i || result.Request.Description != reqs[i].Description {
+			t.Errorf("result %d is of request %d (%s)", i, result.Index, result.Request.Description)
+		}
+		if result.Result == nil {
+			t.Errorf("result %d has no cart", i)
+		} else if want := fmt.Sprintf("cart-%d", i); result.Result.CartID != want {
+			t.Errorf("result %d: cart = %s, want %s", i, result.Result.CartID, want)
+		}
+		var apiErr *ovh.APIError
+		if !errors.As(result.Err, &apiErr) || apiErr.Code != http.StatusBadRequest {
+			t.Errorf("result %d: err = %v, want a 400", i, result.Err)
+		}
+	}
+}
+
+// Run with -race: no more than MaxPerDatacenter orders of a datacenter are
+// run at a time, however many workers are free
+func TestOrderBulkMaxPerDatacenter(t *testing.T) {
+	const perDatacenter = 2
+	var mu sync.Mutex
+	running := make(map[string]int)
+	maxRunning := make(map[string]int)
+	client := &stubClient{answer: func(method, path string, body interface{}) (interface{}, error) {
+		if method+" "+path != "POST /order/cart" {
+			return nil, &ovh.APIError{Code: http.StatusBadRequest, Message: "rejected " + path}
+		}
+		datacenter := strings.Fields(body.(map[string]interface{})["description"].(string))[0]
+		mu.Lock()
+		running[datacenter]++
+		maxRunning[datacenter] = max(maxRunning[datacenter], running[datacenter])
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running[datacenter]--
+		mu.Unlock()
+		return map[string]interface{}{"cartId": "cart-" + datacenter}, nil
+	}}
+	o := New(client, Options{Clock: newFakeClock()})
+
+	var reqs []OrderRequest
+	for i := 0; i < 12; i++ {
+		datacenter := []string{"rbx", "gra"}[i%2]
+		reqs = append(reqs, OrderRequest{
+			Subsidiary:    "FR",
+			PlanCode:      "24ska01",
+			Description:   datacenter,
+			Configuration: []Configuration{{Label: labelDatacenter, Value: datacenter}},
+		})
+	}
+	results := o.OrderBulk(context.Background(), reqs, BulkOptions{Workers: 8, MaxPerDatacenter: perDatacenter})
+	for i, result := range results {
+		if result.Result == nil {
+			t.Errorf("result %d has no cart: %v", i, result.Err)
+		}
+	}
+	for _, datacenter := range []string{"rbx", "gra"} {
+		if got := maxRunning[datacenter]; got != perDatacenter {
+			t.Errorf("%d orders at a time in %s, want %d", got, datacenter, perDatacenter)
+		}
+	}
+}
+
+// An order outliving OrderTimeout is abandoned and its cart deleted, and the
+// next order still runs
+func TestOrderBulkOrderTimeout(t *testing.T) {
+	var calls callCounter
+	o := newTestServer(t, Options{}, func(w http.ResponseWriter, r *http.Request) {
+		calls.add(r)
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/order/cart":
+			var body struct{ Description string }
+			json.NewDecoder(r.Body).Decode(&body)
+			fmt.Fprintf(w, `{"cartId":%q}`, "cart-"+strings.Fields(body.Description)[0])
+		case r.Method == http.MethodGet && r.URL.Path == "/order/cart":
+			fmt.Fprint(w, "[]")
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusOK)
+		case strings.HasPrefix(r.URL.Path, "/order/cart/cart-stuck"):
+			// Hang until the order is abandoned
+			<-r.Context().Done()
+		default:
+			writeAPIError(w, http.StatusBadRequest, "rejected "+r.URL.Path)
+		}
+	})
+
+	reqs := []OrderRequest{
+		{Subsidiary: "FR", PlanCode: "24ska01", Description: "stuck"},
+		{Subsidiary: "FR", PlanCode: "24ska01", Description: "other"},
+	}
+	results := o.OrderBulk(context.Background(), reqs, BulkOptions{OrderTimeout: 100 * time.Millisecond})
+	if !results[0].Abandoned || !errors.Is(results[0].Err, context.DeadlineExceeded) {
+		t.Errorf("stuck order: abandoned = %t, err = %v, want abandoned after its timeout", results[0].Abandoned, results[0].Err)
+	}
+	if calls.get("DELETE /order/cart/cart-stuck") == 0 {
+		t.Error("the cart of the abandoned order was not deleted")
+	}
+	var apiErr *ovh.APIError
+	if results[1].Abandoned || !errors.As(results[1].Err, &apiErr) {
+		t.Errorf("other order: abandoned = %t, err = %v, want a 400", results[1].Abandoned, results[1].Err)
+	}
+}
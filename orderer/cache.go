@@ -0,0 +1,92 @@
+package orderer
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// CatalogCache caches the responses of the discovery calls (plans, options,
+// plan descriptions and specs, availabilities) on disk, so that repeated
+// discovery does not refetch the catalog each run.
+type CatalogCache struct {
+	// Dir holds a file per cached response.
+	Dir string
+
+	// TTL is how long a cached response is used before being refetched. A
+	// zero TTL disables the cache.
+	TTL time.Duration
+
+	// Refresh refetches the responses even when their cached copy is fresh,
+	// and caches them again.
+	Refresh bool
+
+	// FS holds the files of Dir. Defaults to the disk.
+	FS FS
+}
+
+// cachedResponse is the content of a cache file
+type cachedResponse struct {
+	Fetched time.Time       `json:"fetched"`
+	Data    json.RawMessage `json:"data"`
+}
+
+var unsafeCacheKey = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// path returns the cache file of key
+func (c *CatalogCache) path(key string) string {
+	return filepath.Join(c.Dir, unsafeCacheKey.ReplaceAllString(key, "_")+".json")
+}
+
+// load decodes the cached response of key into v, if it is fresh at now
+func (c *CatalogCache) load(key string, v interface{}, now time.Time) bool {
+	data, err := fsOrDisk(c.FS).ReadFile(c.path(key))
+	if err != nil {
+		return false
+	}
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil || now.Sub(cached.Fetched) > c.TTL {
+		return false
+	}
+	return json.Unmarshal(cached.Data, v) == nil
+}
+
+// save caches v as the response of key fetched at now
+func (c *CatalogCache) save(key string, v interface{}, now time.Time) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if data, err = json.Marshal(cachedResponse{Fetched: now, Data: data}); err != nil {
+		return err
+	}
+	fsys := fsOrDisk(c.FS)
+	if err := fsys.MkdirAll(c.Dir, 0o700); err != nil {
+		return err
+	}
+	return fsys.WriteFile(c.path(key), data, 0o600)
+}
+
+// cached decodes the cached response of key into v when Options.CatalogCache
+// has a fresh one, and otherwise calls fetch to fill v and caches it. The
+// key is scoped to the endpoint of the Orderer.
+func (o *Orderer) cached(key string, v interface{}, fetch func() error) error {
+	c := o.opts.CatalogCache
+	if c == nil || c.TTL <= 0 {
+		return fetch()
+	}
+	key = endpointName(o.opts.Endpoint) + "-" + key
+	now := o.clock.Now()
+	if !c.Refresh && c.load(key, v, now) {
+		o.debugf("Using the cached %s", key)
+		return nil
+	}
+	if err := fetch(); err != nil {
+		return err
+	}
+	if err := c.save(key, v, now); err != nil {
+		o.logger.Printf("Error caching %s: %v", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package orderer
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCatalogCache(t *testing.T) {
+	cache := &CatalogCache{Dir: t.TempDir(), TTL: time.Hour}
+	clock := newFakeClock()
+	o := New(&stubClient{}, Options{Clock: clock, CatalogCache: cache})
+
+	fetches := 0
+	get := func() string {
+		var v string
+		err := o.cached("catalog/FR", &v, func() error {
+			fetches++
+			v = "catalog " + strconv.Itoa(fetches)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	if got := get(); got != "catalog 1" {
+		t.Errorf("first = %q, want catalog 1", got)
+	}
+	if entries, err := os.ReadDir(cache.Dir); err != nil || len(entries) != 1 {
+		t.Errorf("cache dir has %d files (%v), want 1", len(entries), err)
+	}
+	if got := get(); got != "catalog 1" || fetches != 1 {
+		t.Errorf("fresh = %q after %d fetches, want the cached catalog 1", got, fetches)
+	}
+	<-clock.After(2 * time.Hour)
+	if got := get(); got != "catalog 2" {
+		t.Errorf("stale = %q, want catalog 2", got)
+	}
+	cache.Refresh = true
+	if got := get(); got != "catalog 3" {
+		t.Errorf("refreshed = %q, want catalog 3", got)
+	}
+}
@@ -0,0 +1,722 @@
+package orderer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// createCart creates a new cart for req and assigns it to the logged-in user.
+// The cart description carries the metadata identifying carts of this tool,
+// with wait as CartMetadata.Wait.
+func (o *Orderer) createCart(ctx context.Context, req OrderRequest, wait string) (string, error) {
+	var cart struct {
+		CartID string `json:"cartId"`
+	}
+	now := o.clock.Now()
+	expireDate := now.Add(cartLifetime).Format(time.RFC3339)
+	description := withMetadata(req.Description, CartMetadata{
+		Version: Version,
+		Created: now,
+		RunID:   req.RunID,
+		Wait:    wait,
+
+		CustomerReference: req.CustomerReference,
+	})
+	// A cart created by a call that failed is found by its description,
+	// which holds its creation time
+	postCtx := withResendCheck(ctx, func(ctx context.Context) (bool, error) {
+		carts, err := o.ListCarts(ctx)
+		if err != nil {
+			return false, err
+		}
+		for _, created := range carts {
+			if created.Metadata != nil && withMetadata(created.Description, *created.Metadata) == description {
+				cart.CartID = created.CartID
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	post := func(expireDate string) error {
+		return o.client.PostWithContext(postCtx, "/order/cart", map[string]interface{}{
+			"ovhSubsidiary": req.Subsidiary,
+			"description":   description,
+			"expire":        expireDate,
+		}, &cart)
+	}
+	err := post(expireDate)
+	if isExpireRejected(err) {
+		// The local clock is behind: retry once with the time of the API
+		serverNow, timeErr := o.serverTime(ctx)
+		if timeErr != nil {
+			return "", fmt.Errorf("error creating cart: %w (reading the API time to retry: %v)", err, timeErr)
+		}
+		o.logger.Printf("Cart expiry %s rejected, the local clock is %s off the API clock; retrying with the API time", expireDate, now.Sub(serverNow).Round(time.Second))
+		err = post(serverNow.Add(cartLifetime).Format(time.RFC3339))
+	}
+	if err != nil {
+		return "", fmt.Errorf("error creating cart: %w", err)
+	}
+	if cart.CartID == "" {
+		return "", fmt.Errorf("missing cartId in the created cart")
+	}
+	cartID := cart.CartID
+	o.logger.Printf("Created Cart with ID: %s", cartID)
+	return cartID, o.assignCart(ctx, cartID)
+}
+
+// isExpireRejected reports whether err is the rejection of the expiry of a
+// new cart, which happens when it is in the past for the API
+func isExpireRejected(err error) bool {
+	var apiErr *ovh.APIError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest && strings.Contains(strings.ToLower(apiErr.Message), "expire")
+}
+
+// serverTime returns the current time of the API
+func (o *Orderer) serverTime(ctx context.Context) (time.Time, error) {
+	var timestamp int64
+	if err := o.client.GetWithContext(ctx, "/auth/time", &timestamp); err != nil {
+		return time.Time{}, fmt.Errorf("error fetching the API time: %w", err)
+	}
+	return time.Unix(timestamp, 0), nil
+}
+
+// ErrCartNotAssigned is returned when a cart is still not among the carts of
+// the logged-in user after being assigned to it, e.g. because the consumer
+// key is scoped to another account.
+var ErrCartNotAssigned = errors.New("cart is not assigned to the logged-in user")
+
+// ownsCart reports whether cartID is among the carts of the logged-in user
+func (o *Orderer) ownsCart(ctx context.Context, cartID string) (bool, error) {
+	var cartIDs []string
+	if err := o.client.GetWithContext(ctx, "/order/cart", &cartIDs); err != nil {
+		return false, fmt.Errorf("error listing carts: %w", err)
+	}
+	return contains(cartIDs, cartID), nil
+}
+
+// assignCart binds cartID to the logged-in user, unless it already belongs
+// to them, then verifies that the cart is listed and readable for them
+func (o *Orderer) assignCart(ctx context.Context, cartID string) error {
+	owned, err := o.ownsCart(ctx, cartID)
+	if err != nil {
+		return err
+	}
+	if owned {
+		o.logger.Printf("Cart already belongs to the logged-in user, not assigning it.")
+		return nil
+	}
+
+	postCtx := withResendCheck(ctx, func(ctx context.Context) (bool, error) {
+		return o.ownsCart(ctx, cartID)
+	})
+	if err := o.client.PostWithContext(postCtx, "/order/cart/"+cartID+"/assign", nil, nil); err != nil {
+		return fmt.Errorf("error assigning cart: %w", err)
+	}
+	if owned, err = o.ownsCart(ctx, cartID); err != nil {
+		return err
+	}
+	if !owned {
+		return fmt.Errorf("%w: %s is not listed after assign", ErrCartNotAssigned, cartID)
+	}
+	if _, err := o.GetCart(ctx, cartID); err != nil {
+		return fmt.Errorf("%w: %w", ErrCartNotAssigned, err)
+	}
+	o.logger.Printf("Assigned cart to the logged-in user.")
+	return nil
+}
+
+// cartLifetime is how long the carts created by the tool are kept by OVH
+const cartLifetime = 30 * 24 * time.Hour
+
+// ErrCartCheckedOut is returned when extending the expiry of a cart that has
+// already been checked out.
+var ErrCartCheckedOut = errors.New("cart is already checked out")
+
+// RefreshCartExpiry extends the expiry of cartID to expire.
+func (o *Orderer) RefreshCartExpiry(ctx context.Context, cartID string, expire time.Time) error {
+	err := o.client.PutWithContext(ctx, "/order/cart/"+cartID, map[string]interface{}{
+		"expire": expire.Format(time.RFC3339),
+	}, nil)
+	if err != nil {
+		// OVH refuses to modify a checked out cart, tell it apart
+		if cart, getErr := o.GetCart(ctx, cartID); getErr == nil && cart.ReadOnly {
+			return fmt.Errorf("error extending expiry of cart %s: %w", cartID, ErrCartCheckedOut)
+		}
+		return fmt.Errorf("error extending expiry of cart %s: %w", cartID, err)
+	}
+	o.logger.Printf("Extended expiry of cart %s to %s", cartID, expire.Format(time.RFC3339))
+	return nil
+}
+
+// refreshCartIfExpiring extends the expiry of cartID when it expires within
+// Options.CartRefreshMargin, so that a long wait does not make checkout fail
+func (o *Orderer) refreshCartIfExpiring(ctx context.Context, cartID string) error {
+	cart, err := o.GetCart(ctx, cartID)
+	if err != nil {
+		return err
+	}
+	if cart.ReadOnly {
+		return fmt.Errorf("cart %s: %w", cartID, ErrCartCheckedOut)
+	}
+	now := o.clock.Now()
+	if cart.Expire.IsZero() || cart.Expire.Sub(now) > o.opts.CartRefreshMargin {
+		return nil
+	}
+	return o.RefreshCartExpiry(ctx, cartID, now.Add(cartLifetime))
+}
+
+// Concurrency on a cart
+//
+// The cart API does not serialize the changes made to a cart: items added
+// concurrently are created in any order, and a checkout racing with an item
+// being added may or may not include it. Within an Orderer:
+//
+//   - the calls creating or removing items, or the cart itself (adding the
+//     server or an option, checking out, deleting the cart), hold the lock of
+//     the cart, so that they run one at a time per cart whatever the
+//     goroutines calling them;
+//   - the configuration labels of existing items may be posted concurrently,
+//     on one item or several, except for labels that depend on each other
+//     (region before dedicated_datacenter), which configureAll posts in
+//     order;
+//   - reads (summary, requiredConfiguration, the catalog of the cart) are
+//     always safe.
+
+// lockCart takes the lock serializing the item changes of cartID and
+// returns the function releasing it
+func (o *Orderer) lockCart(cartID string) func() {
+	o.mu.Lock()
+	if o.cartLocks == nil {
+		o.cartLocks = make(map[string]*sync.Mutex)
+	}
+	lock, ok := o.cartLocks[cartID]
+	if !ok {
+		lock = &sync.Mutex{}
+		o.cartLocks[cartID] = lock
+	}
+	o.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// deleteCart deletes a cart that is no longer needed
+func (o *Orderer) deleteCart(ctx context.Context, cartID string) error {
+	unlock := o.lockCart(cartID)
+	err := o.client.DeleteWithContext(ctx, "/order/cart/"+cartID, nil)
+	unlock()
+	if err != nil {
+		return fmt.Errorf("error deleting cart %s: %w", cartID, err)
+	}
+	o.mu.Lock()
+	delete(o.cartLocks, cartID)
+	o.mu.Unlock()
+	o.logger.Printf("Deleted cart %s", cartID)
+	return nil
+}
+
+// cartItem is the part of a cart item response the tool reads. Decoding the
+// ID into an int64 keeps it exact whatever its magnitude, and whatever the
+// decoder decodes numbers of untyped values as.
+type cartItem struct {
+	ItemID       int64  `json:"itemId"`
+	ParentItemID int64  `json:"parentItemId"`
+	Duration     string `json:"duration"`
+	Settings     struct {
+		PlanCode    string `json:"planCode"`
+		PricingMode string `json:"pricingMode"`
+		Quantity    int    `json:"quantity"`
+	} `json:"settings"`
+}
+
+// findCartItem returns the first item of cartID for which match is true, or
+// nil
+func (o *Orderer) findCartItem(ctx context.Context, cartID string, match func(cartItem) bool) (*cartItem, error) {
+	var itemIDs []int64
+	if err := o.client.GetWithContext(ctx, "/order/cart/"+cartID+"/item", &itemIDs); err != nil {
+		return nil, fmt.Errorf("error listing items of cart %s: %w", cartID, err)
+	}
+	for _, itemID := range itemIDs {
+		var item cartItem
+		if err := o.client.GetWithContext(ctx, fmt.Sprintf("/order/cart/%s/item/%d", cartID, itemID), &item); err != nil {
+			return nil, fmt.Errorf("error fetching item %d of cart %s: %w", itemID, cartID, err)
+		}
+		if match(item) {
+			return &item, nil
+		}
+	}
+	return nil, nil
+}
+
+// addServer adds the dedicated server of req to the cart and returns its item ID
+func (o *Orderer) addServer(ctx context.Context, cartID string, req OrderRequest) (int64, error) {
+	var server cartItem
+	// The cart holds no server before, whatever it holds is the one added
+	postCtx := withResendCheck(ctx, func(ctx context.Context) (bool, error) {
+		item, err := o.findCartItem(ctx, cartID, func(item cartItem) bool {
+			return item.ParentItemID == 0 && item.Settings.PlanCode == req.PlanCode
+		})
+		if item != nil {
+			server = *item
+		}
+		return item != nil, err
+	})
+	unlock := o.lockCart(cartID)
+	err := o.client.PostWithContext(postCtx, o.serverProductPath(cartID), mergeParams(map[string]interface{}{
+		"duration":    req.Duration,
+		"planCode":    req.PlanCode,
+		"pricingMode": req.PricingMode,
+		"quantity":    req.Quantity,
+	}, req.ExtraParams), &server)
+	unlock()
+	if err != nil {
+		return 0, fmt.Errorf("error adding server to cart: %w", err)
+	}
+
+	if server.ItemID == 0 {
+		return 0, fmt.Errorf("missing itemId in the server cart item")
+	}
+	itemID := server.ItemID
+	o.logger.Printf("Added Server to Cart with Item ID: %d", itemID)
+
+	// Make sure the cart did not fall back to another pricing mode
+	if req.RequirePricingMode != "" {
+		if mode := server.Settings.PricingMode; mode != "" && mode != req.RequirePricingMode {
+			return itemID, fmt.Errorf("%w: server item %d was added with pricing mode %q, %q is required", ErrPricingModeMismatch, itemID, mode, req.RequirePricingMode)
+		}
+	}
+	return itemID, nil
+}
+
+// formatOrderID formats the orderId of a checkout as a plain integer, so that
+// it can be used in URLs. The field is decoded as a json.Number, which
+// accepts the ID as a JSON number or string and keeps all its digits,
+// instead of a float64 that %v would format as "1.23456e+07".
+func formatOrderID(n json.Number) (string, error) {
+	if n == "" {
+		return "", fmt.Errorf("missing orderId in checkout response")
+	}
+	id, err := strconv.ParseInt(n.String(), 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid orderId: %w", err)
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+// reservedParams are the cart item parameters set by the tool, which extra
+// parameters cannot override
+var reservedParams = []string{"planCode", "itemId", "duration", "pricingMode", "quantity"}
+
+// validateExtraParams rejects extra parameters overriding reserved ones
+func validateExtraParams(extra map[string]interface{}) error {
+	for _, key := range reservedParams {
+		if _, ok := extra[key]; ok {
+			return fmt.Errorf("extra parameter %q is set by the tool and cannot be overridden", key)
+		}
+	}
+	return nil
+}
+
+// mergeParams adds the extra parameters to the body of a cart item POST
+func mergeParams(body, extra map[string]interface{}) map[string]interface{} {
+	for key, value := range extra {
+		body[key] = value
+	}
+	return body
+}
+
+// configurationEntry is a configuration label set on a cart item
+type configurationEntry struct {
+	ID    int64  `json:"id"`
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// findConfiguration returns the configuration of itemID setting label, or
+// nil
+func (o *Orderer) findConfiguration(ctx context.Context, cartID string, itemID int64, label string) (*configurationEntry, error) {
+	var ids []int64
+	path := fmt.Sprintf("/order/cart/%s/item/%d/configuration", cartID, itemID)
+	if err := o.client.GetWithContext(ctx, path, &ids); err != nil {
+		return nil, fmt.Errorf("error listing the configuration of item %d: %w", itemID, err)
+	}
+	for _, id := range ids {
+		var entry configurationEntry
+		if err := o.client.GetWithContext(ctx, fmt.Sprintf("%s/%d", path, id), &entry); err != nil {
+			return nil, fmt.Errorf("error fetching configuration %d of item %d: %w", id, itemID, err)
+		}
+		if entry.Label == label {
+			return &entry, nil
+		}
+	}
+	return nil, nil
+}
+
+// configure sets a single configuration label on a cart item and returns
+// the entry OVH created for it
+func (o *Orderer) configure(ctx context.Context, cartID string, itemID int64, config Configuration) (*ConfigurationResult, error) {
+	var configResponse configurationEntry
+	postCtx := withResendCheck(ctx, func(ctx context.Context) (bool, error) {
+		entry, err := o.findConfiguration(ctx, cartID, itemID, config.Label)
+		if entry != nil {
+			configResponse = *entry
+		}
+		return entry != nil, err
+	})
+	err := o.client.PostWithContext(postCtx, fmt.Sprintf("/order/cart/%s/item/%d/configuration", cartID, itemID), map[string]interface{}{
+		"label": config.Label,
+		"value": config.Value,
+	}, &configResponse)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring %s: %w", config.Label, err)
+	}
+	// Report the value OVH recorded, which is the one the server gets
+	value := config.Value
+	if configResponse.Value != "" {
+		value = configResponse.Value
+	}
+	o.logger.Printf("Configured %s with value %s (configuration ID %d)", config.Label, value, configResponse.ID)
+	return &ConfigurationResult{ItemID: itemID, ID: configResponse.ID, Label: config.Label, Value: value}, nil
+}
+
+// addOption adds option to the server item, then sets the configuration
+// labels of the option on the option's own cart item.
+func (o *Orderer) addOption(ctx context.Context, cartID string, itemID int64, option Option, req OrderRequest) (*OptionResult, error) {
+	var optionResponse cartItem
+	postCtx := withResendCheck(ctx, func(ctx context.Context) (bool, error) {
+		item, err := o.findCartItem(ctx, cartID, func(item cartItem) bool {
+			return item.ParentItemID == itemID && item.Settings.PlanCode == option.PlanCode
+		})
+		if item != nil {
+			optionResponse = *item
+		}
+		return item != nil, err
+	})
+	unlock := o.lockCart(cartID)
+	err := o.client.PostWithContext(postCtx, o.serverOptionsPath(cartID), mergeParams(map[string]interface{}{
+		"duration":    option.duration(req),
+		"itemId":      itemID, // Pass itemId as integer
+		"planCode":    option.PlanCode,
+		"pricingMode": option.pricingMode(req),
+		"quantity":    req.Quantity,
+	}, option.ExtraParams), &optionResponse)
+	unlock()
+	if err != nil {
+		return nil, fmt.Errorf("error adding option with planCode %s: %w", option.PlanCode, err)
+	}
+	if optionResponse.ItemID == 0 {
+		return nil, fmt.Errorf("option %s: missing itemId in the option cart item", option.PlanCode)
+	}
+	optionItemID := optionResponse.ItemID
+	o.logger.Printf("Added option with planCode %s (item ID %d)", option.PlanCode, optionItemID)
+
+	result := &OptionResult{
+		PlanCode:    option.PlanCode,
+		ItemID:      optionItemID,
+		Duration:    option.duration(req),
+		PricingMode: option.pricingMode(req),
+	}
+	for _, config := range option.Configuration {
+		configured, err := o.configure(ctx, cartID, optionItemID, config)
+		if err != nil {
+			return result, fmt.Errorf("option %s: %w", option.PlanCode, err)
+		}
+		result.Configuration = append(result.Configuration, *configured)
+	}
+	return result, nil
+}
+
+// ErrOutOfStock is returned, wrapped, when the cart cannot be checked out
+// because the server or one of its options is out of stock. The cart is
+// deleted.
+var ErrOutOfStock = errors.New("out of stock")
+
+// isOutOfStock reports whether err is the rejection of a checkout for lack
+// of stock: a 409 Conflict, or a message mentioning the stock, unless the
+// cart is locked by another operation
+func isOutOfStock(err error) bool {
+	var apiErr *ovh.APIError
+	if !errors.As(err, &apiErr) || isLocked(err) {
+		return false
+	}
+	message := strings.ToLower(apiErr.Message)
+	return apiErr.Code == http.StatusConflict || strings.Contains(message, "out of stock") || strings.Contains(message, "not available")
+}
+
+// ErrCheckoutUncertain is returned, wrapped, when a checkout failed without
+// an answer telling whether it went through, and the cart turned out to be
+// checked out: an order was probably created, which the account lists.
+var ErrCheckoutUncertain = errors.New("checkout failed but the cart is checked out, an order may have been created")
+
+// ErrCheckoutDeclined is returned when Options.ConfirmCheckout declines the
+// checkout of a cart. The cart is deleted.
+var ErrCheckoutDeclined = errors.New("checkout declined")
+
+// CartSummary is the price of a cart if it were checked out now.
+type CartSummary struct {
+	Prices  SummaryPrices   `json:"prices"`
+	Details []SummaryDetail `json:"details"`
+
+	// Contracts are accepted by checking the cart out. They carry the terms
+	// of the commitment of committed pricing modes.
+	Contracts []Contract `json:"contracts"`
+
+	// OrderID and URL are those of the order the checkout would create,
+	// null in a summary.
+	OrderID json.Number `json:"orderId"`
+	URL     string      `json:"url"`
+
+	// Engagement is the commitment of the server, if its pricing mode has one.
+	Engagement *Engagement `json:"-"`
+}
+
+// SummaryPrices are the total prices of a cart.
+type SummaryPrices struct {
+	WithTax    Price `json:"withTax"`
+	WithoutTax Price `json:"withoutTax"`
+	Tax        Price `json:"tax"`
+
+	// OriginalWithoutTax is the total before Reduction.
+	OriginalWithoutTax Price `json:"originalWithoutTax"`
+	Reduction          Price `json:"reduction"`
+}
+
+// SummaryDetail is a line of a cart summary.
+type SummaryDetail struct {
+	CartItemID  int64  `json:"cartItemID"`
+	Description string `json:"description"`
+	Quantity    int    `json:"quantity"`
+	TotalPrice  Price  `json:"totalPrice"`
+
+	// DetailType tells what the line bills, e.g. DURATION for a period of
+	// the service or INSTALLATION for its setup fees, see SplitBilling.
+	DetailType string `json:"detailType"`
+
+	// Domain is the service the line is for, "*" for a new one.
+	Domain    string `json:"domain"`
+	UnitPrice Price  `json:"unitPrice"`
+
+	// OriginalTotalPrice is TotalPrice before the reductions, whose total
+	// is ReductionTotalPrice.
+	OriginalTotalPrice  Price       `json:"originalTotalPrice"`
+	ReductionTotalPrice Price       `json:"reductionTotalPrice"`
+	Reductions          []Reduction `json:"reductions"`
+}
+
+// Reduction is a promotion or voucher applied to a line of a cart summary.
+type Reduction struct {
+	// Context is what grants the reduction, e.g. promotion or voucher, and
+	// Type how its Value applies, e.g. percentage or fixed_amount.
+	Context     string `json:"context"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Value       Price  `json:"value"`
+	Price       Price  `json:"price"`
+}
+
+// summary returns the prices of the cart without checking it out
+func (o *Orderer) summary(ctx context.Context, cartID string) (*CartSummary, error) {
+	var summary CartSummary
+	err := o.client.GetWithContext(ctx, fmt.Sprintf("/order/cart/%s/checkout", cartID), strict(&summary))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching cart summary: %w", err)
+	}
+	return &summary, nil
+}
+
+// checkoutResult is what checkout returns about the created order
+type checkoutResult struct {
+	OrderID string
+
+	// URL is where the order can be paid interactively.
+	URL string
+
+	Contracts []Contract
+}
+
+// checkout validates the cart and returns the created order. With autoPay,
+// OVH charges the preferred payment method of the account itself.
+func (o *Orderer) checkout(ctx context.Context, cartID string, autoPay bool) (*checkoutResult, error) {
+	var body interface{}
+	if autoPay {
+		body = map[string]interface{}{"autoPayWithPreferredPaymentMethod": true}
+	}
+	var order struct {
+		OrderID   json.Number `json:"orderId"`
+		URL       string      `json:"url"`
+		Contracts []Contract  `json:"contracts"`
+	}
+	// A cart is read-only once checked out: its order cannot be read back
+	// from it, so a checkout that may have gone through is not sent again
+	postCtx := withResendCheck(ctx, func(ctx context.Context) (bool, error) {
+		cart, err := o.GetCart(ctx, cartID)
+		if err != nil {
+			return false, err
+		}
+		if cart.ReadOnly {
+			return false, fmt.Errorf("%w: cart %s", ErrCheckoutUncertain, cartID)
+		}
+		return false, nil
+	})
+	unlock := o.lockCart(cartID)
+	err := o.client.PostWithContext(postCtx, fmt.Sprintf("/order/cart/%s/checkout", cartID), body, &order)
+	unlock()
+	if isOutOfStock(err) {
+		// The cart cannot be checked out anymore, do not leave it behind
+		if err := o.deleteCart(ctx, cartID); err != nil {
+			o.logger.Printf("Error deleting cart: %v", err)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrOutOfStock, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error validating order: %w", err)
+	}
+	orderID, err := formatOrderID(order.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	result := &checkoutResult{OrderID: orderID, URL: order.URL}
+	for _, contract := range order.Contracts {
+		if contract.URL != "" {
+			result.Contracts = append(result.Contracts, contract)
+		}
+	}
+	o.logger.Printf("Order validated. Order ID: %s", result.OrderID)
+	return result, nil
+}
+
+// RequiredConfiguration describes a configuration label accepted by a cart item.
+type RequiredConfiguration struct {
+	Label         string   `json:"label"`
+	Required      bool     `json:"required"`
+	AllowedValues []string `json:"allowedValues"`
+}
+
+// ProductPrice is one of the prices a cart product is offered at.
+type ProductPrice struct {
+	Duration    string   `json:"duration"`
+	PricingMode string   `json:"pricingMode"`
+	Capacities  []string `json:"capacities"`
+	Price       Price    `json:"price"`
+
+	// MinimumQuantity and MaximumQuantity bound the quantity that can be
+	// ordered at this price, 0 meaning no bound.
+	MinimumQuantity int `json:"minimumQuantity"`
+	MaximumQuantity int `json:"maximumQuantity"`
+}
+
+// allowsQuantity reports whether quantity can be ordered at price p
+func (p ProductPrice) allowsQuantity(quantity int) bool {
+	if p.MinimumQuantity > 0 && quantity < p.MinimumQuantity {
+		return false
+	}
+	return p.MaximumQuantity <= 0 || quantity <= p.MaximumQuantity
+}
+
+// String describes the combination of duration, pricing mode and quantity
+// the price is offered for, e.g. "P1M/default (quantity 1-10)".
+func (p ProductPrice) String() string {
+	s := p.Duration + "/" + p.PricingMode
+	switch {
+	case p.MinimumQuantity > 0 && p.MaximumQuantity > 0:
+		s += fmt.Sprintf(" (quantity %d-%d)", p.MinimumQuantity, p.MaximumQuantity)
+	case p.MinimumQuantity > 0:
+		s += fmt.Sprintf(" (quantity %d or more)", p.MinimumQuantity)
+	case p.MaximumQuantity > 0:
+		s += fmt.Sprintf(" (quantity up to %d)", p.MaximumQuantity)
+	}
+	return s
+}
+
+// Product is a product offered in a cart.
+type Product struct {
+	PlanCode    string         `json:"planCode"`
+	ProductName string         `json:"productName"`
+	Prices      []ProductPrice `json:"prices"`
+}
+
+// findServerProduct returns the baremetal server product offered for planCode in the cart
+func (o *Orderer) findServerProduct(ctx context.Context, cartID, planCode string) (*Product, error) {
+	var products []Product
+	err := o.client.GetWithContext(ctx, o.serverProductPath(cartID), &products)
+	if err != nil {
+		return nil, fmt.Errorf("error listing servers offered in cart: %w", err)
+	}
+	for i := range products {
+		if products[i].PlanCode == planCode {
+			return &products[i], nil
+		}
+	}
+	return nil, fmt.Errorf("plan %s is not offered", planCode)
+}
+
+// ErrPricingModeMismatch is returned, wrapped, when the server is not
+// offered or added with OrderRequest.RequirePricingMode.
+var ErrPricingModeMismatch = errors.New("pricing mode does not match the required one")
+
+// ErrIllegalPricing is returned, wrapped, when the plan is not offered for
+// the duration, pricing mode and quantity of the order.
+var ErrIllegalPricing = errors.New("illegal combination of duration, pricing mode and quantity")
+
+// checkPricing verifies that the plan of req is offered for its duration,
+// with its required pricing mode if any, and that its duration, pricing mode
+// and quantity are a combination of the prices of the plan
+func (o *Orderer) checkPricing(ctx context.Context, cartID string, req OrderRequest) error {
+	product, err := o.findServerProduct(ctx, cartID, req.PlanCode)
+	if err != nil {
+		return err
+	}
+	if req.RequirePricingMode != "" {
+		if _, ok := PriceFor(product.Prices, req.Duration, req.RequirePricingMode); !ok {
+			var offered []string
+			for _, price := range product.Prices {
+				if price.Duration == req.Duration {
+					offered = append(offered, price.PricingMode)
+				}
+			}
+			return fmt.Errorf("%w: plan %s is not offered with pricing mode %q for duration %s, offered pricing modes: %v",
+				ErrPricingModeMismatch, req.PlanCode, req.RequirePricingMode, req.Duration, offered)
+		}
+	}
+	var offered []string
+	for _, price := range product.Prices {
+		if !contains(offered, price.Duration) {
+			offered = append(offered, price.Duration)
+		}
+	}
+	if !contains(offered, req.Duration) {
+		return fmt.Errorf("plan %s is not offered for duration %s, offered durations: %v", req.PlanCode, req.Duration, offered)
+	}
+
+	var legal []string
+	for _, price := range product.Prices {
+		if price.Duration == req.Duration && price.PricingMode == req.PricingMode && price.allowsQuantity(req.Quantity) {
+			return nil
+		}
+		if combination := price.String(); !contains(legal, combination) {
+			legal = append(legal, combination)
+		}
+	}
+	return fmt.Errorf("%w: plan %s is not offered for duration %s with pricing mode %q and quantity %d, legal combinations: %s",
+		ErrIllegalPricing, req.PlanCode, req.Duration, req.PricingMode, req.Quantity, strings.Join(legal, ", "))
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
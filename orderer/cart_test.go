@@ -0,0 +1,243 @@
+package orderer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestCheckoutOutOfStock(t *testing.T) {
+	var calls callCounter
+	o := newTestServer(t, Options{}, func(w http.ResponseWriter, r *http.Request) {
+		calls.add(r)
+		switch r.Method + " " + r.URL.Path {
+		case "POST /order/cart/cart-1/checkout":
+			writeAPIError(w, http.StatusConflict, "The product 24ska01 is not available")
+		case "DELETE /order/cart/cart-1":
+			fmt.Fprint(w, `null`)
+		default:
+			writeAPIError(w, http.StatusNotFound, "not found")
+		}
+	})
+
+	_, err := o.checkout(context.Background(), "cart-1", false)
+	if !errors.Is(err, ErrOutOfStock) {
+		t.Errorf("err = %v, want ErrOutOfStock", err)
+	}
+	if n := calls.get("POST /order/cart/cart-1/checkout"); n != 1 {
+		t.Errorf("checkout sent %d times, want 1", n)
+	}
+	if n := calls.get("DELETE /order/cart/cart-1"); n != 1 {
+		t.Errorf("cart deleted %d times, want 1", n)
+	}
+}
+
+func TestCheckoutRetriedAfter503(t *testing.T) {
+	var calls callCounter
+	o := newTestServer(t, Options{}, func(w http.ResponseWriter, r *http.Request) {
+		n := calls.add(r)
+		switch r.Method + " " + r.URL.Path {
+		case "POST /order/cart/cart-1/checkout":
+			if n == 1 {
+				writeAPIError(w, http.StatusServiceUnavailable, "Service unavailable")
+				return
+			}
+			fmt.Fprint(w, `{"orderId":"234567890","url":"https://example.com/pay"}`)
+		case "GET /order/cart/cart-1":
+			fmt.Fprint(w, `{"cartId":"cart-1","readOnly":false}`)
+		default:
+			writeAPIError(w, http.StatusNotFound, "not found")
+		}
+	})
+
+	order, err := o.checkout(context.Background(), "cart-1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.OrderID != "234567890" {
+		t.Errorf("order ID = %q, want 234567890", order.OrderID)
+	}
+	if n := calls.get("POST /order/cart/cart-1/checkout"); n != 2 {
+		t.Errorf("checkout sent %d times, want 2", n)
+	}
+	// The cart was read back before the checkout was sent again
+	if n := calls.get("GET /order/cart/cart-1"); n != 1 {
+		t.Errorf("cart read %d times before resending, want 1", n)
+	}
+}
+
+func TestCheckout503GivesUp(t *testing.T) {
+	var calls callCounter
+	o := newTestServer(t, Options{MaxAttempts: 3}, func(w http.ResponseWriter, r *http.Request) {
+		calls.add(r)
+		switch r.Method + " " + r.URL.Path {
+		case "POST /order/cart/cart-1/checkout":
+			writeAPIError(w, http.StatusServiceUnavailable, "Service unavailable")
+		case "GET /order/cart/cart-1":
+			fmt.Fprint(w, `{"cartId":"cart-1","readOnly":false}`)
+		default:
+			writeAPIError(w, http.StatusNotFound, "not found")
+		}
+	})
+
+	if _, err := o.checkout(context.Background(), "cart-1", false); err == nil {
+		t.Fatal("checkout succeeded")
+	}
+	if n := calls.get("POST /order/cart/cart-1/checkout"); n != 3 {
+		t.Errorf("checkout sent %d times, want MaxAttempts 3", n)
+	}
+}
+
+func TestFormatOrderID(t *testing.T) {
+	tests := []struct {
+		name    string
+		orderID interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "json.Number", orderID: json.Number("123456789"), want: "123456789"},
+		{name: "string", orderID: "123456789", want: "123456789"},
+		{name: "float64", orderID: float64(123456789), want: "123456789"},
+		// %v of a float64 this large gives 1.2345678e+07
+		{name: "float64 large", orderID: float64(12345678), want: "12345678"},
+		{name: "int64", orderID: int64(234567890), want: "234567890"},
+		{name: "int64 above float64 precision", orderID: int64(9007199254740993), want: "9007199254740993"},
+		{name: "int64 max", orderID: int64(math.MaxInt64), want: "9223372036854775807"},
+		{name: "overflow", orderID: json.Number("9223372036854775808"), wantErr: true},
+		{name: "fraction", orderID: 1.5, wantErr: true},
+		{name: "not a number", orderID: "abc", wantErr: true},
+		{name: "null", orderID: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Decode the ID as the checkout does, from the JSON OVH sends
+			body, err := json.Marshal(map[string]interface{}{"orderId": tt.orderID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var order struct {
+				OrderID json.Number `json:"orderId"`
+			}
+			if err := json.Unmarshal(body, &order); err != nil {
+				if !tt.wantErr {
+					t.Fatal(err)
+				}
+				return
+			}
+			got, err := formatOrderID(order.OrderID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("formatOrderID(%s) = %q, %v, want error: %t", body, got, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("formatOrderID(%s) = %q, want %q", body, got, tt.want)
+			}
+		})
+	}
+}
+
+// BenchmarkCriticalPath measures the overhead of the tool on the calls an
+// order cannot do without, from the cart creation to the checkout, against
+// a client answering at once
+func BenchmarkCriticalPath(b *testing.B) {
+	client := &stubClient{answer: func(method, path string, body interface{}) (interface{}, error) {
+		switch method + " " + path {
+		case "POST /order/cart":
+			return map[string]interface{}{"cartId": "cart-1"}, nil
+		case "GET /order/cart":
+			return []string{"cart-1"}, nil
+		case "POST /order/cart/cart-1/baremetalServers":
+			return map[string]interface{}{"itemId": 1001, "settings": map[string]interface{}{"planCode": "24ska01", "pricingMode": "default"}}, nil
+		case "POST /order/cart/cart-1/item/1001/configuration":
+			config := body.(map[string]interface{})
+			return map[string]interface{}{"id": 1, "label": config["label"], "value": config["value"]}, nil
+		case "POST /order/cart/cart-1/checkout":
+			return map[string]interface{}{"orderId": 234567890, "url": "https://example.com/pay"}, nil
+		}
+		return nil, fmt.Errorf("unexpected call %s %s", method, path)
+	}}
+	o := New(client, Options{Clock: newFakeClock()})
+	ctx := context.Background()
+	req := OrderRequest{Subsidiary: "FR", PlanCode: "24ska01"}.withDefaults()
+	configs := []Configuration{
+		{Label: labelRegion, Value: "europe"},
+		{Label: labelDatacenter, Value: "gra"},
+		{Label: "dedicated_os", Value: "none_64.en"},
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		cartID, err := o.createCart(ctx, req, "")
+		if err != nil {
+			b.Fatal(err)
+		}
+		itemID, err := o.addServer(ctx, cartID, req)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := o.configureAll(ctx, cartID, itemID, configs); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := o.checkout(ctx, cartID, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// The item and order IDs decode exactly whatever their magnitude, both
+// through go-ovh, which decodes with UseNumber, and through a client
+// decoding with the defaults, which would give float64 in a raw map
+func TestDecodeIDs(t *testing.T) {
+	ids := []string{"1", "123456789", "9007199254740993", "9223372036854775807"}
+	for _, id := range ids {
+		answer := func(method, path string) string {
+			switch method + " " + path {
+			case "POST /order/cart/cart-1/baremetalServers":
+				return `{"itemId":` + id + `,"settings":{"planCode":"24ska01"}}`
+			case "POST /order/cart/cart-1/checkout":
+				return `{"orderId":` + id + `,"url":"https://example.com/pay"}`
+			}
+			return ""
+		}
+		clients := map[string]*Orderer{
+			"go-ovh": newTestServer(t, Options{}, func(w http.ResponseWriter, r *http.Request) {
+				body := answer(r.Method, r.URL.Path)
+				if body == "" {
+					writeAPIError(w, http.StatusNotFound, "not found")
+					return
+				}
+				fmt.Fprint(w, body)
+			}),
+			"default decoder": New(&stubClient{answer: func(method, path string, body interface{}) (interface{}, error) {
+				res := answer(method, path)
+				if res == "" {
+					return nil, fmt.Errorf("unexpected call %s %s", method, path)
+				}
+				return json.RawMessage(res), nil
+			}}, Options{Clock: newFakeClock()}),
+		}
+		for name, o := range clients {
+			t.Run(name+" "+id, func(t *testing.T) {
+				req := OrderRequest{Subsidiary: "FR", PlanCode: "24ska01"}.withDefaults()
+				itemID, err := o.addServer(context.Background(), "cart-1", req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := strconv.FormatInt(itemID, 10); got != id {
+					t.Errorf("item ID = %s, want %s", got, id)
+				}
+				order, err := o.checkout(context.Background(), "cart-1", false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if order.OrderID != id {
+					t.Errorf("order ID = %s, want %s", order.OrderID, id)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,531 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// OptionOffer is an option offered for a baremetal server plan.
+type OptionOffer struct {
+	PlanCode    string         `json:"planCode"`
+	ProductName string         `json:"productName"`
+	Family      string         `json:"family"`
+	Mandatory   bool           `json:"mandatory"`
+	Exclusive   bool           `json:"exclusive"`
+	Prices      []ProductPrice `json:"prices"`
+}
+
+// Option kinds, see OptionOffer.Kind.
+const (
+	// OptionKindHardware options change the server itself, such as its
+	// memory, disks, RAID controller mode or CPU configuration.
+	OptionKindHardware = "hardware"
+	// OptionKindService options come with the server, such as bandwidth,
+	// IPs, backups, licenses or commitment terms.
+	OptionKindService = "service"
+)
+
+// hardwareFamilies are the words of the option families that change the
+// hardware of the server
+var hardwareFamilies = []string{"memory", "ram", "storage", "disk", "raid", "controller", "cpu", "processor", "gpu", "bios", "nic"}
+
+// Kind returns whether the offer is a hardware or a service option, from
+// its family: OptionKindHardware or OptionKindService.
+func (offer OptionOffer) Kind() string {
+	family := strings.ToLower(offer.Family)
+	for _, word := range hardwareFamilies {
+		if strings.Contains(family, word) {
+			return OptionKindHardware
+		}
+	}
+	return OptionKindService
+}
+
+// findOffer returns the offer of planCode among offers, if any
+func findOffer(offers []OptionOffer, planCode string) (OptionOffer, bool) {
+	for _, offer := range offers {
+		if offer.PlanCode == planCode {
+			return offer, true
+		}
+	}
+	return OptionOffer{}, false
+}
+
+// PlanDescription is what a plan accepts when it is ordered.
+type PlanDescription struct {
+	PlanCode      string
+	Configuration []RequiredConfiguration
+	Options       []OptionOffer
+}
+
+// PriceFor returns the price of prices for duration and pricingMode, if any.
+func PriceFor(prices []ProductPrice, duration, pricingMode string) (Price, bool) {
+	for _, price := range prices {
+		if price.Duration == duration && price.PricingMode == pricingMode {
+			return price.Price, true
+		}
+	}
+	return Price{}, false
+}
+
+// withTemporaryCart runs fn with a cart created for subsidiary, which is
+// deleted before returning
+func (o *Orderer) withTemporaryCart(ctx context.Context, subsidiary, purpose string, fn func(cartID string) error) error {
+	req := OrderRequest{
+		Subsidiary:  subsidiary,
+		Description: "Temporary cart for " + purpose,
+	}.withDefaults()
+
+	cartID, err := o.createCart(ctx, req, "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := o.deleteCart(ctx, cartID); err != nil {
+			o.logger.Printf("Error deleting temporary cart: %v", err)
+		}
+	}()
+	return fn(cartID)
+}
+
+// ListPlans returns the dedicated server plans offered to subsidiary.
+func (o *Orderer) ListPlans(ctx context.Context, subsidiary string) ([]Product, error) {
+	if err := o.checkSubsidiary(subsidiary); err != nil {
+		return nil, err
+	}
+	var products []Product
+	err := o.cached("plans-"+subsidiary, &products, func() error {
+		return o.withTemporaryCart(ctx, subsidiary, "list-plans", func(cartID string) error {
+			err := o.client.GetWithContext(ctx, o.serverProductPath(cartID), &products)
+			if err != nil {
+				return fmt.Errorf("error listing servers offered in cart: %w", err)
+			}
+			return nil
+		})
+	})
+	return products, err
+}
+
+// listOptions returns the options offered for planCode in the cart
+func (o *Orderer) listOptions(ctx context.Context, cartID, planCode string) ([]OptionOffer, error) {
+	var options []OptionOffer
+	path := o.serverOptionsPath(cartID) + "?planCode=" + url.QueryEscape(planCode)
+	if err := o.client.GetWithContext(ctx, path, &options); err != nil {
+		return nil, fmt.Errorf("error listing options of plan %s: %w", planCode, err)
+	}
+	return options, nil
+}
+
+// ListOptions returns the options offered for planCode to subsidiary.
+func (o *Orderer) ListOptions(ctx context.Context, subsidiary, planCode string) ([]OptionOffer, error) {
+	if err := o.checkSubsidiary(subsidiary); err != nil {
+		return nil, err
+	}
+	var options []OptionOffer
+	err := o.cached("options-"+subsidiary+"-"+planCode, &options, func() error {
+		return o.withTemporaryCart(ctx, subsidiary, "list-options "+planCode, func(cartID string) (err error) {
+			options, err = o.listOptions(ctx, cartID, planCode)
+			return err
+		})
+	})
+	return options, err
+}
+
+// DescribePlan returns the configuration labels and the options accepted by
+// planCode. It adds the plan to a temporary cart, which is deleted before
+// returning. The options, which do not depend on the cart item, are listed
+// while the plan is added and its configuration fetched.
+func (o *Orderer) DescribePlan(ctx context.Context, subsidiary, planCode string) (*PlanDescription, error) {
+	if err := o.checkSubsidiary(subsidiary); err != nil {
+		return nil, err
+	}
+	description := &PlanDescription{PlanCode: planCode}
+	err := o.cached("plan-"+subsidiary+"-"+planCode, description, func() error {
+		return o.withTemporaryCart(ctx, subsidiary, "describe-plan "+planCode, func(cartID string) error {
+			var optionsErr error
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				description.Options, optionsErr = o.listOptions(ctx, cartID, planCode)
+			}()
+			err := func() error {
+				req := OrderRequest{Subsidiary: subsidiary, PlanCode: planCode}.withDefaults()
+				itemID, err := o.addServer(ctx, cartID, req)
+				if err != nil {
+					return err
+				}
+				description.Configuration, err = o.requiredConfiguration(ctx, cartID, itemID)
+				return err
+			}()
+			wg.Wait()
+			if err != nil {
+				return err
+			}
+			return optionsErr
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return description, nil
+}
+
+// ErrOptionNotOffered is returned, wrapped, when an option is not offered
+// for the plan ordered.
+var ErrOptionNotOffered = errors.New("option is not offered for this plan")
+
+// checkOptionOffered verifies that option is among offers, listing the
+// compatible options otherwise, so that an incompatible option fails with a
+// clear message instead of an opaque 400 from the cart
+func checkOptionOffered(option Option, offers []OptionOffer, planCode string) error {
+	var compatible []string
+	for _, offer := range offers {
+		if offer.PlanCode == option.PlanCode {
+			return nil
+		}
+		compatible = append(compatible, offer.PlanCode)
+	}
+	return fmt.Errorf("%w: %s is not offered for plan %s, compatible options: %s",
+		ErrOptionNotOffered, option.PlanCode, planCode, strings.Join(compatible, ", "))
+}
+
+// duration returns the duration option is billed for, that of the server of
+// req unless it sets its own
+func (option Option) duration(req OrderRequest) string {
+	if option.Duration != "" {
+		return option.Duration
+	}
+	return req.Duration
+}
+
+// pricingMode returns the pricing mode of option, that of the server of req
+// unless it sets its own
+func (option Option) pricingMode(req OrderRequest) string {
+	if option.PricingMode != "" {
+		return option.PricingMode
+	}
+	return req.PricingMode
+}
+
+// checkOptionPricing fails with ErrIllegalPricing, listing the legal
+// combinations, when the offer of option has no price for its duration and
+// pricing mode, which OVH would reject when adding it
+func checkOptionPricing(option Option, offers []OptionOffer, req OrderRequest) error {
+	duration, pricingMode := option.duration(req), option.pricingMode(req)
+	for _, offer := range offers {
+		if offer.PlanCode != option.PlanCode || len(offer.Prices) == 0 {
+			continue
+		}
+		var legal []string
+		for _, price := range offer.Prices {
+			if price.Duration == duration && price.PricingMode == pricingMode && price.allowsQuantity(req.Quantity) {
+				return nil
+			}
+			if combination := price.String(); !contains(legal, combination) {
+				legal = append(legal, combination)
+			}
+		}
+		return fmt.Errorf("%w: option %s is not offered for duration %s with pricing mode %q and quantity %d, legal combinations: %s",
+			ErrIllegalPricing, option.PlanCode, duration, pricingMode, req.Quantity, strings.Join(legal, ", "))
+	}
+	return nil
+}
+
+// optionFamilyAliases maps the short names accepted in option names to the
+// option families of the catalog
+var optionFamilyAliases = map[string]string{
+	"ram":  "memory",
+	"disk": "storage",
+	"bw":   "bandwidth",
+}
+
+// optionName returns the name an offer can be selected with: its family and
+// the capacity of its plan code, e.g. memory=32g for ram-32g-ecc-3200-24rise-us
+func optionName(offer OptionOffer) string {
+	tokens := strings.Split(offer.PlanCode, "-")
+	if len(tokens) < 2 {
+		return offer.Family + "=" + offer.PlanCode
+	}
+	return offer.Family + "=" + tokens[1]
+}
+
+// resolveOptionName returns option with its plan code resolved when it is
+// given by name, as family=capacity (e.g. ram=32g, storage=2x512nvme),
+// among the offers of the plan. Plan codes are returned unchanged.
+func resolveOptionName(option Option, offers []OptionOffer, planCode string) (Option, error) {
+	family, capacity, ok := strings.Cut(option.PlanCode, "=")
+	if !ok {
+		return option, nil
+	}
+	family = strings.ToLower(strings.TrimSpace(family))
+	if alias, ok := optionFamilyAliases[family]; ok {
+		family = alias
+	}
+	capacity = strings.ToLower(strings.TrimSpace(capacity))
+
+	var matches, names []string
+	for _, offer := range offers {
+		if !strings.EqualFold(offer.Family, family) {
+			continue
+		}
+		names = append(names, optionName(offer))
+		if strings.Contains("-"+strings.ToLower(offer.PlanCode)+"-", "-"+capacity+"-") {
+			matches = append(matches, offer.PlanCode)
+		}
+	}
+	switch {
+	case len(matches) == 1:
+		option.PlanCode = matches[0]
+		return option, nil
+	case len(matches) > 1:
+		return option, fmt.Errorf("option %s is ambiguous for plan %s, it matches %s", option.PlanCode, planCode, strings.Join(matches, ", "))
+	}
+	if len(names) == 0 {
+		for _, offer := range offers {
+			names = append(names, optionName(offer))
+		}
+	}
+	return option, fmt.Errorf("%w: no option %s for plan %s, available options: %s",
+		ErrOptionNotOffered, option.PlanCode, planCode, strings.Join(names, ", "))
+}
+
+// resolveOptions returns the options of req with the plan codes of the
+// options given by name resolved (see resolveOptionName) and the mandatory
+// term, prerequisite and included options and the delivery option added
+// (see addTermOptions, addPrerequisites, addIncludedOptions and
+// addDeliveryOption), after checking that they select an option of each
+// family the plan requires one of
+func (o *Orderer) resolveOptions(ctx context.Context, cartID string, req OrderRequest) ([]Option, error) {
+	offers, err := o.listOptions(ctx, cartID, req.PlanCode)
+	if err != nil {
+		return nil, err
+	}
+	options := make([]Option, len(req.Options))
+	for i, option := range req.Options {
+		options[i], err = resolveOptionName(option, offers, req.PlanCode)
+		if err != nil && !(req.BestEffort && errors.Is(err, ErrOptionNotOffered)) {
+			return nil, err
+		}
+		// With BestEffort an unknown name is skipped when options are added
+	}
+	options = dedupeOptions(o.logger, options, req.AllowDuplicateOptions)
+	options = addTermOptions(o.logger, offers, req.PlanCode, options)
+	options, err = addPrerequisites(o.logger, offers, o.optionDependencies(), options)
+	if err != nil {
+		return nil, err
+	}
+	options = addIncludedOptions(o.logger, offers, req, options)
+	options, err = addDeliveryOption(o.logger, offers, req, options)
+	if err != nil {
+		return nil, err
+	}
+	return options, checkMandatoryOptions(offers, req.PlanCode, options)
+}
+
+// dedupeOptions returns options with the options listed several times, by
+// plan code or by a name resolving to it, kept once, unless allow is set.
+// Duplicates are logged either way, as each listing is charged when it is
+// added.
+func dedupeOptions(logger Logger, options []Option, allow bool) []Option {
+	counts := make(map[string]int)
+	for _, option := range options {
+		counts[option.PlanCode]++
+	}
+	deduped := make([]Option, 0, len(options))
+	seen := make(map[string]bool)
+	for _, option := range options {
+		first := !seen[option.PlanCode]
+		seen[option.PlanCode] = true
+		if count := counts[option.PlanCode]; count > 1 && first {
+			if allow {
+				logger.Printf("Option %s is listed %d times, adding it %d times as duplicate options are allowed", option.PlanCode, count, count)
+			} else {
+				logger.Printf("Warning: option %s is listed %d times, adding it once; allow duplicate options to add it %d times", option.PlanCode, count, count)
+			}
+		}
+		if first || allow {
+			deduped = append(deduped, option)
+		}
+	}
+	return deduped
+}
+
+// isTermFamily reports whether an option family is a commitment term, which
+// some plans require as an option item instead of a pricing mode
+func isTermFamily(family string) bool {
+	family = strings.ToLower(family)
+	return strings.Contains(family, "commitment") || strings.Contains(family, "engagement") || strings.Contains(family, "term")
+}
+
+// addTermOptions returns options with the term option added for each
+// mandatory term family not selected yet, when the plan offers a single
+// option in the family. Families offering several terms are left to the
+// user, checkMandatoryOptions then lists them.
+func addTermOptions(logger Logger, offers []OptionOffer, planCode string, options []Option) []Option {
+	selected := make(map[string]bool)
+	for _, option := range options {
+		selected[option.PlanCode] = true
+	}
+	covered := make(map[string]bool)
+	candidates := make(map[string][]string)
+	var families []string
+	for _, offer := range offers {
+		if !offer.Mandatory || !isTermFamily(offer.Family) {
+			continue
+		}
+		if !contains(families, offer.Family) {
+			families = append(families, offer.Family)
+		}
+		if selected[offer.PlanCode] {
+			covered[offer.Family] = true
+		}
+		candidates[offer.Family] = append(candidates[offer.Family], offer.PlanCode)
+	}
+	for _, family := range families {
+		if covered[family] || len(candidates[family]) != 1 {
+			continue
+		}
+		logger.Printf("Plan %s requires a %s option, adding %s", planCode, family, candidates[family][0])
+		options = append(options, Option{PlanCode: candidates[family][0]})
+	}
+	return options
+}
+
+// addIncludedOptions returns options with the included option added for
+// each mandatory family not selected yet, when the plan offers a single free
+// option in the family for the duration and pricing mode of req. Such items,
+// e.g. the default storage of some plans, come with the server but must be
+// in the cart all the same, or the checkout fails. Families with several
+// free options are left to the user, checkMandatoryOptions then lists them.
+func addIncludedOptions(logger Logger, offers []OptionOffer, req OrderRequest, options []Option) []Option {
+	selected := make(map[string]bool)
+	for _, option := range options {
+		selected[option.PlanCode] = true
+	}
+	covered := make(map[string]bool)
+	free := make(map[string][]string)
+	var families []string
+	for _, offer := range offers {
+		if !offer.Mandatory {
+			continue
+		}
+		if !contains(families, offer.Family) {
+			families = append(families, offer.Family)
+		}
+		if selected[offer.PlanCode] {
+			covered[offer.Family] = true
+		}
+		price, ok := PriceFor(offer.Prices, req.Duration, req.PricingMode)
+		if !ok {
+			continue
+		}
+		if amount, err := price.Amount(); err == nil && amount.Sign() == 0 {
+			free[offer.Family] = append(free[offer.Family], offer.PlanCode)
+		}
+	}
+	for _, family := range families {
+		if covered[family] || len(free[family]) != 1 {
+			continue
+		}
+		logger.Printf("Plan %s includes the %s option %s at no cost, adding it", req.PlanCode, family, free[family][0])
+		options = append(options, Option{PlanCode: free[family][0], included: true})
+	}
+	return options
+}
+
+// ErrMissingPrerequisite is returned, wrapped, when an option requires an
+// option of another family that is not selected and cannot be chosen
+// automatically.
+var ErrMissingPrerequisite = errors.New("option requires another option")
+
+// DefaultOptionDependencies maps option families to the families an option
+// must also be selected from for the cart to accept them. The options
+// listing of the cart does not describe these dependencies, so they are
+// declared here; Options.OptionDependencies adds to them.
+var DefaultOptionDependencies = map[string][]string{
+	"vrack-bandwidth": {"vrack"},
+}
+
+// optionDependencies returns DefaultOptionDependencies with the families of
+// Options.OptionDependencies added
+func (o *Orderer) optionDependencies() map[string][]string {
+	dependencies := make(map[string][]string, len(DefaultOptionDependencies)+len(o.opts.OptionDependencies))
+	for family, required := range DefaultOptionDependencies {
+		dependencies[family] = append(dependencies[family], required...)
+	}
+	for family, required := range o.opts.OptionDependencies {
+		dependencies[family] = append(dependencies[family], required...)
+	}
+	return dependencies
+}
+
+// addPrerequisites returns options with, for each option whose family
+// depends on another family (see DefaultOptionDependencies) with no option
+// selected, the option of that family when the plan offers a single one. It
+// fails with ErrMissingPrerequisite, listing the candidates, when the plan
+// offers none or several, so that the order fails before checkout instead
+// of with an opaque error from the cart.
+func addPrerequisites(logger Logger, offers []OptionOffer, dependencies map[string][]string, options []Option) ([]Option, error) {
+	families := make(map[string]string, len(offers))
+	candidates := make(map[string][]string)
+	for _, offer := range offers {
+		families[offer.PlanCode] = offer.Family
+		candidates[offer.Family] = append(candidates[offer.Family], offer.PlanCode)
+	}
+	selected := make(map[string]bool)
+	for _, option := range options {
+		selected[families[option.PlanCode]] = true
+	}
+	for i := 0; i < len(options); i++ {
+		option := options[i]
+		for _, required := range dependencies[families[option.PlanCode]] {
+			if selected[required] {
+				continue
+			}
+			if len(candidates[required]) != 1 {
+				return nil, fmt.Errorf("%w: option %s requires an option of family %s, one of: %s",
+					ErrMissingPrerequisite, option.PlanCode, required, strings.Join(candidates[required], ", "))
+			}
+			logger.Printf("Option %s requires a %s option, adding %s", option.PlanCode, required, candidates[required][0])
+			options = append(options, Option{PlanCode: candidates[required][0]})
+			selected[required] = true
+		}
+	}
+	return options, nil
+}
+
+// checkMandatoryOptions verifies that options select an option of each
+// family the plan requires one of, so that a cart without options fails
+// before checkout instead of at checkout
+func checkMandatoryOptions(offers []OptionOffer, planCode string, options []Option) error {
+	selected := make(map[string]bool)
+	for _, option := range options {
+		selected[option.PlanCode] = true
+	}
+	covered := make(map[string]bool)
+	candidates := make(map[string][]string)
+	var families []string
+	for _, offer := range offers {
+		if !offer.Mandatory {
+			continue
+		}
+		if !contains(families, offer.Family) {
+			families = append(families, offer.Family)
+		}
+		if selected[offer.PlanCode] {
+			covered[offer.Family] = true
+		}
+		candidates[offer.Family] = append(candidates[offer.Family], offer.PlanCode)
+	}
+	for _, family := range families {
+		if !covered[family] {
+			return fmt.Errorf("plan %s requires an option of family %s, one of: %s", planCode, family, strings.Join(candidates[family], ", "))
+		}
+	}
+	return nil
+}
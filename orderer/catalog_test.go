@@ -0,0 +1,165 @@
+package orderer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The options of a plan are listed while the plan is added to the
+// temporary cart: the server is only added once the options are asked for
+func TestDescribePlanListsOptionsConcurrently(t *testing.T) {
+	listing := make(chan struct{})
+	var once sync.Once
+	client := &stubClient{answer: func(method, path string, body interface{}) (interface{}, error) {
+		switch method + " " + path {
+		case "POST /order/cart":
+			return map[string]interface{}{"cartId": "cart-1"}, nil
+		case "GET /order/cart":
+			return []string{"cart-1"}, nil
+		case "GET /order/cart/cart-1/baremetalServers/options?planCode=24ska01":
+			once.Do(func() { close(listing) })
+			return []map[string]interface{}{{"planCode": "ram-32g-ecc-2400", "family": "memory"}}, nil
+		case "POST /order/cart/cart-1/baremetalServers":
+			select {
+			case <-listing:
+			case <-time.After(5 * time.Second):
+				return nil, errors.New("options not listed while the server is added")
+			}
+			return map[string]interface{}{"itemId": 1001, "settings": map[string]interface{}{"planCode": "24ska01"}}, nil
+		case "GET /order/cart/cart-1/item/1001/requiredConfiguration":
+			return []map[string]interface{}{{"label": labelDatacenter, "required": true, "allowedValues": []string{"gra", "rbx"}}}, nil
+		case "DELETE /order/cart/cart-1":
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unexpected call %s %s", method, path)
+	}}
+	o := New(client, Options{Clock: newFakeClock()})
+
+	description, err := o.DescribePlan(context.Background(), "FR", "24ska01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(description.Options) != 1 || description.Options[0].PlanCode != "ram-32g-ecc-2400" {
+		t.Errorf("options = %+v", description.Options)
+	}
+	if len(description.Configuration) != 1 || description.Configuration[0].Label != labelDatacenter {
+		t.Errorf("configuration = %+v", description.Configuration)
+	}
+}
+
+func TestDedupeOptions(t *testing.T) {
+	options := []Option{
+		{PlanCode: "ram-32g"},
+		{PlanCode: "softraid-2x960ssd"},
+		{PlanCode: "ram-32g"},
+		{PlanCode: "bandwidth-1000"},
+		{PlanCode: "ram-32g"},
+	}
+	tests := []struct {
+		name  string
+		allow bool
+
+		want    []string
+		wantLog string
+	}{
+		{
+			name:    "default",
+			want:    []string{"ram-32g", "softraid-2x960ssd", "bandwidth-1000"},
+			wantLog: "Warning: option ram-32g is listed 3 times, adding it once",
+		},
+		{
+			name:    "allowed",
+			allow:   true,
+			want:    []string{"ram-32g", "softraid-2x960ssd", "ram-32g", "bandwidth-1000", "ram-32g"},
+			wantLog: "Option ram-32g is listed 3 times, adding it 3 times",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			deduped := dedupeOptions(log.New(&buf, "", 0), options, tt.allow)
+			var got []string
+			for _, option := range deduped {
+				got = append(got, option.PlanCode)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("options = %v, want %v", got, tt.want)
+			}
+			if !strings.Contains(buf.String(), tt.wantLog) {
+				t.Errorf("log = %q, want %q", buf.String(), tt.wantLog)
+			}
+			// Duplicates are logged once, and options listed once not at all
+			if n := strings.Count(buf.String(), "\n"); n != 1 {
+				t.Errorf("%d lines logged, want 1: %q", n, buf.String())
+			}
+		})
+	}
+
+	t.Run("no duplicates", func(t *testing.T) {
+		var buf bytes.Buffer
+		unique := options[1:4]
+		if got := dedupeOptions(log.New(&buf, "", 0), unique, false); !reflect.DeepEqual(got, unique) {
+			t.Errorf("options = %v, want %v", got, unique)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("log = %q, want nothing", buf.String())
+		}
+	})
+}
+
+func TestAddIncludedOptions(t *testing.T) {
+	offer := func(planCode, family string, mandatory bool, price string) OptionOffer {
+		return OptionOffer{
+			PlanCode:  planCode,
+			Family:    family,
+			Mandatory: mandatory,
+			Prices:    []ProductPrice{{Duration: "P1M", PricingMode: "default", Price: Price{Value: json.Number(price)}}},
+		}
+	}
+	offers := []OptionOffer{
+		// A single free mandatory storage: added
+		offer("softraid-2x512nvme", "storage", true, "0"),
+		offer("softraid-2x2tb", "storage", true, "30"),
+		// Two free mandatory memories: left to the user
+		offer("ram-32g", "memory", true, "0"),
+		offer("ram-32g-ecc", "memory", true, "0"),
+		// Free but optional: not added
+		offer("bandwidth-500", "bandwidth", false, "0"),
+		// Mandatory, free, and already selected in another option of its family
+		offer("vrack-1g", "vrack", true, "0"),
+		offer("vrack-10g", "vrack", true, "50"),
+	}
+	req := OrderRequest{PlanCode: "24rise01", Duration: "P1M", PricingMode: "default"}
+	selected := []Option{{PlanCode: "vrack-10g"}}
+
+	var buf bytes.Buffer
+	options := addIncludedOptions(log.New(&buf, "", 0), offers, req, selected)
+	var got []string
+	for _, option := range options {
+		got = append(got, option.PlanCode)
+		if option.included != (option.PlanCode == "softraid-2x512nvme") {
+			t.Errorf("option %s included = %t", option.PlanCode, option.included)
+		}
+	}
+	if want := []string{"vrack-10g", "softraid-2x512nvme"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("options = %v, want %v", got, want)
+	}
+	if !strings.Contains(buf.String(), "softraid-2x512nvme at no cost") {
+		t.Errorf("log = %q, want the added option", buf.String())
+	}
+
+	// Prices of another duration do not count
+	req.Duration = "P12M"
+	if options := addIncludedOptions(log.New(&buf, "", 0), offers, req, nil); len(options) != 0 {
+		t.Errorf("options for P12M = %+v, want none", options)
+	}
+}
@@ -0,0 +1,126 @@
+package orderer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// PlanQuote is the price of a plan built in a temporary cart by
+// ComparePlans.
+type PlanQuote struct {
+	PlanCode string
+
+	// Price is the total of the cart, tax included, for its duration.
+	Price Price
+
+	// Monthly is Price spread over the months of the duration, and Total is
+	// Monthly over the term: the commitment of the pricing mode, or the
+	// duration when it has none. Setup fees billed with the first period are
+	// spread too, so Total is an estimate.
+	Monthly Price
+	Total   Price
+	Months  int
+
+	// SkippedOptions are the options of the request the plan does not
+	// offer, which are left out of its price.
+	SkippedOptions []SkippedOption
+
+	// Err is set when the plan could not be priced, e.g. out of stock; the
+	// other fields are then empty.
+	Err error
+}
+
+// ComparePlans prices req for each of planCodes: it builds a temporary cart
+// with the server and the options of req, reads its summary and deletes it.
+// Options a plan does not offer are skipped, so that one option spec can be
+// shared by plans of different ranges. A plan that cannot be priced gets its
+// error in its quote instead of failing the others, and its cart is deleted
+// all the same. Quotes are in the order of planCodes.
+func (o *Orderer) ComparePlans(ctx context.Context, req OrderRequest, planCodes []string) ([]PlanQuote, error) {
+	req, err := o.prepare(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := durationMonths(req.Duration); !ok {
+		return nil, fmt.Errorf("cannot compare plans over duration %s: it is not a whole number of months", req.Duration)
+	}
+	req.BestEffort = true
+	req.Description = "Temporary cart for compare-plans"
+
+	quotes := make([]PlanQuote, len(planCodes))
+	for i, planCode := range planCodes {
+		planReq := req
+		planReq.PlanCode = planCode
+		quotes[i] = o.quote(ctx, planReq)
+	}
+	return quotes, nil
+}
+
+// quote prices req, already prepared, in a temporary cart
+func (o *Orderer) quote(ctx context.Context, req OrderRequest) PlanQuote {
+	quote := PlanQuote{PlanCode: req.PlanCode}
+	months, ok := durationMonths(req.Duration)
+	if !ok {
+		quote.Err = fmt.Errorf("cannot price duration %s: it is not a whole number of months", req.Duration)
+		return quote
+	}
+	term := months
+	if engagement := ParseEngagement(req.PricingMode); engagement != nil && engagement.Months > term {
+		term = engagement.Months
+	}
+	summary, skipped, err := o.quotePlan(ctx, req)
+	if err != nil {
+		quote.Err = err
+		return quote
+	}
+	quote.Price = summary.Prices.WithTax
+	quote.SkippedOptions = skipped
+	quote.Months = term
+	quote.Monthly, quote.Total, err = spreadPrice(summary.Prices.WithTax, months, term)
+	if err != nil {
+		quote.Err = err
+	}
+	return quote
+}
+
+// quotePlan builds the cart of req, returns its summary and deletes it,
+// whether it could be built or not
+func (o *Orderer) quotePlan(ctx context.Context, req OrderRequest) (*CartSummary, []SkippedOption, error) {
+	result := &OrderResult{}
+	defer func() {
+		if result.CartID == "" {
+			return
+		}
+		if err := o.deleteCart(ctx, result.CartID); err != nil {
+			o.logger.Printf("Error deleting temporary cart %s: %v", result.CartID, err)
+		}
+	}()
+	if err := o.buildCart(ctx, req, result); err != nil {
+		return nil, nil, err
+	}
+	summary, err := o.summary(ctx, result.CartID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return summary, result.SkippedOptions, nil
+}
+
+// spreadPrice returns the monthly price of price, billed for months, and
+// that monthly price over term months
+func spreadPrice(price Price, months, term int) (Price, Price, error) {
+	amount, err := price.Amount()
+	if err != nil {
+		return Price{}, Price{}, fmt.Errorf("invalid cart price: %w", err)
+	}
+	monthly := new(big.Rat).Quo(amount, big.NewRat(int64(months), 1))
+	total := new(big.Rat).Mul(monthly, big.NewRat(int64(term), 1))
+	return ratPrice(monthly, price.CurrencyCode), ratPrice(total, price.CurrencyCode), nil
+}
+
+// ratPrice returns a price of amount, rounded to the cent
+func ratPrice(amount *big.Rat, currency string) Price {
+	value := amount.FloatString(2)
+	return Price{Value: json.Number(value), CurrencyCode: currency, Text: value + " " + currency}
+}
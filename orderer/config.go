@@ -0,0 +1,416 @@
+package orderer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigVersion is the version of the configuration file format written by
+// this version of the tool. It is bumped whenever a change to the format
+// would make older files be read differently.
+//
+// Version history:
+//
+//	1: initial format
+const ConfigVersion = 1
+
+// Config is the configuration file describing an order, in YAML (or JSON).
+type Config struct {
+	// Version is the format version of the file, see ConfigVersion.
+	Version int `yaml:"version" json:"version"`
+
+	Subsidiary     string          `yaml:"subsidiary,omitempty" json:"subsidiary,omitempty"`
+	Description    string          `yaml:"description,omitempty" json:"description,omitempty"`
+	Plan           string          `yaml:"plan" json:"plan"`
+	Duration       string          `yaml:"duration,omitempty" json:"duration,omitempty"`
+	PricingMode    string          `yaml:"pricingMode,omitempty" json:"pricingMode,omitempty"`
+	Quantity       int             `yaml:"quantity,omitempty" json:"quantity,omitempty"`
+	OS             string          `yaml:"os,omitempty" json:"os,omitempty"`
+	Configuration  []ConfigLabel   `yaml:"configuration,omitempty" json:"configuration,omitempty"`
+	Options        []ConfigOption  `yaml:"options,omitempty" json:"options,omitempty"`
+	ExtraIPs       *ConfigExtraIPs `yaml:"extraIps,omitempty" json:"extraIps,omitempty"`
+	Install        *ConfigInstall  `yaml:"install,omitempty" json:"install,omitempty"`
+	Tag            string          `yaml:"tag,omitempty" json:"tag,omitempty"`
+	MaxPrice       string          `yaml:"maxPrice,omitempty" json:"maxPrice,omitempty"`
+	MaxPriceBasis  string          `yaml:"maxPriceBasis,omitempty" json:"maxPriceBasis,omitempty"`
+	AutoPay        bool            `yaml:"autoPay,omitempty" json:"autoPay,omitempty"`
+	BestEffort     bool            `yaml:"bestEffort,omitempty" json:"bestEffort,omitempty"`
+	IPv6           bool            `yaml:"ipv6,omitempty" json:"ipv6,omitempty"`
+	Reverse        string          `yaml:"reverse,omitempty" json:"reverse,omitempty"`
+	BillingAccount string          `yaml:"billingAccount,omitempty" json:"billingAccount,omitempty"`
+
+	// DeliveryPriority selects the delivery option of the server, see
+	// OrderRequest.DeliveryPriority.
+	DeliveryPriority string `yaml:"deliveryPriority,omitempty" json:"deliveryPriority,omitempty"`
+
+	// CustomerReference identifies the end customer of a reseller order.
+	CustomerReference string `yaml:"customerReference,omitempty" json:"customerReference,omitempty"`
+
+	// ExtraParams are merged into the body adding the server to the cart,
+	// for parameters the tool does not know about yet.
+	ExtraParams map[string]interface{} `yaml:"extraParams,omitempty" json:"extraParams,omitempty"`
+}
+
+// ConfigLabel is a configuration label of the server, as printed by describe-plan.
+type ConfigLabel struct {
+	Label string `yaml:"label" json:"label"`
+	Value string `yaml:"value" json:"value"`
+}
+
+// ConfigOption is an option of the server. It is either written as its plan
+// code alone, or as a mapping when the option needs configuration labels:
+//
+//	options:
+//	  - ram-32g-ecc-3200-24rise-us
+//	  - planCode: windows-server-2022-standard-license
+//	    configuration:
+//	      - label: ...
+//	        value: ...
+type ConfigOption struct {
+	PlanCode      string                 `yaml:"planCode" json:"planCode"`
+	Duration      string                 `yaml:"duration,omitempty" json:"duration,omitempty"`
+	PricingMode   string                 `yaml:"pricingMode,omitempty" json:"pricingMode,omitempty"`
+	Configuration []ConfigLabel          `yaml:"configuration,omitempty" json:"configuration,omitempty"`
+	ExtraParams   map[string]interface{} `yaml:"extraParams,omitempty" json:"extraParams,omitempty"`
+}
+
+// UnmarshalYAML accepts a plan code alone in place of the mapping.
+func (o *ConfigOption) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&o.PlanCode)
+	}
+	type plain ConfigOption
+	return node.Decode((*plain)(o))
+}
+
+// MarshalYAML writes options without their own billing terms, configuration
+// nor extra parameters as their plan code alone.
+func (o ConfigOption) MarshalYAML() (interface{}, error) {
+	if o.Duration == "" && o.PricingMode == "" && len(o.Configuration) == 0 && len(o.ExtraParams) == 0 {
+		return o.PlanCode, nil
+	}
+	type plain ConfigOption
+	return plain(o), nil
+}
+
+// ConfigExtraIPs describes the additional IPs to order once delivered.
+type ConfigExtraIPs struct {
+	Count   int    `yaml:"count" json:"count"`
+	Type    string `yaml:"type" json:"type"`
+	Country string `yaml:"country,omitempty" json:"country,omitempty"`
+}
+
+// ConfigInstall describes the OS installed once delivered.
+type ConfigInstall struct {
+	Template        string `yaml:"template" json:"template"`
+	PartitionScheme string `yaml:"partitionScheme,omitempty" json:"partitionScheme,omitempty"`
+	Hostname        string `yaml:"hostname,omitempty" json:"hostname,omitempty"`
+}
+
+// LoadConfig reads and validates the configuration file at path of fsys.
+func LoadConfig(fsys FS, path string) (*Config, error) {
+	data, err := fsOrDisk(fsys).ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseConfig parses and validates a configuration file content.
+func ParseConfig(data []byte) (*Config, error) {
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := config.checkVersion(); err != nil {
+		return nil, err
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// validate checks the fields of a decoded configuration
+func (c *Config) validate() error {
+	if c.Plan == "" {
+		return fmt.Errorf("invalid config: plan is required")
+	}
+	if err := validateExtraParams(c.ExtraParams); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	for _, option := range c.Options {
+		if err := validateExtraParams(option.ExtraParams); err != nil {
+			return fmt.Errorf("invalid config: option %s: %w", option.PlanCode, err)
+		}
+	}
+	return nil
+}
+
+// checkVersion rejects files written for another version of the format
+func (c *Config) checkVersion() error {
+	switch {
+	case c.Version == 0:
+		return fmt.Errorf("config has no version: add \"version: %d\" at the top of the file", ConfigVersion)
+	case c.Version > ConfigVersion:
+		return fmt.Errorf("config version %d is newer than the supported version %d: upgrade the tool", c.Version, ConfigVersion)
+	case c.Version < ConfigVersion:
+		return fmt.Errorf("config version %d is no longer supported: update the file to version %d", c.Version, ConfigVersion)
+	}
+	return nil
+}
+
+// configLabelAliases are the short names accepted by Set for common
+// configuration labels
+var configLabelAliases = map[string]string{
+	"datacenter": labelDatacenter,
+	"region":     labelRegion,
+}
+
+// Set applies an override written key=value, or key+=value to append to a
+// list, on top of a configuration loaded from a template. Keys are the
+// top-level scalar fields of the file (plan, duration, os, deliveryPriority,
+// ...), options, datacenter, region, or configuration.<label> for any other
+// label.
+func (c *Config) Set(assignment string) error {
+	key, value, found := strings.Cut(assignment, "=")
+	appendValue := strings.HasSuffix(key, "+")
+	key = strings.TrimSuffix(key, "+")
+	if !found || key == "" {
+		return fmt.Errorf("invalid override %q: expected key=value or key+=value", assignment)
+	}
+	if appendValue && key != "options" {
+		return fmt.Errorf("invalid override %q: only options can be appended to", assignment)
+	}
+
+	switch key {
+	case "subsidiary":
+		c.Subsidiary = value
+	case "description":
+		c.Description = value
+	case "plan":
+		c.Plan = value
+	case "duration":
+		c.Duration = value
+	case "pricingMode":
+		c.PricingMode = value
+	case "os":
+		c.OS = value
+	case "deliveryPriority":
+		c.DeliveryPriority = value
+	case "quantity":
+		quantity, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid override %q: quantity must be an integer", assignment)
+		}
+		c.Quantity = quantity
+	case "options":
+		if !appendValue {
+			c.Options = nil
+		}
+		if value != "" {
+			c.Options = append(c.Options, ConfigOption{PlanCode: value})
+		}
+	default:
+		label, ok := configLabelAliases[key]
+		if !ok {
+			if label, ok = strings.CutPrefix(key, "configuration."); !ok || label == "" {
+				return fmt.Errorf("invalid override %q: unknown key %q", assignment, key)
+			}
+		}
+		c.setLabel(label, value)
+	}
+	return nil
+}
+
+// setLabel sets the value of a configuration label, adding it if needed
+func (c *Config) setLabel(label, value string) {
+	for i := range c.Configuration {
+		if c.Configuration[i].Label == label {
+			c.Configuration[i].Value = value
+			return
+		}
+	}
+	c.Configuration = append(c.Configuration, ConfigLabel{Label: label, Value: value})
+}
+
+// OrderRequest converts the configuration to an OrderRequest.
+func (c *Config) OrderRequest() OrderRequest {
+	req := OrderRequest{
+		Subsidiary:     c.Subsidiary,
+		Description:    c.Description,
+		PlanCode:       c.Plan,
+		Duration:       c.Duration,
+		PricingMode:    c.PricingMode,
+		Quantity:       c.Quantity,
+		OS:             c.OS,
+		ExtraParams:    c.ExtraParams,
+		Tag:            c.Tag,
+		MaxPrice:       c.MaxPrice,
+		MaxPriceBasis:  c.MaxPriceBasis,
+		AutoPay:        c.AutoPay,
+		BestEffort:     c.BestEffort,
+		RequireIPv6:    c.IPv6,
+		Reverse:        c.Reverse,
+		BillingAccount: c.BillingAccount,
+
+		DeliveryPriority:  c.DeliveryPriority,
+		CustomerReference: c.CustomerReference,
+	}
+	req.Configuration = configurationFromLabels(c.Configuration)
+	for _, option := range c.Options {
+		req.Options = append(req.Options, Option{
+			PlanCode:      option.PlanCode,
+			Duration:      option.Duration,
+			PricingMode:   option.PricingMode,
+			Configuration: configurationFromLabels(option.Configuration),
+			ExtraParams:   option.ExtraParams,
+		})
+	}
+	if c.ExtraIPs != nil {
+		req.ExtraIPs = &ExtraIPs{Count: c.ExtraIPs.Count, Type: c.ExtraIPs.Type, Country: c.ExtraIPs.Country}
+	}
+	if c.Install != nil {
+		req.Install = &InstallRequest{Template: c.Install.Template, PartitionScheme: c.Install.PartitionScheme, Hostname: c.Install.Hostname}
+	}
+	return req
+}
+
+// configurationFromLabels converts configuration labels of a config file
+func configurationFromLabels(labels []ConfigLabel) []Configuration {
+	var configs []Configuration
+	for _, label := range labels {
+		configs = append(configs, Configuration{Label: label.Label, Value: label.Value})
+	}
+	return configs
+}
+
+// ConfigFromRequest converts an OrderRequest back to a configuration file,
+// e.g. to print the effective configuration of a run. The run ID is left
+// out as it identifies a single run.
+func ConfigFromRequest(req OrderRequest) *Config {
+	c := &Config{
+		Version:        ConfigVersion,
+		Subsidiary:     req.Subsidiary,
+		Description:    req.Description,
+		Plan:           req.PlanCode,
+		Duration:       req.Duration,
+		PricingMode:    req.PricingMode,
+		Quantity:       req.Quantity,
+		OS:             req.OS,
+		Tag:            req.Tag,
+		MaxPrice:       req.MaxPrice,
+		MaxPriceBasis:  req.MaxPriceBasis,
+		AutoPay:        req.AutoPay,
+		BestEffort:     req.BestEffort,
+		IPv6:           req.RequireIPv6,
+		Reverse:        req.Reverse,
+		BillingAccount: req.BillingAccount,
+		ExtraParams:    req.ExtraParams,
+
+		DeliveryPriority:  req.DeliveryPriority,
+		CustomerReference: req.CustomerReference,
+	}
+	c.Configuration = labelsFromConfiguration(req.Configuration)
+	for _, option := range req.Options {
+		c.Options = append(c.Options, ConfigOption{
+			PlanCode:      option.PlanCode,
+			Duration:      option.Duration,
+			PricingMode:   option.PricingMode,
+			Configuration: labelsFromConfiguration(option.Configuration),
+			ExtraParams:   option.ExtraParams,
+		})
+	}
+	if req.ExtraIPs != nil {
+		c.ExtraIPs = &ConfigExtraIPs{Count: req.ExtraIPs.Count, Type: req.ExtraIPs.Type, Country: req.ExtraIPs.Country}
+	}
+	if req.Install != nil {
+		c.Install = &ConfigInstall{Template: req.Install.Template, PartitionScheme: req.Install.PartitionScheme, Hostname: req.Install.Hostname}
+	}
+	return c
+}
+
+// labelsFromConfiguration converts configuration labels to a config file
+func labelsFromConfiguration(configs []Configuration) []ConfigLabel {
+	var labels []ConfigLabel
+	for _, config := range configs {
+		labels = append(labels, ConfigLabel{Label: config.Label, Value: config.Value})
+	}
+	return labels
+}
+
+// ConfigFromResult returns the configuration reproducing the order of req:
+// that of ConfigFromRequest, with the configuration labels and options as
+// they were added to the cart, e.g. with the region inferred from the
+// datacenter, the OS picked and the options added automatically. Options
+// skipped with BestEffort are left out.
+func ConfigFromResult(req OrderRequest, result *OrderResult) *Config {
+	c := ConfigFromRequest(req)
+	if result == nil || result.ItemID == 0 {
+		return c
+	}
+	c.Configuration = nil
+	for _, configured := range result.Configuration {
+		c.Configuration = append(c.Configuration, ConfigLabel{Label: configured.Label, Value: configured.Value})
+	}
+	// The OS is one of the configuration labels now
+	c.OS = ""
+
+	requested := make(map[string]ConfigOption, len(c.Options))
+	for _, option := range c.Options {
+		requested[option.PlanCode] = option
+	}
+	c.Options = nil
+	for _, added := range result.Options {
+		option := requested[added.PlanCode]
+		option.PlanCode = added.PlanCode
+		option.Configuration = nil
+		for _, configured := range added.Configuration {
+			option.Configuration = append(option.Configuration, ConfigLabel{Label: configured.Label, Value: configured.Value})
+		}
+		c.Options = append(c.Options, option)
+	}
+	return c
+}
+
+// Redacted returns a copy of the configuration whose extra parameters have
+// their secrets (passwords, tokens, keys) replaced, for printing.
+func (c Config) Redacted() Config {
+	c.ExtraParams = redactParams(c.ExtraParams)
+	options := make([]ConfigOption, len(c.Options))
+	for i, option := range c.Options {
+		option.ExtraParams = redactParams(option.ExtraParams)
+		options[i] = option
+	}
+	c.Options = options
+	return c
+}
+
+// redactParams returns a deep copy of params with the values of
+// scrubbedFields replaced
+func redactParams(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		return nil
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil
+	}
+	var redacted map[string]interface{}
+	if err := json.Unmarshal(data, &redacted); err != nil {
+		return nil
+	}
+	scrubValue(redacted)
+	return redacted
+}
@@ -0,0 +1,287 @@
+package orderer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Configuration labels handled specifically.
+const (
+	// labelOS selects the operating system installed at delivery.
+	labelOS = "dedicated_os"
+	// labelRegion and labelDatacenter select where the server is delivered.
+	labelRegion     = "region"
+	labelDatacenter = "dedicated_datacenter"
+)
+
+// isPlacementLabel reports whether label selects where the server is
+// delivered: the region and datacenter, and the finer placement some plans
+// offer, such as an availability zone or a rack
+func isPlacementLabel(label string) bool {
+	if label == labelRegion || label == labelDatacenter {
+		return true
+	}
+	return strings.Contains(label, "zone") || strings.Contains(label, "rack")
+}
+
+// datacenterRegions maps the known dedicated_datacenter values to the region
+// value they belong to.
+var datacenterRegions = map[string]string{
+	"hil": "united_states",
+	"vin": "united_states",
+	"bhs": "canada",
+	"yyz": "canada",
+	"gra": "europe",
+	"rbx": "europe",
+	"sbg": "europe",
+	"lim": "europe",
+	"waw": "europe",
+	"eri": "europe",
+	"fra": "europe",
+	"sgp": "asia",
+	"syd": "asia",
+	"ynm": "asia",
+}
+
+// labelDependencies lists, for configuration labels whose allowed values
+// depend on other labels, the labels that must be set before them. The
+// datacenter offered depends on the region.
+var labelDependencies = map[string][]string{
+	labelDatacenter: {labelRegion},
+}
+
+// sortConfiguration returns configs in the order they must be posted to the
+// cart. The order of the request is kept, except that a label is always
+// posted after the labels it depends on (see labelDependencies): with the
+// default configuration, region is set before dedicated_datacenter.
+func sortConfiguration(configs []Configuration) []Configuration {
+	byLabel := make(map[string]Configuration, len(configs))
+	for _, config := range configs {
+		byLabel[config.Label] = config
+	}
+
+	sorted := make([]Configuration, 0, len(configs))
+	done := make(map[string]bool, len(configs))
+	var visit func(config Configuration)
+	visit = func(config Configuration) {
+		if done[config.Label] {
+			return
+		}
+		done[config.Label] = true
+		for _, dependency := range labelDependencies[config.Label] {
+			if dep, ok := byLabel[dependency]; ok {
+				visit(dep)
+			}
+		}
+		sorted = append(sorted, config)
+	}
+	for _, config := range configs {
+		visit(config)
+	}
+	return sorted
+}
+
+// configurationLevels splits configs, sorted by sortConfiguration, into
+// levels: the labels of a level only depend on labels of earlier levels, so
+// the labels of a level can be posted concurrently
+func configurationLevels(configs []Configuration) [][]Configuration {
+	configured := make(map[string]bool, len(configs))
+	for _, config := range configs {
+		configured[config.Label] = true
+	}
+	level := make(map[string]int, len(configs))
+	var levels [][]Configuration
+	for _, config := range sortConfiguration(configs) {
+		l := 0
+		for _, dependency := range labelDependencies[config.Label] {
+			if configured[dependency] && level[dependency]+1 > l {
+				l = level[dependency] + 1
+			}
+		}
+		level[config.Label] = l
+		for len(levels) <= l {
+			levels = append(levels, nil)
+		}
+		levels[l] = append(levels[l], config)
+	}
+	return levels
+}
+
+// configureAll posts configs to a cart item, concurrently for the labels
+// that do not depend on each other, and returns the entries created in the
+// order they were posted
+func (o *Orderer) configureAll(ctx context.Context, cartID string, itemID int64, configs []Configuration) ([]ConfigurationResult, error) {
+	var results []ConfigurationResult
+	for _, level := range configurationLevels(configs) {
+		configured := make([]*ConfigurationResult, len(level))
+		errs := make([]error, len(level))
+		var wg sync.WaitGroup
+		for i, config := range level {
+			wg.Add(1)
+			go func(i int, config Configuration) {
+				defer wg.Done()
+				configured[i], errs[i] = o.configure(ctx, cartID, itemID, config)
+			}(i, config)
+		}
+		wg.Wait()
+
+		for i, err := range errs {
+			if err != nil {
+				return results, err
+			}
+			results = append(results, *configured[i])
+		}
+	}
+	return results, nil
+}
+
+// requiredConfiguration fetches the configuration labels accepted by a cart item
+func (o *Orderer) requiredConfiguration(ctx context.Context, cartID string, itemID int64) ([]RequiredConfiguration, error) {
+	var required []RequiredConfiguration
+	err := o.client.GetWithContext(ctx, fmt.Sprintf("/order/cart/%s/item/%d/requiredConfiguration", cartID, itemID), &required)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching required configuration: %w", err)
+	}
+	return required, nil
+}
+
+// findRequired returns the description of label, or nil if the item does not accept it
+func findRequired(required []RequiredConfiguration, label string) *RequiredConfiguration {
+	for i := range required {
+		if required[i].Label == label {
+			return &required[i]
+		}
+	}
+	return nil
+}
+
+// resolveOS returns configs with the dedicated_os label set to a value
+// offered by the plan. os, when set, takes precedence over a dedicated_os
+// entry of configs. When neither is set, the "no OS" value of the plan
+// (none_64...) is used.
+func resolveOS(configs []Configuration, os string, required []RequiredConfiguration) ([]Configuration, error) {
+	osConfig := findRequired(required, labelOS)
+	if osConfig == nil || len(osConfig.AllowedValues) == 0 {
+		// The plan does not let us choose, keep whatever was asked
+		if os != "" {
+			return nil, fmt.Errorf("the plan does not offer choosing the OS at order time")
+		}
+		return configs, nil
+	}
+
+	index := -1
+	for i, config := range configs {
+		if config.Label == labelOS {
+			index = i
+		}
+	}
+	if os == "" && index >= 0 {
+		os = configs[index].Value
+	}
+	if os == "" {
+		for _, value := range osConfig.AllowedValues {
+			if strings.HasPrefix(value, "none_") {
+				os = value
+				break
+			}
+		}
+		if os == "" {
+			return nil, fmt.Errorf("no OS specified and the plan has no \"none\" OS, valid values: %s", strings.Join(osConfig.AllowedValues, ", "))
+		}
+	}
+	if !contains(osConfig.AllowedValues, os) {
+		return nil, fmt.Errorf("invalid OS %q, valid values: %s", os, strings.Join(osConfig.AllowedValues, ", "))
+	}
+
+	resolved := append([]Configuration(nil), configs...)
+	if index >= 0 {
+		resolved[index].Value = os
+	} else {
+		resolved = append(resolved, Configuration{Label: labelOS, Value: os})
+	}
+	return resolved, nil
+}
+
+// labelValue returns the value of label in configs and its index, or -1
+func labelValue(configs []Configuration, label string) (string, int) {
+	for i, config := range configs {
+		if config.Label == label {
+			return config.Value, i
+		}
+	}
+	return "", -1
+}
+
+// checkAllowed verifies that value is allowed for label, when the item
+// restricts its values
+func checkAllowed(required []RequiredConfiguration, label, value string) error {
+	config := findRequired(required, label)
+	if config == nil || len(config.AllowedValues) == 0 || contains(config.AllowedValues, value) {
+		return nil
+	}
+	return fmt.Errorf("invalid %s %q, valid values: %s", label, value, strings.Join(config.AllowedValues, ", "))
+}
+
+// resolvePlacement validates the region and datacenter of configs against
+// each other and against the values allowed by the plan, as well as the
+// other placement labels (see isPlacementLabel). When only the datacenter is
+// given and the plan accepts a region, the region is inferred from the
+// datacenter.
+func resolvePlacement(configs []Configuration, required []RequiredConfiguration) ([]Configuration, error) {
+	region, regionIndex := labelValue(configs, labelRegion)
+	datacenter, datacenterIndex := labelValue(configs, labelDatacenter)
+
+	if datacenterIndex >= 0 {
+		if err := checkAllowed(required, labelDatacenter, datacenter); err != nil {
+			return nil, err
+		}
+		known, ok := datacenterRegions[datacenter]
+		switch {
+		case regionIndex < 0 && findRequired(required, labelRegion) != nil:
+			if !ok {
+				return nil, fmt.Errorf("cannot infer the region of datacenter %q, please set the region label", datacenter)
+			}
+			region, regionIndex = known, len(configs)
+			configs = append(append([]Configuration(nil), configs...), Configuration{Label: labelRegion, Value: region})
+		case regionIndex >= 0 && ok && known != region:
+			return nil, fmt.Errorf("datacenter %q is in region %q, not %q", datacenter, known, region)
+		}
+	}
+	if regionIndex >= 0 {
+		if err := checkAllowed(required, labelRegion, region); err != nil {
+			return nil, err
+		}
+	}
+
+	// The finer placement labels are only valid if the plan offers them
+	for _, config := range configs {
+		if !isPlacementLabel(config.Label) || config.Label == labelRegion || config.Label == labelDatacenter {
+			continue
+		}
+		if findRequired(required, config.Label) == nil {
+			return nil, fmt.Errorf("the plan does not offer choosing the %s", config.Label)
+		}
+		if err := checkAllowed(required, config.Label, config.Value); err != nil {
+			return nil, err
+		}
+	}
+	return configs, nil
+}
+
+// placement returns the placement labels of configured and the value OVH
+// recorded for each
+func placement(configured []ConfigurationResult) map[string]string {
+	var granted map[string]string
+	for _, config := range configured {
+		if !isPlacementLabel(config.Label) {
+			continue
+		}
+		if granted == nil {
+			granted = make(map[string]string)
+		}
+		granted[config.Label] = config.Value
+	}
+	return granted
+}
@@ -0,0 +1,78 @@
+package orderer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Run with -race: the labels of two items of a cart are posted from
+// concurrent configureAll calls, and each posts its independent labels
+// concurrently, the region still before the datacenter
+func TestConfigureAllConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	posted := make(map[string]bool)
+	nextID := 0
+	client := &stubClient{answer: func(method, path string, body interface{}) (interface{}, error) {
+		if method != "POST" || !strings.HasSuffix(path, "/configuration") {
+			return nil, fmt.Errorf("unexpected call %s %s", method, path)
+		}
+		config := body.(map[string]interface{})
+		label := config["label"].(string)
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if label == labelDatacenter && !posted[path+" "+labelRegion] {
+			return nil, fmt.Errorf("%s posted before the region", label)
+		}
+		posted[path+" "+label] = true
+		nextID++
+		return map[string]interface{}{"id": nextID, "label": label, "value": config["value"]}, nil
+	}}
+	o := New(client, Options{Clock: newFakeClock()})
+	configs := []Configuration{
+		{Label: labelDatacenter, Value: "gra"},
+		{Label: labelRegion, Value: "europe"},
+		{Label: labelOS, Value: "none_64.en"},
+		{Label: "dedicated_rack", Value: "rack-1"},
+	}
+
+	items := []int64{1001, 1002}
+	results := make([][]ConfigurationResult, len(items))
+	errs := make([]error, len(items))
+	var wg sync.WaitGroup
+	for i, itemID := range items {
+		wg.Add(1)
+		go func(i int, itemID int64) {
+			defer wg.Done()
+			results[i], errs[i] = o.configureAll(context.Background(), "cart-1", itemID, configs)
+		}(i, itemID)
+	}
+	wg.Wait()
+
+	for i, itemID := range items {
+		if errs[i] != nil {
+			t.Fatalf("item %d: %v", itemID, errs[i])
+		}
+		if len(results[i]) != len(configs) {
+			t.Fatalf("item %d: %d labels configured, want %d", itemID, len(results[i]), len(configs))
+		}
+		if first := results[i][0].Label; first != labelRegion {
+			t.Errorf("item %d: %s configured first, want %s", itemID, first, labelRegion)
+		}
+		for _, result := range results[i] {
+			if result.ItemID != itemID {
+				t.Errorf("item %d: %s configured on item %d", itemID, result.Label, result.ItemID)
+			}
+		}
+		for _, config := range configs {
+			if !posted[fmt.Sprintf("/order/cart/cart-1/item/%d/configuration %s", itemID, config.Label)] {
+				t.Errorf("item %d: %s not posted", itemID, config.Label)
+			}
+		}
+	}
+}
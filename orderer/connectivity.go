@@ -0,0 +1,94 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// ErrUnreachable is returned when a delivered server does not answer on the
+// port checked by VerifyConnectivity in time.
+var ErrUnreachable = errors.New("server unreachable")
+
+// ConnectivityRequest checks that a delivered server answers on the network.
+type ConnectivityRequest struct {
+	// Port is the TCP port dialed on the primary IP of the server. Defaults
+	// to 22.
+	Port int
+
+	// Timeout bounds the attempts, as the server may still be booting or
+	// installing when it is delivered. Defaults to 10 minutes.
+	Timeout time.Duration
+}
+
+// validate checks the port before anything is ordered
+func (c ConnectivityRequest) validate() error {
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("invalid connectivity port %d", c.Port)
+	}
+	return nil
+}
+
+// ConnectivityState is the result of VerifyConnectivity.
+type ConnectivityState struct {
+	IP        string
+	Port      int
+	Reachable bool
+
+	// Attempts is the number of dials made.
+	Attempts int
+}
+
+// VerifyConnectivity dials the primary IP of a delivered server on
+// req.Port over TCP until the connection is accepted or req.Timeout elapses,
+// in which case the state is returned with ErrUnreachable. ICMP is not used,
+// as it needs privileges the tool usually runs without, and an open port
+// shows the server is up far better than an answer to a ping.
+func (o *Orderer) VerifyConnectivity(ctx context.Context, serviceName string, req ConnectivityRequest) (*ConnectivityState, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	if req.Port == 0 {
+		req.Port = 22
+	}
+	if req.Timeout <= 0 {
+		req.Timeout = 10 * time.Minute
+	}
+	var server struct {
+		IP string `json:"ip"`
+	}
+	if err := o.client.GetWithContext(ctx, fmt.Sprintf("/dedicated/server/%s", serviceName), &server); err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", serviceName, err)
+	}
+	if server.IP == "" {
+		return nil, fmt.Errorf("%w: %s has no primary IP", ErrUnreachable, serviceName)
+	}
+
+	state := &ConnectivityState{IP: server.IP, Port: req.Port}
+	address := net.JoinHostPort(server.IP, strconv.Itoa(req.Port))
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	var lastErr error
+	err := o.pollUntil(ctx, poll{Interval: 5 * time.Second, MaxInterval: time.Minute, Timeout: req.Timeout}, func() (bool, error) {
+		state.Attempts++
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			lastErr = err
+			o.debugf("Dialing %s: %v", address, err)
+			return false, nil
+		}
+		conn.Close()
+		return true, nil
+	})
+	if errors.Is(err, ErrTimeout) {
+		return state, fmt.Errorf("%w: %s did not accept a connection on %s within %s: %v", ErrUnreachable, serviceName, address, req.Timeout, lastErr)
+	}
+	if err != nil {
+		return state, err
+	}
+	state.Reachable = true
+	o.logger.Printf("%s accepts connections on %s", serviceName, address)
+	return state, nil
+}
@@ -0,0 +1,79 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestVerifyConnectivity(t *testing.T) {
+	open, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer open.Close()
+	go func() {
+		for {
+			conn, err := open.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	// A port nothing listens on any more refuses the connections
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	tests := []struct {
+		name string
+		ip   string
+		req  ConnectivityRequest
+
+		wantReachable bool
+		wantErr       error
+	}{
+		{name: "reachable", ip: "127.0.0.1", req: ConnectivityRequest{Port: open.Addr().(*net.TCPAddr).Port}, wantReachable: true},
+		{name: "unreachable", ip: "127.0.0.1", req: ConnectivityRequest{Port: closed.Addr().(*net.TCPAddr).Port, Timeout: time.Minute}, wantErr: ErrUnreachable},
+		{name: "no IP", req: ConnectivityRequest{Port: 22}, wantErr: ErrUnreachable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &stubClient{answer: func(method, path string, body interface{}) (interface{}, error) {
+				if method+" "+path != "GET /dedicated/server/ns123.ip-1-2-3.eu" {
+					return nil, fmt.Errorf("unexpected call %s %s", method, path)
+				}
+				return map[string]interface{}{"name": "ns123.ip-1-2-3.eu", "ip": tt.ip}, nil
+			}}
+			o := New(client, Options{Clock: newFakeClock()})
+
+			state, err := o.VerifyConnectivity(context.Background(), "ns123.ip-1-2-3.eu", tt.req)
+			if tt.wantErr == nil && err != nil || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if state == nil {
+				return
+			}
+			if state.Reachable != tt.wantReachable {
+				t.Errorf("reachable = %t, want %t", state.Reachable, tt.wantReachable)
+			}
+			// The dials are retried until the timeout
+			if !tt.wantReachable && state.Attempts < 2 {
+				t.Errorf("%d attempts, want several", state.Attempts)
+			}
+		})
+	}
+
+	t.Run("invalid port", func(t *testing.T) {
+		o := New(&stubClient{}, Options{Clock: newFakeClock()})
+		if _, err := o.VerifyConnectivity(context.Background(), "ns123.ip-1-2-3.eu", ConnectivityRequest{Port: 70000}); err == nil {
+			t.Error("port 70000 accepted")
+		}
+	})
+}
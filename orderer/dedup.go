@@ -0,0 +1,63 @@
+package orderer
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// sharedPaths are the prefixes of the read-only GETs whose concurrent
+// identical calls share a single request: the catalogs and the stock. Carts,
+// orders and services change under the caller and are always fetched, and
+// mutating calls are never shared.
+var sharedPaths = []string{
+	"/order/catalog/",
+	"/dedicated/server/datacenter/availabilities",
+}
+
+// dedupClient sends a single request for identical concurrent GETs of
+// sharedPaths, e.g. the availabilities every order of a bulk run checks, and
+// hands its response to every caller. It wraps the retryingClient, so the
+// retries of a shared call are shared too.
+type dedupClient struct {
+	Client
+	group singleflight.Group
+}
+
+func (c *dedupClient) GetWithContext(ctx context.Context, url string, resType interface{}) error {
+	if !isSharedPath(url) {
+		return c.Client.GetWithContext(ctx, url, resType)
+	}
+	// The shared call must outlive the caller that started it, whose context
+	// may be cancelled (e.g. by BulkOptions.OrderTimeout) while others wait
+	// for the response; each caller stops waiting on its own context instead.
+	results := c.group.DoChan(url, func() (interface{}, error) {
+		var raw json.RawMessage
+		err := c.Client.GetWithContext(context.WithoutCancel(ctx), url, &raw)
+		return raw, err
+	})
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return result.Err
+		}
+		// Every caller decodes its own copy, so none can alter the
+		// response of another
+		return json.Unmarshal(result.Val.(json.RawMessage), resType)
+	}
+}
+
+// isSharedPath reports whether a GET of url may be shared with identical
+// concurrent calls
+func isSharedPath(url string) bool {
+	for _, prefix := range sharedPaths {
+		if strings.HasPrefix(url, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package orderer
+
+// PlanBuild is a typical build of a plan: the options and configuration it
+// is usually ordered with.
+type PlanBuild struct {
+	Options       []Option
+	Configuration []Configuration
+}
+
+// DefaultPlanBuilds maps well-known plan codes to their typical build, which
+// is suggested when such a plan is ordered without options. Programs may add
+// or replace entries before ordering.
+var DefaultPlanBuilds = map[string]PlanBuild{
+	"24rise01-us": {
+		Options: []Option{
+			{PlanCode: "vrack-bandwidth-1000-24rise-us"},
+			{PlanCode: "softraid-2x512nvme-24rise-us"},
+			{PlanCode: "ram-32g-ecc-3200-24rise-us"},
+			{PlanCode: "bandwidth-1000-unguaranteed-24rise-us"},
+		},
+		Configuration: []Configuration{
+			{Label: "region", Value: "united_states"},
+			{Label: "dedicated_datacenter", Value: "hil"},
+		},
+	},
+}
+
+// SuggestBuild returns the typical build of planCode from
+// DefaultPlanBuilds, if it has one.
+func SuggestBuild(planCode string) (PlanBuild, bool) {
+	build, ok := DefaultPlanBuilds[planCode]
+	return build, ok
+}
+
+// ApplyBuild adds the options of build to the request when it has none, and
+// the configuration labels of build it does not set, so that whatever the
+// request already specifies takes precedence.
+func (r *OrderRequest) ApplyBuild(build PlanBuild) {
+	if len(r.Options) == 0 {
+		r.Options = append([]Option(nil), build.Options...)
+	}
+	for _, config := range build.Configuration {
+		if _, i := labelValue(r.Configuration, config.Label); i < 0 {
+			r.Configuration = append(r.Configuration, config)
+		}
+	}
+}
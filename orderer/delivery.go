@@ -0,0 +1,118 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// ErrOrderCancelled is returned when an order is cancelled while waiting for
+// its delivery.
+var ErrOrderCancelled = errors.New("order was cancelled")
+
+// deliveryPoll is the interval between two status checks of an order being
+// delivered: Options.DeliveryPollInterval until the server is being built,
+// then from Options.DeliveryBuildPollInterval doubling up to
+// Options.DeliveryMaxPollInterval. A rate limited check waits at least as
+// long as its Retry-After asks.
+type deliveryPoll struct {
+	opts     *Options
+	interval time.Duration
+	building bool
+
+	retryAfter *retryAfter
+}
+
+// observe updates the interval after a check answering status
+func (p *deliveryPoll) observe(status string) {
+	if status != "delivering" {
+		p.interval = p.opts.DeliveryPollInterval
+		return
+	}
+	if !p.building {
+		p.building = true
+		p.interval = p.opts.DeliveryBuildPollInterval
+		return
+	}
+	if p.interval < p.opts.DeliveryMaxPollInterval {
+		p.interval *= 2
+		if p.interval > p.opts.DeliveryMaxPollInterval {
+			p.interval = p.opts.DeliveryMaxPollInterval
+		}
+	}
+}
+
+// next returns the delay before the next check
+func (p *deliveryPoll) next() time.Duration {
+	if d := p.retryAfter.take(); d > p.interval {
+		return d
+	}
+	return p.interval
+}
+
+// waitForOrderDelivered polls the status of orderID until it is delivered
+func (o *Orderer) waitForOrderDelivered(ctx context.Context, orderID string) error {
+	p := &deliveryPoll{opts: &o.opts, interval: o.opts.DeliveryPollInterval}
+	ctx, p.retryAfter = withRetryAfter(ctx)
+	err := o.pollUntil(ctx, poll{Timeout: o.opts.DeliveryTimeout, Next: p.next}, func() (bool, error) {
+		var status string
+		err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%s/status", orderID), &status)
+		var apiErr *ovh.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests {
+			// Still rate limited once the retries are spent: check again later
+			o.logger.Printf("Status checks of order %s are rate limited, waiting before the next one...", orderID)
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("error fetching status of order %s: %w", orderID, err)
+		}
+		switch status {
+		case "delivered":
+			o.logger.Printf("Order %s has been delivered.", orderID)
+			return true, nil
+		case "cancelled", "cancelling":
+			return false, fmt.Errorf("order %s: %w", orderID, ErrOrderCancelled)
+		}
+		p.observe(status)
+		o.logger.Printf("Order %s is %s, waiting for its delivery...", orderID, status)
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("error waiting for delivery of order %s: %w", orderID, err)
+	}
+	return nil
+}
+
+// WaitForDelivery waits until orderID is delivered and returns the service
+// name of the delivered dedicated server.
+func (o *Orderer) WaitForDelivery(ctx context.Context, orderID string) (string, error) {
+	if err := o.waitForOrderDelivered(ctx, orderID); err != nil {
+		return "", err
+	}
+
+	var detailIDs []int64
+	err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%s/details", orderID), &detailIDs)
+	if err != nil {
+		return "", fmt.Errorf("error fetching details of order %s: %w", orderID, err)
+	}
+	for _, detailID := range detailIDs {
+		var detail struct {
+			Domain string `json:"domain"`
+		}
+		err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%s/details/%s", orderID, strconv.FormatInt(detailID, 10)), &detail)
+		if err != nil {
+			return "", fmt.Errorf("error fetching detail %d of order %s: %w", detailID, orderID, err)
+		}
+		// Options and fees are listed with a "*" domain, the server with its service name
+		if detail.Domain != "" && detail.Domain != "*" {
+			o.logger.Printf("Delivered server: %s", detail.Domain)
+			return detail.Domain, nil
+		}
+	}
+	return "", fmt.Errorf("no service name found in the details of order %s", orderID)
+}
@@ -0,0 +1,85 @@
+package orderer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DeliveryOption is the delivery option of an order, such as an express
+// delivery some plans offer.
+type DeliveryOption struct {
+	PlanCode string
+
+	// Description is the name of the option in the catalog, which tells the
+	// expected delivery time when OVH gives it: the API exposes it nowhere
+	// else.
+	Description string
+}
+
+// isDeliveryCode reports whether planCode is the plan code of a delivery
+// option, for order details which come without their family
+func isDeliveryCode(planCode string) bool {
+	return strings.Contains(strings.ToLower(planCode), "delivery")
+}
+
+// IsDelivery reports whether the offer changes the delivery of the server,
+// e.g. an express or priority delivery, from its family or its plan code.
+func (offer OptionOffer) IsDelivery() bool {
+	return strings.Contains(strings.ToLower(offer.Family), "delivery") || isDeliveryCode(offer.PlanCode)
+}
+
+// DeliveryOptions returns the delivery options among offers.
+func DeliveryOptions(offers []OptionOffer) []OptionOffer {
+	var delivery []OptionOffer
+	for _, offer := range offers {
+		if offer.IsDelivery() {
+			delivery = append(delivery, offer)
+		}
+	}
+	return delivery
+}
+
+// addDeliveryOption returns options with the delivery option of
+// req.DeliveryPriority added: the delivery option of that plan code, or
+// whose plan code or name contains it (e.g. express). A plan offering no
+// delivery option is ordered with its standard delivery, which is logged,
+// while a priority the plan does not offer fails with ErrOptionNotOffered,
+// listing those it does.
+func addDeliveryOption(logger Logger, offers []OptionOffer, req OrderRequest, options []Option) ([]Option, error) {
+	if req.DeliveryPriority == "" {
+		return options, nil
+	}
+	delivery := DeliveryOptions(offers)
+	if len(delivery) == 0 {
+		logger.Printf("Plan %s offers no delivery option, %s is skipped and the standard delivery applies", req.PlanCode, req.DeliveryPriority)
+		return options, nil
+	}
+
+	priority := strings.ToLower(req.DeliveryPriority)
+	var matches, names []string
+	for _, offer := range delivery {
+		names = append(names, offer.PlanCode)
+		if strings.Contains(strings.ToLower(offer.PlanCode), priority) || strings.Contains(strings.ToLower(offer.ProductName), priority) {
+			matches = append(matches, offer.PlanCode)
+		}
+	}
+	if _, ok := findOffer(delivery, req.DeliveryPriority); ok {
+		matches = []string{req.DeliveryPriority}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: no delivery option %s for plan %s, delivery options: %s",
+			ErrOptionNotOffered, req.DeliveryPriority, req.PlanCode, strings.Join(names, ", "))
+	case 1:
+	default:
+		return nil, fmt.Errorf("delivery priority %s of plan %s is ambiguous, it matches: %s", req.DeliveryPriority, req.PlanCode, strings.Join(matches, ", "))
+	}
+	for _, option := range options {
+		if option.PlanCode == matches[0] {
+			return options, nil
+		}
+	}
+	offer, _ := findOffer(delivery, matches[0])
+	logger.Printf("Adding the delivery option %s (%s)", offer.PlanCode, offer.ProductName)
+	return append(options, Option{PlanCode: offer.PlanCode}), nil
+}
@@ -0,0 +1,74 @@
+package orderer
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddDeliveryOption(t *testing.T) {
+	offers := []OptionOffer{
+		{PlanCode: "ram-64g", Family: "memory", ProductName: "64 GB of RAM"},
+		{PlanCode: "express-delivery", Family: "delivery", ProductName: "Express delivery within 24h"},
+		{PlanCode: "priority-delivery", Family: "delivery", ProductName: "Priority delivery within 72h"},
+		{PlanCode: "priority-delivery-weekend", Family: "delivery", ProductName: "Priority delivery on weekends"},
+	}
+	selected := []Option{{PlanCode: "ram-64g"}}
+	tests := []struct {
+		name     string
+		priority string
+		offers   []OptionOffer
+		options  []Option
+
+		want    []string
+		wantErr error
+		wantLog string
+	}{
+		{name: "none", offers: offers, options: selected, want: []string{"ram-64g"}},
+		{name: "word of the plan code", priority: "express", offers: offers, options: selected, want: []string{"ram-64g", "express-delivery"}, wantLog: "Adding the delivery option express-delivery"},
+		{name: "word of the name", priority: "24H", offers: offers, options: selected, want: []string{"ram-64g", "express-delivery"}},
+		{name: "plan code", priority: "priority-delivery", offers: offers, options: selected, want: []string{"ram-64g", "priority-delivery"}},
+		{name: "already selected", priority: "express", offers: offers, options: []Option{{PlanCode: "express-delivery"}}, want: []string{"express-delivery"}},
+		{name: "not offered", priority: "overnight", offers: offers, options: selected, wantErr: ErrOptionNotOffered},
+		{name: "no delivery option", priority: "express", offers: offers[:1], options: selected, want: []string{"ram-64g"}, wantLog: "offers no delivery option"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			req := OrderRequest{PlanCode: "24rise01", DeliveryPriority: tt.priority}
+			options, err := addDeliveryOption(log.New(&buf, "", 0), tt.offers, req, tt.options)
+			if tt.wantErr == nil && err != nil || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, option := range options {
+				got = append(got, option.PlanCode)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("options = %v, want %v", got, tt.want)
+			}
+			if !strings.Contains(buf.String(), tt.wantLog) {
+				t.Errorf("log = %q, want %q in it", buf.String(), tt.wantLog)
+			}
+		})
+	}
+}
+
+// A word matching several delivery options is rejected, listing them
+func TestAddDeliveryOptionAmbiguous(t *testing.T) {
+	offers := []OptionOffer{
+		{PlanCode: "priority-delivery", Family: "delivery"},
+		{PlanCode: "priority-delivery-weekend", Family: "delivery"},
+	}
+	req := OrderRequest{PlanCode: "24rise01", DeliveryPriority: "priority"}
+	_, err := addDeliveryOption(log.New(&bytes.Buffer{}, "", 0), offers, req, nil)
+	if err == nil || errors.Is(err, ErrOptionNotOffered) {
+		t.Fatalf("err = %v, want an ambiguous priority", err)
+	}
+	if !strings.Contains(err.Error(), "priority-delivery, priority-delivery-weekend") {
+		t.Errorf("err = %v, want the matches", err)
+	}
+}
@@ -0,0 +1,72 @@
+package orderer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// The checks slow down once the server is being built, and wait at least as
+// long as a Retry-After asks
+func TestWaitForOrderDelivered(t *testing.T) {
+	// A status, or the Retry-After of a rate limited check
+	answers := []string{"checking", "delivering", "delivering", "600", "delivering", "delivering", "30", "delivered"}
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/auth/time" {
+			fmt.Fprint(w, time.Now().Unix())
+			return
+		}
+		if r.URL.Path != "/me/order/42/status" || calls >= len(answers) {
+			writeAPIError(w, http.StatusNotFound, "unexpected call "+r.URL.Path)
+			return
+		}
+		answer := answers[calls]
+		calls++
+		if answer[0] >= '0' && answer[0] <= '9' {
+			w.Header().Set("Retry-After", answer)
+			writeAPIError(w, http.StatusTooManyRequests, "Too many requests")
+			return
+		}
+		fmt.Fprintf(w, "%q", answer)
+	}))
+	defer srv.Close()
+	client, err := ovh.NewClient(srv.URL, "key", "secret", "consumer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Client.Transport = NewRetryAfterTransport(nil)
+	clock := &recordingClock{fakeClock: newFakeClock()}
+	o := New(client, Options{
+		Clock:                     clock,
+		MaxAttempts:               1,
+		DeliveryPollInterval:      15 * time.Second,
+		DeliveryBuildPollInterval: time.Minute,
+		DeliveryMaxPollInterval:   4 * time.Minute,
+	})
+
+	if err := o.waitForOrderDelivered(context.Background(), "42"); err != nil {
+		t.Fatal(err)
+	}
+	if calls != len(answers) {
+		t.Errorf("%d checks, want %d", calls, len(answers))
+	}
+	want := []time.Duration{
+		15 * time.Second,
+		time.Minute,
+		2 * time.Minute,
+		10 * time.Minute, // the Retry-After of the rate limited check
+		4 * time.Minute,
+		4 * time.Minute,
+		4 * time.Minute, // a shorter Retry-After does not shorten the interval
+	}
+	if !reflect.DeepEqual(clock.delays, want) {
+		t.Errorf("delays = %v, want %v", clock.delays, want)
+	}
+}
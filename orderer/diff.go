@@ -0,0 +1,168 @@
+package orderer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// ConfigChange is a field whose value differs between two configs. Fields
+// are named by their path in the config file, with the configuration labels
+// and the options keyed by label and plan code rather than by position,
+// e.g. configuration.dedicated_datacenter or options.ram-64g.duration. Old
+// or New is empty when the field is only set in the other config.
+type ConfigChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// ConfigDiff compares two configs: what changes in their spec, and what the
+// change costs.
+type ConfigDiff struct {
+	Changes []ConfigChange
+
+	// Old and New are the prices of the configs, with their error when one
+	// could not be priced.
+	Old, New PlanQuote
+
+	// Delta and MonthlyDelta are the price and monthly price of New minus
+	// those of Old, set when both are priced in the same currency.
+	Delta        *Price
+	MonthlyDelta *Price
+}
+
+// DiffConfigs compares the spec of before and after, and prices both in
+// temporary carts, deleted before returning, as ComparePlans does. A config
+// that cannot be priced, e.g. out of stock, gets its error in its quote
+// instead of failing the diff.
+func (o *Orderer) DiffConfigs(ctx context.Context, before, after *Config) (*ConfigDiff, error) {
+	changes, err := diffConfigFields(before, after)
+	if err != nil {
+		return nil, err
+	}
+	diff := &ConfigDiff{Changes: changes}
+	for _, side := range []struct {
+		config *Config
+		quote  *PlanQuote
+	}{{before, &diff.Old}, {after, &diff.New}} {
+		req, err := o.prepare(side.config.OrderRequest())
+		if err != nil {
+			return nil, err
+		}
+		req.Description = "Temporary cart for diff-config"
+		*side.quote = o.quote(ctx, req)
+	}
+	if diff.Old.Err == nil && diff.New.Err == nil {
+		diff.Delta = priceDelta(diff.Old.Price, diff.New.Price)
+		diff.MonthlyDelta = priceDelta(diff.Old.Monthly, diff.New.Monthly)
+	}
+	return diff, nil
+}
+
+// priceDelta returns after minus before, or nil when they cannot be
+// subtracted
+func priceDelta(before, after Price) *Price {
+	if before.CurrencyCode != after.CurrencyCode {
+		return nil
+	}
+	beforeAmount, err := before.Amount()
+	if err != nil {
+		return nil
+	}
+	afterAmount, err := after.Amount()
+	if err != nil {
+		return nil
+	}
+	delta := ratPrice(new(big.Rat).Sub(afterAmount, beforeAmount), after.CurrencyCode)
+	return &delta
+}
+
+// diffConfigFields returns the fields differing between before and after,
+// sorted by name
+func diffConfigFields(before, after *Config) ([]ConfigChange, error) {
+	oldFields, err := configFields(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := configFields(after)
+	if err != nil {
+		return nil, err
+	}
+	var changes []ConfigChange
+	for field, value := range oldFields {
+		if newFields[field] != value {
+			changes = append(changes, ConfigChange{Field: field, Old: value, New: newFields[field]})
+		}
+	}
+	for field, value := range newFields {
+		if _, ok := oldFields[field]; !ok {
+			changes = append(changes, ConfigChange{Field: field, New: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// configFields flattens the JSON form of c to its leaf values by path,
+// leaving out the version of the file
+func configFields(c *Config) (map[string]string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding config: %w", err)
+	}
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("error decoding config: %w", err)
+	}
+	fields := make(map[string]string)
+	flattenField(fields, "", tree)
+	delete(fields, "version")
+	return fields, nil
+}
+
+// flattenField adds value, found at path, to fields. Configuration labels
+// are keyed by label and options by plan code, so that reordering a list is
+// not a change; an option without other fields is "selected".
+func flattenField(fields map[string]string, path string, value interface{}) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			flattenField(fields, join(key), child)
+		}
+	case []interface{}:
+		for i, child := range value {
+			object, _ := child.(map[string]interface{})
+			if label, ok := object["label"].(string); ok && len(object) == 2 {
+				fields[join(label)] = fmt.Sprint(object["value"])
+				continue
+			}
+			if planCode, ok := object["planCode"].(string); ok {
+				if len(object) == 1 {
+					fields[join(planCode)] = "selected"
+					continue
+				}
+				rest := make(map[string]interface{}, len(object)-1)
+				for key, v := range object {
+					if key != "planCode" {
+						rest[key] = v
+					}
+				}
+				flattenField(fields, join(planCode), rest)
+				continue
+			}
+			flattenField(fields, join(fmt.Sprint(i)), child)
+		}
+	case nil:
+	default:
+		fields[path] = fmt.Sprint(value)
+	}
+}
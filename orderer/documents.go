@@ -0,0 +1,61 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Contract is a contract accepted with an order.
+type Contract struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+
+	// Content is the text of the contract.
+	Content string `json:"content"`
+}
+
+// OrderDocuments links to the paperwork of an order.
+type OrderDocuments struct {
+	// OrderURL is the order page in the OVH manager.
+	OrderURL string
+
+	// PDFURL is the order form (bon de commande) as a PDF.
+	PDFURL string
+
+	// RetractionDate is the last day the order can be retracted, if any.
+	RetractionDate *time.Time
+
+	// Contracts accepted with the order.
+	Contracts []Contract
+}
+
+// orderDocuments fetches the document links of a paid order. The PDF may not
+// be generated right after payment, so the order is fetched again until it is
+// or DocumentsWait has elapsed.
+func (o *Orderer) orderDocuments(ctx context.Context, orderID string, contracts []Contract) (*OrderDocuments, error) {
+	var documents *OrderDocuments
+	err := o.pollUntil(ctx, poll{Interval: 5 * time.Second, Timeout: o.opts.DocumentsWait}, func() (bool, error) {
+		var order struct {
+			URL            string     `json:"url"`
+			PDFURL         string     `json:"pdfUrl"`
+			RetractionDate *time.Time `json:"retractionDate"`
+		}
+		if err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%s", orderID), &order); err != nil {
+			return false, fmt.Errorf("error fetching documents of order %s: %w", orderID, err)
+		}
+		documents = &OrderDocuments{
+			OrderURL:       order.URL,
+			PDFURL:         order.PDFURL,
+			RetractionDate: order.RetractionDate,
+			Contracts:      contracts,
+		}
+		return order.PDFURL != "", nil
+	})
+	if errors.Is(err, ErrTimeout) {
+		// Return what is available without the PDF
+		return documents, nil
+	}
+	return documents, err
+}
@@ -0,0 +1,173 @@
+package orderer
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// endpointSubsidiaries lists the subsidiaries whose accounts live on each
+// OVH API endpoint.
+var endpointSubsidiaries = map[string][]string{
+	"ovh-eu": {"CZ", "DE", "ES", "EU", "FI", "FR", "GB", "IE", "IT", "LT", "MA", "NL", "PL", "PT", "SN", "TN"},
+	"ovh-ca": {"ASIA", "AU", "CA", "IN", "QC", "SG", "WE", "WS"},
+	"ovh-us": {"US"},
+}
+
+// NormalizeEndpoint returns the go-ovh name of endpoint ("ovh-eu", ...). The
+// name is matched case-insensitively, and an API URL such as
+// "https://eu.api.ovh.com" is mapped to the endpoint with the same host. An
+// unknown value gives an error listing the valid endpoints.
+func NormalizeEndpoint(endpoint string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(endpoint))
+	if _, ok := ovh.Endpoints[name]; ok {
+		return name, nil
+	}
+	if u, err := url.Parse(strings.TrimSpace(endpoint)); err == nil && u.Host != "" {
+		for known, knownURL := range ovh.Endpoints {
+			if k, err := url.Parse(knownURL); err == nil && strings.EqualFold(k.Host, u.Host) {
+				return known, nil
+			}
+		}
+	}
+	var names []string
+	for known := range ovh.Endpoints {
+		names = append(names, known)
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("unknown endpoint %q, expected one of %s or their API URL", endpoint, strings.Join(names, ", "))
+}
+
+// ErrEndpointNotAllowed is returned by Order when the endpoint of the
+// Orderer is not in Options.AllowedEndpoints.
+var ErrEndpointNotAllowed = errors.New("endpoint not allowed")
+
+// endpointName returns the name of endpoint, which may be an endpoint name or URL
+func endpointName(endpoint string) string {
+	for name, url := range ovh.Endpoints {
+		if endpoint == url {
+			return name
+		}
+	}
+	return endpoint
+}
+
+// endpointRegion returns the ovh-* endpoint family of endpoint, which may be
+// an endpoint name or URL
+func endpointRegion(endpoint string) string {
+	endpoint = endpointName(endpoint)
+	switch endpoint {
+	case "kimsufi-eu", "soyoustart-eu":
+		return "ovh-eu"
+	case "kimsufi-ca", "soyoustart-ca":
+		return "ovh-ca"
+	}
+	return endpoint
+}
+
+// EndpointSubsidiaries returns the subsidiaries served by endpoint, or nil if
+// the endpoint is unknown.
+func EndpointSubsidiaries(endpoint string) []string {
+	return endpointSubsidiaries[endpointRegion(endpoint)]
+}
+
+// NormalizeSubsidiary returns subsidiary in the case OVH expects ("FR",
+// "GB", ...), whatever the case it is given in, as OVH treats "fr" as
+// another, unknown, subsidiary. It fails, listing the valid subsidiaries,
+// on an unknown subsidiary or, when endpoint is known, on a subsidiary
+// whose accounts live on another endpoint.
+func NormalizeSubsidiary(subsidiary, endpoint string) (string, error) {
+	name := strings.ToUpper(strings.TrimSpace(subsidiary))
+	served := EndpointSubsidiaries(endpoint)
+	if served != nil {
+		if contains(served, name) {
+			return name, nil
+		}
+		if knownSubsidiary(name) {
+			return "", fmt.Errorf("subsidiary %s is not served by endpoint %s, which serves %s", name, endpointName(endpoint), strings.Join(served, ", "))
+		}
+		return "", fmt.Errorf("unknown subsidiary %q, endpoint %s serves %s", subsidiary, endpointName(endpoint), strings.Join(served, ", "))
+	}
+	if knownSubsidiary(name) {
+		return name, nil
+	}
+	var all []string
+	for _, subsidiaries := range endpointSubsidiaries {
+		all = append(all, subsidiaries...)
+	}
+	sort.Strings(all)
+	return "", fmt.Errorf("unknown subsidiary %q, expected one of %s", subsidiary, strings.Join(all, ", "))
+}
+
+// knownSubsidiary reports whether subsidiary is served by any endpoint
+func knownSubsidiary(subsidiary string) bool {
+	for _, subsidiaries := range endpointSubsidiaries {
+		if contains(subsidiaries, subsidiary) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultSubsidiary returns the subsidiary discovery commands use on endpoint
+// when none is given, or "" if the endpoint is unknown.
+func DefaultSubsidiary(endpoint string) string {
+	if subsidiaries := EndpointSubsidiaries(endpoint); len(subsidiaries) > 0 {
+		return subsidiaries[0]
+	}
+	return ""
+}
+
+// serverProduct returns the cart product dedicated servers are sold as on
+// endpoint: Kimsufi and So you Start sell theirs as "eco"
+func serverProduct(endpoint string) string {
+	switch endpointName(endpoint) {
+	case "kimsufi-eu", "kimsufi-ca", "soyoustart-eu", "soyoustart-ca":
+		return "eco"
+	}
+	return "baremetalServers"
+}
+
+// checkSubsidiary verifies that subsidiary is served by the endpoint of the
+// Orderer, so that discovery does not silently query another catalog. An
+// unset or unknown endpoint is not checked.
+func (o *Orderer) checkSubsidiary(subsidiary string) error {
+	subsidiaries := EndpointSubsidiaries(o.opts.Endpoint)
+	if subsidiaries == nil || contains(subsidiaries, subsidiary) {
+		return nil
+	}
+	return fmt.Errorf("subsidiary %s is not served by endpoint %s, which serves %s", subsidiary, o.opts.Endpoint, strings.Join(subsidiaries, ", "))
+}
+
+// checkEndpointAllowed verifies that the endpoint of the Orderer is one of
+// Options.AllowedEndpoints, when they are set. Both sides are normalized,
+// so names and API URLs can be mixed.
+func (o *Orderer) checkEndpointAllowed() error {
+	if o.opts.AllowedEndpoints == nil {
+		return nil
+	}
+	endpoint, err := NormalizeEndpoint(o.opts.Endpoint)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrEndpointNotAllowed, err)
+	}
+	for _, allowed := range o.opts.AllowedEndpoints {
+		if name, err := NormalizeEndpoint(allowed); err == nil && name == endpoint {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: ordering on %s, only %s allowed", ErrEndpointNotAllowed, endpoint, strings.Join(o.opts.AllowedEndpoints, ", "))
+}
+
+// serverProductPath returns the path of the dedicated servers of a cart
+func (o *Orderer) serverProductPath(cartID string) string {
+	return "/order/cart/" + cartID + "/" + serverProduct(o.opts.Endpoint)
+}
+
+// serverOptionsPath returns the path of the dedicated server options of a cart
+func (o *Orderer) serverOptionsPath(cartID string) string {
+	return o.serverProductPath(cartID) + "/options"
+}
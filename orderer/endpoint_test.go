@@ -0,0 +1,86 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+func TestNormalizeSubsidiary(t *testing.T) {
+	tests := []struct {
+		name       string
+		subsidiary string
+		endpoint   string
+
+		want    string
+		wantErr bool
+	}{
+		{name: "upper case", subsidiary: "FR", endpoint: "ovh-eu", want: "FR"},
+		{name: "lower case", subsidiary: "us", endpoint: "ovh-us", want: "US"},
+		{name: "spaces", subsidiary: " gb ", endpoint: "ovh-eu", want: "GB"},
+		{name: "endpoint URL", subsidiary: "ca", endpoint: "https://ca.api.ovh.com/1.0", want: "CA"},
+		{name: "kimsufi", subsidiary: "fr", endpoint: "kimsufi-eu", want: "FR"},
+		{name: "other endpoint", subsidiary: "US", endpoint: "ovh-eu", wantErr: true},
+		{name: "unknown", subsidiary: "XX", endpoint: "ovh-eu", wantErr: true},
+		{name: "unknown endpoint", subsidiary: "de", endpoint: "http://localhost:8080", want: "DE"},
+		{name: "unknown on unknown endpoint", subsidiary: "XX", endpoint: "http://localhost:8080", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeSubsidiary(tt.subsidiary, tt.endpoint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeSubsidiary(%q, %q) = %q, %v, want error: %t", tt.subsidiary, tt.endpoint, got, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeSubsidiary(%q, %q) = %q, want %q", tt.subsidiary, tt.endpoint, got, tt.want)
+			}
+		})
+	}
+}
+
+// A cart can be built on any endpoint, but not bought outside the allowed ones
+func TestAllowedEndpoints(t *testing.T) {
+	var calls []string
+	client := &stubClient{answer: func(method, path string, body interface{}) (interface{}, error) {
+		calls = append(calls, method+" "+path)
+		if method+" "+path == "POST /order/cart" {
+			return map[string]interface{}{"cartId": "cart-1"}, nil
+		}
+		return nil, &ovh.APIError{Code: http.StatusBadRequest, Message: "rejected " + path}
+	}}
+	o := New(client, Options{Clock: newFakeClock(), Endpoint: "ovh-ca", AllowedEndpoints: []string{"ovh-eu"}})
+	req := OrderRequest{Subsidiary: "CA", PlanCode: "24ska01"}
+
+	calls = nil
+	if _, err := o.BuildCart(context.Background(), req); err == nil || errors.Is(err, ErrEndpointNotAllowed) || len(calls) == 0 || calls[0] != "POST /order/cart" {
+		t.Errorf("BuildCart: err = %v, calls = %v, want the cart created", err, calls)
+	}
+
+	purchases := []struct {
+		name     string
+		purchase func() error
+	}{
+		{name: "order", purchase: func() error {
+			_, err := o.Order(context.Background(), req)
+			return err
+		}},
+		{name: "checkout", purchase: func() error {
+			return o.purchaseCart(context.Background(), "cart-1", req.purchaseOptions(), &OrderResult{CartID: "cart-1"})
+		}},
+		{name: "payment", purchase: func() error {
+			_, _, err := o.pay(context.Background(), "1", "")
+			return err
+		}},
+	}
+	for _, tt := range purchases {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			if err := tt.purchase(); !errors.Is(err, ErrEndpointNotAllowed) || len(calls) != 0 {
+				t.Errorf("err = %v, calls = %v, want %v before any call", err, calls, ErrEndpointNotAllowed)
+			}
+		})
+	}
+}
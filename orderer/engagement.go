@@ -0,0 +1,62 @@
+package orderer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// ErrEngagementNotAcknowledged is returned by Order when the pricing mode
+// commits to a period longer than Options.EngagementAckMonths and the
+// request does not acknowledge it.
+var ErrEngagementNotAcknowledged = errors.New("engagement not acknowledged")
+
+// engagementPattern matches the committed pricing modes, such as
+// degressivity12 (monthly payments) or upfront24 (paid in advance)
+var engagementPattern = regexp.MustCompile(`^(degressivity|upfront)(\d+)$`)
+
+// Engagement is the commitment of a committed pricing mode.
+type Engagement struct {
+	PricingMode string
+
+	// Months is the length of the commitment.
+	Months int
+
+	// Upfront is set when the whole commitment is paid at checkout, instead
+	// of monthly.
+	Upfront bool
+}
+
+// ParseEngagement returns the commitment of pricingMode, or nil if the
+// pricing mode has none.
+func ParseEngagement(pricingMode string) *Engagement {
+	match := engagementPattern.FindStringSubmatch(pricingMode)
+	if match == nil {
+		return nil
+	}
+	months, err := strconv.Atoi(match[2])
+	if err != nil || months == 0 {
+		return nil
+	}
+	return &Engagement{PricingMode: pricingMode, Months: months, Upfront: match[1] == "upfront"}
+}
+
+func (e *Engagement) String() string {
+	payment := "paid monthly"
+	if e.Upfront {
+		payment = "paid upfront"
+	}
+	return fmt.Sprintf("%d-month commitment (%s, %s): terminating the server early does not end the billing before the end of the commitment, which renews under the terms of the contracts", e.Months, e.PricingMode, payment)
+}
+
+// checkEngagement fails with ErrEngagementNotAcknowledged when the pricing
+// mode of req commits for longer than Options.EngagementAckMonths without
+// req.AckEngagement
+func (o *Orderer) checkEngagement(req OrderRequest) error {
+	engagement := ParseEngagement(req.PricingMode)
+	if engagement == nil || engagement.Months <= o.opts.EngagementAckMonths || req.AckEngagement {
+		return nil
+	}
+	return fmt.Errorf("%w: pricing mode %s commits for %d months", ErrEngagementNotAcknowledged, req.PricingMode, engagement.Months)
+}
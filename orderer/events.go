@@ -0,0 +1,133 @@
+package orderer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Steps of the order flow, as reported in events and timings.
+const (
+	StepReuse        = "reuse"
+	StepCreateCart   = "create-cart"
+	StepAddServer    = "add-server"
+	StepConfigure    = "configure"
+	StepOptions      = "options"
+	StepValidate     = "validate"
+	StepCheckout     = "checkout"
+	StepPayment      = "payment"
+	StepDelivery     = "delivery"
+	StepInstall      = "install"
+	StepExtraIPs     = "extra-ips"
+	StepNetwork      = "network"
+	StepReverse      = "reverse"
+	StepMonitoring   = "monitoring"
+	StepRenewal      = "renewal"
+	StepTag          = "tag"
+	StepConnectivity = "connectivity"
+
+	// StepDone is the last event of an order placed by Order or
+	// RaceDatacenters, emitted whether it succeeded or not. Its Duration is
+	// that of the whole order, and its Err the error the order returned.
+	StepDone = "done"
+)
+
+// Event is a progress event emitted when a step of the flow completes.
+type Event struct {
+	Step     string
+	Start    time.Time
+	Duration time.Duration
+
+	// RunID is the OrderRequest.RunID of the order, if any.
+	RunID string
+
+	// CartID, OrderID and ServiceName are those of the order, once known.
+	CartID      string
+	OrderID     string
+	ServiceName string
+
+	// CustomerReference is that of the request, if any.
+	CustomerReference string
+
+	// Err is the error the step failed with, if any.
+	Err error
+}
+
+// StepTiming is the duration of a step of the flow, and the retries of its
+// API calls.
+type StepTiming struct {
+	Step     string
+	Duration time.Duration
+	RetryStats
+}
+
+// StepError is the error returned by Order when a step fails. It names the
+// step and the cart and order concerned, and wraps the cause so that
+// errors.Is and errors.As see through it.
+type StepError struct {
+	Step    string
+	CartID  string
+	OrderID string
+	Err     error
+}
+
+func (e *StepError) Error() string {
+	var ids []string
+	if e.CartID != "" {
+		ids = append(ids, "cart "+e.CartID)
+	}
+	if e.OrderID != "" {
+		ids = append(ids, "order "+e.OrderID)
+	}
+	if len(ids) == 0 {
+		return fmt.Sprintf("step %s: %v", e.Step, e.Err)
+	}
+	return fmt.Sprintf("step %s (%s): %v", e.Step, strings.Join(ids, ", "), e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// step runs fn as the named step, recording its duration in result and
+// emitting a progress event. Errors are wrapped in a StepError.
+func (o *Orderer) step(result *OrderResult, name string, fn func() error) error {
+	o.explainStep(name)
+	start := o.clock.Now()
+	result.stepStats.take()
+	err := fn()
+	elapsed := o.clock.Now().Sub(start)
+
+	result.Timings = append(result.Timings, StepTiming{Step: name, Duration: elapsed, RetryStats: result.stepStats.take()})
+	o.debugf("Step %s took %s", name, elapsed)
+	if o.opts.OnEvent != nil {
+		o.opts.OnEvent(Event{Step: name, Start: start, Duration: elapsed, RunID: result.RunID, CartID: result.CartID, OrderID: result.OrderID,
+			ServiceName: result.ServiceName, CustomerReference: result.CustomerReference, Err: err})
+	}
+	if err != nil {
+		return &StepError{Step: name, CartID: result.CartID, OrderID: result.OrderID, Err: err}
+	}
+	return nil
+}
+
+// done emits the StepDone event of an order of req started at start, which
+// returned result, possibly nil, and err
+func (o *Orderer) done(req OrderRequest, start time.Time, result *OrderResult, err error) {
+	if o.opts.OnEvent == nil {
+		return
+	}
+	event := Event{Step: StepDone, Start: start, Duration: o.clock.Now().Sub(start), RunID: req.RunID, CustomerReference: req.CustomerReference, Err: err}
+	if result != nil {
+		event.CartID = result.CartID
+		event.OrderID = result.OrderID
+		event.ServiceName = result.ServiceName
+	}
+	o.opts.OnEvent(event)
+}
+
+// debugf logs a message when debug messages are enabled
+func (o *Orderer) debugf(format string, v ...interface{}) {
+	if o.opts.Debug {
+		o.logger.Printf("DEBUG "+format, v...)
+	}
+}
@@ -0,0 +1,144 @@
+package orderer
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// callPurpose explains why the Orderer makes the calls of method to the
+// paths matching pattern, a path.Match pattern
+type callPurpose struct {
+	method  string
+	pattern string
+	why     string
+}
+
+// callPurposes are tried in order, so that specific patterns come before
+// the generic ones they overlap with
+var callPurposes = []callPurpose{
+	{http.MethodPost, "/order/cart", "creating a cart for the subsidiary, which holds the server and its options until checkout"},
+	{http.MethodPost, "/order/cart/*/assign", "assigning the cart to the account of the credentials, which is required to check it out"},
+	{http.MethodGet, "/order/cart/*/checkout", "reading the summary of the cart, whose price is checked against the maximum price and confirmed before buying"},
+	{http.MethodPost, "/order/cart/*/checkout", "checking the cart out: this creates the order, which must then be paid"},
+	{http.MethodGet, "/order/cart/*/item", "listing the items of the cart, to validate it before checkout"},
+	{http.MethodGet, "/order/cart/*/item/*", "reading an item of the cart, to check what it orders"},
+	{http.MethodDelete, "/order/cart/*/item/*", "removing an item from the cart"},
+	{http.MethodGet, "/order/cart/*/item/*/requiredConfiguration", "reading the configuration labels the item needs, such as its datacenter and OS, to fill them in"},
+	{http.MethodGet, "/order/cart/*/item/*/configuration", "reading back the configuration of the item, to validate the cart before checkout"},
+	{http.MethodPost, "/order/cart/*/item/*/configuration", "setting a configuration label of the item, such as its datacenter or OS"},
+	{http.MethodGet, "/order/cart/*/*/options", "listing the options the plan offers, to resolve the options of the order and check they are offered with its pricing"},
+	{http.MethodPost, "/order/cart/*/*/options", "adding an option to the server of the cart"},
+	{http.MethodGet, "/order/cart/*/*", "listing the plans offered to the cart, to check that the plan is sold with the pricing mode and duration of the order"},
+	{http.MethodPost, "/order/cart/*/*", "adding the server to the cart"},
+	{http.MethodGet, "/order/cart/*", "reading the cart, to check that it still exists and when it expires"},
+	{http.MethodPut, "/order/cart/*", "extending the expiry of the cart, so that it does not expire before checkout"},
+	{http.MethodDelete, "/order/cart/*", "deleting the cart, which is no longer needed"},
+	{http.MethodGet, "/order/catalog/*/*", "reading the catalog of the subsidiary, to check the plans, options and pricing modes it sells"},
+	{http.MethodGet, "/dedicated/server/datacenter/availabilities", "checking the stock of the plan in the datacenters, so that an order out of stock fails before anything is bought"},
+	{http.MethodGet, "/me/order", "listing the orders of the account"},
+	{http.MethodGet, "/me/order/*", "reading the order, for its price and links"},
+	{http.MethodGet, "/me/order/*/status", "reading the status of the order, to follow its payment and delivery"},
+	{http.MethodGet, "/me/order/*/availablePaymentMethod", "listing the payment methods the order accepts, to pick the one matching the payment method criteria"},
+	{http.MethodPost, "/me/order/*/pay", "paying the order with the chosen payment method"},
+	{http.MethodGet, "/me/order/*/details", "listing the details of the order, to find the server it delivers"},
+	{http.MethodGet, "/me/order/*/details/*", "reading a detail of the order, to find the service name of the server"},
+	{http.MethodGet, "/me/order/*/details/*/extension", "reading the extension of a detail of the order, to report what it ordered"},
+	{http.MethodPost, "/me/order/*/retraction", "retracting the order, which cancels it while it is still possible"},
+	{http.MethodGet, "/me", "checking that the credentials are valid, and the account they act on"},
+	{http.MethodGet, "/auth/currentCredential", "reading the rights of the credentials, to check they allow ordering"},
+	{http.MethodGet, "/auth/time", "reading the time of the API, to check the local clock signs requests correctly"},
+	{http.MethodGet, "/dedicated/server", "listing the servers of the account"},
+	{http.MethodGet, "/dedicated/server/*", "reading the server, e.g. its primary IP or monitoring state"},
+	{http.MethodPut, "/dedicated/server/*", "updating the server, e.g. its display name or monitoring"},
+	{http.MethodGet, "/dedicated/server/*/task/*", "reading a task of the server, to wait until it is done"},
+	{http.MethodPost, "/dedicated/server/*/install/start", "starting the installation of the OS on the delivered server"},
+	{http.MethodGet, "/dedicated/installationTemplate/*/partitionScheme", "listing the partition schemes of the installation template, to pick the one to install"},
+	{http.MethodGet, "/dedicated/installationTemplate/*/partitionScheme/*", "reading a partition scheme of the installation template, to pick the one to install"},
+	{http.MethodGet, "/dedicated/server/*/ips", "listing the IPs routed to the server"},
+	{http.MethodGet, "/dedicated/server/*/ipCountryAvailable", "listing the countries extra IPs of the server can be located in"},
+	{http.MethodPost, "/order/dedicated/server/*/ip", "ordering extra IPs for the delivered server"},
+	{http.MethodPost, "/ip/*/reverse", "setting the reverse DNS of the primary IP of the server"},
+	{http.MethodPost, "/dedicated/server/*/serviceMonitoring", "monitoring the SSH port of the server, to alert when it stops answering"},
+	{http.MethodPost, "/dedicated/server/*/serviceMonitoring/*/alert/email", "adding the alert email of the monitoring"},
+	{http.MethodGet, "/dedicated/server/*/serviceInfos", "reading the renewal settings of the server"},
+	{http.MethodPut, "/dedicated/server/*/serviceInfos", "applying the renewal settings of the order to the server"},
+	{http.MethodPost, "/dedicated/server/*/terminate", "requesting the termination of the server"},
+	{http.MethodPost, "/dedicated/server/*/confirmTermination", "confirming the termination of the server"},
+	{http.MethodGet, "/services/*", "reading the service of the server, to check whether it can be reused"},
+	{http.MethodGet, "/status/task", "reading the incidents and maintenances OVH announces, which may delay the order"},
+}
+
+// explainCall returns why the Orderer calls method on url
+func explainCall(method, url string) string {
+	p, _, _ := strings.Cut(url, "?")
+	for _, purpose := range callPurposes {
+		if purpose.method != method {
+			continue
+		}
+		if ok, _ := path.Match(purpose.pattern, p); ok {
+			return purpose.why
+		}
+	}
+	return "calling the API"
+}
+
+// stepPurposes explain the steps of the order flow
+var stepPurposes = map[string]string{
+	StepReuse:        "looking for a delivered server marked as unused, to return it instead of ordering one",
+	StepCreateCart:   "creating the cart of the order, or resuming the cart of a previous run",
+	StepAddServer:    "checking the plan, its pricing and options, then adding the server to the cart",
+	StepConfigure:    "setting the configuration labels the plan requires, such as the datacenter and OS",
+	StepOptions:      "adding the options to the server, each checked against the options the plan offers",
+	StepValidate:     "checking the cart is complete, so that a mistake fails before anything is bought",
+	StepCheckout:     "checking the price of the cart, then checking it out, which creates the order",
+	StepPayment:      "paying the order, without which OVH does not deliver the server",
+	StepDelivery:     "waiting for OVH to deliver the server, which may take from minutes to days",
+	StepInstall:      "installing the OS on the delivered server",
+	StepExtraIPs:     "ordering the extra IPs of the server",
+	StepNetwork:      "checking the IPs routed to the server",
+	StepReverse:      "setting the reverse DNS of the server",
+	StepMonitoring:   "enabling the monitoring of the server",
+	StepRenewal:      "applying the renewal settings of the server",
+	StepTag:          "setting the display name of the server",
+	StepConnectivity: "checking that the server accepts connections",
+}
+
+// explainStep narrates the start of the step name, when Options.Explain is set
+func (o *Orderer) explainStep(name string) {
+	if o.opts.Explain == nil {
+		return
+	}
+	if why, ok := stepPurposes[name]; ok {
+		o.opts.Explain.Printf("Step %s: %s", name, why)
+	}
+}
+
+// explainingClient narrates each API call, and why it is made, to
+// Options.Explain before sending it. It wraps the other clients, so that a
+// call is explained once whatever its retries.
+type explainingClient struct {
+	Client
+	explain Logger
+}
+
+func (c *explainingClient) GetWithContext(ctx context.Context, url string, resType interface{}) error {
+	c.explain.Printf("GET %s: %s", url, explainCall(http.MethodGet, url))
+	return c.Client.GetWithContext(ctx, url, resType)
+}
+
+func (c *explainingClient) PostWithContext(ctx context.Context, url string, reqBody, resType interface{}) error {
+	c.explain.Printf("POST %s: %s", url, explainCall(http.MethodPost, url))
+	return c.Client.PostWithContext(ctx, url, reqBody, resType)
+}
+
+func (c *explainingClient) PutWithContext(ctx context.Context, url string, reqBody, resType interface{}) error {
+	c.explain.Printf("PUT %s: %s", url, explainCall(http.MethodPut, url))
+	return c.Client.PutWithContext(ctx, url, reqBody, resType)
+}
+
+func (c *explainingClient) DeleteWithContext(ctx context.Context, url string, resType interface{}) error {
+	c.explain.Printf("DELETE %s: %s", url, explainCall(http.MethodDelete, url))
+	return c.Client.DeleteWithContext(ctx, url, resType)
+}
@@ -0,0 +1,73 @@
+package orderer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExplainCall(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		want   string
+	}{
+		{method: "POST", url: "/order/cart", want: "creating a cart"},
+		{method: "POST", url: "/order/cart/cart-1/assign", want: "assigning the cart"},
+		{method: "GET", url: "/order/cart/cart-1/checkout", want: "reading the summary"},
+		{method: "POST", url: "/order/cart/cart-1/checkout", want: "checking the cart out"},
+		{method: "GET", url: "/order/cart/cart-1/item/42/requiredConfiguration", want: "configuration labels"},
+		{method: "POST", url: "/order/cart/cart-1/baremetalServers/options", want: "adding an option"},
+		{method: "POST", url: "/order/cart/cart-1/baremetalServers", want: "adding the server"},
+		{method: "GET", url: "/order/cart/cart-1/baremetalServers?planCode=24ska01", want: "listing the plans"},
+		{method: "DELETE", url: "/order/cart/cart-1", want: "deleting the cart"},
+		{method: "GET", url: "/dedicated/server/datacenter/availabilities?planCode=24ska01", want: "checking the stock"},
+		{method: "GET", url: "/unknown/path", want: "calling the API"},
+		{method: "PATCH", url: "/order/cart/cart-1", want: "calling the API"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.url, func(t *testing.T) {
+			if got := explainCall(tt.method, tt.url); !strings.Contains(got, tt.want) {
+				t.Errorf("explainCall() = %q, want %q in it", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStepPurposes(t *testing.T) {
+	for _, step := range []string{StepReuse, StepCreateCart, StepAddServer, StepConfigure, StepOptions,
+		StepValidate, StepCheckout, StepPayment, StepDelivery, StepInstall, StepExtraIPs, StepNetwork,
+		StepReverse, StepMonitoring, StepRenewal, StepTag, StepConnectivity} {
+		if stepPurposes[step] == "" {
+			t.Errorf("step %s is not explained", step)
+		}
+	}
+}
+
+// A call retried after a reset is explained once
+func TestExplainingClient(t *testing.T) {
+	var calls callCounter
+	var buf bytes.Buffer
+	o := newTestServer(t, Options{Explain: log.New(&buf, "", 0)}, func(w http.ResponseWriter, r *http.Request) {
+		if calls.add(r) == 1 {
+			resetConnection(t, w)
+			return
+		}
+		fmt.Fprint(w, `{"cartId":"cart-1"}`)
+	})
+
+	if _, err := o.GetCart(context.Background(), "cart-1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.get("GET /order/cart/cart-1"); n != 2 {
+		t.Errorf("GET sent %d times, want 2", n)
+	}
+	want := "GET /order/cart/cart-1: " + explainCall(http.MethodGet, "/order/cart/cart-1") + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("explained %q, want %q", got, want)
+	}
+}
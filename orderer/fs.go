@@ -0,0 +1,78 @@
+package orderer
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FS abstracts the files the package reads and writes, so that the caller
+// can keep them off the disk, e.g. in memory in tests. Errors for missing or
+// existing files must match fs.ErrNotExist and fs.ErrExist. A nil FS, for
+// the functions taking one, is the disk.
+type FS interface {
+	ReadFile(name string) ([]byte, error)
+
+	// ReadDir lists the directory name, sorted by file name.
+	ReadDir(name string) ([]fs.DirEntry, error)
+
+	// WriteFile replaces the file name with data at once, so that a
+	// concurrent reader never sees a partial file.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+
+	// CreateExclusive creates the empty file name, failing with fs.ErrExist
+	// when it exists, for lock files.
+	CreateExclusive(name string, perm fs.FileMode) error
+
+	MkdirAll(path string, perm fs.FileMode) error
+	Stat(name string) (fs.FileInfo, error)
+	Remove(name string) error
+}
+
+// OSFS is the FS of the disk, the default.
+type OSFS struct{}
+
+func (OSFS) ReadFile(name string) ([]byte, error)       { return os.ReadFile(name) }
+func (OSFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+
+func (OSFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	// Write then rename, so that a reader never sees a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+"-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (OSFS) CreateExclusive(name string, perm fs.FileMode) error {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func (OSFS) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+func (OSFS) Stat(name string) (fs.FileInfo, error)        { return os.Stat(name) }
+func (OSFS) Remove(name string) error                     { return os.Remove(name) }
+
+// fsOrDisk returns fsys, or the disk when it is nil
+func fsOrDisk(fsys FS) FS {
+	if fsys == nil {
+		return OSFS{}
+	}
+	return fsys
+}
@@ -0,0 +1,169 @@
+package orderer
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memFS is an FS in memory. Directories exist as soon as a file is in them.
+type memFS struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func newMemFS(files map[string]string) *memFS {
+	m := &memFS{files: make(map[string][]byte)}
+	for name, data := range files {
+		m.files[name] = []byte(data)
+	}
+	return m
+}
+
+func (m *memFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (m *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var entries []fs.DirEntry
+	for file := range m.files {
+		if path.Dir(file) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(memFileInfo{name: path.Base(file)}))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (m *memFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[name] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *memFS) CreateExclusive(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[name]; ok {
+		return &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	}
+	m.files[name] = nil
+	return nil
+}
+
+func (m *memFS) MkdirAll(path string, perm fs.FileMode) error { return nil }
+
+func (m *memFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if data, ok := m.files[name]; ok {
+		return memFileInfo{name: path.Base(name), size: int64(len(data))}, nil
+	}
+	for file := range m.files {
+		if strings.HasPrefix(file, name+"/") {
+			return memFileInfo{name: path.Base(name), dir: true}, nil
+		}
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *memFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.files, name)
+	return nil
+}
+
+type memFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (fi memFileInfo) Name() string       { return fi.name }
+func (fi memFileInfo) Size() int64        { return fi.size }
+func (fi memFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi memFileInfo) IsDir() bool        { return fi.dir }
+func (fi memFileInfo) Sys() interface{}   { return nil }
+
+func (fi memFileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0o700
+	}
+	return 0o600
+}
+
+func TestLoadReplayerFS(t *testing.T) {
+	fsys := newMemFS(map[string]string{
+		"fixtures/2-checkout.json": `{"interactions":[{"method":"POST","path":"/order/cart/c/checkout","status":200}]}`,
+		"fixtures/1-cart.json":     `{"interactions":[{"method":"POST","path":"/order/cart","status":200}]}`,
+		"fixtures/notes.txt":       `not fixtures`,
+	})
+	replayer, err := LoadReplayer(fsys, "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, interaction := range replayer.fixtures.Interactions {
+		paths = append(paths, interaction.Path)
+	}
+	if got := strings.Join(paths, " "); got != "/order/cart /order/cart/c/checkout" {
+		t.Errorf("interactions = %s, want those of 1-cart.json then 2-checkout.json", got)
+	}
+
+	if _, err := LoadReplayer(fsys, "missing.json"); err == nil {
+		t.Error("missing fixtures loaded")
+	}
+}
+
+func TestLoadAliasesFS(t *testing.T) {
+	fsys := newMemFS(map[string]string{"aliases.yaml": "version: 1\naliases:\n  small:\n    plan: 24ska01\n"})
+	aliases, err := LoadAliases(fsys, "aliases.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aliases["small"] == nil {
+		t.Errorf("aliases = %v, want small", aliases)
+	}
+}
+
+// roundTripFunc is an http.RoundTripper calling itself
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestRecorderFS(t *testing.T) {
+	fsys := newMemFS(nil)
+	recorder := NewRecorder(fsys, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"cartId":"c"}`))}, nil
+	}), "recording.json")
+	req, _ := http.NewRequest(http.MethodPost, "https://eu.api.ovh.com/1.0/order/cart", strings.NewReader(`{"ovhSubsidiary":"FR"}`))
+	if _, err := recorder.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	replayer, err := LoadReplayer(fsys, "recording.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(replayer.fixtures.Interactions); n != 1 {
+		t.Errorf("%d interactions recorded, want 1", n)
+	}
+}
@@ -0,0 +1,127 @@
+package orderer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The fuzz targets check that malformed input fails with an error rather
+// than a panic. Their seeds are the inputs the tool is known to handle, such
+// as the configs of testdata/config, and the corpus of testdata/fuzz adds
+// malformed ones.
+
+func FuzzParseConfig(f *testing.F) {
+	paths, err := filepath.Glob("testdata/config/*")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte("version: 1\nplan: 24ska01\nquantity: 99999999999999999999\n"))
+	f.Add([]byte("version: 1\nplan: [24ska01]\n"))
+	f.Add([]byte("version: null\nplan: null\n"))
+	f.Add([]byte(`{"version":1,"plan":"24ska01","options":[null,1,{"planCode":-1}]}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		config, err := ParseConfig(data)
+		if err != nil {
+			return
+		}
+		config.OrderRequest()
+		// A valid config written back is read the same
+		written, err := yaml.Marshal(config)
+		if err != nil {
+			t.Fatalf("writing %+v: %v", config, err)
+		}
+		if _, err := ParseConfig(written); err != nil {
+			t.Fatalf("config written as\n%s\nis invalid: %v", written, err)
+		}
+	})
+}
+
+func TestParseConfigSeeds(t *testing.T) {
+	paths, err := filepath.Glob("testdata/config/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		if _, err := LoadConfig(nil, path); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+}
+
+func FuzzParseAmount(f *testing.F) {
+	for _, seed := range []string{"129.99", "0", "-1", "1.5e2", "1e999", "99999999999999999999999999.999999", "1/3", "0x10", "1_000", "", "NaN", "Inf", ".5", "5."} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		amount, err := ParseAmount(s)
+		if err != nil {
+			return
+		}
+		if strings.ContainsAny(s, "/xX_") {
+			t.Errorf("ParseAmount(%q) = %s, want an error", s, amount.RatString())
+		}
+		// The exact value survives formatting
+		again, err := ParseAmount(amount.FloatString(0))
+		if err != nil || (amount.IsInt() && again.Cmp(amount) != 0) {
+			t.Errorf("ParseAmount(%q) = %s, which does not parse back: %v", s, amount.RatString(), err)
+		}
+	})
+}
+
+// FuzzDecodeIDs decodes the IDs of cart items and orders from any JSON
+// value: huge, negative, fractional, strings and nulls
+func FuzzDecodeIDs(f *testing.F) {
+	for _, seed := range []string{`123456789`, `"123456789"`, `9223372036854775807`, `9223372036854775808`, `-1`, `1.5`, `1e3`, `null`, `"abc"`, `[]`, `{}`, `""`} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		var item cartItem
+		if err := json.Unmarshal([]byte(`{"itemId":`+value+`,"parentItemId":`+value+`}`), &item); err == nil {
+			if id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil && item.ItemID != id {
+				t.Errorf("itemId %s decoded as %d", value, item.ItemID)
+			}
+		}
+		var order struct {
+			OrderID json.Number `json:"orderId"`
+		}
+		if err := json.Unmarshal([]byte(`{"orderId":`+value+`}`), &order); err != nil {
+			return
+		}
+		id, err := formatOrderID(order.OrderID)
+		if err != nil {
+			return
+		}
+		if n, err := strconv.ParseInt(id, 10, 64); err != nil || strconv.FormatInt(n, 10) != id {
+			t.Errorf("orderId %s formatted as %q", value, id)
+		}
+	})
+}
+
+func FuzzValidateDuration(f *testing.F) {
+	for _, seed := range []string{"P1M", "P12M", "P1Y", "P1Y6M", "P1W", "P", "PT1H", "P99999999999999999999M", "P0M", "p1m"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, duration string) {
+		err := ValidateDuration(duration)
+		months, ok := durationMonths(duration)
+		if ok && err != nil {
+			t.Errorf("%q counts %d months but is invalid: %v", duration, months, err)
+		}
+		if ok && (months <= 0 || months > maxDurationMonths) {
+			t.Errorf("%q counts %d months", duration, months)
+		}
+	})
+}
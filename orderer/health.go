@@ -0,0 +1,122 @@
+package orderer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// HealthStatus is the result of the last check of a HealthChecker.
+type HealthStatus struct {
+	// Checked is when the check ran, zero before the first one.
+	Checked time.Time `json:"checked"`
+
+	// Reachable is set when the API answered, whatever the answer, and
+	// CredentialsValid when it accepted the credentials.
+	Reachable        bool   `json:"reachable"`
+	CredentialsValid bool   `json:"credentialsValid"`
+	Error            string `json:"error,omitempty"`
+}
+
+// HealthChecker checks periodically, with GET /me, that the OVH API is
+// reachable and accepts the credentials of an Orderer, for services
+// embedding it. It serves the result of the last check over HTTP:
+//
+//   - /healthz answers 200 as long as the checker runs, with the status as
+//     JSON, for liveness probes;
+//   - /readyz answers 200 when the last check is recent and succeeded, 503
+//     otherwise, for readiness probes.
+//
+// Requests never call the API themselves, so probes cannot exhaust the rate
+// limit of the account.
+type HealthChecker struct {
+	o        *Orderer
+	interval time.Duration
+
+	mu     sync.Mutex
+	status HealthStatus
+}
+
+// NewHealthChecker returns a checker of o running every interval (defaults
+// to 1 minute) once Run is called.
+func (o *Orderer) NewHealthChecker(interval time.Duration) *HealthChecker {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &HealthChecker{o: o, interval: interval}
+}
+
+// Run checks the API every interval until ctx is done.
+func (h *HealthChecker) Run(ctx context.Context) {
+	for {
+		h.Check(ctx)
+		if err := h.o.sleep(ctx, h.interval); err != nil {
+			return
+		}
+	}
+}
+
+// Check checks the API once and records the result.
+func (h *HealthChecker) Check(ctx context.Context) HealthStatus {
+	var me struct {
+		Nichandle string `json:"nichandle"`
+	}
+	err := h.o.client.GetWithContext(ctx, "/me", &me)
+	status := HealthStatus{Checked: h.o.clock.Now()}
+	var apiErr *ovh.APIError
+	switch {
+	case err == nil:
+		status.Reachable = true
+		status.CredentialsValid = true
+	case errors.As(err, &apiErr):
+		status.Reachable = true
+		status.CredentialsValid = apiErr.Code != http.StatusUnauthorized && apiErr.Code != http.StatusForbidden
+		status.Error = err.Error()
+	default:
+		status.Error = err.Error()
+	}
+	h.mu.Lock()
+	h.status = status
+	h.mu.Unlock()
+	return status
+}
+
+// Status returns the result of the last check.
+func (h *HealthChecker) Status() HealthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.status
+}
+
+// ready reports whether status is recent and successful. A check is stale
+// after three intervals without a newer one.
+func (h *HealthChecker) ready(status HealthStatus) bool {
+	if status.Checked.IsZero() || h.o.clock.Now().Sub(status.Checked) > 3*h.interval {
+		return false
+	}
+	return status.Reachable && status.CredentialsValid && status.Error == ""
+}
+
+// ServeHTTP serves /healthz and /readyz.
+func (h *HealthChecker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status := h.Status()
+	code := http.StatusOK
+	switch r.URL.Path {
+	case "/healthz":
+	case "/readyz":
+		if !h.ready(status) {
+			code = http.StatusServiceUnavailable
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
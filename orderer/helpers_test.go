@@ -0,0 +1,142 @@
+package orderer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// fakeClock is a Clock whose time only moves when it is waited on, so that
+// retries and polls run at once
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	waited time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.waited += d
+	now := c.now
+	c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	ch <- now
+	return ch
+}
+
+// newTestServer starts a server answering the API calls with handler, and
+// /auth/time itself, and returns an Orderer calling it with a fake clock
+func newTestServer(t *testing.T, opts Options, handler http.HandlerFunc) *Orderer {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/auth/time" {
+			fmt.Fprint(w, time.Now().Unix())
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	client, err := ovh.NewClient(srv.URL, "key", "secret", "consumer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Clock == nil {
+		opts.Clock = newFakeClock()
+	}
+	return New(client, opts)
+}
+
+// resetConnection resets the connection of the request w answers, without
+// writing a response
+func resetConnection(t *testing.T, w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	conn.(*net.TCPConn).SetLinger(0)
+	conn.Close()
+}
+
+// writeAPIError answers with an OVH API error
+func writeAPIError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, `{"message":%q}`, message)
+}
+
+// callCounter counts the calls by method and path
+type callCounter struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (c *callCounter) add(r *http.Request) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.calls == nil {
+		c.calls = make(map[string]int)
+	}
+	key := r.Method + " " + r.URL.Path
+	c.calls[key]++
+	return c.calls[key]
+}
+
+func (c *callCounter) get(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[key]
+}
+
+// stubClient is a Client answering the API calls with answer, without HTTP.
+// What answer returns goes through JSON into the response, as with go-ovh.
+type stubClient struct {
+	answer func(method, path string, body interface{}) (interface{}, error)
+}
+
+func (c *stubClient) call(method, path string, body, resType interface{}) error {
+	res, err := c.answer(method, path, body)
+	if err != nil || resType == nil {
+		return err
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, resType)
+}
+
+func (c *stubClient) GetWithContext(ctx context.Context, url string, resType interface{}) error {
+	return c.call(http.MethodGet, url, nil, resType)
+}
+
+func (c *stubClient) PostWithContext(ctx context.Context, url string, reqBody, resType interface{}) error {
+	return c.call(http.MethodPost, url, reqBody, resType)
+}
+
+func (c *stubClient) PutWithContext(ctx context.Context, url string, reqBody, resType interface{}) error {
+	return c.call(http.MethodPut, url, reqBody, resType)
+}
+
+func (c *stubClient) DeleteWithContext(ctx context.Context, url string, resType interface{}) error {
+	return c.call(http.MethodDelete, url, nil, resType)
+}
@@ -0,0 +1,85 @@
+package orderer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// InstallRequest describes the OS installation run once the server is delivered.
+type InstallRequest struct {
+	// Template is the installation template, e.g. "debian12_64".
+	Template string
+
+	// PartitionScheme is the name of a partition scheme of the template.
+	// Defaults to the scheme of the template with the highest priority.
+	PartitionScheme string
+
+	// Hostname optionally sets a custom hostname.
+	Hostname string
+}
+
+// partitionScheme is a partition scheme of an installation template
+type partitionScheme struct {
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+}
+
+// resolvePartitionScheme validates name against the schemes of template, or
+// returns the default scheme of the template when name is empty
+func (o *Orderer) resolvePartitionScheme(ctx context.Context, template, name string) (string, error) {
+	var names []string
+	err := o.client.GetWithContext(ctx, fmt.Sprintf("/dedicated/installationTemplate/%s/partitionScheme", template), &names)
+	if err != nil {
+		return "", fmt.Errorf("error listing partition schemes of template %s: %w", template, err)
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("template %s has no partition scheme", template)
+	}
+	if name != "" {
+		if !contains(names, name) {
+			return "", fmt.Errorf("invalid partition scheme %q for template %s, valid schemes: %s", name, template, strings.Join(names, ", "))
+		}
+		return name, nil
+	}
+
+	var best *partitionScheme
+	for _, n := range names {
+		var scheme partitionScheme
+		err := o.client.GetWithContext(ctx, fmt.Sprintf("/dedicated/installationTemplate/%s/partitionScheme/%s", template, n), &scheme)
+		if err != nil {
+			return "", fmt.Errorf("error fetching partition scheme %s of template %s: %w", n, template, err)
+		}
+		if best == nil || scheme.Priority > best.Priority {
+			best = &scheme
+		}
+	}
+	return best.Name, nil
+}
+
+// Install installs the OS described by install on a delivered server and
+// waits for the installation task to complete.
+func (o *Orderer) Install(ctx context.Context, serviceName string, install InstallRequest) error {
+	scheme, err := o.resolvePartitionScheme(ctx, install.Template, install.PartitionScheme)
+	if err != nil {
+		return fmt.Errorf("installation of %s: %w", serviceName, err)
+	}
+
+	body := map[string]interface{}{
+		"templateName":        install.Template,
+		"partitionSchemeName": scheme,
+	}
+	if install.Hostname != "" {
+		body["details"] = map[string]interface{}{"customHostname": install.Hostname}
+	}
+	var task struct {
+		TaskID int64 `json:"taskId"`
+	}
+	err = o.client.PostWithContext(ctx, fmt.Sprintf("/dedicated/server/%s/install/start", serviceName), body, &task)
+	if err != nil {
+		return fmt.Errorf("error starting installation of %s on %s: %w", install.Template, serviceName, err)
+	}
+	o.logger.Printf("Installing %s with partition scheme %s on %s (task %d)", install.Template, scheme, serviceName, task.TaskID)
+	_, err = o.WaitForTask(ctx, serviceName, task.TaskID)
+	return err
+}
@@ -0,0 +1,235 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// Extra IP types accepted by ExtraIPs.Type.
+const (
+	// ExtraIPsFailover orders Count single failover IPs.
+	ExtraIPsFailover = "failover"
+	// ExtraIPsBlock orders one block of Count addresses.
+	ExtraIPsBlock = "block"
+)
+
+// ExtraIPs describes additional IPs ordered for the server once delivered.
+type ExtraIPs struct {
+	// Count is the number of addresses.
+	Count int
+
+	// Type is ExtraIPsFailover or ExtraIPsBlock.
+	Type string
+
+	// Country of the addresses. Defaults to the first country available for the server.
+	Country string
+}
+
+// validate checks the count and type of the requested IPs
+func (e ExtraIPs) validate() error {
+	switch e.Type {
+	case ExtraIPsFailover:
+		if e.Count < 1 {
+			return fmt.Errorf("invalid extra IP count %d: must be at least 1", e.Count)
+		}
+	case ExtraIPsBlock:
+		switch e.Count {
+		case 1, 4, 8, 16, 32, 64, 128, 256:
+		default:
+			return fmt.Errorf("invalid extra IP block size %d: must be one of 1, 4, 8, 16, 32, 64, 128, 256", e.Count)
+		}
+	default:
+		return fmt.Errorf("invalid extra IP type %q: must be %q or %q", e.Type, ExtraIPsFailover, ExtraIPsBlock)
+	}
+	return nil
+}
+
+// ErrNoIPv6 is returned when OrderRequest.RequireIPv6 is set and no IPv6
+// block is routed to the delivered server.
+var ErrNoIPv6 = errors.New("no IPv6 block routed to the server")
+
+// checkNetwork lists the IP blocks routed to a delivered server and, with
+// requireIPv6, verifies that one of them is an IPv6 block
+func (o *Orderer) checkNetwork(ctx context.Context, serviceName string, requireIPv6 bool) ([]string, error) {
+	ips, err := o.listServerIPs(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if requireIPv6 {
+		for _, ip := range ips {
+			if strings.Contains(ip, ":") {
+				o.logger.Printf("IPv6 block of %s: %s", serviceName, ip)
+				return ips, nil
+			}
+		}
+		return ips, fmt.Errorf("%w %s: %s", ErrNoIPv6, serviceName, strings.Join(ips, ", "))
+	}
+	return ips, nil
+}
+
+// hostnameLabel matches a label of a host name (RFC 1123)
+var hostnameLabel = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// ValidateReverse checks that reverse is a fully qualified host name, as
+// required for the reverse DNS of an IP. A trailing dot is accepted.
+func ValidateReverse(reverse string) error {
+	name := strings.TrimSuffix(reverse, ".")
+	if len(name) > 253 {
+		return fmt.Errorf("invalid reverse %q: longer than 253 characters", reverse)
+	}
+	labels := strings.Split(name, ".")
+	if len(labels) < 2 {
+		return fmt.Errorf("invalid reverse %q: expected a fully qualified host name, e.g. server1.example.com", reverse)
+	}
+	for _, label := range labels {
+		if !hostnameLabel.MatchString(label) {
+			return fmt.Errorf("invalid reverse %q: %q is not a valid host name label", reverse, label)
+		}
+	}
+	return nil
+}
+
+// SetReverse sets the reverse DNS of the primary IP of a delivered server to
+// reverse, and reads it back until OVH has applied it. It returns the reverse
+// set. OVH rejects a reverse whose host name does not resolve to the IP: such
+// rejections, and IPs whose reverse cannot be set, are logged as a warning
+// and skipped.
+func (o *Orderer) SetReverse(ctx context.Context, serviceName, reverse string) (string, error) {
+	if err := ValidateReverse(reverse); err != nil {
+		return "", err
+	}
+	var server struct {
+		IP string `json:"ip"`
+	}
+	if err := o.client.GetWithContext(ctx, fmt.Sprintf("/dedicated/server/%s", serviceName), &server); err != nil {
+		return "", fmt.Errorf("error fetching %s: %w", serviceName, err)
+	}
+	if server.IP == "" {
+		o.logger.Printf("Warning: %s has no primary IP, its reverse is not set", serviceName)
+		return "", nil
+	}
+
+	path := "/ip/" + url.PathEscape(server.IP) + "/reverse"
+	err := o.client.PostWithContext(ctx, path, map[string]interface{}{
+		"ipReverse": server.IP,
+		"reverse":   reverse,
+	}, nil)
+	var apiErr *ovh.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound:
+			o.logger.Printf("Warning: cannot set the reverse of %s to %s: %s", server.IP, reverse, apiErr.Message)
+			return "", nil
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("error setting the reverse of %s: %w", server.IP, err)
+	}
+
+	// The reverse is applied asynchronously, without a task to wait for
+	want := strings.TrimSuffix(reverse, ".")
+	err = o.pollUntil(ctx, poll{Interval: o.opts.TaskPollInterval, Timeout: o.opts.TaskTimeout}, func() (bool, error) {
+		var current struct {
+			Reverse string `json:"reverse"`
+		}
+		if err := o.client.GetWithContext(ctx, path+"/"+url.PathEscape(server.IP), &current); err != nil {
+			return false, fmt.Errorf("error fetching the reverse of %s: %w", server.IP, err)
+		}
+		return strings.EqualFold(strings.TrimSuffix(current.Reverse, "."), want), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	o.logger.Printf("Reverse of %s set to %s", server.IP, reverse)
+	return reverse, nil
+}
+
+// listServerIPs returns the IP blocks routed to a dedicated server
+func (o *Orderer) listServerIPs(ctx context.Context, serviceName string) ([]string, error) {
+	var ips []string
+	err := o.client.GetWithContext(ctx, fmt.Sprintf("/dedicated/server/%s/ips", serviceName), &ips)
+	if err != nil {
+		return nil, fmt.Errorf("error listing IPs of %s: %w", serviceName, err)
+	}
+	return ips, nil
+}
+
+// OrderExtraIPs orders the extra IPs described by extra for a delivered
+// server, pays for them and waits until they are routed to the server. It
+// returns the newly assigned IP blocks.
+func (o *Orderer) OrderExtraIPs(ctx context.Context, serviceName string, extra ExtraIPs) ([]string, error) {
+	if err := extra.validate(); err != nil {
+		return nil, err
+	}
+
+	country := extra.Country
+	if country == "" {
+		var countries []string
+		err := o.client.GetWithContext(ctx, fmt.Sprintf("/dedicated/server/%s/ipCountryAvailable", serviceName), &countries)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching IP countries available for %s: %w", serviceName, err)
+		}
+		if len(countries) == 0 {
+			return nil, fmt.Errorf("no IP country available for %s", serviceName)
+		}
+		country = countries[0]
+	}
+
+	before, err := o.listServerIPs(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	// A block is a single order, failover IPs are ordered one by one
+	blockSize, orders := extra.Count, 1
+	if extra.Type == ExtraIPsFailover {
+		blockSize, orders = 1, extra.Count
+	}
+	for i := 0; i < orders; i++ {
+		var order struct {
+			OrderID int64  `json:"orderId"`
+			URL     string `json:"url"`
+		}
+		err := o.client.PostWithContext(ctx, fmt.Sprintf("/order/dedicated/server/%s/ip", serviceName), map[string]interface{}{
+			"blockSize": fmt.Sprint(blockSize),
+			"country":   country,
+			"type":      "failover",
+		}, &order)
+		if err != nil {
+			return nil, fmt.Errorf("error ordering IP block of size %d for %s: %w", blockSize, serviceName, err)
+		}
+		orderID := fmt.Sprint(order.OrderID)
+		o.logger.Printf("Ordered IP block of size %d (%s) for %s, order ID: %s", blockSize, country, serviceName, orderID)
+
+		if _, _, err := o.pay(ctx, orderID, order.URL); err != nil {
+			return nil, fmt.Errorf("IP order %s: %w", orderID, err)
+		}
+		if err := o.waitForOrderDelivered(ctx, orderID); err != nil {
+			return nil, err
+		}
+	}
+
+	after, err := o.listServerIPs(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(before))
+	for _, ip := range before {
+		known[ip] = true
+	}
+	var added []string
+	for _, ip := range after {
+		if !known[ip] {
+			added = append(added, ip)
+		}
+	}
+	o.logger.Printf("Assigned IPs to %s: %v", serviceName, added)
+	return added, nil
+}
@@ -0,0 +1,192 @@
+package orderer
+
+import (
+	"context"
+	"fmt"
+)
+
+// CancelOrder asks OVH to cancel orderID through the order retraction
+// endpoint. It is only accepted before delivery and within the retraction
+// period; reason is one of the me.order.RetractionReasonEnum values
+// ("other", "unused", "expensive", ...).
+func (o *Orderer) CancelOrder(ctx context.Context, orderID, reason, comment string) error {
+	var status string
+	if err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%s/status", orderID), &status); err != nil {
+		return fmt.Errorf("error fetching status of order %s: %w", orderID, err)
+	}
+	switch status {
+	case "delivered":
+		return fmt.Errorf("order %s is already delivered: terminate the service instead", orderID)
+	case "cancelled", "cancelling":
+		return fmt.Errorf("order %s is already %s", orderID, status)
+	}
+
+	err := o.client.PostWithContext(ctx, fmt.Sprintf("/me/order/%s/retraction", orderID), map[string]interface{}{
+		"reason":  reason,
+		"comment": comment,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("error cancelling order %s: %w", orderID, err)
+	}
+	o.logger.Printf("Cancellation of order %s requested.", orderID)
+	return nil
+}
+
+// TerminateService starts the termination of a delivered dedicated server.
+// OVH then emails a token which must be passed to ConfirmTermination.
+func (o *Orderer) TerminateService(ctx context.Context, serviceName string) error {
+	if err := o.client.PostWithContext(ctx, fmt.Sprintf("/dedicated/server/%s/terminate", serviceName), nil, nil); err != nil {
+		return fmt.Errorf("error terminating %s: %w", serviceName, err)
+	}
+	o.logger.Printf("Termination of %s requested, a confirmation token has been sent by email.", serviceName)
+	return nil
+}
+
+// ConfirmTermination confirms the termination of serviceName with the token
+// received by email. reason is one of the service.TerminationReasonEnum values.
+func (o *Orderer) ConfirmTermination(ctx context.Context, serviceName, token, reason, commentary string) error {
+	body := map[string]interface{}{
+		"token":  token,
+		"reason": reason,
+	}
+	if commentary != "" {
+		body["commentary"] = commentary
+	}
+	if err := o.client.PostWithContext(ctx, fmt.Sprintf("/dedicated/server/%s/confirmTermination", serviceName), body, nil); err != nil {
+		return fmt.Errorf("error confirming termination of %s: %w", serviceName, err)
+	}
+	o.logger.Printf("Termination of %s confirmed.", serviceName)
+	return nil
+}
+
+// ServiceRenew is the renewal setup of a service.
+type ServiceRenew struct {
+	Automatic          bool `json:"automatic"`
+	DeleteAtExpiration bool `json:"deleteAtExpiration"`
+	Forced             bool `json:"forced"`
+	ManualPayment      bool `json:"manualPayment"`
+
+	// Period is the renewal period in months.
+	Period int `json:"period"`
+}
+
+// ServiceInfo is the billing information of a delivered server. Dates are
+// days, as returned by OVH (e.g. 2024-01-31).
+type ServiceInfo struct {
+	ServiceID   int64         `json:"serviceId"`
+	Status      string        `json:"status"`
+	Creation    string        `json:"creation"`
+	Expiration  string        `json:"expiration"`
+	RenewalType string        `json:"renewalType"`
+	Renew       *ServiceRenew `json:"renew"`
+
+	// PossibleRenewPeriods are the renewal periods, in months, the service
+	// accepts.
+	PossibleRenewPeriods []int `json:"possibleRenewPeriod"`
+
+	// EngagedUpTo is the end of the commitment of the server, if any.
+	EngagedUpTo string `json:"engagedUpTo"`
+}
+
+// GetServiceInfo returns the billing information of a delivered server.
+func (o *Orderer) GetServiceInfo(ctx context.Context, serviceName string) (*ServiceInfo, error) {
+	var info ServiceInfo
+	if err := o.client.GetWithContext(ctx, fmt.Sprintf("/dedicated/server/%s/serviceInfos", serviceName), &info); err != nil {
+		return nil, fmt.Errorf("error fetching service information of %s: %w", serviceName, err)
+	}
+	return &info, nil
+}
+
+// SetAutomaticRenewal turns the automatic renewal of a delivered server on
+// or off, keeping the rest of its renewal setup.
+func (o *Orderer) SetAutomaticRenewal(ctx context.Context, serviceName string, automatic bool) error {
+	_, err := o.SetRenewal(ctx, serviceName, RenewalRequest{Automatic: automatic})
+	return err
+}
+
+// RenewalRequest is the renewal mode set on a delivered server.
+type RenewalRequest struct {
+	// Automatic renews the server at the end of each period, instead of
+	// waiting for a manual payment.
+	Automatic bool
+
+	// Period is the renewal period in months, checked against the periods
+	// the service accepts. Zero keeps the current period.
+	Period int
+}
+
+// validate checks the request before anything is ordered
+func (r RenewalRequest) validate() error {
+	if r.Period < 0 {
+		return fmt.Errorf("invalid renewal period %d: expected a number of months", r.Period)
+	}
+	return nil
+}
+
+func (r RenewalRequest) String() string {
+	if !r.Automatic {
+		return "manual"
+	}
+	if r.Period == 0 {
+		return "automatic"
+	}
+	return fmt.Sprintf("automatic every %d months", r.Period)
+}
+
+// SetRenewal sets the renewal mode of a delivered server, keeping the rest
+// of its renewal setup, and returns the renewal read back once applied.
+func (o *Orderer) SetRenewal(ctx context.Context, serviceName string, req RenewalRequest) (*ServiceRenew, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	info, err := o.GetServiceInfo(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if req.Period != 0 && len(info.PossibleRenewPeriods) > 0 && !containsInt(info.PossibleRenewPeriods, req.Period) {
+		return nil, fmt.Errorf("renewal period of %d months is not accepted by %s, accepted periods: %v", req.Period, serviceName, info.PossibleRenewPeriods)
+	}
+	renew := ServiceRenew{}
+	if info.Renew != nil {
+		renew = *info.Renew
+	}
+	renew.Automatic = req.Automatic
+	if req.Period != 0 {
+		renew.Period = req.Period
+	}
+	body := map[string]interface{}{
+		"renew": map[string]interface{}{
+			"automatic":          renew.Automatic,
+			"deleteAtExpiration": renew.DeleteAtExpiration,
+			"forced":             renew.Forced,
+			"period":             renew.Period,
+		},
+	}
+	if err := o.client.PutWithContext(ctx, fmt.Sprintf("/dedicated/server/%s/serviceInfos", serviceName), body, nil); err != nil {
+		return nil, fmt.Errorf("error updating the renewal of %s: %w", serviceName, err)
+	}
+
+	info, err = o.GetServiceInfo(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if info.Renew == nil {
+		return nil, fmt.Errorf("%s has no renewal after updating it", serviceName)
+	}
+	applied := RenewalRequest{Automatic: info.Renew.Automatic, Period: info.Renew.Period}
+	if info.Renew.Automatic != req.Automatic {
+		return info.Renew, fmt.Errorf("renewal of %s is still %s after setting it to %s", serviceName, applied, req)
+	}
+	o.logger.Printf("Renewal of %s set to %s.", serviceName, applied)
+	return info.Renew, nil
+}
+
+// containsInt reports whether values contains value
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
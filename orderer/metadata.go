@@ -0,0 +1,211 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// Version is the version of the tool, recorded in the metadata of its carts.
+const Version = "0.1.0"
+
+// metadataTag prefixes the metadata appended to cart descriptions.
+const metadataTag = "ovh-ds-orderer"
+
+var metadataPattern = regexp.MustCompile(`\s*\[` + metadataTag + ` ([^\]]*)\]$`)
+
+// CartMetadata identifies a cart created by this tool. It is appended to the
+// cart description as "[ovh-ds-orderer version=... created=... run=...]".
+type CartMetadata struct {
+	Version string
+	Created time.Time
+	RunID   string
+
+	// Wait is set on the carts reserved by WaitInCart while they wait for
+	// stock, and identifies the order they wait for (see waitKey).
+	Wait string
+
+	// CustomerReference is OrderRequest.CustomerReference.
+	CustomerReference string
+}
+
+// customerReferencePattern is what a customer reference may be: a single
+// word, so that it fits in the metadata of a cart description
+var customerReferencePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:@/+-]*$`)
+
+// maxCustomerReference is the longest customer reference accepted
+const maxCustomerReference = 64
+
+// ValidateCustomerReference checks that ref can be recorded as a customer
+// reference: at most 64 letters, digits and . _ : @ / + - characters,
+// starting with a letter or digit.
+func ValidateCustomerReference(ref string) error {
+	if len(ref) > maxCustomerReference {
+		return fmt.Errorf("invalid customer reference %q: longer than %d characters", ref, maxCustomerReference)
+	}
+	if !customerReferencePattern.MatchString(ref) {
+		return fmt.Errorf("invalid customer reference %q: only letters, digits and . _ : @ / + - are allowed, starting with a letter or digit", ref)
+	}
+	return nil
+}
+
+// String formats the metadata as appended to a cart description.
+func (m CartMetadata) String() string {
+	s := fmt.Sprintf("[%s version=%s created=%s", metadataTag, m.Version, m.Created.UTC().Format(time.RFC3339))
+	if m.RunID != "" {
+		s += " run=" + m.RunID
+	}
+	if m.Wait != "" {
+		s += " wait=" + m.Wait
+	}
+	if m.CustomerReference != "" {
+		s += " customer=" + m.CustomerReference
+	}
+	return s + "]"
+}
+
+// withMetadata appends m to a cart description
+func withMetadata(description string, m CartMetadata) string {
+	return description + " " + m.String()
+}
+
+// ParseCartDescription splits a cart description into the user description
+// and the metadata of this tool. The metadata is nil for carts created by
+// other means.
+func ParseCartDescription(description string) (string, *CartMetadata) {
+	match := metadataPattern.FindStringSubmatchIndex(description)
+	if match == nil {
+		return description, nil
+	}
+	m := &CartMetadata{}
+	for _, field := range strings.Fields(description[match[2]:match[3]]) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "version":
+			m.Version = value
+		case "created":
+			m.Created, _ = time.Parse(time.RFC3339, value)
+		case "run":
+			m.RunID = value
+		case "wait":
+			m.Wait = value
+		case "customer":
+			m.CustomerReference = value
+		}
+	}
+	return description[:match[0]], m
+}
+
+// CartInfo is a cart of the account.
+type CartInfo struct {
+	CartID      string
+	Description string
+	Expire      time.Time
+	ReadOnly    bool
+
+	// Metadata is set for carts created by this tool.
+	Metadata *CartMetadata
+}
+
+// ListCarts returns the carts of the account with their parsed metadata.
+func (o *Orderer) ListCarts(ctx context.Context) ([]CartInfo, error) {
+	var cartIDs []string
+	if err := o.client.GetWithContext(ctx, "/order/cart", &cartIDs); err != nil {
+		return nil, fmt.Errorf("error listing carts: %w", err)
+	}
+
+	carts := make([]CartInfo, 0, len(cartIDs))
+	for _, cartID := range cartIDs {
+		cart, err := o.GetCart(ctx, cartID)
+		if err != nil {
+			return nil, err
+		}
+		carts = append(carts, *cart)
+	}
+	return carts, nil
+}
+
+// GetCart returns a cart of the account with its parsed metadata.
+func (o *Orderer) GetCart(ctx context.Context, cartID string) (*CartInfo, error) {
+	var cart struct {
+		CartID      string    `json:"cartId"`
+		Description string    `json:"description"`
+		Expire      time.Time `json:"expire"`
+		ReadOnly    bool      `json:"readOnly"`
+	}
+	if err := o.client.GetWithContext(ctx, "/order/cart/"+cartID, &cart); err != nil {
+		return nil, fmt.Errorf("error fetching cart %s: %w", cartID, err)
+	}
+	info := &CartInfo{
+		CartID:   cart.CartID,
+		Expire:   cart.Expire,
+		ReadOnly: cart.ReadOnly,
+	}
+	info.Description, info.Metadata = ParseCartDescription(cart.Description)
+	return info, nil
+}
+
+// CleanCarts deletes the carts created by this tool which have not been
+// checked out and were created before olderThan. It returns the deleted carts.
+func (o *Orderer) CleanCarts(ctx context.Context, olderThan time.Time) ([]CartInfo, error) {
+	carts, err := o.ListCarts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var deleted []CartInfo
+	for _, cart := range carts {
+		if cart.Metadata == nil || cart.ReadOnly || !cart.Metadata.Created.Before(olderThan) {
+			continue
+		}
+		if err := o.deleteCart(ctx, cart.CartID); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, cart)
+	}
+	return deleted, nil
+}
+
+// ErrCartGone is returned when resuming a cart that no longer exists, e.g.
+// because it expired or was deleted since it was created.
+var ErrCartGone = errors.New("cart no longer exists")
+
+// ErrStaleCart is returned when resuming a cart created longer than
+// Options.MaxStateAge ago.
+var ErrStaleCart = errors.New("cart is too old to resume")
+
+// checkResumable fails with ErrCartGone when cart has expired, and with
+// ErrStaleCart when it was created longer than Options.MaxStateAge ago. The
+// age of carts created by other tools, which have no metadata, is unknown
+// and not checked.
+func (o *Orderer) checkResumable(cart CartInfo) error {
+	now := o.clock.Now()
+	if !cart.Expire.IsZero() && cart.Expire.Before(now) {
+		return fmt.Errorf("%w: %s expired at %s", ErrCartGone, cart.CartID, cart.Expire.Format(time.RFC3339))
+	}
+	if o.opts.MaxStateAge <= 0 || cart.Metadata == nil || cart.Metadata.Created.IsZero() {
+		return nil
+	}
+	if age := now.Sub(cart.Metadata.Created); age > o.opts.MaxStateAge {
+		return fmt.Errorf("%w: %s was created %s ago, more than %s", ErrStaleCart, cart.CartID, age.Round(time.Minute), o.opts.MaxStateAge)
+	}
+	return nil
+}
+
+// resumableCart fetches cartID and checks that it can be resumed
+func (o *Orderer) resumableCart(ctx context.Context, cartID string) (*CartInfo, error) {
+	cart, err := o.GetCart(ctx, cartID)
+	var apiErr *ovh.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrCartGone, cartID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cart, o.checkResumable(*cart)
+}
@@ -0,0 +1,60 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+func TestResumableCart(t *testing.T) {
+	now := newFakeClock().Now()
+	cart := func(created, expire time.Time) map[string]interface{} {
+		return map[string]interface{}{
+			"cartId":      "cart-1",
+			"description": withMetadata("", CartMetadata{Version: Version, Created: created}),
+			"expire":      expire,
+		}
+	}
+	tests := []struct {
+		name        string
+		maxStateAge time.Duration
+		cart        map[string]interface{} // nil when the cart does not exist
+
+		wantErr error
+	}{
+		{name: "fresh", maxStateAge: time.Hour, cart: cart(now.Add(-time.Minute), now.Add(time.Hour))},
+		{name: "stale", maxStateAge: time.Hour, cart: cart(now.Add(-2*time.Hour), now.Add(time.Hour)), wantErr: ErrStaleCart},
+		{name: "no max age", cart: cart(now.Add(-48*time.Hour), now.Add(time.Hour))},
+		{name: "expired", cart: cart(now.Add(-48*time.Hour), now.Add(-time.Hour)), wantErr: ErrCartGone},
+		{name: "deleted", wantErr: ErrCartGone},
+		{
+			name:        "created by another tool",
+			maxStateAge: time.Hour,
+			cart:        map[string]interface{}{"cartId": "cart-1", "description": "by hand", "expire": now.Add(time.Hour)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &stubClient{answer: func(method, path string, body interface{}) (interface{}, error) {
+				if method+" "+path != "GET /order/cart/cart-1" {
+					return nil, fmt.Errorf("unexpected call %s %s", method, path)
+				}
+				if tt.cart == nil {
+					return nil, &ovh.APIError{Code: http.StatusNotFound, Message: "This cart does not exist"}
+				}
+				return tt.cart, nil
+			}}
+			o := New(client, Options{Clock: newFakeClock(), MaxStateAge: tt.maxStateAge})
+
+			_, err := o.resumableCart(context.Background(), "cart-1")
+			if tt.wantErr == nil && err != nil || !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
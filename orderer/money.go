@@ -0,0 +1,200 @@
+package orderer
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// ErrMaxPriceExceeded is returned, wrapped, when the price of a cart is above
+// OrderRequest.MaxPrice. The cart is deleted.
+var ErrMaxPriceExceeded = errors.New("cart price exceeds the maximum price")
+
+// amountPattern matches the decimal amounts of prices: big.Rat alone would
+// also take fractions (1/3), hexadecimal (0x10) and underscores (1_000).
+// The short exponent covers JSON numbers such as 1.5e2.
+var amountPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]{1,3})?$`)
+
+// ParseAmount parses a decimal amount such as "129.99" exactly, without going
+// through a float.
+func ParseAmount(s string) (*big.Rat, error) {
+	if !amountPattern.MatchString(s) {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	amount, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
+
+// Amount returns the exact value of the price.
+func (p Price) Amount() (*big.Rat, error) {
+	return ParseAmount(p.Value.String())
+}
+
+// samePrice reports whether a and b are the same amount in the same currency
+func samePrice(a, b Price) bool {
+	if a.CurrencyCode != b.CurrencyCode {
+		return false
+	}
+	x, errX := a.Amount()
+	y, errY := b.Amount()
+	if errX != nil || errY != nil {
+		return a.Value == b.Value
+	}
+	return x.Cmp(y) == 0
+}
+
+// samePrices reports whether two summaries of a cart bill the same: the
+// same totals and the same itemized lines
+func samePrices(a, b *CartSummary) bool {
+	if !samePrice(a.Prices.WithTax, b.Prices.WithTax) || !samePrice(a.Prices.WithoutTax, b.Prices.WithoutTax) || len(a.Details) != len(b.Details) {
+		return false
+	}
+	for i, detail := range a.Details {
+		other := b.Details[i]
+		if detail.CartItemID != other.CartItemID || detail.Quantity != other.Quantity || !samePrice(detail.TotalPrice, other.TotalPrice) {
+			return false
+		}
+	}
+	return true
+}
+
+// Price bases OrderRequest.MaxPrice applies to
+const (
+	// PriceBasisGross compares the maximum price with the price of the cart
+	// tax included, the default.
+	PriceBasisGross = "gross"
+
+	// PriceBasisNet compares it with the price without tax, e.g. for
+	// budgets kept without the VAT the company recovers.
+	PriceBasisNet = "net"
+)
+
+// ValidatePriceBasis checks a price basis, empty meaning PriceBasisGross.
+func ValidatePriceBasis(basis string) error {
+	switch basis {
+	case "", PriceBasisGross, PriceBasisNet:
+		return nil
+	}
+	return fmt.Errorf("invalid price basis %q: expected %s or %s", basis, PriceBasisGross, PriceBasisNet)
+}
+
+// describePriceBasis names a price basis in messages
+func describePriceBasis(basis string) string {
+	if basis == PriceBasisNet {
+		return "without tax"
+	}
+	return "tax included"
+}
+
+// checkMaxPrice verifies that the price of the cart on basis is not above
+// max, both being compared as exact decimals
+func checkMaxPrice(prices SummaryPrices, max, basis string) error {
+	limit, err := ParseAmount(max)
+	if err != nil {
+		return fmt.Errorf("invalid maximum price: %w", err)
+	}
+	price := prices.WithTax
+	if basis == PriceBasisNet {
+		price = prices.WithoutTax
+	}
+	amount, err := price.Amount()
+	if err != nil {
+		return fmt.Errorf("invalid cart price: %w", err)
+	}
+	if amount.Cmp(limit) > 0 {
+		return fmt.Errorf("%w: %s %s is above %s", ErrMaxPriceExceeded, price.Text, describePriceBasis(basis), max)
+	}
+	return nil
+}
+
+// priceLocale is how the prices of a subsidiary are written
+type priceLocale struct {
+	thousands string
+	decimal   string
+
+	// suffix writes the currency symbol after the amount, e.g. 1 234,56 €,
+	// instead of before it, e.g. $1,234.56
+	suffix bool
+}
+
+var (
+	localeEnglish = priceLocale{thousands: ",", decimal: "."}
+	localeFrench  = priceLocale{thousands: " ", decimal: ",", suffix: true}
+	localeGerman  = priceLocale{thousands: ".", decimal: ",", suffix: true}
+)
+
+// subsidiaryLocales maps the subsidiaries to the way they write prices.
+// Subsidiaries not listed write them the English way.
+var subsidiaryLocales = map[string]priceLocale{
+	"FR": localeFrench,
+	"QC": localeFrench,
+	"MA": localeFrench,
+	"SN": localeFrench,
+	"TN": localeFrench,
+	"CZ": localeFrench,
+	"FI": localeFrench,
+	"LT": localeFrench,
+	"PL": localeFrench,
+	"PT": localeFrench,
+	"DE": localeGerman,
+	"ES": localeGerman,
+	"IT": localeGerman,
+	"NL": localeGerman,
+}
+
+// currencySymbols maps the currency codes to their symbol. Currencies not
+// listed are written with their code.
+var currencySymbols = map[string]string{
+	"EUR": "€",
+	"GBP": "£",
+	"USD": "$",
+	"CAD": "$",
+	"AUD": "$",
+	"SGD": "$",
+	"INR": "₹",
+	"PLN": "zł",
+	"CZK": "Kč",
+}
+
+// Format writes the price the way subsidiary does, e.g. 1 234,56 € for FR
+// and $1,234.56 for US, for human output. It returns Text when the value
+// cannot be read.
+func (p Price) Format(subsidiary string) string {
+	amount, err := p.Amount()
+	if err != nil {
+		return p.Text
+	}
+	locale, ok := subsidiaryLocales[strings.ToUpper(subsidiary)]
+	if !ok {
+		locale = localeEnglish
+	}
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+		amount.Neg(amount)
+	}
+	whole, cents, _ := strings.Cut(amount.FloatString(2), ".")
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteString(locale.thousands)
+		}
+		grouped.WriteRune(digit)
+	}
+	number := grouped.String() + locale.decimal + cents
+
+	symbol, ok := currencySymbols[p.CurrencyCode]
+	switch {
+	case !ok:
+		return sign + number + " " + p.CurrencyCode
+	case locale.suffix:
+		return sign + number + " " + symbol
+	default:
+		return sign + symbol + number
+	}
+}
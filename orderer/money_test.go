@@ -0,0 +1,81 @@
+package orderer
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckMaxPrice(t *testing.T) {
+	prices := SummaryPrices{
+		WithTax:    Price{Value: "119.99", Text: "119.99 €"},
+		WithoutTax: Price{Value: "99.99", Text: "99.99 €"},
+	}
+	tests := []struct {
+		name  string
+		max   string
+		basis string
+
+		wantErr error
+	}{
+		{name: "gross below", max: "120", basis: PriceBasisGross},
+		{name: "gross equal", max: "119.99", basis: PriceBasisGross},
+		{name: "gross above", max: "110", basis: PriceBasisGross, wantErr: ErrMaxPriceExceeded},
+		{name: "default is gross", max: "110", wantErr: ErrMaxPriceExceeded},
+		{name: "net", max: "110", basis: PriceBasisNet},
+		{name: "net above", max: "99.98", basis: PriceBasisNet, wantErr: ErrMaxPriceExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMaxPrice(prices, tt.max, tt.basis)
+			if tt.wantErr == nil && err != nil || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			// The error says which price was compared
+			if err != nil && !strings.Contains(err.Error(), describePriceBasis(tt.basis)) {
+				t.Errorf("err = %v, want the basis %q", err, describePriceBasis(tt.basis))
+			}
+		})
+	}
+}
+
+func TestValidatePriceBasis(t *testing.T) {
+	for _, basis := range []string{"", PriceBasisGross, PriceBasisNet} {
+		if err := ValidatePriceBasis(basis); err != nil {
+			t.Errorf("ValidatePriceBasis(%q) = %v", basis, err)
+		}
+	}
+	if err := ValidatePriceBasis("Gross"); err == nil {
+		t.Error("ValidatePriceBasis(Gross) accepted")
+	}
+}
+
+func TestSamePrices(t *testing.T) {
+	summary := func(total, currency string, details ...SummaryDetail) *CartSummary {
+		price := Price{Value: json.Number(total), CurrencyCode: currency}
+		return &CartSummary{Prices: SummaryPrices{WithTax: price, WithoutTax: price}, Details: details}
+	}
+	detail := func(itemID int64, total string) SummaryDetail {
+		return SummaryDetail{CartItemID: itemID, Quantity: 1, TotalPrice: Price{Value: json.Number(total), CurrencyCode: "EUR"}}
+	}
+	tests := []struct {
+		name string
+		a, b *CartSummary
+		want bool
+	}{
+		{name: "same", a: summary("100", "EUR", detail(1, "100")), b: summary("100", "EUR", detail(1, "100")), want: true},
+		{name: "same amount written differently", a: summary("100", "EUR"), b: summary("100.00", "EUR"), want: true},
+		{name: "total", a: summary("100", "EUR"), b: summary("100.01", "EUR")},
+		{name: "currency", a: summary("100", "EUR"), b: summary("100", "USD")},
+		{name: "line price", a: summary("100", "EUR", detail(1, "60"), detail(2, "40")), b: summary("100", "EUR", detail(1, "50"), detail(2, "50"))},
+		{name: "line added", a: summary("100", "EUR", detail(1, "100")), b: summary("100", "EUR", detail(1, "100"), detail(2, "0"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := samePrices(tt.a, tt.b); got != tt.want {
+				t.Errorf("samePrices() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
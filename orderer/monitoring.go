@@ -0,0 +1,120 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// MonitoringRequest enables the monitoring of a delivered server.
+type MonitoringRequest struct {
+	// AlertEmail, when set, is emailed when the SSH port of the server stops
+	// answering, through a service monitoring of the server. OVH otherwise
+	// alerts the contacts of the account only.
+	AlertEmail string
+
+	// Language of the alert emails, e.g. en or fr. Defaults to "en".
+	Language string
+}
+
+// validate checks the alert address before anything is ordered
+func (m MonitoringRequest) validate() error {
+	if m.AlertEmail == "" {
+		return nil
+	}
+	if _, err := mail.ParseAddress(m.AlertEmail); err != nil {
+		return fmt.Errorf("invalid monitoring alert email %q: %w", m.AlertEmail, err)
+	}
+	return nil
+}
+
+// MonitoringState is the monitoring of a server once EnableMonitoring is done.
+type MonitoringState struct {
+	// Enabled is the monitoring flag of the server, as read back.
+	Enabled bool
+
+	// ServiceMonitoringID is the service monitoring created for the alert
+	// email, or 0 when there is none.
+	ServiceMonitoringID int64
+	AlertEmail          string
+}
+
+// EnableMonitoring turns on the OVH monitoring of a delivered server and,
+// when req.AlertEmail is set, monitors its SSH port with an email alert to
+// that address. Both apply at once, without a task to wait for, and the
+// monitoring flag is read back to report its final state. Servers whose
+// monitoring cannot be changed are logged as a warning and skipped.
+func (o *Orderer) EnableMonitoring(ctx context.Context, serviceName string, req MonitoringRequest) (*MonitoringState, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("/dedicated/server/%s", serviceName)
+	err := o.client.PutWithContext(ctx, path, map[string]interface{}{
+		"monitoring": true,
+	}, nil)
+	var apiErr *ovh.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusMethodNotAllowed:
+			o.logger.Printf("Warning: cannot enable the monitoring of %s: %s", serviceName, apiErr.Message)
+			return &MonitoringState{}, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error enabling the monitoring of %s: %w", serviceName, err)
+	}
+
+	var server struct {
+		IP         string `json:"ip"`
+		Monitoring bool   `json:"monitoring"`
+	}
+	if err := o.client.GetWithContext(ctx, path, &server); err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", serviceName, err)
+	}
+	state := &MonitoringState{Enabled: server.Monitoring}
+	if !server.Monitoring {
+		o.logger.Printf("Warning: the monitoring of %s is still disabled", serviceName)
+	} else {
+		o.logger.Printf("Monitoring of %s enabled", serviceName)
+	}
+	if req.AlertEmail == "" {
+		return state, nil
+	}
+	if server.IP == "" {
+		o.logger.Printf("Warning: %s has no primary IP, no alert is sent to %s", serviceName, req.AlertEmail)
+		return state, nil
+	}
+
+	var monitoring struct {
+		MonitoringID int64 `json:"monitoringId"`
+	}
+	err = o.client.PostWithContext(ctx, path+"/serviceMonitoring", map[string]interface{}{
+		"ip":       server.IP,
+		"port":     22,
+		"protocol": "SSH",
+		"interval": "300",
+	}, &monitoring)
+	if err != nil {
+		return state, fmt.Errorf("error monitoring the SSH port of %s: %w", serviceName, err)
+	}
+	state.ServiceMonitoringID = monitoring.MonitoringID
+
+	language := req.Language
+	if language == "" {
+		language = "en"
+	}
+	err = o.client.PostWithContext(ctx, fmt.Sprintf("%s/serviceMonitoring/%d/alert/email", path, monitoring.MonitoringID), map[string]interface{}{
+		"email":    req.AlertEmail,
+		"language": language,
+	}, nil)
+	if err != nil {
+		return state, fmt.Errorf("error adding the alert email of %s: %w", serviceName, err)
+	}
+	state.AlertEmail = req.AlertEmail
+	o.logger.Printf("Alerts of %s are emailed to %s", serviceName, req.AlertEmail)
+	return state, nil
+}
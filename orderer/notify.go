@@ -0,0 +1,249 @@
+package orderer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// DefaultNotifyTimeout bounds a notification sent for an event, all its
+// attempts included, when no other timeout is set.
+const DefaultNotifyTimeout = 10 * time.Second
+
+// notifyClient is the HTTP client of the notifiers without one: unlike
+// http.DefaultClient, it gives up on a server that does not answer.
+var notifyClient = &http.Client{Timeout: DefaultNotifyTimeout}
+
+// Notifier is told of the milestones and failures of an order. Webhook,
+// SlackNotifier and EmailNotifier implement it.
+type Notifier interface {
+	Notify(ctx context.Context, payload WebhookPayload) error
+}
+
+// Notifiers notifies each of its notifiers in turn.
+type Notifiers []Notifier
+
+// Notify notifies every notifier, even when some fail, and returns their
+// errors joined.
+func (n Notifiers) Notify(ctx context.Context, payload WebhookPayload) error {
+	var errs []error
+	for _, notifier := range n {
+		if err := notifier.Notify(ctx, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyEvents returns a handler for Options.OnEvent notifying notifier when
+// the payment or delivery step of an order succeeds, and when a step fails.
+// Notification failures are logged to logger, if not nil, and never fail the
+// order. As the handler runs within the flow, each notification is given up
+// after timeout, DefaultNotifyTimeout when not positive, so that a notifier
+// that hangs does not hold up the order.
+func NotifyEvents(notifier Notifier, runID string, timeout time.Duration, logger Logger) func(Event) {
+	if timeout <= 0 {
+		timeout = DefaultNotifyTimeout
+	}
+	return func(event Event) {
+		// The failure of the order was notified with that of its step
+		if event.Step == StepDone {
+			return
+		}
+		payload := WebhookPayload{
+			OrderID:     event.OrderID,
+			ServiceName: event.ServiceName,
+			RunID:       runID,
+			Timestamp:   event.Start.Add(event.Duration),
+
+			CustomerReference: event.CustomerReference,
+		}
+		switch {
+		case event.Err != nil:
+			payload.Status = WebhookFailed
+			payload.Step = event.Step
+			payload.Error = event.Err.Error()
+		case event.Step == StepPayment:
+			payload.Status = WebhookPaid
+		case event.Step == StepDelivery:
+			payload.Status = WebhookDelivered
+		default:
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := notifier.Notify(ctx, payload); err != nil && logger != nil {
+			logger.Printf("Error sending notification: %v", err)
+		}
+	}
+}
+
+// notificationText is the human readable text of a notification
+func notificationText(payload WebhookPayload) string {
+	var b strings.Builder
+	order := "Order"
+	if payload.OrderID != "" {
+		order += " " + payload.OrderID
+	}
+	if payload.CustomerReference != "" {
+		order += " for customer " + payload.CustomerReference
+	}
+	switch payload.Status {
+	case WebhookFailed:
+		fmt.Fprintf(&b, "%s failed at step %s: %s", order, payload.Step, payload.Error)
+	case WebhookDelivered:
+		fmt.Fprintf(&b, "%s delivered", order)
+		if payload.ServiceName != "" {
+			fmt.Fprintf(&b, ": %s", payload.ServiceName)
+		}
+	default:
+		fmt.Fprintf(&b, "%s %s", order, payload.Status)
+	}
+	if payload.RunID != "" {
+		fmt.Fprintf(&b, " (run %s)", payload.RunID)
+	}
+	return b.String()
+}
+
+// SlackNotifier posts notifications as messages to a Slack incoming webhook.
+type SlackNotifier struct {
+	WebhookURL string
+
+	// Client defaults to a client giving up after DefaultNotifyTimeout.
+	Client *http.Client
+}
+
+// Notify posts the text of payload to the Slack incoming webhook.
+func (s *SlackNotifier) Notify(ctx context.Context, payload WebhookPayload) error {
+	icon := ":white_check_mark:"
+	if payload.Status == WebhookFailed {
+		icon = ":x:"
+	}
+	body, err := json.Marshal(map[string]string{"text": icon + " " + notificationText(payload)})
+	if err != nil {
+		return err
+	}
+	client := s.Client
+	if client == nil {
+		client = notifyClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("slack webhook: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// EmailNotifier sends notifications by email through an SMTP server.
+type EmailNotifier struct {
+	// Addr is the host:port of the SMTP server.
+	Addr string
+	From string
+	To   []string
+
+	// Username and Password, when Username is set, authenticate with PLAIN
+	// authentication, which net/smtp only allows over TLS or to localhost.
+	Username string
+	Password string
+
+	// Timeout bounds the exchange with the SMTP server, from the dial to
+	// the end of the message. Defaults to DefaultNotifyTimeout.
+	Timeout time.Duration
+}
+
+// Notify emails the text of payload to the recipients, giving up once ctx
+// is done or the timeout elapsed.
+func (e *EmailNotifier) Notify(ctx context.Context, payload WebhookPayload) error {
+	text := notificationText(payload)
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	// Error messages may span lines, which must not leak into the headers
+	fmt.Fprintf(&msg, "Subject: [ovh-order] %s\r\n", strings.Join(strings.Fields(text), " "))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n", text)
+
+	if err := e.send(ctx, msg.Bytes()); err != nil {
+		return fmt.Errorf("email to %s: %w", strings.Join(e.To, ", "), err)
+	}
+	return nil
+}
+
+// send sends msg like smtp.SendMail, which takes no context nor timeout, on
+// a connection whose deadline is that of ctx or the timeout, and which is
+// closed when ctx is done
+func (e *EmailNotifier) send(ctx context.Context, msg []byte) error {
+	host, _, err := net.SplitHostPort(e.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address %s: %w", e.Addr, err)
+	}
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = DefaultNotifyTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", e.Addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if ok, _ := c.Extension("AUTH"); ok && e.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.Username, e.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.From); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,159 @@
+package orderer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A webhook that never answers is given up after the timeout, without
+// failing the event
+func TestNotifyEventsTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	var buf bytes.Buffer
+	webhook := &Webhook{URL: srv.URL, Timeout: 50 * time.Millisecond, Logger: log.New(&buf, "", 0)}
+	start := time.Now()
+	webhook.OnEvent(Event{Step: StepPayment, OrderID: "234567890", Start: start})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("notified in %s, want about the timeout", elapsed)
+	}
+	if !strings.Contains(buf.String(), context.DeadlineExceeded.Error()) {
+		t.Errorf("log = %q, want the timeout", buf.String())
+	}
+}
+
+// The delay between attempts ends with the context
+func TestWebhookRetryCancelled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	webhook := &Webhook{URL: srv.URL, Attempts: 5}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := webhook.Notify(ctx, WebhookPayload{OrderID: "234567890", Status: WebhookPaid})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("gave up in %s, want about the timeout", elapsed)
+	}
+}
+
+// smtpServer serves a single SMTP session on a local port, accepting the
+// message, which it sends on the returned channel, or never answering
+func smtpServer(t *testing.T, hang bool) (addr string, messages <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if hang {
+			io.Copy(io.Discard, conn)
+			return
+		}
+		text := textproto.NewConn(conn)
+		text.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb, _, _ := strings.Cut(line, " "); strings.ToUpper(verb) {
+			case "EHLO", "HELO", "MAIL", "RCPT":
+				text.PrintfLine("250 OK")
+			case "DATA":
+				text.PrintfLine("354 Go ahead")
+				lines, err := text.ReadDotLines()
+				if err != nil {
+					return
+				}
+				ch <- strings.Join(lines, "\n")
+				text.PrintfLine("250 Queued")
+			case "QUIT":
+				text.PrintfLine("221 Bye")
+				return
+			default:
+				text.PrintfLine("502 Not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String(), ch
+}
+
+func TestEmailNotifier(t *testing.T) {
+	addr, messages := smtpServer(t, false)
+	email := &EmailNotifier{Addr: addr, From: "orders@example.com", To: []string{"ops@example.com"}}
+	payload := WebhookPayload{OrderID: "234567890", Status: WebhookFailed, Step: StepPayment, Error: "no payment\nmethod"}
+	if err := email.Notify(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+	msg := <-messages
+	if !strings.Contains(msg, "Subject: [ovh-order] Order 234567890 failed at step payment: no payment method\n") {
+		t.Errorf("message = %q, want the failure on one subject line", msg)
+	}
+}
+
+// An SMTP server that never answers is given up after the timeout
+func TestEmailNotifierTimeout(t *testing.T) {
+	addr, _ := smtpServer(t, true)
+	email := &EmailNotifier{Addr: addr, From: "orders@example.com", To: []string{"ops@example.com"}, Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	if err := email.Notify(context.Background(), WebhookPayload{OrderID: "234567890", Status: WebhookPaid}); err == nil {
+		t.Fatal("sent to a server that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("gave up in %s, want about the timeout", elapsed)
+	}
+}
+
+// A Slack webhook that never answers is given up with the context
+func TestSlackNotifierCancelled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	slack := &SlackNotifier{WebhookURL: srv.URL}
+	if err := slack.Notify(ctx, WebhookPayload{OrderID: "234567890", Status: WebhookPaid}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if notifyClient.Timeout <= 0 {
+		t.Error("the default client of the notifiers never gives up")
+	}
+}
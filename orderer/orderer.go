@@ -0,0 +1,1330 @@
+// Package orderer orders OVH dedicated servers through the /order/cart API.
+//
+// It contains the whole cart -> checkout -> payment flow without any process
+// level side effects, so it can be used both by the command line tool and
+// embedded in a long running service.
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// Client is the subset of the go-ovh client used by the Orderer.
+// *ovh.Client satisfies it.
+type Client interface {
+	GetWithContext(ctx context.Context, url string, resType interface{}) error
+	PostWithContext(ctx context.Context, url string, reqBody, resType interface{}) error
+	PutWithContext(ctx context.Context, url string, reqBody, resType interface{}) error
+	DeleteWithContext(ctx context.Context, url string, resType interface{}) error
+}
+
+// Clock abstracts time so that waits can be controlled by the caller.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Logger receives the progress messages of the flow. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Options configures an Orderer. The zero value is usable.
+type Options struct {
+	// Logger receives all the human-facing progress messages; the package
+	// never writes to stdout or stderr itself. Defaults to discarding them,
+	// the command line tool logs them to stdout.
+	Logger Logger
+
+	// Endpoint is the name or URL of the API endpoint the client talks to.
+	// When set, discovery checks that the subsidiary is served by it, and
+	// Kimsufi and So you Start endpoints order their "eco" servers.
+	Endpoint string
+
+	// AllowedEndpoints, when set, lists the endpoints orders may be placed
+	// on. Order fails with ErrEndpointNotAllowed before creating a cart if
+	// Endpoint is not one of them, and so do checkouts and payments, so that
+	// a configuration meant for one account cannot buy a server on another.
+	// Building a cart with BuildCart is allowed anywhere.
+	AllowedEndpoints []string
+
+	// CatalogCache, when set, caches the responses of the discovery calls on
+	// disk. The order flow itself always reads the live catalog.
+	CatalogCache *CatalogCache
+
+	// Clock is used for timestamps and waits. Defaults to the system clock.
+	Clock Clock
+
+	// PaymentMethodWait is how long to keep polling for payment methods after
+	// checkout before concluding there are none. Defaults to 30 seconds.
+	PaymentMethodWait time.Duration
+
+	// PaymentSettleWait is how long to wait, after /pay accepted the
+	// payment, for the order to leave the notPaid status. Defaults to 5
+	// minutes.
+	PaymentSettleWait time.Duration
+
+	// PaymentMethod restricts the payment methods orders are paid with.
+	// Defaults to the first one usable through the API.
+	PaymentMethod PaymentMethodCriteria
+
+	// DocumentsWait is how long to wait for the documents of a paid order
+	// to be available. Defaults to 15 seconds.
+	DocumentsWait time.Duration
+
+	// DeliveryPollInterval is the interval between two order status checks
+	// right after the payment, while OVH checks the order. Defaults to 15
+	// seconds.
+	DeliveryPollInterval time.Duration
+
+	// DeliveryBuildPollInterval is the interval between the first two checks
+	// once the server is being built, doubled after each check up to
+	// DeliveryMaxPollInterval, as a build takes minutes to hours. Defaults to
+	// 1 minute and 10 minutes.
+	DeliveryBuildPollInterval time.Duration
+	DeliveryMaxPollInterval   time.Duration
+
+	// DeliveryTimeout bounds the wait for a delivery. Defaults to 4 hours.
+	DeliveryTimeout time.Duration
+
+	// TaskPollInterval is the interval between two checks of a task of a
+	// delivered server. Defaults to 30 seconds.
+	TaskPollInterval time.Duration
+
+	// TaskTimeout bounds the wait for a task. Defaults to 2 hours.
+	TaskTimeout time.Duration
+
+	// MaxAttempts is the number of times an API call failing with a
+	// retryable error, such as a 503 or a connection reset, is attempted.
+	// A call changing state, such as a checkout or a payment, is only sent
+	// again when the error proves it was not applied, or once the cart or
+	// order has been read back to check it was not. Defaults to 4; 1
+	// disables retries.
+	MaxAttempts int
+
+	// RetryBackoff is the delay before the first retry, doubled after each
+	// attempt. Defaults to 1 second.
+	RetryBackoff time.Duration
+
+	// RetryBudget, when set, caps the time spent waiting between retries
+	// over the whole life of the Orderer. Once it is spent, API calls are no
+	// longer retried and fail with ErrRetryBudgetExhausted.
+	RetryBudget time.Duration
+
+	// CartRefreshMargin is how close to its expiry a cart must be for its
+	// expiry to be extended before checkout. Defaults to 10 minutes.
+	CartRefreshMargin time.Duration
+
+	// Debug enables debug messages, such as the duration of each step.
+	Debug bool
+
+	// Explain, when set, is given a narration of the flow for learning and
+	// debugging: the purpose of each step as it starts, and of each API
+	// call before it is sent. It is independent of Debug.
+	Explain Logger
+
+	// OnEvent, when set, is called synchronously with the progress events of
+	// the flow. It must not block.
+	OnEvent func(Event)
+
+	// EngagementAckMonths is the longest commitment that can be ordered
+	// without OrderRequest.AckEngagement. Defaults to 1 month, so that any
+	// yearly commitment must be acknowledged.
+	EngagementAckMonths int
+
+	// StrictAvailability makes WaitForAvailability fail when the
+	// availabilities cannot be read, instead of letting the order proceed.
+	StrictAvailability bool
+
+	// MaxStateAge, when set, refuses to resume a cart created longer ago: a
+	// cart held by an earlier WaitInCart is deleted and replaced with a new
+	// one, and PurchaseCart fails with ErrStaleCart. An old cart has likely
+	// been replaced by another run, and its prices may have changed since.
+	MaxStateAge time.Duration
+
+	// StrictJSON makes the calls fetching orders, their payment methods and
+	// cart summaries fail with ErrUnknownField when OVH answers with a field
+	// the tool does not model, to notice changes of the API early, e.g. in
+	// CI. Off by default, as new fields are usually harmless.
+	StrictJSON bool
+
+	// OptionDependencies maps option families to the families an option
+	// must also be selected from, in addition to DefaultOptionDependencies.
+	OptionDependencies map[string][]string
+
+	// ConfirmCheckout, when set, is called with the prices of the cart
+	// before it is checked out. Returning false deletes the cart and fails
+	// the order with ErrCheckoutDeclined. As the answer may take a while,
+	// the prices are read again once approved: when they changed, it is
+	// called again with the new prices, up to 3 times before the order
+	// fails with ErrPriceChanged.
+	ConfirmCheckout func(*CartSummary) bool
+
+	// CheckoutPolicy, when set, is called before each checkout, see
+	// CheckoutPolicy. Policy.Check enforces the rules of a policy file.
+	CheckoutPolicy CheckoutPolicy
+}
+
+// ErrClosed is returned by Order once the Orderer has been closed.
+var ErrClosed = errors.New("orderer is closed")
+
+// Orderer runs dedicated server orders against the OVH API.
+type Orderer struct {
+	client Client
+	clock  Clock
+	logger Logger
+	opts   Options
+
+	mu      sync.Mutex
+	closed  bool
+	closers []func() error
+
+	// retrySlept is the time spent waiting between retries, against
+	// Options.RetryBudget
+	retrySlept time.Duration
+
+	// retryStats are the retries of all calls, see RetryStats
+	retryStats RetryStats
+
+	// claimed are the servers reused by OrderRequest.ReuseMarker
+	claimed map[string]bool
+
+	// cartLocks serialize the item changes of each cart, see lockCart
+	cartLocks map[string]*sync.Mutex
+}
+
+// New returns an Orderer using client for all API calls. An *ovh.Client is
+// copied, with its http.Client, and the transport of the copy wrapped so
+// that the retries can tell a response cut short by the connection from a
+// complete one which does not decode: client is left unchanged, and later
+// changes to it are not seen by the Orderer.
+func New(client Client, opts Options) *Orderer {
+	if opts.Logger == nil {
+		opts.Logger = log.New(io.Discard, "", 0)
+	}
+	if opts.Clock == nil {
+		opts.Clock = realClock{}
+	}
+	if opts.PaymentMethodWait == 0 {
+		opts.PaymentMethodWait = 30 * time.Second
+	}
+	if opts.DocumentsWait == 0 {
+		opts.DocumentsWait = 15 * time.Second
+	}
+	if opts.PaymentSettleWait == 0 {
+		opts.PaymentSettleWait = 5 * time.Minute
+	}
+	if opts.DeliveryPollInterval == 0 {
+		opts.DeliveryPollInterval = 15 * time.Second
+	}
+	if opts.DeliveryBuildPollInterval == 0 {
+		opts.DeliveryBuildPollInterval = time.Minute
+	}
+	if opts.DeliveryMaxPollInterval == 0 {
+		opts.DeliveryMaxPollInterval = 10 * time.Minute
+	}
+	if opts.DeliveryTimeout == 0 {
+		opts.DeliveryTimeout = 4 * time.Hour
+	}
+	if opts.TaskPollInterval == 0 {
+		opts.TaskPollInterval = 30 * time.Second
+	}
+	if opts.TaskTimeout == 0 {
+		opts.TaskTimeout = 2 * time.Hour
+	}
+	if opts.CartRefreshMargin == 0 {
+		opts.CartRefreshMargin = 10 * time.Minute
+	}
+	if opts.MaxAttempts == 0 {
+		opts.MaxAttempts = 4
+	}
+	if opts.EngagementAckMonths == 0 {
+		opts.EngagementAckMonths = 1
+	}
+	if opts.RetryBackoff == 0 {
+		opts.RetryBackoff = time.Second
+	}
+	o := &Orderer{
+		clock:  opts.Clock,
+		logger: opts.Logger,
+		opts:   opts,
+	}
+	if c, ok := client.(*ovh.Client); ok && c.Client != nil {
+		// Wrap the transport of copies, leaving the caller's client as it is
+		httpClient := *c.Client
+		if _, ok := httpClient.Transport.(*bodyReadTransport); !ok {
+			httpClient.Transport = &bodyReadTransport{transport: httpClient.Transport}
+		}
+		ovhClient := *c
+		ovhClient.Client = &httpClient
+		client = &ovhClient
+		o.onClose(func() error {
+			httpClient.CloseIdleConnections()
+			return nil
+		})
+	}
+	o.client = &dedupClient{Client: &retryingClient{o: o, client: client}}
+	if opts.StrictJSON {
+		o.client = &strictClient{Client: o.client}
+	}
+	if opts.Explain != nil {
+		o.client = &explainingClient{Client: o.client, explain: opts.Explain}
+	}
+	return o
+}
+
+// onClose registers fn to be called by Close, to release a resource
+func (o *Orderer) onClose(fn func() error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closers = append(o.closers, fn)
+}
+
+// isClosed reports whether Close has been called
+func (o *Orderer) isClosed() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.closed
+}
+
+// Close releases the resources of the Orderer, such as the idle connections
+// of the client, and flushes what it buffers. Order must not be called
+// after Close, and fails with ErrClosed if it is. Close is idempotent.
+func (o *Orderer) Close() error {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil
+	}
+	o.closed = true
+	closers := o.closers
+	o.closers = nil
+	o.mu.Unlock()
+
+	var errs []error
+	for _, fn := range closers {
+		if err := fn(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Configuration is a single cart item configuration label and its value.
+type Configuration struct {
+	Label string
+	Value string
+}
+
+// Option is an option added to the server, such as extra RAM or a license.
+type Option struct {
+	// PlanCode is the plan code of the option, or its name as
+	// family=capacity (e.g. ram=32g, storage=2x512nvme), which is resolved
+	// among the options offered for the plan.
+	PlanCode string
+
+	// Duration and PricingMode default to those of the server. They let an
+	// option be billed on its own terms, e.g. a server committed for 12
+	// months with a bandwidth option paid monthly.
+	Duration    string
+	PricingMode string
+
+	// Configuration is posted to the cart item of the option, for add-ons
+	// such as licenses which need their own configuration.
+	Configuration []Configuration
+
+	// ExtraParams are merged into the body adding the option to the cart,
+	// for parameters this package does not know about.
+	ExtraParams map[string]interface{}
+
+	// included is set on the free options the plan requires, added by
+	// addIncludedOptions
+	included bool
+}
+
+// ConfigurationResult is a configuration entry created on a cart item.
+type ConfigurationResult struct {
+	ItemID int64
+
+	// ID identifies the entry in the cart, under
+	// /order/cart/{cartId}/item/{itemId}/configuration/{id}.
+	ID int64
+
+	Label string
+	Value string
+}
+
+// OptionResult is an option added to the cart.
+type OptionResult struct {
+	PlanCode string
+	ItemID   int64
+
+	// Duration and PricingMode are those the option is billed with.
+	Duration    string
+	PricingMode string
+
+	// Configuration lists the entries created on the item of the option.
+	Configuration []ConfigurationResult
+
+	// Family is the family of the option, and Kind whether it is a hardware
+	// or a service option (see OptionOffer.Kind).
+	Family string
+	Kind   string
+
+	// Included is set when the option was added automatically as a free
+	// item the plan requires, such as its default storage.
+	Included bool
+}
+
+// OrderRequest describes the server to order.
+type OrderRequest struct {
+	// Subsidiary is the OVH subsidiary the cart is created for. Defaults to "US".
+	Subsidiary string
+
+	// Description is stored on the cart, followed by the CartMetadata of the
+	// run. Defaults to "Automated Dedicated Server Order".
+	Description string
+
+	// RunID optionally identifies the run in the cart metadata.
+	RunID string
+
+	// CustomerReference optionally identifies the end customer a reseller
+	// orders for. It is recorded in the cart metadata, as OVH has no field
+	// attributing an order to a customer, and reported in the result, the
+	// events and the notifications. See ValidateCustomerReference.
+	CustomerReference string
+
+	// CartID, when set, is an empty cart of the account the order is built
+	// in instead of creating one, such as the cart returned by WaitInCart.
+	CartID string
+
+	// BillingAccount, when set, is the nichandle the order must be billed
+	// to. The order fails before creating a cart unless the client acts on
+	// that account. Defaults to the authenticated account.
+	BillingAccount string
+
+	// PlanCode is the baremetal server plan, e.g. "24rise01-us".
+	PlanCode string
+
+	// Duration is the ISO 8601 billing period. Defaults to "P1M".
+	Duration string
+
+	// PricingMode defaults to "default".
+	PricingMode string
+
+	// Quantity defaults to 1.
+	Quantity int
+
+	// RequirePricingMode, when set, fails the order before checkout unless
+	// the server is ordered with this pricing mode (e.g. a 12 month
+	// commitment), instead of falling back to another one.
+	RequirePricingMode string
+
+	// AckEngagement acknowledges the commitment of the pricing mode, which
+	// is required for commitments longer than Options.EngagementAckMonths.
+	AckEngagement bool
+
+	// Configuration is posted to the server item in order, except that labels
+	// are moved after the labels they depend on (region before datacenter).
+	Configuration []Configuration
+
+	// OS is the dedicated_os value of the server, validated against the
+	// values offered by the plan. It overrides a dedicated_os entry of
+	// Configuration; when neither is set the plan's "no OS" value is used.
+	OS string
+
+	// ExtraParams are merged into the body adding the server to the cart,
+	// for parameters this package does not know about. They cannot override
+	// the parameters it sets (planCode, itemId, duration, pricingMode and
+	// quantity).
+	ExtraParams map[string]interface{}
+
+	// Options added to the server. May be empty to order the bare plan, as
+	// long as the plan has no mandatory option.
+	Options []Option
+
+	// ExtraIPs, when set, are ordered and routed to the server once it has
+	// been delivered.
+	ExtraIPs *ExtraIPs
+
+	// Install, when set, installs an OS once the server has been delivered.
+	Install *InstallRequest
+
+	// RequireIPv6 fails the order once the server is delivered unless an
+	// IPv6 block is routed to it. The IPs of the server are then reported in
+	// OrderResult.IPs.
+	RequireIPv6 bool
+
+	// Reverse, when set, is the host name set as the reverse DNS of the
+	// primary IP of the delivered server.
+	Reverse string
+
+	// Monitoring, when set, enables the monitoring of the delivered server.
+	Monitoring *MonitoringRequest
+
+	// Renewal, when set, is the renewal mode set on the delivered server.
+	Renewal *RenewalRequest
+
+	// Connectivity, when set, checks that the delivered server accepts TCP
+	// connections once everything else is done.
+	Connectivity *ConnectivityRequest
+
+	// AutoPay checks the cart out with autoPayWithPreferredPaymentMethod, so
+	// that OVH charges the preferred payment method of the account, instead
+	// of paying the order through the API.
+	AutoPay bool
+
+	// BestEffort skips the options failing with an error that is not
+	// retryable instead of failing the order, which is then checked out
+	// with the options that could be added.
+	BestEffort bool
+
+	// AllowDuplicateOptions adds an option listed several times in Options
+	// once per listing. By default, an option listed twice, usually a copy
+	// and paste mistake that would be charged twice, is added once.
+	AllowDuplicateOptions bool
+
+	// DeliveryPriority selects the delivery option of the server, such as
+	// an express delivery, by plan code or by a word of it (e.g. express).
+	// It must match a single delivery option when the plan offers some, and
+	// is skipped with a log line when it offers none.
+	DeliveryPriority string
+
+	// Tag, when set, is written as the display name of the server once it
+	// has been delivered.
+	Tag string
+
+	// MaxPrice, when set, is the decimal amount (e.g. "129.99") the price of
+	// the cart must not exceed for it to be checked out. MaxPriceBasis tells
+	// whether it is compared with the price tax included (PriceBasisGross,
+	// the default) or without tax (PriceBasisNet).
+	MaxPrice      string
+	MaxPriceBasis string
+
+	// PriceBaseline, when set, is the approved prices of the order: the
+	// cart is checked out only if its prices, item by item and in total,
+	// are within PriceTolerance of them (see PriceBaseline).
+	// PriceTolerance is an amount (e.g. "0.50") or a percentage of the
+	// baseline price (e.g. "2%"); empty means the prices must be equal.
+	PriceBaseline  *PriceBaseline
+	PriceTolerance string
+
+	// ReuseMarker, when set, looks for a delivered server of the plan and
+	// datacenter of the order whose display name is ReuseMarker before
+	// ordering one. Such a server is returned instead of ordering, after the
+	// post-delivery steps; it is tagged with Tag, or its service name when
+	// Tag is empty, so that it is no longer marked as unused.
+	ReuseMarker string
+}
+
+func (r OrderRequest) withDefaults() OrderRequest {
+	if r.Subsidiary == "" {
+		r.Subsidiary = "US"
+	}
+	if r.Description == "" {
+		r.Description = "Automated Dedicated Server Order"
+	}
+	if r.Duration == "" {
+		r.Duration = "P1M"
+	}
+	if r.PricingMode == "" {
+		r.PricingMode = "default"
+	}
+	if r.Quantity == 0 {
+		r.Quantity = 1
+	}
+	return r
+}
+
+// SkippedOption is an option that could not be added to the cart in best
+// effort mode.
+type SkippedOption struct {
+	PlanCode string
+	Err      error
+}
+
+// Roles of the items of a cart.
+const (
+	// ItemRoleServer is the dedicated server item.
+	ItemRoleServer = "server"
+	// ItemRoleOption is an option item attached to another item.
+	ItemRoleOption = "option"
+)
+
+// CartItem is an item added to the cart of an order.
+type CartItem struct {
+	ItemID   int64
+	Role     string
+	PlanCode string
+
+	// ParentID is the item an option item is attached to.
+	ParentID int64
+}
+
+// OrderResult is the outcome of a successful Order.
+type OrderResult struct {
+	CartID string
+
+	// RunID and CustomerReference are those of the request.
+	RunID             string
+	CustomerReference string
+
+	// ItemID is the item of the server, the one item of Items with
+	// ItemRoleServer.
+	ItemID  int64
+	Options []OptionResult
+
+	// Items lists every item added to the cart, in the order they were
+	// added, with their role.
+	Items []CartItem
+
+	// Configuration lists the entries created on the server item, in the
+	// order they were posted.
+	Configuration []ConfigurationResult
+
+	// Placement maps the placement labels of the server item (region,
+	// dedicated_datacenter, and any zone or rack label the plan offers) to
+	// the value OVH recorded for them.
+	Placement map[string]string
+
+	// SkippedOptions lists the options left out with OrderRequest.BestEffort.
+	SkippedOptions []SkippedOption
+
+	// Delivery is the delivery option added to the cart, if any.
+	Delivery *DeliveryOption
+
+	OrderID           string
+	PaymentMethodID   string
+	PaymentMethodType string
+
+	// PaymentStatus is PaymentPaid, PaymentPending or PaymentFailed once the
+	// payment was sent, empty otherwise.
+	PaymentStatus string
+
+	// Engagement is the commitment of the server, if its pricing mode has one.
+	Engagement *Engagement
+
+	// Billing splits the price of the cart between the first invoice and
+	// the renewals, when the summary could be split.
+	Billing *BillingSplit
+
+	// AutoPay is set when the order was submitted for auto-payment, in which
+	// case no payment method is reported.
+	AutoPay bool
+
+	// Prices are those of the cart as it was checked out, with and without
+	// tax. Both are equal where no tax applies.
+	Prices *SummaryPrices
+
+	// PaymentURL is where the order can be paid from a browser.
+	PaymentURL string
+
+	// Documents links to the paperwork of the paid order.
+	Documents *OrderDocuments
+
+	// ServiceName and ExtraIPs are only set when the order waited for delivery.
+	ServiceName string
+	ExtraIPs    []string
+
+	// IPs lists the IP blocks routed to the delivered server, IPv4 and IPv6,
+	// when extra IPs were ordered or IPv6 was required.
+	IPs []string
+
+	// Reverse is the reverse DNS set on the primary IP of the server, empty
+	// when OrderRequest.Reverse is not set or could not be applied.
+	Reverse string
+
+	// Monitoring is the final monitoring state of the server when
+	// OrderRequest.Monitoring is set.
+	Monitoring *MonitoringState
+
+	// Renewal is the renewal of the server as read back once
+	// OrderRequest.Renewal is applied.
+	Renewal *ServiceRenew
+
+	// Connectivity is the result of the check of OrderRequest.Connectivity.
+	Connectivity *ConnectivityState
+
+	// Reused is set when ServiceName is an existing server reused with
+	// OrderRequest.ReuseMarker, in which case nothing was ordered.
+	Reused bool
+
+	// Timings lists the duration and the retries of each step run, in order.
+	Timings       []StepTiming
+	TotalDuration time.Duration
+
+	// stepStats counts the retries of the step being run
+	stepStats *stepStats
+}
+
+// addItem records an item added to the cart
+func (r *OrderResult) addItem(item CartItem) {
+	r.Items = append(r.Items, item)
+}
+
+// HardwareOptions returns the options of the order that change the hardware
+// of the server, such as its RAID controller mode or CPU configuration.
+func (r *OrderResult) HardwareOptions() []OptionResult {
+	var options []OptionResult
+	for _, option := range r.Options {
+		if option.Kind == OptionKindHardware {
+			options = append(options, option)
+		}
+	}
+	return options
+}
+
+// IncludedOptions returns the options of the order added automatically as
+// free items the plan requires.
+func (r *OrderResult) IncludedOptions() []OptionResult {
+	var options []OptionResult
+	for _, option := range r.Options {
+		if option.Included {
+			options = append(options, option)
+		}
+	}
+	return options
+}
+
+// item returns the first item of the cart with role, if any
+func (r *OrderResult) item(role string) (CartItem, bool) {
+	for _, item := range r.Items {
+		if item.Role == role {
+			return item, true
+		}
+	}
+	return CartItem{}, false
+}
+
+// Order creates a cart for req, checks it out and pays the resulting order
+// with the first available payment method. When req.Install, req.ExtraIPs,
+// req.Tag, req.RequireIPv6, req.Reverse, req.Monitoring, req.Renewal or
+// req.Connectivity is set it also waits for the delivery of the server, then
+// installs it, orders the extra IPs, checks its network, sets its reverse
+// DNS, enables its monitoring, sets its renewal, tags it and checks that it
+// accepts connections. With req.ReuseMarker, a matching
+// unused server is returned instead of ordering one.
+//
+// Steps run one after the other, as each needs the cart item created by the
+// previous one. Within a step, only calls that cannot affect each other run
+// concurrently: the catalog checks before adding the server, and the
+// configuration labels that do not depend on each other (region and
+// dedicated_datacenter are posted in that order). Options are added one at
+// a time so that the cart items are created in a predictable order.
+func (o *Orderer) Order(ctx context.Context, req OrderRequest) (result *OrderResult, err error) {
+	start := o.clock.Now()
+	defer func() {
+		if result != nil {
+			result.TotalDuration = o.clock.Now().Sub(start)
+		}
+		o.done(req, start, result, err)
+	}()
+	req, err = o.prepare(req)
+	if err != nil {
+		return nil, err
+	}
+	// Fail before creating a cart that could not be bought
+	if err := o.checkEndpointAllowed(); err != nil {
+		return nil, err
+	}
+	result = &OrderResult{RunID: req.RunID, CustomerReference: req.CustomerReference}
+	ctx = withStepStats(ctx, result)
+
+	// Step 0: Reuse an unused server instead of ordering one
+	if req.ReuseMarker != "" {
+		err = o.step(result, StepReuse, func() (err error) {
+			datacenter, _ := labelValue(req.Configuration, labelDatacenter)
+			result.ServiceName, err = o.FindReusableServer(ctx, req.PlanCode, datacenter, req.ReuseMarker)
+			return err
+		})
+		if err != nil {
+			return result, err
+		}
+		if result.ServiceName != "" {
+			result.Reused = true
+			if req.CartID != "" {
+				if err := o.deleteCart(ctx, req.CartID); err != nil {
+					o.logger.Printf("Error deleting cart %s: %v", req.CartID, err)
+				}
+			}
+			if req.Tag == "" {
+				req.Tag = result.ServiceName
+			}
+			return result, o.afterDelivery(ctx, result, req)
+		}
+	}
+
+	// Steps 1 to 9: Build the cart, check it out and pay for the order
+	if err := o.buildCart(ctx, req, result); err != nil {
+		return result, err
+	}
+	if err := o.purchaseCart(ctx, result.CartID, req.purchaseOptions(), result); err != nil {
+		return result, err
+	}
+	return result, o.deliver(ctx, result, req)
+}
+
+// HasPostDelivery reports whether r has steps to run once the server is
+// delivered, such as an installation, for which Order waits for the
+// delivery. Without them, Order returns once the order is paid.
+func (r OrderRequest) HasPostDelivery() bool {
+	return r.ExtraIPs != nil || r.Install != nil || r.Tag != "" || r.RequireIPv6 || r.Reverse != "" || r.Monitoring != nil || r.Renewal != nil || r.Connectivity != nil
+}
+
+// deliver waits for the delivery of the paid order of result and runs the
+// post-delivery steps, when req has any
+func (o *Orderer) deliver(ctx context.Context, result *OrderResult, req OrderRequest) error {
+	if !req.HasPostDelivery() {
+		return nil
+	}
+
+	// Step 10: Wait for the delivery and run the post-delivery steps
+	err := o.step(result, StepDelivery, func() (err error) {
+		result.ServiceName, err = o.WaitForDelivery(ctx, result.OrderID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return o.afterDelivery(ctx, result, req)
+}
+
+// BuildCart runs the steps of Order that build the cart of req: it creates
+// the cart, adds and configures the server and its options and validates
+// the cart, without checking it out. The cart can then be reviewed, e.g. in
+// the OVH manager, and bought with PurchaseCart, possibly by another
+// process. The ID of the cart is returned even when err is set, once it has
+// been created.
+func (o *Orderer) BuildCart(ctx context.Context, req OrderRequest) (string, error) {
+	req, err := o.prepare(req)
+	if err != nil {
+		return "", err
+	}
+	result := &OrderResult{RunID: req.RunID, CustomerReference: req.CustomerReference}
+	err = o.buildCart(withStepStats(ctx, result), req, result)
+	return result.CartID, err
+}
+
+// PurchaseOptions configures PurchaseCart.
+type PurchaseOptions struct {
+	// PricingMode is the pricing mode the cart was built with, whose
+	// commitment must be acknowledged with AckEngagement when it is longer
+	// than Options.EngagementAckMonths.
+	PricingMode   string
+	AckEngagement bool
+
+	// Duration is the duration the cart was built with, which splits its
+	// price between its first invoice and its renewals (see SplitBilling).
+	// Optional.
+	Duration string
+
+	// AutoPay, MaxPrice, MaxPriceBasis, PriceBaseline and PriceTolerance
+	// are those of OrderRequest.
+	AutoPay        bool
+	MaxPrice       string
+	MaxPriceBasis  string
+	PriceBaseline  *PriceBaseline
+	PriceTolerance string
+
+	// request is the order of the cart when Order buys it, passed to
+	// Options.CheckoutPolicy
+	request *OrderRequest
+}
+
+// purchaseOptions returns the options purchasing the cart of r
+func (r OrderRequest) purchaseOptions() PurchaseOptions {
+	return PurchaseOptions{
+		PricingMode:    r.PricingMode,
+		AckEngagement:  r.AckEngagement,
+		Duration:       r.Duration,
+		AutoPay:        r.AutoPay,
+		MaxPrice:       r.MaxPrice,
+		MaxPriceBasis:  r.MaxPriceBasis,
+		PriceBaseline:  r.PriceBaseline,
+		PriceTolerance: r.PriceTolerance,
+		request:        &r,
+	}
+}
+
+// PurchaseCart runs the steps of Order that buy a cart built by BuildCart:
+// it checks the cart out and pays the resulting order, whose ID it returns.
+// The cart must still exist (ErrCartGone) and be recent enough
+// (Options.MaxStateAge). The ID of the order is returned even when the
+// payment fails.
+func (o *Orderer) PurchaseCart(ctx context.Context, cartID string, opts PurchaseOptions) (string, error) {
+	if o.isClosed() {
+		return "", ErrClosed
+	}
+	if err := o.checkEngagement(OrderRequest{PricingMode: opts.PricingMode, AckEngagement: opts.AckEngagement}); err != nil {
+		return "", err
+	}
+	if opts.MaxPrice != "" {
+		if _, err := ParseAmount(opts.MaxPrice); err != nil {
+			return "", fmt.Errorf("invalid maximum price: %w", err)
+		}
+	}
+	if err := ValidatePriceBasis(opts.MaxPriceBasis); err != nil {
+		return "", err
+	}
+	if err := ValidatePriceTolerance(opts.PriceTolerance); err != nil {
+		return "", err
+	}
+	if _, err := o.resumableCart(ctx, cartID); err != nil {
+		return "", err
+	}
+	result := &OrderResult{CartID: cartID}
+	err := o.purchaseCart(withStepStats(ctx, result), cartID, opts, result)
+	return result.OrderID, err
+}
+
+// prepare validates req, with its defaults, before anything is created
+func (o *Orderer) prepare(req OrderRequest) (OrderRequest, error) {
+	if o.isClosed() {
+		return req, ErrClosed
+	}
+	req = req.withDefaults()
+	subsidiary, err := NormalizeSubsidiary(req.Subsidiary, o.opts.Endpoint)
+	if err != nil {
+		return req, err
+	}
+	req.Subsidiary = subsidiary
+	if err := ValidateDuration(req.Duration); err != nil {
+		return req, err
+	}
+	if req.ExtraIPs != nil {
+		if err := req.ExtraIPs.validate(); err != nil {
+			return req, err
+		}
+	}
+	if err := o.checkEngagement(req); err != nil {
+		return req, err
+	}
+	for _, option := range req.Options {
+		if option.Duration != "" {
+			if err := ValidateDuration(option.Duration); err != nil {
+				return req, fmt.Errorf("option %s: %w", option.PlanCode, err)
+			}
+		}
+		if option.PricingMode != "" && option.PricingMode != req.PricingMode {
+			if err := o.checkEngagement(OrderRequest{PricingMode: option.PricingMode, AckEngagement: req.AckEngagement}); err != nil {
+				return req, fmt.Errorf("option %s: %w", option.PlanCode, err)
+			}
+		}
+	}
+	if req.RequirePricingMode != "" && req.PricingMode != req.RequirePricingMode {
+		return req, fmt.Errorf("%w: the order uses pricing mode %q, %q is required", ErrPricingModeMismatch, req.PricingMode, req.RequirePricingMode)
+	}
+	if err := validateExtraParams(req.ExtraParams); err != nil {
+		return req, err
+	}
+	for _, option := range req.Options {
+		if err := validateExtraParams(option.ExtraParams); err != nil {
+			return req, fmt.Errorf("option %s: %w", option.PlanCode, err)
+		}
+	}
+	if req.MaxPrice != "" {
+		if _, err := ParseAmount(req.MaxPrice); err != nil {
+			return req, fmt.Errorf("invalid maximum price: %w", err)
+		}
+	}
+	if err := ValidatePriceBasis(req.MaxPriceBasis); err != nil {
+		return req, err
+	}
+	if err := ValidatePriceTolerance(req.PriceTolerance); err != nil {
+		return req, err
+	}
+	if req.CustomerReference != "" {
+		if err := ValidateCustomerReference(req.CustomerReference); err != nil {
+			return req, err
+		}
+	}
+	if req.Reverse != "" {
+		if err := ValidateReverse(req.Reverse); err != nil {
+			return req, err
+		}
+	}
+	if req.Monitoring != nil {
+		if err := req.Monitoring.validate(); err != nil {
+			return req, err
+		}
+	}
+	if req.Renewal != nil {
+		if err := req.Renewal.validate(); err != nil {
+			return req, err
+		}
+	}
+	if req.Connectivity != nil {
+		if err := req.Connectivity.validate(); err != nil {
+			return req, err
+		}
+	}
+	if req.Install != nil && req.Install.Template == "" {
+		return req, fmt.Errorf("an installation template is required to install the server")
+	}
+	return req, nil
+}
+
+// buildCart runs the steps 1 to 6 of Order, filling result
+func (o *Orderer) buildCart(ctx context.Context, req OrderRequest, result *OrderResult) error {
+	// Step 1 and 2: Create a new cart, unless req.CartID provides one, and
+	// assign it to the logged-in user
+	err := o.step(result, StepCreateCart, func() (err error) {
+		if req.BillingAccount != "" {
+			if err := o.checkBillingAccount(ctx, req.BillingAccount); err != nil {
+				return err
+			}
+		}
+		if req.CartID != "" {
+			result.CartID = req.CartID
+			return o.refreshCartIfExpiring(ctx, req.CartID)
+		}
+		result.CartID, err = o.createCart(ctx, req, "")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	cartID := result.CartID
+
+	// Step 3: Add the dedicated server to the cart
+	err = o.step(result, StepAddServer, func() (err error) {
+		// Both checks only read the catalog of the cart
+		var pricingErr, optionsErr error
+		var options []Option
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			pricingErr = o.checkPricing(ctx, cartID, req)
+		}()
+		go func() {
+			defer wg.Done()
+			options, optionsErr = o.resolveOptions(ctx, cartID, req)
+		}()
+		wg.Wait()
+		if pricingErr != nil {
+			return pricingErr
+		}
+		if optionsErr != nil {
+			return optionsErr
+		}
+		req.Options = options
+		result.ItemID, err = o.addServer(ctx, cartID, req)
+		if err == nil {
+			result.addItem(CartItem{ItemID: result.ItemID, Role: ItemRoleServer, PlanCode: req.PlanCode})
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// The following steps target the server item explicitly
+	server, _ := result.item(ItemRoleServer)
+	itemID := server.ItemID
+
+	// Step 4: Configure the server
+	err = o.step(result, StepConfigure, func() error {
+		required, err := o.requiredConfiguration(ctx, cartID, itemID)
+		if err != nil {
+			return err
+		}
+		configs, err := resolveOS(req.Configuration, req.OS, required)
+		if err != nil {
+			return fmt.Errorf("item %d: %w", itemID, err)
+		}
+		configs, err = resolvePlacement(configs, required)
+		if err != nil {
+			return fmt.Errorf("item %d: %w", itemID, err)
+		}
+		result.Configuration, err = o.configureAll(ctx, cartID, itemID, configs)
+		result.Placement = placement(result.Configuration)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Step 5: Add options, if any
+	if len(req.Options) > 0 {
+		err = o.step(result, StepOptions, func() error {
+			offers, err := o.listOptions(ctx, cartID, req.PlanCode)
+			if err != nil {
+				return err
+			}
+			for _, option := range req.Options {
+				err := checkOptionOffered(option, offers, req.PlanCode)
+				if err == nil {
+					err = checkOptionPricing(option, offers, req)
+				}
+				if err != nil {
+					if !req.BestEffort {
+						return err
+					}
+					o.logger.Printf("Skipping option %s: %v", option.PlanCode, err)
+					result.SkippedOptions = append(result.SkippedOptions, SkippedOption{PlanCode: option.PlanCode, Err: err})
+					continue
+				}
+				optionResult, err := o.addOption(ctx, cartID, itemID, option, req)
+				if optionResult != nil {
+					offer, _ := findOffer(offers, option.PlanCode)
+					optionResult.Family, optionResult.Kind = offer.Family, offer.Kind()
+					optionResult.Included = option.included
+				}
+				if err != nil && req.BestEffort && !isRetryable(err) {
+					o.logger.Printf("Skipping option %s: %v", option.PlanCode, err)
+					result.SkippedOptions = append(result.SkippedOptions, SkippedOption{PlanCode: option.PlanCode, Err: err})
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				result.Options = append(result.Options, *optionResult)
+				if offer, _ := findOffer(offers, option.PlanCode); offer.IsDelivery() {
+					result.Delivery = &DeliveryOption{PlanCode: offer.PlanCode, Description: offer.ProductName}
+				}
+				result.addItem(CartItem{ItemID: optionResult.ItemID, Role: ItemRoleOption, PlanCode: option.PlanCode, ParentID: itemID})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Step 6: Validate the cart
+	return o.step(result, StepValidate, func() error {
+		return o.validateCart(ctx, cartID, itemID, result)
+	})
+}
+
+// purchaseCart runs the checkout and steps 7 and 8 of Order on a built cart, filling result
+func (o *Orderer) purchaseCart(ctx context.Context, cartID string, opts PurchaseOptions, result *OrderResult) error {
+	if err := o.checkEndpointAllowed(); err != nil {
+		return err
+	}
+
+	// Step 7: Check the cart out
+	var contracts []Contract
+	err := o.step(result, StepCheckout, func() error {
+		if err := o.refreshCartIfExpiring(ctx, cartID); err != nil {
+			return err
+		}
+		summary, err := o.summary(ctx, cartID)
+		if err != nil {
+			return err
+		}
+		// An item lost to an earlier silent failure must not be checked out
+		if err := checkSummaryItems(cartID, summary, result.Items); err != nil {
+			return err
+		}
+		if err := o.approveCheckout(ctx, cartID, opts, summary, result); err != nil {
+			if err := o.deleteCart(ctx, cartID); err != nil {
+				o.logger.Printf("Error deleting cart: %v", err)
+			}
+			return err
+		}
+		order, err := o.checkout(ctx, cartID, opts.AutoPay)
+		if err != nil {
+			return err
+		}
+		result.OrderID, result.PaymentURL, contracts = order.OrderID, order.URL, order.Contracts
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Step 8 and 9: Pay for the order with the first available payment
+	// method, unless OVH charges the preferred one itself
+	if opts.AutoPay {
+		result.AutoPay = true
+		o.logger.Printf("Order %s submitted for auto-payment.", result.OrderID)
+	} else {
+		err = o.step(result, StepPayment, func() (err error) {
+			result.PaymentMethodID, result.PaymentMethodType, err = o.pay(ctx, result.OrderID, result.PaymentURL)
+			result.PaymentStatus = paymentStatus(result.PaymentMethodID, err)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// The order is paid: failing to get its documents must not fail it
+	result.Documents, err = o.orderDocuments(ctx, result.OrderID, contracts)
+	if err != nil {
+		o.logger.Printf("Warning: %v", err)
+	}
+	return nil
+}
+
+// maxConfirmations bounds the confirmations asked by approveCheckout when
+// the price of the cart keeps changing
+const maxConfirmations = 3
+
+// ErrPriceChanged is returned, wrapped, when the price of a cart changed
+// after every confirmation approveCheckout asked for.
+var ErrPriceChanged = errors.New("the price of the cart changed during the confirmation")
+
+// approveCheckout checks summary against the maximum price and the policy
+// of opts, then asks Options.ConfirmCheckout to approve it. As a human may
+// take a while to answer, the summary is then read again: when its price
+// changed in between, e.g. a price update of OVH, the new price goes
+// through the checks and the confirmation again, so that the approval holds
+// for the exact price checked out.
+func (o *Orderer) approveCheckout(ctx context.Context, cartID string, opts PurchaseOptions, summary *CartSummary, result *OrderResult) error {
+	for confirmations := 1; ; confirmations++ {
+		if err := o.checkSummary(ctx, cartID, opts, summary, result); err != nil {
+			return err
+		}
+		if o.opts.ConfirmCheckout == nil {
+			return nil
+		}
+		if !o.opts.ConfirmCheckout(summary) {
+			return ErrCheckoutDeclined
+		}
+		current, err := o.summary(ctx, cartID)
+		if err != nil {
+			return err
+		}
+		if samePrices(summary, current) {
+			return nil
+		}
+		if confirmations == maxConfirmations {
+			return fmt.Errorf("%w: it was confirmed %d times, last at %s, and is now %s",
+				ErrPriceChanged, confirmations, summary.Prices.WithTax.Text, current.Prices.WithTax.Text)
+		}
+		o.logger.Printf("The price of cart %s changed from %s to %s since it was confirmed, confirm the new price",
+			cartID, summary.Prices.WithTax.Text, current.Prices.WithTax.Text)
+		summary = current
+	}
+}
+
+// checkSummary records the engagement, billing and prices of summary in
+// result, and checks them against the maximum price, the price baseline and
+// the policy of opts
+func (o *Orderer) checkSummary(ctx context.Context, cartID string, opts PurchaseOptions, summary *CartSummary, result *OrderResult) error {
+	engagement := ParseEngagement(opts.PricingMode)
+	if summary.Engagement = engagement; engagement != nil {
+		o.logger.Printf("This order is a %s", engagement)
+		for _, contract := range summary.Contracts {
+			o.logger.Printf("Contract %s: %s", contract.Name, contract.URL)
+		}
+		result.Engagement = engagement
+	}
+	if billing, err := SplitBilling(summary, opts.Duration); err != nil {
+		o.logger.Printf("Warning: cannot split the price of cart %s: %v", cartID, err)
+	} else {
+		result.Billing = billing
+	}
+	result.Prices = &summary.Prices
+	if opts.MaxPrice != "" {
+		o.logger.Printf("Maximum price %s applies to the price %s", opts.MaxPrice, describePriceBasis(opts.MaxPriceBasis))
+		if err := checkMaxPrice(summary.Prices, opts.MaxPrice, opts.MaxPriceBasis); err != nil {
+			return err
+		}
+	}
+	if opts.PriceBaseline != nil {
+		planCodes, err := o.itemPlanCodes(ctx, cartID)
+		if err != nil {
+			return err
+		}
+		current, err := baselineFromSummary(summary, planCodes)
+		if err != nil {
+			return fmt.Errorf("error pricing cart %s against the baseline: %w", cartID, err)
+		}
+		if err := checkBaseline(opts.PriceBaseline, current, opts.PriceTolerance); err != nil {
+			return err
+		}
+		o.logger.Printf("The prices of cart %s match the baseline", cartID)
+	}
+	return o.checkPolicy(ctx, cartID, opts, summary)
+}
+
+// afterDelivery installs the delivered server of result, orders its extra
+// IPs, sets its reverse DNS, tags it and checks its connectivity, as set by
+// req
+func (o *Orderer) afterDelivery(ctx context.Context, result *OrderResult, req OrderRequest) (err error) {
+	if req.Install != nil {
+		err = o.step(result, StepInstall, func() error {
+			return o.Install(ctx, result.ServiceName, *req.Install)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if req.ExtraIPs != nil {
+		err = o.step(result, StepExtraIPs, func() (err error) {
+			result.ExtraIPs, err = o.OrderExtraIPs(ctx, result.ServiceName, *req.ExtraIPs)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if req.ExtraIPs != nil || req.RequireIPv6 {
+		err = o.step(result, StepNetwork, func() (err error) {
+			result.IPs, err = o.checkNetwork(ctx, result.ServiceName, req.RequireIPv6)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if req.Reverse != "" {
+		err = o.step(result, StepReverse, func() (err error) {
+			result.Reverse, err = o.SetReverse(ctx, result.ServiceName, req.Reverse)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if req.Monitoring != nil {
+		err = o.step(result, StepMonitoring, func() (err error) {
+			result.Monitoring, err = o.EnableMonitoring(ctx, result.ServiceName, *req.Monitoring)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if req.Renewal != nil {
+		err = o.step(result, StepRenewal, func() (err error) {
+			result.Renewal, err = o.SetRenewal(ctx, result.ServiceName, *req.Renewal)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if req.Tag != "" {
+		err = o.step(result, StepTag, func() error {
+			return o.TagServer(ctx, result.ServiceName, req.Tag)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if req.Connectivity != nil {
+		err = o.step(result, StepConnectivity, func() (err error) {
+			result.Connectivity, err = o.VerifyConnectivity(ctx, result.ServiceName, *req.Connectivity)
+			return err
+		})
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package orderer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+func TestHasPostDelivery(t *testing.T) {
+	tests := []struct {
+		name string
+		req  OrderRequest
+		want bool
+	}{
+		{name: "none", req: OrderRequest{PlanCode: "24ska01", DeliveryPriority: "express"}},
+		{name: "install", req: OrderRequest{Install: &InstallRequest{Template: "debian12_64"}}, want: true},
+		{name: "extra IPs", req: OrderRequest{ExtraIPs: &ExtraIPs{Count: 1}}, want: true},
+		{name: "tag", req: OrderRequest{Tag: "rack-12"}, want: true},
+		{name: "IPv6", req: OrderRequest{RequireIPv6: true}, want: true},
+		{name: "reverse", req: OrderRequest{Reverse: "host.example.com"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.HasPostDelivery(); got != tt.want {
+				t.Errorf("HasPostDelivery() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApproveCheckout(t *testing.T) {
+	summary := func(total string) *CartSummary {
+		return &CartSummary{Prices: SummaryPrices{
+			WithTax:    Price{Value: json.Number(total), Text: total + " €"},
+			WithoutTax: Price{Value: json.Number(total), Text: total + " €"},
+		}}
+	}
+	tests := []struct {
+		name     string
+		totals   []string // the total confirmed first, then those read again
+		decline  bool
+		maxPrice string
+
+		wantConfirmed []string
+		wantErr       error
+	}{
+		{name: "unchanged", totals: []string{"100", "100.00"}, wantConfirmed: []string{"100"}},
+		{name: "changed once", totals: []string{"100", "105", "105"}, wantConfirmed: []string{"100", "105"}},
+		{name: "keeps changing", totals: []string{"100", "101", "102", "103"}, wantConfirmed: []string{"100", "101", "102"}, wantErr: ErrPriceChanged},
+		{name: "declined", totals: []string{"100"}, decline: true, wantConfirmed: []string{"100"}, wantErr: ErrCheckoutDeclined},
+		{name: "changed above the maximum", totals: []string{"100", "130"}, maxPrice: "120", wantConfirmed: []string{"100"}, wantErr: ErrMaxPriceExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reads := 0
+			client := &stubClient{answer: func(method, path string, body interface{}) (interface{}, error) {
+				if method+" "+path != "GET /order/cart/cart-1/checkout" {
+					return nil, fmt.Errorf("unexpected call %s %s", method, path)
+				}
+				reads++
+				if reads >= len(tt.totals) {
+					return nil, fmt.Errorf("summary read %d times", reads)
+				}
+				return summary(tt.totals[reads]), nil
+			}}
+			var confirmed []string
+			o := New(client, Options{Clock: newFakeClock(), ConfirmCheckout: func(s *CartSummary) bool {
+				confirmed = append(confirmed, s.Prices.WithTax.Value.String())
+				return !tt.decline
+			}})
+
+			var result OrderResult
+			err := o.approveCheckout(context.Background(), "cart-1", PurchaseOptions{MaxPrice: tt.maxPrice}, summary(tt.totals[0]), &result)
+			if tt.wantErr == nil && err != nil || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(confirmed, tt.wantConfirmed) {
+				t.Errorf("confirmed %v, want %v", confirmed, tt.wantConfirmed)
+			}
+		})
+	}
+}
+
+// New wraps the transport of a copy of an *ovh.Client, not of the caller's
+func TestNewLeavesClient(t *testing.T) {
+	calls := 0
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("1767323045")), Request: r}, nil
+	})
+	client, err := ovh.NewClient("https://eu.api.ovh.com/1.0", "key", "secret", "consumer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient := &http.Client{Transport: transport}
+	client.Client = httpClient
+
+	o := New(client, Options{Clock: newFakeClock()})
+	if _, ok := httpClient.Transport.(roundTripFunc); client.Client != httpClient || !ok {
+		t.Errorf("the client was changed: transport = %T", client.Client.Transport)
+	}
+	var now int64
+	if err := o.client.GetWithContext(context.Background(), "/auth/time", &now); err != nil {
+		t.Fatal(err)
+	}
+	if calls == 0 {
+		t.Error("the Orderer did not call the transport of the client")
+	}
+}
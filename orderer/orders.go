@@ -0,0 +1,167 @@
+package orderer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Price is an amount as returned by the OVH API.
+type Price struct {
+	Value        json.Number `json:"value"`
+	CurrencyCode string      `json:"currencyCode"`
+	Text         string      `json:"text"`
+
+	// PriceInUcents is the value in millionths of a cent, which the API
+	// returns along with it.
+	PriceInUcents int64 `json:"priceInUcents"`
+}
+
+// OrderInfo is an order of the account, as returned by /me/order/{orderId}.
+type OrderInfo struct {
+	OrderID         int64     `json:"orderId"`
+	Date            time.Time `json:"date"`
+	ExpirationDate  time.Time `json:"expirationDate"`
+	PriceWithTax    Price     `json:"priceWithTax"`
+	PriceWithoutTax Price     `json:"priceWithoutTax"`
+	Tax             Price     `json:"tax"`
+	URL             string    `json:"url"`
+
+	// PDFURL is the order form as a PDF, and Password the password of the
+	// links of the order.
+	PDFURL   string `json:"pdfUrl"`
+	Password string `json:"password"`
+
+	// RetractionDate is the end of the withdrawal period, zero when the
+	// order has none.
+	RetractionDate time.Time `json:"retractionDate"`
+
+	// Descriptions of the order details, only fetched when filtering on them.
+	Descriptions []string `json:"-"`
+}
+
+// OrderFilter selects the orders returned by ListOrders.
+type OrderFilter struct {
+	// Since restricts the listing to orders placed after it. It is applied by
+	// the API, so it is the cheapest way to bound a listing.
+	Since time.Time
+
+	// DescriptionContains keeps the orders having a detail whose description
+	// contains it. Matching requires fetching the details of every candidate.
+	DescriptionContains string
+
+	// Limit stops the listing after that many matching orders, newest first.
+	// Zero means no limit.
+	Limit int
+
+	// Concurrency bounds the number of orders fetched in parallel. Defaults to 4.
+	Concurrency int
+}
+
+// ListOrders returns the orders of the account matching filter, newest first.
+// Order IDs are listed in a single call, then orders are fetched in batches
+// of filter.Concurrency until filter.Limit matches are found.
+func (o *Orderer) ListOrders(ctx context.Context, filter OrderFilter) ([]OrderInfo, error) {
+	if filter.Concurrency <= 0 {
+		filter.Concurrency = 4
+	}
+
+	path := "/me/order"
+	if !filter.Since.IsZero() {
+		path += "?date.from=" + url.QueryEscape(filter.Since.Format(time.RFC3339))
+	}
+	var orderIDs []int64
+	if err := o.client.GetWithContext(ctx, path, &orderIDs); err != nil {
+		return nil, fmt.Errorf("error listing orders: %w", err)
+	}
+	// Order IDs grow over time, so the newest orders come first
+	sort.Slice(orderIDs, func(i, j int) bool { return orderIDs[i] > orderIDs[j] })
+
+	var orders []OrderInfo
+	for start := 0; start < len(orderIDs); start += filter.Concurrency {
+		end := start + filter.Concurrency
+		if end > len(orderIDs) {
+			end = len(orderIDs)
+		}
+		batch, err := o.fetchOrders(ctx, orderIDs[start:end], filter.DescriptionContains != "")
+		if err != nil {
+			return nil, err
+		}
+		for _, order := range batch {
+			if !order.matches(filter) {
+				continue
+			}
+			orders = append(orders, order)
+			if filter.Limit > 0 && len(orders) == filter.Limit {
+				return orders, nil
+			}
+		}
+	}
+	return orders, nil
+}
+
+// matches reports whether the order satisfies the client side part of filter
+func (order OrderInfo) matches(filter OrderFilter) bool {
+	if filter.DescriptionContains == "" {
+		return true
+	}
+	for _, description := range order.Descriptions {
+		if strings.Contains(description, filter.DescriptionContains) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchOrders fetches the given orders concurrently, keeping their order
+func (o *Orderer) fetchOrders(ctx context.Context, orderIDs []int64, withDetails bool) ([]OrderInfo, error) {
+	orders := make([]OrderInfo, len(orderIDs))
+	errs := make([]error, len(orderIDs))
+	var wg sync.WaitGroup
+	for i, orderID := range orderIDs {
+		wg.Add(1)
+		go func(i int, orderID int64) {
+			defer wg.Done()
+			orders[i], errs[i] = o.fetchOrder(ctx, orderID, withDetails)
+		}(i, orderID)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+// fetchOrder fetches a single order and, if asked, the descriptions of its details
+func (o *Orderer) fetchOrder(ctx context.Context, orderID int64, withDetails bool) (OrderInfo, error) {
+	var order OrderInfo
+	if err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%d", orderID), strict(&order)); err != nil {
+		return order, fmt.Errorf("error fetching order %d: %w", orderID, err)
+	}
+	if !withDetails {
+		return order, nil
+	}
+
+	var detailIDs []int64
+	if err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%d/details", orderID), &detailIDs); err != nil {
+		return order, fmt.Errorf("error fetching details of order %d: %w", orderID, err)
+	}
+	for _, detailID := range detailIDs {
+		var detail struct {
+			Description string `json:"description"`
+		}
+		if err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%d/details/%d", orderID, detailID), &detail); err != nil {
+			return order, fmt.Errorf("error fetching detail %d of order %d: %w", detailID, orderID, err)
+		}
+		order.Descriptions = append(order.Descriptions, detail.Description)
+	}
+	return order, nil
+}
@@ -0,0 +1,94 @@
+package orderer
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// The tool keeps its files in the XDG base directories, under AppName:
+//
+//   - config, $XDG_CONFIG_HOME (defaults to ~/.config): the plan aliases
+//     (AliasesFile) and purchasing rules (PolicyFile) files, used when they
+//     exist
+//   - state, $XDG_STATE_HOME (defaults to ~/.local/state): the watch list of
+//     the watch command (WatchListFile)
+//   - cache, $XDG_CACHE_HOME (defaults to ~/.cache): the catalog cache
+//
+// Paths finds them. Other files, such as configs, reports and Terraform
+// resources, are only read or written where the caller says.
+const (
+	AppName       = "ovh-ds-orderer"
+	AliasesFile   = "aliases.yaml"
+	PolicyFile    = "policy.yaml"
+	WatchListFile = "watch.json"
+
+	XDGConfigVar = "XDG_CONFIG_HOME"
+	XDGStateVar  = "XDG_STATE_HOME"
+	XDGCacheVar  = "XDG_CACHE_HOME"
+)
+
+// Paths finds the default directories and files of the tool. The zero value
+// reads the environment and the disk.
+type Paths struct {
+	// Getenv and UserHomeDir default to os.Getenv and os.UserHomeDir.
+	Getenv      func(key string) string
+	UserHomeDir func() (string, error)
+
+	// FS tells whether the optional files exist. Defaults to the disk.
+	FS FS
+}
+
+// dir returns the directory of the tool in the XDG base directory of
+// variable, or of fallback in the home directory when variable is unset or
+// relative, as the specification requires. It returns "" when there is no
+// home directory either.
+func (p Paths) dir(variable, fallback string) string {
+	getenv, userHomeDir := p.Getenv, p.UserHomeDir
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	if userHomeDir == nil {
+		userHomeDir = os.UserHomeDir
+	}
+	dir := getenv(variable)
+	if !filepath.IsAbs(dir) {
+		home, err := userHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, fallback)
+	}
+	return filepath.Join(dir, AppName)
+}
+
+// ConfigDir, StateDir and CacheDir return the directories of the tool in
+// the XDG base directories, "" when they cannot be found.
+func (p Paths) ConfigDir() string { return p.dir(XDGConfigVar, ".config") }
+func (p Paths) StateDir() string  { return p.dir(XDGStateVar, filepath.Join(".local", "state")) }
+func (p Paths) CacheDir() string  { return p.dir(XDGCacheVar, ".cache") }
+
+// ConfigFile returns the file name of the config directory when it exists,
+// "" otherwise, for the default of an optional file.
+func (p Paths) ConfigFile(name string) string {
+	path := joinDir(p.ConfigDir(), name)
+	if path == "" {
+		return ""
+	}
+	if _, err := fsOrDisk(p.FS).Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// WatchList returns the default watch list, in the state directory.
+func (p Paths) WatchList() string {
+	return joinDir(p.StateDir(), WatchListFile)
+}
+
+// joinDir joins name to dir, unless dir is unknown
+func joinDir(dir, name string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, name)
+}
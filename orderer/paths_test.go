@@ -0,0 +1,85 @@
+package orderer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPaths(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		home string
+
+		wantConfig, wantState, wantCache string
+	}{
+		{
+			name:       "home",
+			home:       "/home/op",
+			wantConfig: "/home/op/.config/ovh-ds-orderer",
+			wantState:  "/home/op/.local/state/ovh-ds-orderer",
+			wantCache:  "/home/op/.cache/ovh-ds-orderer",
+		},
+		{
+			name:       "variables",
+			env:        map[string]string{XDGConfigVar: "/etc/xdg", XDGStateVar: "/var/lib", XDGCacheVar: "/var/cache"},
+			home:       "/home/op",
+			wantConfig: "/etc/xdg/ovh-ds-orderer",
+			wantState:  "/var/lib/ovh-ds-orderer",
+			wantCache:  "/var/cache/ovh-ds-orderer",
+		},
+		{
+			// The specification ignores relative paths
+			name:       "relative variable",
+			env:        map[string]string{XDGConfigVar: "config"},
+			home:       "/home/op",
+			wantConfig: "/home/op/.config/ovh-ds-orderer",
+			wantState:  "/home/op/.local/state/ovh-ds-orderer",
+			wantCache:  "/home/op/.cache/ovh-ds-orderer",
+		},
+		{
+			name:      "no home",
+			env:       map[string]string{XDGCacheVar: "/var/cache"},
+			wantCache: "/var/cache/ovh-ds-orderer",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Paths{
+				Getenv: func(key string) string { return tt.env[key] },
+				UserHomeDir: func() (string, error) {
+					if tt.home == "" {
+						return "", errors.New("no home")
+					}
+					return tt.home, nil
+				},
+			}
+			if got := p.ConfigDir(); got != tt.wantConfig {
+				t.Errorf("ConfigDir() = %q, want %q", got, tt.wantConfig)
+			}
+			if got := p.StateDir(); got != tt.wantState {
+				t.Errorf("StateDir() = %q, want %q", got, tt.wantState)
+			}
+			if got := p.CacheDir(); got != tt.wantCache {
+				t.Errorf("CacheDir() = %q, want %q", got, tt.wantCache)
+			}
+		})
+	}
+}
+
+func TestPathsFiles(t *testing.T) {
+	p := Paths{
+		Getenv:      func(string) string { return "" },
+		UserHomeDir: func() (string, error) { return "/home/op", nil },
+		FS:          newMemFS(map[string]string{"/home/op/.config/ovh-ds-orderer/policy.yaml": "version: 1\n"}),
+	}
+	if got, want := p.ConfigFile(PolicyFile), "/home/op/.config/ovh-ds-orderer/policy.yaml"; got != want {
+		t.Errorf("ConfigFile(PolicyFile) = %q, want %q", got, want)
+	}
+	if got := p.ConfigFile(AliasesFile); got != "" {
+		t.Errorf("ConfigFile(AliasesFile) = %q, want none as it does not exist", got)
+	}
+	if got, want := p.WatchList(), "/home/op/.local/state/ovh-ds-orderer/watch.json"; got != want {
+		t.Errorf("WatchList() = %q, want %q", got, want)
+	}
+}
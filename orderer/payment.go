@@ -0,0 +1,269 @@
+package orderer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNoPaymentMethod is returned when an order has no available payment method
+// once the wait window has elapsed.
+var ErrNoPaymentMethod = errors.New("no available payment methods found")
+
+// ErrInteractivePaymentRequired is returned, wrapped in an
+// InteractivePaymentError, when an order cannot be paid through the API and
+// must be paid from a browser, e.g. because of 3-D Secure.
+var ErrInteractivePaymentRequired = errors.New("order requires interactive payment")
+
+// InteractivePaymentError carries the URL where an order must be paid.
+type InteractivePaymentError struct {
+	OrderID string
+	URL     string
+}
+
+func (e *InteractivePaymentError) Error() string {
+	return fmt.Sprintf("order %s requires interactive payment at %s", e.OrderID, e.URL)
+}
+
+// Unwrap makes errors.Is(err, ErrInteractivePaymentRequired) match.
+func (e *InteractivePaymentError) Unwrap() error {
+	return ErrInteractivePaymentRequired
+}
+
+// Payment statuses, as reported in OrderResult.PaymentStatus.
+const (
+	PaymentPaid    = "paid"
+	PaymentPending = "pending"
+	PaymentFailed  = "failed"
+)
+
+// ErrPaymentPending is returned, wrapped, when a payment accepted by /pay has
+// not settled within Options.PaymentSettleWait. The order may still be paid
+// later: check it before paying it again.
+var ErrPaymentPending = errors.New("payment is still pending")
+
+// ErrPaymentFailed is returned, wrapped, when the order is cancelled instead
+// of paid after /pay accepted the payment.
+var ErrPaymentFailed = errors.New("payment failed")
+
+// paymentStatus returns the payment status of an order paid with methodID,
+// or empty if no payment was sent
+func paymentStatus(methodID string, err error) string {
+	switch {
+	case errors.Is(err, ErrPaymentPending):
+		return PaymentPending
+	case errors.Is(err, ErrPaymentFailed):
+		return PaymentFailed
+	case err == nil && methodID != "":
+		return PaymentPaid
+	}
+	return ""
+}
+
+// ErrOrderAlreadyPaid is returned by PayOrder for an order that has already
+// been paid.
+var ErrOrderAlreadyPaid = errors.New("order is already paid")
+
+// AvailablePaymentMethod is a payment method offered for an order.
+type AvailablePaymentMethod struct {
+	ID   json.Number `json:"id"`
+	Type string      `json:"type"`
+
+	// Default is set on the preferred payment method of the account.
+	Default bool `json:"default"`
+
+	// Integration is how the method is completed; methods integrated by
+	// redirection or iframe need a browser.
+	Integration string `json:"integration"`
+
+	Description string `json:"description"`
+}
+
+// usable reports whether the method can be used through the API
+func (m AvailablePaymentMethod) usable() bool {
+	return m.ID != "" && m.Type != "" && (m.Integration == "" || m.Integration == "NONE")
+}
+
+func (m AvailablePaymentMethod) String() string {
+	s := m.Type + " " + m.ID.String()
+	if m.Default {
+		s += " (default)"
+	}
+	return s
+}
+
+// PaymentMethodCriteria restricts the payment methods an order may be paid
+// with. The zero value accepts any method.
+type PaymentMethodCriteria struct {
+	// ID, when set, only accepts the method with this ID.
+	ID string
+
+	// Type, when set, only accepts methods of this type, e.g. "CREDIT_CARD".
+	Type string
+
+	// Default only accepts the default payment method of the account.
+	Default bool
+}
+
+func (c PaymentMethodCriteria) match(m AvailablePaymentMethod) bool {
+	return (c.ID == "" || c.ID == m.ID.String()) &&
+		(c.Type == "" || strings.EqualFold(c.Type, m.Type)) &&
+		(!c.Default || m.Default)
+}
+
+// SelectPaymentMethod returns the first method of methods that can be used
+// through the API and matches criteria. It returns an error wrapping
+// ErrNoPaymentMethod, listing the methods available, when none does.
+func SelectPaymentMethod(methods []AvailablePaymentMethod, criteria PaymentMethodCriteria) (*AvailablePaymentMethod, error) {
+	var usable []string
+	for i, method := range methods {
+		if !method.usable() {
+			continue
+		}
+		if criteria.match(method) {
+			return &methods[i], nil
+		}
+		usable = append(usable, method.String())
+	}
+	if len(usable) == 0 {
+		return nil, ErrNoPaymentMethod
+	}
+	return nil, fmt.Errorf("%w matching the criteria, usable methods: %s", ErrNoPaymentMethod, strings.Join(usable, ", "))
+}
+
+// fetchPaymentMethods returns the payment methods available for an order.
+// Right after checkout the order may not be fully registered yet and the list
+// comes back empty, so it is polled with exponential backoff until it is
+// non-empty or the wait window has elapsed.
+func (o *Orderer) fetchPaymentMethods(ctx context.Context, orderID string) ([]AvailablePaymentMethod, error) {
+	var paymentMethods []AvailablePaymentMethod
+	err := o.pollUntil(ctx, poll{Interval: time.Second, MaxInterval: time.Minute, Timeout: o.opts.PaymentMethodWait}, func() (bool, error) {
+		paymentMethods = nil
+		err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%s/availablePaymentMethod", orderID), strict(&paymentMethods))
+		if err != nil {
+			return false, fmt.Errorf("error fetching payment methods: %w", err)
+		}
+		if len(paymentMethods) == 0 {
+			o.logger.Printf("No payment methods available yet for order %s...", orderID)
+			return false, nil
+		}
+		return true, nil
+	})
+	if errors.Is(err, ErrTimeout) {
+		// The window elapsed: there are genuinely no payment methods
+		return paymentMethods, nil
+	}
+	return paymentMethods, err
+}
+
+// pay pays orderID with the first usable payment method matching
+// Options.PaymentMethod and returns the ID and type of the method used. When no method can be used
+// through the API and paymentURL is known, an InteractivePaymentError is
+// returned. The ID and type are also returned when the payment was sent but
+// did not settle, along with an error wrapping ErrPaymentPending or
+// ErrPaymentFailed.
+func (o *Orderer) pay(ctx context.Context, orderID, paymentURL string) (string, string, error) {
+	if err := o.checkEndpointAllowed(); err != nil {
+		return "", "", err
+	}
+	paymentMethods, err := o.fetchPaymentMethods(ctx, orderID)
+	if err != nil {
+		return "", "", err
+	}
+	o.logger.Printf("Available Payment Methods: %v", paymentMethods)
+	method, err := SelectPaymentMethod(paymentMethods, o.opts.PaymentMethod)
+	if err == ErrNoPaymentMethod && paymentURL != "" {
+		return "", "", &InteractivePaymentError{OrderID: orderID, URL: paymentURL}
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	var paymentResponse struct {
+		Status string `json:"status"`
+	}
+	// An order leaves notPaid once a payment is made, which must not be
+	// made twice
+	postCtx := withResendCheck(ctx, func(ctx context.Context) (bool, error) {
+		var status string
+		if err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%s/status", orderID), &status); err != nil {
+			return false, fmt.Errorf("error fetching status of order %s: %w", orderID, err)
+		}
+		return status != "notPaid", nil
+	})
+	err = o.client.PostWithContext(postCtx, fmt.Sprintf("/me/order/%s/pay", orderID), map[string]interface{}{
+		"paymentMethod": map[string]interface{}{
+			"id":   method.ID,
+			"type": method.Type,
+		},
+	}, &paymentResponse)
+	if err != nil {
+		return "", "", fmt.Errorf("error paying for the order: %w", err)
+	}
+	if paymentResponse.Status != "" {
+		o.logger.Printf("Payment of order %s answered with status %s", orderID, paymentResponse.Status)
+	}
+
+	// /pay may accept a payment which settles asynchronously: the order
+	// status tells whether it actually cleared
+	if err := o.settlePayment(ctx, orderID); err != nil {
+		return method.ID.String(), method.Type, err
+	}
+	o.logger.Printf("Order has been successfully paid.")
+	return method.ID.String(), method.Type, nil
+}
+
+// settlePayment waits for orderID to leave the notPaid status after its
+// payment was accepted, for at most Options.PaymentSettleWait
+func (o *Orderer) settlePayment(ctx context.Context, orderID string) error {
+	var status string
+	err := o.pollUntil(ctx, poll{Interval: 5 * time.Second, MaxInterval: time.Minute, Timeout: o.opts.PaymentSettleWait}, func() (bool, error) {
+		if err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%s/status", orderID), &status); err != nil {
+			return false, fmt.Errorf("error fetching status of order %s: %w", orderID, err)
+		}
+		switch status {
+		case "notPaid":
+			o.logger.Printf("Payment of order %s is pending...", orderID)
+			return false, nil
+		case "cancelled", "cancelling":
+			return false, fmt.Errorf("order %s is %s: %w", orderID, status, ErrPaymentFailed)
+		}
+		return true, nil
+	})
+	if errors.Is(err, ErrTimeout) {
+		return fmt.Errorf("order %s is still %s after %s: %w", orderID, status, o.opts.PaymentSettleWait, ErrPaymentPending)
+	}
+	return err
+}
+
+// PayOrder pays an order that has been checked out but not paid, e.g. because
+// a previous run stopped between checkout and payment, and returns the ID and
+// type of the payment method used. The method is selected as in Order. It
+// fails with ErrOrderAlreadyPaid if the order is past payment, and with an
+// InteractivePaymentError if it can only be paid from a browser.
+func (o *Orderer) PayOrder(ctx context.Context, orderID string) (string, string, error) {
+	if o.isClosed() {
+		return "", "", ErrClosed
+	}
+	var status string
+	if err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%s/status", orderID), &status); err != nil {
+		return "", "", fmt.Errorf("error fetching status of order %s: %w", orderID, err)
+	}
+	switch status {
+	case "checking", "delivering", "delivered", "documentsRequested":
+		return "", "", fmt.Errorf("order %s is %s: %w", orderID, status, ErrOrderAlreadyPaid)
+	case "cancelled", "cancelling":
+		return "", "", fmt.Errorf("order %s: %w", orderID, ErrOrderCancelled)
+	}
+
+	var order struct {
+		URL string `json:"url"`
+	}
+	if err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%s", orderID), &order); err != nil {
+		return "", "", fmt.Errorf("error fetching order %s: %w", orderID, err)
+	}
+	return o.pay(ctx, orderID, order.URL)
+}
@@ -0,0 +1,208 @@
+package orderer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrPolicyViolation is returned, wrapped with the error of the hook, when
+// Options.CheckoutPolicy rejects a cart. The cart is deleted.
+var ErrPolicyViolation = errors.New("order violates the purchasing policy")
+
+// CheckoutPolicy is a hook enforcing the purchasing policy of an
+// organization, set with Options.CheckoutPolicy. It is called once per cart,
+// right before checkout, after the MaxPrice check and before
+// Options.ConfirmCheckout, with:
+//
+//   - req, the order being checked out, with its defaults applied. For a
+//     cart bought with PurchaseCart, req is read back from the cart: its
+//     plan, duration, quantity, configuration and options, along with the
+//     PurchaseOptions;
+//   - summary, the prices of the cart as they would be checked out.
+//
+// Returning an error aborts the checkout: the cart is deleted and the order
+// fails with ErrPolicyViolation wrapping the error. The hook must not modify
+// the cart, and may be called concurrently by bulk orders.
+type CheckoutPolicy func(req OrderRequest, summary CartSummary) error
+
+// Policy is a set of simple purchasing rules, read from a policy file:
+//
+//	allowedDatacenters: [gra, rbx, sbg]
+//	forbiddenOptions:
+//	  - "*-windows-*"
+//	allowedPlans:
+//	  - "24rise*"
+//	namePattern: "^(web|db)-[0-9]+$"
+//
+// Plans and options are matched with path.Match patterns. Empty lists allow
+// everything. Policy.Check is a CheckoutPolicy.
+type Policy struct {
+	// AllowedDatacenters and ForbiddenDatacenters restrict the
+	// dedicated_datacenter of the server. With AllowedDatacenters, the
+	// order must set one.
+	AllowedDatacenters   []string `yaml:"allowedDatacenters"`
+	ForbiddenDatacenters []string `yaml:"forbiddenDatacenters"`
+
+	// AllowedPlans restricts the plan of the server.
+	AllowedPlans []string `yaml:"allowedPlans"`
+
+	// ForbiddenOptions are options that cannot be ordered.
+	ForbiddenOptions []string `yaml:"forbiddenOptions"`
+
+	// NamePattern, when set, is a regular expression the display name of
+	// the server (OrderRequest.Tag) must match.
+	NamePattern string `yaml:"namePattern"`
+
+	namePattern *regexp.Regexp
+}
+
+// LoadPolicy reads and validates the policy file at path of fsys.
+func LoadPolicy(fsys FS, path string) (*Policy, error) {
+	data, err := fsOrDisk(fsys).ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading policy: %w", err)
+	}
+	policy, err := ParsePolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// ParsePolicy parses and validates a policy file content.
+func ParsePolicy(data []byte) (*Policy, error) {
+	var policy Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+	for _, pattern := range append(append([]string{}, policy.AllowedPlans...), policy.ForbiddenOptions...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	if policy.NamePattern != "" {
+		var err error
+		if policy.namePattern, err = regexp.Compile(policy.NamePattern); err != nil {
+			return nil, fmt.Errorf("invalid name pattern: %w", err)
+		}
+	}
+	return &policy, nil
+}
+
+// Check returns an error listing every rule req breaks, or nil.
+func (p *Policy) Check(req OrderRequest, summary CartSummary) error {
+	var violations []string
+	if len(p.AllowedPlans) > 0 && !matchAny(p.AllowedPlans, req.PlanCode) {
+		violations = append(violations, fmt.Sprintf("plan %s is not allowed", req.PlanCode))
+	}
+	datacenter, _ := labelValue(req.Configuration, labelDatacenter)
+	switch {
+	case len(p.AllowedDatacenters) > 0 && datacenter == "":
+		violations = append(violations, "a datacenter must be set")
+	case len(p.AllowedDatacenters) > 0 && !contains(p.AllowedDatacenters, datacenter),
+		contains(p.ForbiddenDatacenters, datacenter):
+		violations = append(violations, fmt.Sprintf("datacenter %s is not allowed", datacenter))
+	}
+	for _, option := range req.Options {
+		if matchAny(p.ForbiddenOptions, option.PlanCode) {
+			violations = append(violations, fmt.Sprintf("option %s is forbidden", option.PlanCode))
+		}
+	}
+	if p.namePattern != nil && !p.namePattern.MatchString(req.Tag) {
+		violations = append(violations, fmt.Sprintf("server name %q does not match %s", req.Tag, p.NamePattern))
+	}
+	if len(violations) > 0 {
+		return errors.New(strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// matchAny reports whether value matches one of patterns
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPolicy runs Options.CheckoutPolicy on the cart about to be checked
+// out, reading its request back from the cart when PurchaseCart buys it
+func (o *Orderer) checkPolicy(ctx context.Context, cartID string, opts PurchaseOptions, summary *CartSummary) error {
+	if o.opts.CheckoutPolicy == nil {
+		return nil
+	}
+	var req OrderRequest
+	if opts.request != nil {
+		req = *opts.request
+	} else {
+		var err error
+		if req, err = o.cartRequest(ctx, cartID); err != nil {
+			return err
+		}
+		req.PricingMode, req.AckEngagement, req.AutoPay, req.MaxPrice = opts.PricingMode, opts.AckEngagement, opts.AutoPay, opts.MaxPrice
+	}
+	if err := o.opts.CheckoutPolicy(req, *summary); err != nil {
+		return fmt.Errorf("%w: %w", ErrPolicyViolation, err)
+	}
+	return nil
+}
+
+// cartRequest reads back what a cart orders: the server, with its
+// configuration, and its options
+func (o *Orderer) cartRequest(ctx context.Context, cartID string) (OrderRequest, error) {
+	var req OrderRequest
+	var itemIDs []int64
+	if err := o.client.GetWithContext(ctx, "/order/cart/"+cartID+"/item", &itemIDs); err != nil {
+		return req, fmt.Errorf("error listing items of cart %s: %w", cartID, err)
+	}
+	var serverID int64
+	var options []cartItem
+	for _, itemID := range itemIDs {
+		var item cartItem
+		if err := o.client.GetWithContext(ctx, fmt.Sprintf("/order/cart/%s/item/%d", cartID, itemID), &item); err != nil {
+			return req, fmt.Errorf("error fetching item %d of cart %s: %w", itemID, cartID, err)
+		}
+		if item.ParentItemID != 0 {
+			options = append(options, item)
+			continue
+		}
+		if serverID != 0 {
+			return req, fmt.Errorf("cart %s has more than one server", cartID)
+		}
+		serverID = item.ItemID
+		req.PlanCode, req.Duration, req.PricingMode, req.Quantity = item.Settings.PlanCode, item.Duration, item.Settings.PricingMode, item.Settings.Quantity
+	}
+	if serverID == 0 {
+		return req, fmt.Errorf("cart %s has no server", cartID)
+	}
+	labels, err := o.configuredLabels(ctx, cartID, serverID)
+	if err != nil {
+		return req, err
+	}
+	names := make([]string, 0, len(labels))
+	for label := range labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+	for _, label := range names {
+		req.Configuration = append(req.Configuration, Configuration{Label: label, Value: labels[label]})
+	}
+	for _, option := range options {
+		if option.ParentItemID == serverID {
+			req.Options = append(req.Options, Option{PlanCode: option.Settings.PlanCode})
+		}
+	}
+	return req, nil
+}
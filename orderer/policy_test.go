@@ -0,0 +1,100 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "no rules", data: "{}\n"},
+		{name: "full", data: "allowedDatacenters: [gra, rbx]\nforbiddenOptions: [\"*-windows-*\"]\nallowedPlans: [\"24rise*\"]\nnamePattern: \"^web-[0-9]+$\"\n"},
+		{name: "unknown rule", data: "allowedRegions: [europe]\n", wantErr: true},
+		{name: "invalid pattern", data: "allowedPlans: [\"24rise[\"]\n", wantErr: true},
+		{name: "invalid name pattern", data: "namePattern: \"(\"\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePolicy([]byte(tt.data)); (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error: %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy, err := ParsePolicy([]byte(`
+allowedDatacenters: [gra, rbx]
+forbiddenOptions: ["*-windows-*"]
+allowedPlans: ["24rise*"]
+namePattern: "^web-[0-9]+$"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := OrderRequest{
+		PlanCode:      "24rise01",
+		Configuration: []Configuration{{Label: labelDatacenter, Value: "gra"}},
+		Options:       []Option{{PlanCode: "ram-32g"}},
+		Tag:           "web-1",
+	}
+	tests := []struct {
+		name   string
+		modify func(req *OrderRequest)
+
+		// want are the violations reported, none when empty
+		want []string
+	}{
+		{name: "valid", modify: func(*OrderRequest) {}},
+		{name: "plan", modify: func(req *OrderRequest) { req.PlanCode = "24ska01" }, want: []string{"plan 24ska01"}},
+		{name: "datacenter", modify: func(req *OrderRequest) { req.Configuration[0].Value = "bhs" }, want: []string{"datacenter bhs"}},
+		{name: "no datacenter", modify: func(req *OrderRequest) { req.Configuration = nil }, want: []string{"a datacenter must be set"}},
+		{name: "option", modify: func(req *OrderRequest) { req.Options = append(req.Options, Option{PlanCode: "license-windows-std"}) }, want: []string{"option license-windows-std"}},
+		{name: "name", modify: func(req *OrderRequest) { req.Tag = "db-1" }, want: []string{`server name "db-1"`}},
+		{
+			name: "every rule",
+			modify: func(req *OrderRequest) {
+				req.PlanCode, req.Configuration[0].Value, req.Tag = "24ska01", "bhs", ""
+			},
+			want: []string{"plan 24ska01", "datacenter bhs", `server name ""`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			req.Configuration = append([]Configuration(nil), valid.Configuration...)
+			tt.modify(&req)
+			err := policy.Check(req, CartSummary{})
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("err = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("err = nil, want %q", tt.want)
+			}
+			for _, violation := range tt.want {
+				if !strings.Contains(err.Error(), violation) {
+					t.Errorf("err = %v, want %q", err, violation)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	reject := errors.New("datacenter bhs is not allowed")
+	o := New(&stubClient{}, Options{Clock: newFakeClock(), CheckoutPolicy: func(OrderRequest, CartSummary) error { return reject }})
+	req := OrderRequest{PlanCode: "24rise01"}
+	err := o.checkPolicy(context.Background(), "cart-1", PurchaseOptions{request: &req}, &CartSummary{})
+	if !errors.Is(err, ErrPolicyViolation) || !errors.Is(err, reject) {
+		t.Errorf("err = %v, want ErrPolicyViolation wrapping the error of the hook", err)
+	}
+}
@@ -0,0 +1,78 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTimeout is returned when a wait does not complete in time.
+var ErrTimeout = errors.New("timed out")
+
+// poll describes how pollUntil polls
+type poll struct {
+	// Interval is the delay before the second attempt.
+	Interval time.Duration
+
+	// MaxInterval, when greater than Interval, makes the delay double after
+	// each attempt up to MaxInterval.
+	MaxInterval time.Duration
+
+	// Next, when set, returns the delay before the next attempt instead of
+	// Interval and MaxInterval, e.g. from what the last attempt saw.
+	Next func() time.Duration
+
+	// Timeout bounds the whole wait. Zero waits until the context is done.
+	Timeout time.Duration
+}
+
+// pollUntil calls fn until it reports done, returns an error, the timeout
+// elapses (ErrTimeout) or ctx is done. fn is always called once more when the
+// timeout is reached, so a condition met at the last moment is not missed.
+func (o *Orderer) pollUntil(ctx context.Context, p poll, fn func() (done bool, err error)) error {
+	var deadline time.Time
+	if p.Timeout > 0 {
+		deadline = o.clock.Now().Add(p.Timeout)
+	}
+	interval := p.Interval
+	for {
+		done, err := fn()
+		if err != nil || done {
+			return err
+		}
+
+		delay := interval
+		if p.Next != nil {
+			delay = p.Next()
+		}
+		if !deadline.IsZero() {
+			remaining := deadline.Sub(o.clock.Now())
+			if remaining <= 0 {
+				return ErrTimeout
+			}
+			if delay > remaining {
+				delay = remaining
+			}
+		}
+		o.debugf("Polling again in %s", delay)
+		if err := o.sleep(ctx, delay); err != nil {
+			return err
+		}
+		if p.MaxInterval > interval {
+			interval *= 2
+			if interval > p.MaxInterval {
+				interval = p.MaxInterval
+			}
+		}
+	}
+}
+
+// sleep waits for d or until ctx is done
+func (o *Orderer) sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-o.clock.After(d):
+		return nil
+	}
+}
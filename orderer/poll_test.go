@@ -0,0 +1,131 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recordingClock is a fakeClock recording the delays waited on
+type recordingClock struct {
+	*fakeClock
+	delays []time.Duration
+}
+
+func (c *recordingClock) After(d time.Duration) <-chan time.Time {
+	c.delays = append(c.delays, d)
+	return c.fakeClock.After(d)
+}
+
+func TestPollUntil(t *testing.T) {
+	errPoll := errors.New("poll failed")
+	tests := []struct {
+		name   string
+		poll   poll
+		doneAt int   // the call reporting done, 0 for never
+		err    error // returned by the third call
+
+		wantCalls  int
+		wantDelays []time.Duration
+		wantErr    error
+	}{
+		{
+			name:       "done",
+			poll:       poll{Interval: time.Second, MaxInterval: 4 * time.Second},
+			doneAt:     5,
+			wantCalls:  5,
+			wantDelays: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second},
+		},
+		{
+			name:       "fixed interval",
+			poll:       poll{Interval: time.Second},
+			doneAt:     3,
+			wantCalls:  3,
+			wantDelays: []time.Duration{time.Second, time.Second},
+		},
+		{
+			name:       "error",
+			poll:       poll{Interval: time.Second},
+			err:        errPoll,
+			wantCalls:  3,
+			wantDelays: []time.Duration{time.Second, time.Second},
+			wantErr:    errPoll,
+		},
+		{
+			// The last delay is cut to the deadline, and the condition
+			// checked once more at the deadline
+			name:       "timeout",
+			poll:       poll{Interval: 3 * time.Second, Timeout: 10 * time.Second},
+			wantCalls:  5,
+			wantDelays: []time.Duration{3 * time.Second, 3 * time.Second, 3 * time.Second, time.Second},
+			wantErr:    ErrTimeout,
+		},
+		{
+			name:       "next",
+			poll:       poll{Interval: time.Second, Next: func() time.Duration { return time.Minute }},
+			doneAt:     2,
+			wantCalls:  2,
+			wantDelays: []time.Duration{time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &recordingClock{fakeClock: newFakeClock()}
+			o := New(&stubClient{}, Options{Clock: clock})
+			calls := 0
+			err := o.pollUntil(context.Background(), tt.poll, func() (bool, error) {
+				calls++
+				if calls == 3 && tt.err != nil {
+					return false, tt.err
+				}
+				return calls == tt.doneAt, nil
+			})
+			if tt.wantErr == nil && err != nil || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("%d calls, want %d", calls, tt.wantCalls)
+			}
+			if !reflect.DeepEqual(clock.delays, tt.wantDelays) {
+				t.Errorf("delays = %v, want %v", clock.delays, tt.wantDelays)
+			}
+		})
+	}
+}
+
+// stuckClock is a Clock whose waits never end, telling when one starts
+type stuckClock struct {
+	*fakeClock
+	waiting chan time.Duration
+}
+
+func (c *stuckClock) After(d time.Duration) <-chan time.Time {
+	c.waiting <- d
+	return nil
+}
+
+// Cancelling the context ends the wait between two polls
+func TestPollUntilCancelled(t *testing.T) {
+	clock := &stuckClock{fakeClock: newFakeClock(), waiting: make(chan time.Duration, 1)}
+	o := New(&stubClient{}, Options{Clock: clock})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-clock.waiting
+		cancel()
+	}()
+
+	calls := 0
+	err := o.pollUntil(ctx, poll{Interval: time.Hour}, func() (bool, error) {
+		calls++
+		return false, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+	if calls != 1 {
+		t.Errorf("%d calls, want 1", calls)
+	}
+}
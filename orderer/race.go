@@ -0,0 +1,172 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// ErrRaceLost is returned by RaceDatacenters when no cart could be checked
+// out in any of the datacenters raced.
+var ErrRaceLost = errors.New("no datacenter could be ordered")
+
+// raceEntry is the outcome of building the cart of one datacenter of a race
+type raceEntry struct {
+	datacenter string
+	req        OrderRequest
+	result     *OrderResult
+	err        error
+}
+
+// RaceDatacenters orders req in the first of datacenters where it can be
+// bought, for plans whose stock is too scarce to try datacenters one after
+// the other: it builds a cart in every datacenter concurrently, then checks
+// out the carts in the order they are built until one checkout succeeds,
+// and goes on like Order with the payment and the post-delivery steps.
+//
+// Carts are checked out one at a time, never concurrently, and the next
+// cart is only checked out when the checkout of the previous one was
+// refused without creating an order (see raceRefusals). Once a checkout has
+// created an order, or failed in a way that may have created one, e.g.
+// ErrCheckoutUncertain or a 5xx still failing after the retries, no other
+// cart is checked out, even if the payment then fails: at most one order is
+// created, and so paid. Every other cart is deleted, including those still
+// being built, whose build is cancelled. Building a cart per datacenter creates as many carts and
+// multiplies the API calls, which callers must accept explicitly.
+//
+// The datacenter of req, if any, is replaced, and its region inferred
+// again. The result is that of the winning datacenter; when none wins, err
+// wraps ErrRaceLost and the error of each datacenter. When a checkout fails
+// without proof that nothing was ordered, the race stops with that error
+// and the result of its datacenter, whose cart is left for inspection.
+func (o *Orderer) RaceDatacenters(ctx context.Context, req OrderRequest, datacenters []string) (result *OrderResult, err error) {
+	start := o.clock.Now()
+	defer func() {
+		o.done(req, start, result, err)
+	}()
+	if len(datacenters) < 2 {
+		return nil, fmt.Errorf("racing needs at least two datacenters")
+	}
+	if req.CartID != "" || req.ReuseMarker != "" {
+		return nil, fmt.Errorf("racing datacenters cannot resume a cart or reuse a server")
+	}
+	req, err = o.prepare(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.checkEndpointAllowed(); err != nil {
+		return nil, err
+	}
+
+	buildCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	built := make(chan raceEntry, len(datacenters))
+	for _, datacenter := range datacenters {
+		entry := raceEntry{datacenter: datacenter, req: req, result: &OrderResult{RunID: req.RunID, CustomerReference: req.CustomerReference}}
+		entry.req.Configuration = withDatacenter(req.Configuration, datacenter)
+		go func() {
+			entry.err = o.buildCart(withStepStats(buildCtx, entry.result), entry.req, entry.result)
+			built <- entry
+		}()
+	}
+
+	// Only this goroutine checks carts out, so checkouts never overlap
+	var winner *raceEntry
+	var errs []error
+	for range datacenters {
+		entry := <-built
+		switch {
+		case winner != nil:
+			// Lost the race, or built after an order was created
+		case entry.err != nil:
+			o.logger.Printf("Cannot order in %s: %v", entry.datacenter, entry.err)
+			errs = append(errs, fmt.Errorf("%s: %w", entry.datacenter, entry.err))
+		default:
+			o.logger.Printf("Checking out the cart of %s", entry.datacenter)
+			err := o.purchaseCart(withStepStats(ctx, entry.result), entry.result.CartID, entry.req.purchaseOptions(), entry.result)
+			if entry.result.OrderID == "" && isRaceRefusal(err) {
+				o.logger.Printf("Cannot order in %s: %v", entry.datacenter, err)
+				errs = append(errs, fmt.Errorf("%s: %w", entry.datacenter, err))
+				break
+			}
+			// An order was created, or may have been: no other cart may be
+			// checked out
+			winner = &entry
+			entry.err = err
+			// Stop the builds still running, whose carts are deleted below
+			cancel()
+			if entry.result.OrderID == "" {
+				o.logger.Printf("Stopping the race: the checkout in %s failed and may have created an order: %v", entry.datacenter, err)
+			} else {
+				o.logger.Printf("Won the race in %s with order %s", entry.datacenter, entry.result.OrderID)
+			}
+			continue
+		}
+		o.deleteRaceCart(ctx, entry)
+	}
+	if winner == nil {
+		return nil, fmt.Errorf("%w: %w", ErrRaceLost, errors.Join(errs...))
+	}
+	if winner.err != nil {
+		return winner.result, winner.err
+	}
+	return winner.result, o.deliver(withStepStats(ctx, winner.result), winner.result, winner.req)
+}
+
+// raceRefusals are the checkout errors proving that no order was created,
+// after which a race checks out the cart of the next datacenter
+var raceRefusals = []error{
+	ErrOutOfStock,
+	ErrCheckoutDeclined,
+	ErrPriceChanged,
+	ErrMaxPriceExceeded,
+	ErrPriceDrift,
+	ErrPolicyViolation,
+	ErrEndpointNotAllowed,
+}
+
+// isRaceRefusal reports whether the checkout error err proves that no order
+// was created
+func isRaceRefusal(err error) bool {
+	if err == nil || errors.Is(err, ErrCheckoutUncertain) {
+		return false
+	}
+	for _, refusal := range raceRefusals {
+		if errors.Is(err, refusal) {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteRaceCart deletes the cart of a datacenter that did not win a race,
+// if it was created and not checked out
+func (o *Orderer) deleteRaceCart(ctx context.Context, entry raceEntry) {
+	if entry.result.CartID == "" || entry.result.OrderID != "" {
+		return
+	}
+	err := o.deleteCart(ctx, entry.result.CartID)
+	// A checkout refused on price or policy deletes the cart itself
+	var apiErr *ovh.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		return
+	}
+	if err != nil {
+		o.logger.Printf("Error deleting the cart of %s: %v", entry.datacenter, err)
+	}
+}
+
+// withDatacenter returns a copy of configs ordering in datacenter, without
+// the region, which is inferred from the datacenter
+func withDatacenter(configs []Configuration, datacenter string) []Configuration {
+	var result []Configuration
+	for _, config := range configs {
+		if config.Label != labelDatacenter && config.Label != labelRegion {
+			result = append(result, config)
+		}
+	}
+	return append(result, Configuration{Label: labelDatacenter, Value: datacenter})
+}
@@ -0,0 +1,135 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// Run with -race: whichever cart is built first, the carts are checked out
+// until one checkout creates an order, or may have, and every other cart is
+// deleted
+func TestRaceDatacenters(t *testing.T) {
+	tests := []struct {
+		name        string
+		unavailable []string
+		checkoutErr error // of the first checkout
+
+		wantErr       error
+		wantCheckouts int
+	}{
+		{name: "one datacenter out of stock", unavailable: []string{"gra"}, wantCheckouts: 1},
+		{name: "all out of stock", unavailable: []string{"gra", "rbx", "sbg"}, wantErr: ErrRaceLost},
+		{name: "out of stock at checkout", checkoutErr: &ovh.APIError{Code: http.StatusConflict, Message: "24ska01 is out of stock"}, wantCheckouts: 2},
+		{name: "checkout unavailable", checkoutErr: errCheckoutUnavailable, wantErr: errCheckoutUnavailable, wantCheckouts: 1},
+		{name: "checkout reset", checkoutErr: errCheckoutReset, wantErr: syscall.ECONNRESET, wantCheckouts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var carts, checkouts, deleted []string
+			datacenters := make(map[string]string) // by cart
+			cartPath := regexp.MustCompile(`^/order/cart/(cart-\d+)(/.*)?$`)
+			client := &stubClient{answer: func(method, path string, body interface{}) (interface{}, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				switch method + " " + path {
+				case "POST /order/cart":
+					cartID := fmt.Sprintf("cart-%d", len(carts)+1)
+					carts = append(carts, cartID)
+					return map[string]interface{}{"cartId": cartID}, nil
+				case "GET /order/cart":
+					return carts, nil
+				}
+				match := cartPath.FindStringSubmatch(path)
+				if match == nil {
+					return nil, fmt.Errorf("unexpected call %s %s", method, path)
+				}
+				cartID, sub := match[1], match[2]
+				switch method + " " + sub {
+				case "GET ":
+					return map[string]interface{}{"cartId": cartID}, nil
+				case "DELETE ":
+					deleted = append(deleted, cartID)
+					return nil, nil
+				case "GET /baremetalServers":
+					return []map[string]interface{}{{"planCode": "24ska01", "prices": []map[string]interface{}{{"duration": "P1M", "pricingMode": "default"}}}}, nil
+				case "GET /baremetalServers/options?planCode=24ska01":
+					return []interface{}{}, nil
+				case "POST /baremetalServers":
+					return map[string]interface{}{"itemId": 1001, "settings": map[string]interface{}{"planCode": "24ska01"}}, nil
+				case "GET /item":
+					return []int{1001}, nil
+				case "GET /item/1001":
+					return map[string]interface{}{"itemId": 1001, "settings": map[string]interface{}{"planCode": "24ska01"}}, nil
+				case "GET /item/1001/requiredConfiguration":
+					return []map[string]interface{}{{"label": labelDatacenter, "required": true, "allowedValues": []string{"gra", "rbx", "sbg"}}}, nil
+				case "POST /item/1001/configuration":
+					config := body.(map[string]interface{})
+					datacenter := config["value"].(string)
+					if contains(tt.unavailable, datacenter) {
+						return nil, &ovh.APIError{Code: http.StatusBadRequest, Message: "24ska01 is not available in " + datacenter}
+					}
+					datacenters[cartID] = datacenter
+					return map[string]interface{}{"id": 1, "label": config["label"], "value": datacenter}, nil
+				case "GET /item/1001/configuration":
+					return []int{1}, nil
+				case "GET /item/1001/configuration/1":
+					return map[string]interface{}{"id": 1, "label": labelDatacenter, "value": datacenters[cartID]}, nil
+				case "GET /checkout":
+					return map[string]interface{}{"details": []map[string]interface{}{{"cartItemID": 1001, "detailType": "DURATION", "description": "24ska01"}}}, nil
+				case "POST /checkout":
+					checkouts = append(checkouts, cartID)
+					if len(checkouts) == 1 && tt.checkoutErr != nil {
+						return nil, tt.checkoutErr
+					}
+					return map[string]interface{}{"orderId": 234567890, "url": "https://example.com/pay"}, nil
+				}
+				return nil, fmt.Errorf("unexpected call %s %s", method, path)
+			}}
+			o := New(client, Options{Clock: newFakeClock(), MaxAttempts: 1})
+
+			req := OrderRequest{Subsidiary: "FR", PlanCode: "24ska01", AutoPay: true}
+			result, err := o.RaceDatacenters(context.Background(), req, []string{"gra", "rbx", "sbg"})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			var winner string
+			if result != nil {
+				winner = result.CartID
+				if datacenter := result.Placement[labelDatacenter]; datacenter != datacenters[winner] || contains(tt.unavailable, datacenter) {
+					t.Errorf("won in %s with the cart of %s", datacenter, datacenters[winner])
+				}
+			}
+			// The last cart checked out wins, or stops the race
+			if len(checkouts) != tt.wantCheckouts || len(checkouts) > 0 && checkouts[len(checkouts)-1] != winner {
+				t.Errorf("checked out %v, want %d checkouts ending with %s", checkouts, tt.wantCheckouts, winner)
+			}
+			for _, cartID := range carts {
+				if (cartID == winner) == contains(deleted, cartID) {
+					t.Errorf("cart %s of %s: deleted %v, winner %s", cartID, datacenters[cartID], deleted, winner)
+				}
+			}
+		})
+	}
+}
+
+var (
+	errCheckoutUnavailable = &ovh.APIError{Code: http.StatusServiceUnavailable, Message: "Service unavailable"}
+	errCheckoutReset       = &url.Error{Op: "Post", URL: "/order/cart/checkout", Err: syscall.ECONNRESET}
+)
@@ -0,0 +1,266 @@
+package orderer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Interaction is a recorded API call.
+type Interaction struct {
+	Method       string          `json:"method"`
+	Path         string          `json:"path"`
+	RequestBody  json.RawMessage `json:"requestBody,omitempty"`
+	Status       int             `json:"status"`
+	ResponseBody json.RawMessage `json:"responseBody,omitempty"`
+}
+
+// Fixtures is the content of a record/replay fixtures file.
+type Fixtures struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// scrubbedFields are JSON fields whose values are replaced in recordings.
+// Authentication headers are never recorded.
+var scrubbedFields = map[string]bool{
+	"password":          true,
+	"token":             true,
+	"consumerKey":       true,
+	"applicationKey":    true,
+	"applicationSecret": true,
+}
+
+// scrub replaces the values of scrubbedFields in a JSON body. Bodies that are
+// not JSON are recorded as JSON strings.
+func scrub(body []byte) json.RawMessage {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		quoted, _ := json.Marshal(string(body))
+		return quoted
+	}
+	scrubValue(value)
+	scrubbed, _ := json.Marshal(value)
+	return scrubbed
+}
+
+func scrubValue(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if scrubbedFields[key] {
+				v[key] = "REDACTED"
+				continue
+			}
+			scrubValue(field)
+		}
+	case []interface{}:
+		for _, item := range v {
+			scrubValue(item)
+		}
+	}
+}
+
+// Recorder is an http.RoundTripper recording every API call to a fixtures
+// file, which is rewritten after each call so that it survives a crash.
+type Recorder struct {
+	Transport http.RoundTripper
+	fsys      FS
+	path      string
+
+	mu       sync.Mutex
+	fixtures Fixtures
+}
+
+// NewRecorder returns a Recorder forwarding calls to transport (or
+// http.DefaultTransport when nil) and recording them to path of fsys.
+func NewRecorder(fsys FS, transport http.RoundTripper, path string) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Recorder{Transport: transport, fsys: fsOrDisk(fsys), path: path}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		if requestBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+
+	resp, err := r.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fixtures.Interactions = append(r.fixtures.Interactions, Interaction{
+		Method:       req.Method,
+		Path:         req.URL.RequestURI(),
+		RequestBody:  scrub(requestBody),
+		Status:       resp.StatusCode,
+		ResponseBody: scrub(responseBody),
+	})
+	data, err := json.MarshalIndent(r.fixtures, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := r.fsys.WriteFile(r.path, data, 0o600); err != nil {
+		return nil, fmt.Errorf("error writing recording: %w", err)
+	}
+	return resp, nil
+}
+
+// Replayer is an http.RoundTripper answering API calls from recorded
+// fixtures instead of the network. Each call is answered by the first unused
+// interaction with the same method and path.
+type Replayer struct {
+	// Latency delays every answer, to watch the progress of a replayed
+	// flow as it would unfold against the API.
+	Latency time.Duration
+
+	// MatchBodies also requires the body of each request to equal, as JSON,
+	// the recorded one, so that replaying pins down what is sent to the API.
+	// A request with another body fails with the difference.
+	MatchBodies bool
+
+	mu       sync.Mutex
+	fixtures Fixtures
+	used     []bool
+}
+
+// LoadReplayer reads a fixtures file of fsys written by a Recorder, or a
+// directory of such files, whose interactions are replayed in the order of
+// the file names.
+func LoadReplayer(fsys FS, path string) (*Replayer, error) {
+	fsys = fsOrDisk(fsys)
+	paths := []string{path}
+	if info, err := fsys.Stat(path); err == nil && info.IsDir() {
+		entries, err := fsys.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("error listing fixtures: %w", err)
+		}
+		paths = paths[:0]
+		for _, entry := range entries {
+			if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" {
+				paths = append(paths, filepath.Join(path, entry.Name()))
+			}
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("no fixtures (*.json) in %s", path)
+		}
+	}
+
+	var fixtures Fixtures
+	for _, path := range paths {
+		data, err := fsys.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading fixtures: %w", err)
+		}
+		var file Fixtures
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("invalid fixtures %s: %w", path, err)
+		}
+		fixtures.Interactions = append(fixtures.Interactions, file.Interactions...)
+	}
+	return NewReplayer(fixtures), nil
+}
+
+// NewReplayer returns a Replayer serving fixtures.
+func NewReplayer(fixtures Fixtures) *Replayer {
+	return &Replayer{fixtures: fixtures, used: make([]bool, len(fixtures.Interactions))}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		requestBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	path := req.URL.RequestURI()
+
+	if r.Latency > 0 {
+		select {
+		case <-time.After(r.Latency):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.fixtures.Interactions {
+		if r.used[i] || interaction.Method != req.Method || interaction.Path != path {
+			continue
+		}
+		if r.MatchBodies && !sameJSON(interaction.RequestBody, scrub(requestBody)) {
+			return nil, fmt.Errorf("replay: %s %s sent %s, recorded %s", req.Method, path, scrub(requestBody), interaction.RequestBody)
+		}
+		r.used[i] = true
+		return &http.Response{
+			Status:     fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+			StatusCode: interaction.Status,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(bytes.NewReader(interaction.ResponseBody)),
+			Request:    req,
+		}, nil
+	}
+	return nil, fmt.Errorf("replay: no recorded interaction left for %s %s", req.Method, path)
+}
+
+// sameJSON reports whether a and b are the same JSON value, both being
+// empty for requests without a body
+func sameJSON(a, b json.RawMessage) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// Unused returns the recorded interactions that were never replayed, as
+// "METHOD path" strings.
+func (r *Replayer) Unused() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []string
+	for i, interaction := range r.fixtures.Interactions {
+		if !r.used[i] {
+			unused = append(unused, strings.TrimSpace(interaction.Method+" "+interaction.Path))
+		}
+	}
+	return unused
+}
@@ -0,0 +1,54 @@
+package orderer
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// TestGoldenRequestBodies pins down the bodies the order flow sends, from
+// the cart creation to the payment, against testdata/golden/order.json. A
+// change of body fails the replay with the difference: update the file when
+// it is intended.
+func TestGoldenRequestBodies(t *testing.T) {
+	replayer, err := LoadReplayer(nil, "testdata/golden/order.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer.MatchBodies = true
+	client, err := ovh.NewClient("ovh-eu", "key", "secret", "consumer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Client = &http.Client{Transport: replayer}
+	o := New(client, Options{Clock: newFakeClock(), MaxAttempts: 1})
+	ctx := context.Background()
+	req := OrderRequest{Subsidiary: "FR", PlanCode: "24ska01", RunID: "run-1"}.withDefaults()
+
+	cartID, err := o.createCart(ctx, req, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemID, err := o.addServer(ctx, cartID, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.configure(ctx, cartID, itemID, Configuration{Label: labelDatacenter, Value: "gra"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.addOption(ctx, cartID, itemID, Option{PlanCode: "ram-32g-ecc-2400"}, req); err != nil {
+		t.Fatal(err)
+	}
+	order, err := o.checkout(ctx, cartID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := o.pay(ctx, order.OrderID, order.URL); err != nil {
+		t.Fatal(err)
+	}
+	if unused := replayer.Unused(); len(unused) > 0 {
+		t.Errorf("calls not made: %v", unused)
+	}
+}
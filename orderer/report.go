@@ -0,0 +1,106 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// OrderReport is an order of the account with its status and the plans it
+// ordered, as needed by a spend report.
+type OrderReport struct {
+	OrderInfo
+	Status string
+
+	// PlanCodes are the plans of the order details, in the order of the
+	// details. Details without a plan, such as fees, are not listed.
+	PlanCodes []string
+}
+
+// DeliveryCodes returns the delivery options among the plans of the order
+// (see OrderRequest.DeliveryPriority).
+func (r OrderReport) DeliveryCodes() []string {
+	var codes []string
+	for _, code := range r.PlanCodes {
+		if isDeliveryCode(code) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// ReportOrders returns the orders placed since since, newest first, with
+// their status and plans. At most concurrency orders are fetched at a time
+// (defaults to 4); all calls go through the client of the Orderer, so its
+// rate limit and retries apply.
+func (o *Orderer) ReportOrders(ctx context.Context, since time.Time, concurrency int) ([]OrderReport, error) {
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	orders, err := o.ListOrders(ctx, OrderFilter{Since: since, Concurrency: concurrency})
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]OrderReport, len(orders))
+	errs := make([]error, len(orders))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range orders {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			reports[i], errs[i] = o.reportOrder(ctx, orders[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return reports, nil
+}
+
+// reportOrder fetches the status and the plans of order
+func (o *Orderer) reportOrder(ctx context.Context, order OrderInfo) (OrderReport, error) {
+	report := OrderReport{OrderInfo: order}
+	if err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%d/status", order.OrderID), &report.Status); err != nil {
+		return report, fmt.Errorf("error fetching status of order %d: %w", order.OrderID, err)
+	}
+
+	var detailIDs []int64
+	if err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%d/details", order.OrderID), &detailIDs); err != nil {
+		return report, fmt.Errorf("error fetching details of order %d: %w", order.OrderID, err)
+	}
+	for _, detailID := range detailIDs {
+		var extension struct {
+			Order struct {
+				Plan struct {
+					Code string `json:"code"`
+				} `json:"plan"`
+			} `json:"order"`
+		}
+		err := o.client.GetWithContext(ctx, fmt.Sprintf("/me/order/%d/details/%d/extension", order.OrderID, detailID), &extension)
+		var apiErr *ovh.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			// Details of older orders have no extension
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("error fetching detail %d of order %d: %w", detailID, order.OrderID, err)
+		}
+		if code := extension.Order.Plan.Code; code != "" && !contains(report.PlanCodes, code) {
+			report.PlanCodes = append(report.PlanCodes, code)
+		}
+	}
+	return report, nil
+}
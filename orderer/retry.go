@@ -0,0 +1,333 @@
+package orderer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// maxRetryBackoff caps the delay between two attempts of an API call
+const maxRetryBackoff = 30 * time.Second
+
+// lockBackoff is the shortest delay before retrying a call rejected because
+// another operation holds the resource, which usually takes seconds to end
+const lockBackoff = 10 * time.Second
+
+// ErrOperationInProgress is returned, wrapped, when OVH still rejects a call
+// after its retries because another operation is in progress on the same
+// cart or order, e.g. another run working on the same cart.
+var ErrOperationInProgress = errors.New("another operation is in progress")
+
+// isLocked reports whether err is the rejection of a call because another
+// operation holds the resource: a 400, 409 or 423 whose message says an
+// operation is in progress or the resource is locked
+func isLocked(err error) bool {
+	var apiErr *ovh.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.Code {
+	case http.StatusBadRequest, http.StatusConflict, http.StatusLocked:
+	default:
+		return false
+	}
+	message := strings.ToLower(apiErr.Message)
+	return strings.Contains(message, "in progress") || strings.Contains(message, "locked") ||
+		strings.Contains(message, "pending operation")
+}
+
+// ErrRetryBudgetExhausted is returned, along with the error of the last
+// attempt, by API calls that fail once Options.RetryBudget is spent.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// isRetryable reports whether an API call failing with err may succeed if
+// sent again: rate limiting, server side errors, and connections that broke
+// before a complete response arrived (reset, closed, truncated body, TLS
+// handshake timeout). In detail:
+//
+//   - an *ovh.APIError is retried for 429, 500, 502, 503 and 504, and for
+//     the lock errors of isLocked; any other status, e.g. 400, 401, 403,
+//     404 or 409, is final
+//   - context.Canceled and context.DeadlineExceeded are final
+//   - JSON decoding errors (*json.SyntaxError, *json.UnmarshalTypeError) are
+//     final
+//   - connection resets, io.EOF, bodies cut short while they are read
+//     (*bodyReadError) and network timeouts are retried; a bare
+//     io.ErrUnexpectedEOF is the decoding of a complete but truncated JSON
+//     document, and is final
+//   - nil and any other error are final
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *ovh.APIError
+	if errors.As(err, &apiErr) {
+		if isLocked(err) {
+			return true
+		}
+		switch apiErr.Code {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	// A body cut short by the connection fails while it is read, whatever
+	// the status
+	var readErr *bodyReadError
+	if errors.As(err, &readErr) {
+		return true
+	}
+
+	// A body that does not decode after a clean 2xx is a bug to surface, not
+	// a transient condition, and an error response that does not decode
+	// still has its status in the APIError above
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return false
+	}
+
+	// No response arrived
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// IsRetryable reports whether an API call failing with err may succeed if
+// sent again, as the retries of the Orderer decide it for the calls which do
+// not change anything.
+func IsRetryable(err error) bool {
+	return isRetryable(err)
+}
+
+// isUnsent reports whether err proves that OVH did not apply a call, so that
+// a call changing state can be sent again without being applied twice: a 429
+// or a lock error, which reject the call before it is processed, or a
+// connection that could not be opened. A 5xx, a reset connection or a
+// truncated response may come after the call was applied.
+func isUnsent(err error) bool {
+	var apiErr *ovh.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusTooManyRequests || isLocked(err)
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// resendCheck re-reads the cart or order a call changing state acts on,
+// after the call failed without telling whether it was applied. It reports
+// whether the call was applied, in which case it fills the result of the
+// call itself, and fails when the call cannot be sent again safely.
+type resendCheck func(ctx context.Context) (applied bool, err error)
+
+type resendCheckKey struct{}
+
+// withResendCheck returns ctx whose POST calls are retried after any
+// retryable error, running check before sending them again. Without it, a
+// POST is only retried after an error of isUnsent.
+func withResendCheck(ctx context.Context, check resendCheck) context.Context {
+	return context.WithValue(ctx, resendCheckKey{}, check)
+}
+
+// retry calls fn until it succeeds, fails with an error that is not
+// retryable, Options.MaxAttempts attempts have been made or the next delay
+// would exceed Options.RetryBudget, doubling the delay between attempts.
+// Calls rejected because another operation holds the resource wait at least
+// lockBackoff, and fail with ErrOperationInProgress if it is never released.
+func (o *Orderer) retry(ctx context.Context, what string, fn func() error) error {
+	return o.retryCall(ctx, what, false, fn)
+}
+
+// retryMutation is retry for a call changing state, such as adding an item,
+// checking a cart out or paying an order, which must not be applied twice.
+// The errors of isUnsent are retried, and the other retryable errors only
+// when ctx carries a resend check (see withResendCheck), which is run before
+// each resend.
+func (o *Orderer) retryMutation(ctx context.Context, what string, fn func() error) error {
+	return o.retryCall(ctx, what, true, fn)
+}
+
+func (o *Orderer) retryCall(ctx context.Context, what string, mutation bool, fn func() error) error {
+	check, _ := ctx.Value(resendCheckKey{}).(resendCheck)
+	backoff := o.opts.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		var apiErr *ovh.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests {
+			o.countRetries(ctx, RetryStats{RateLimited: 1})
+		}
+		locked := isLocked(err)
+		if locked && attempt >= o.opts.MaxAttempts {
+			return fmt.Errorf("%w: %s is still rejected after %d attempts, another process may be using the same cart or order: %w",
+				ErrOperationInProgress, what, attempt, err)
+		}
+		if attempt >= o.opts.MaxAttempts || !isRetryable(err) {
+			return err
+		}
+		checked := mutation && !isUnsent(err)
+		if checked && check == nil {
+			o.logger.Printf("Attempt %d of %s failed with error: %v. Not retrying, the call may have been applied.", attempt, what, err)
+			return err
+		}
+		if locked && backoff < lockBackoff {
+			backoff = lockBackoff
+		}
+		if !o.spendRetryBudget(backoff) {
+			return fmt.Errorf("%w after attempt %d of %s: %w", ErrRetryBudgetExhausted, attempt, what, err)
+		}
+		o.countRetries(ctx, RetryStats{Retries: 1, Backoff: backoff})
+		if locked {
+			o.logger.Printf("Attempt %d of %s found another operation in progress, maybe from another process: %v. Retrying in %s...", attempt, what, err, backoff)
+		} else {
+			o.logger.Printf("Attempt %d of %s failed with error: %v. Retrying in %s...", attempt, what, err, backoff)
+		}
+		if sleepErr := o.sleep(ctx, backoff); sleepErr != nil {
+			return fmt.Errorf("%s: %w (giving up retrying after: %v)", what, sleepErr, err)
+		}
+		if checked {
+			applied, checkErr := check(ctx)
+			if checkErr != nil {
+				return fmt.Errorf("%s failed with %v and cannot be sent again: %w", what, err, checkErr)
+			}
+			if applied {
+				o.logger.Printf("%s was applied despite the error, not sending it again.", what)
+				return nil
+			}
+		}
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// spendRetryBudget reports whether d may be waited before a retry within
+// Options.RetryBudget, and if so counts it as spent
+func (o *Orderer) spendRetryBudget(d time.Duration) bool {
+	if o.opts.RetryBudget <= 0 {
+		return true
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.retrySlept+d > o.opts.RetryBudget {
+		return false
+	}
+	o.retrySlept += d
+	return true
+}
+
+// RetryStats counts the retries of API calls.
+type RetryStats struct {
+	// Retries is the number of calls sent again after a retryable error, and
+	// Backoff the time waited before them.
+	Retries int
+	Backoff time.Duration
+
+	// RateLimited is the number of calls OVH answered with 429 Too Many
+	// Requests, whether they were retried or not.
+	RateLimited int
+}
+
+func (s *RetryStats) add(other RetryStats) {
+	s.Retries += other.Retries
+	s.Backoff += other.Backoff
+	s.RateLimited += other.RateLimited
+}
+
+// RetryStats returns the retries of all the API calls of the Orderer so far.
+func (o *Orderer) RetryStats() RetryStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.retryStats
+}
+
+// stepStats accumulates the retries of the step of an order being run, which
+// step takes when the step ends. The calls of a step may run concurrently.
+type stepStats struct {
+	mu    sync.Mutex
+	stats RetryStats
+}
+
+// take returns the retries counted since the last call and resets them
+func (s *stepStats) take() RetryStats {
+	if s == nil {
+		return RetryStats{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	s.stats = RetryStats{}
+	return stats
+}
+
+type stepStatsKey struct{}
+
+// withStepStats returns ctx counting the retries of the calls made with it
+// in the steps of result
+func withStepStats(ctx context.Context, result *OrderResult) context.Context {
+	result.stepStats = &stepStats{}
+	return context.WithValue(ctx, stepStatsKey{}, result.stepStats)
+}
+
+// countRetries adds stats to the totals of the Orderer and, when ctx runs
+// the steps of an order, to the current step
+func (o *Orderer) countRetries(ctx context.Context, stats RetryStats) {
+	o.mu.Lock()
+	o.retryStats.add(stats)
+	o.mu.Unlock()
+	if s, ok := ctx.Value(stepStatsKey{}).(*stepStats); ok {
+		s.mu.Lock()
+		s.stats.add(stats)
+		s.mu.Unlock()
+	}
+}
+
+// retryingClient retries the API calls failing with a retryable error, and
+// the POST calls as retryMutation allows
+type retryingClient struct {
+	o      *Orderer
+	client Client
+}
+
+func (c *retryingClient) GetWithContext(ctx context.Context, url string, resType interface{}) error {
+	return c.o.retry(ctx, "GET "+url, func() error {
+		return c.client.GetWithContext(ctx, url, resType)
+	})
+}
+
+func (c *retryingClient) PostWithContext(ctx context.Context, url string, reqBody, resType interface{}) error {
+	return c.o.retryMutation(ctx, "POST "+url, func() error {
+		return c.client.PostWithContext(ctx, url, reqBody, resType)
+	})
+}
+
+func (c *retryingClient) PutWithContext(ctx context.Context, url string, reqBody, resType interface{}) error {
+	return c.o.retry(ctx, "PUT "+url, func() error {
+		return c.client.PutWithContext(ctx, url, reqBody, resType)
+	})
+}
+
+func (c *retryingClient) DeleteWithContext(ctx context.Context, url string, resType interface{}) error {
+	return c.o.retry(ctx, "DELETE "+url, func() error {
+		return c.client.DeleteWithContext(ctx, url, resType)
+	})
+}
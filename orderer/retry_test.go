@@ -0,0 +1,411 @@
+package orderer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// timeoutError is a net.Error timing out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsRetryable(t *testing.T) {
+	apiError := func(code int, message string) error {
+		return &ovh.APIError{Code: code, Message: message}
+	}
+	// Transport errors come wrapped as go-ovh returns them
+	transport := func(err error) error {
+		return &url.Error{Op: "Post", URL: "https://eu.api.ovh.com/1.0/order/cart", Err: err}
+	}
+	var syntaxErr error = &json.SyntaxError{Offset: 3}
+	var typeErr error = &json.UnmarshalTypeError{Value: "string", Type: nil}
+	tests := []struct {
+		name       string
+		err        error
+		want       bool
+		wantUnsent bool
+	}{
+		{name: "nil"},
+		{name: "200 with an error", err: apiError(http.StatusOK, "unexpected")},
+		{name: "400", err: apiError(http.StatusBadRequest, "Invalid parameter")},
+		{name: "401", err: apiError(http.StatusUnauthorized, "Invalid credential")},
+		{name: "403", err: apiError(http.StatusForbidden, "This call has not been granted")},
+		{name: "404", err: apiError(http.StatusNotFound, "The requested object does not exist")},
+		{name: "409", err: apiError(http.StatusConflict, "The product is out of stock")},
+		{name: "429", err: apiError(http.StatusTooManyRequests, "Too many requests"), want: true, wantUnsent: true},
+		{name: "500", err: apiError(http.StatusInternalServerError, "Internal server error"), want: true},
+		{name: "502", err: apiError(http.StatusBadGateway, "Bad gateway"), want: true},
+		{name: "503", err: apiError(http.StatusServiceUnavailable, "Service unavailable"), want: true},
+		{name: "504", err: apiError(http.StatusGatewayTimeout, "Gateway timeout"), want: true},
+		{name: "409 locked", err: apiError(http.StatusConflict, "Another operation is in progress on this cart"), want: true, wantUnsent: true},
+		{name: "423 locked", err: apiError(http.StatusLocked, "The cart is locked"), want: true, wantUnsent: true},
+		{name: "400 pending operation", err: apiError(http.StatusBadRequest, "There is a pending operation on this order"), want: true, wantUnsent: true},
+		{name: "wrapped 503", err: fmt.Errorf("error validating order: %w", apiError(http.StatusServiceUnavailable, "")), want: true},
+		{name: "connection reset", err: transport(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), want: true},
+		{name: "EOF", err: transport(io.EOF), want: true},
+		{name: "truncated body", err: &bodyReadError{err: io.ErrUnexpectedEOF}, want: true},
+		{name: "reset reading body", err: &bodyReadError{err: os.NewSyscallError("read", syscall.ECONNRESET)}, want: true},
+		{name: "truncated JSON", err: io.ErrUnexpectedEOF},
+		{name: "read timeout", err: transport(&net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}), want: true},
+		{name: "dial timeout", err: transport(&net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}), want: true, wantUnsent: true},
+		{name: "connection refused", err: transport(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), wantUnsent: true},
+		{name: "DNS", err: transport(&net.DNSError{Err: "no such host", Name: "eu.api.ovh.com", IsTimeout: true}), want: true, wantUnsent: true},
+		{name: "context canceled", err: transport(context.Canceled)},
+		{name: "context deadline", err: transport(context.DeadlineExceeded)},
+		{name: "JSON syntax", err: fmt.Errorf("decoding: %w", syntaxErr)},
+		{name: "JSON type", err: fmt.Errorf("decoding: %w", typeErr)},
+		{name: "other", err: errors.New("missing cartId in the created cart")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+			if got := isUnsent(tt.err); got != tt.wantUnsent {
+				t.Errorf("isUnsent(%v) = %t, want %t", tt.err, got, tt.wantUnsent)
+			}
+		})
+	}
+}
+
+func TestRetryGetAfterConnectionReset(t *testing.T) {
+	var calls callCounter
+	o := newTestServer(t, Options{}, func(w http.ResponseWriter, r *http.Request) {
+		if calls.add(r) == 1 {
+			resetConnection(t, w)
+			return
+		}
+		fmt.Fprint(w, `{"cartId":"cart-1"}`)
+	})
+
+	cart, err := o.GetCart(context.Background(), "cart-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cart.CartID != "cart-1" {
+		t.Errorf("cart ID = %q, want cart-1", cart.CartID)
+	}
+	if n := calls.get("GET /order/cart/cart-1"); n != 2 {
+		t.Errorf("GET sent %d times, want 2", n)
+	}
+}
+
+// The retries stop once the budget is spent, well before MaxAttempts, and
+// the budget is shared by the calls that follow
+func TestRetryBudgetExhausted(t *testing.T) {
+	var calls callCounter
+	clock := newFakeClock()
+	opts := Options{Clock: clock, MaxAttempts: 10, RetryBackoff: time.Second, RetryBudget: 3 * time.Second}
+	o := newTestServer(t, opts, func(w http.ResponseWriter, r *http.Request) {
+		calls.add(r)
+		writeAPIError(w, http.StatusServiceUnavailable, "Service unavailable")
+	})
+
+	// Waits of 1s and 2s spend the budget, the next one of 4s exceeds it
+	_, err := o.GetCart(context.Background(), "cart-1")
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("err = %v, want %v", err, ErrRetryBudgetExhausted)
+	}
+	var apiErr *ovh.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusServiceUnavailable {
+		t.Errorf("err = %v, want the last 503", err)
+	}
+	if n := calls.get("GET /order/cart/cart-1"); n != 3 {
+		t.Errorf("GET sent %d times, want 3", n)
+	}
+	if clock.waited != 3*time.Second {
+		t.Errorf("waited %s, want the budget of 3s", clock.waited)
+	}
+
+	if _, err := o.GetCart(context.Background(), "cart-2"); !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("err = %v, want %v", err, ErrRetryBudgetExhausted)
+	}
+	if n := calls.get("GET /order/cart/cart-2"); n != 1 {
+		t.Errorf("GET sent %d times once the budget is spent, want 1", n)
+	}
+}
+
+func TestRetryPostAfterConnectionReset(t *testing.T) {
+	tests := []struct {
+		name string
+
+		// check is the resend check of the call, if any
+		check resendCheck
+
+		wantErr   bool
+		wantPosts int
+	}{
+		{name: "no resend check", wantErr: true, wantPosts: 1},
+		{
+			name:      "not applied",
+			check:     func(context.Context) (bool, error) { return false, nil },
+			wantPosts: 2,
+		},
+		{
+			name:      "applied",
+			check:     func(context.Context) (bool, error) { return true, nil },
+			wantPosts: 1,
+		},
+		{
+			name:      "check fails",
+			check:     func(context.Context) (bool, error) { return false, errors.New("unknown state") },
+			wantErr:   true,
+			wantPosts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls callCounter
+			o := newTestServer(t, Options{}, func(w http.ResponseWriter, r *http.Request) {
+				if calls.add(r) == 1 {
+					resetConnection(t, w)
+					return
+				}
+				fmt.Fprint(w, `{}`)
+			})
+
+			ctx := context.Background()
+			if tt.check != nil {
+				ctx = withResendCheck(ctx, tt.check)
+			}
+			err := o.client.PostWithContext(ctx, "/order/cart/cart-1/assign", nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error: %t", err, tt.wantErr)
+			}
+			if n := calls.get("POST /order/cart/cart-1/assign"); n != tt.wantPosts {
+				t.Errorf("POST sent %d times, want %d", n, tt.wantPosts)
+			}
+		})
+	}
+}
+
+func TestRetryPostUnsent(t *testing.T) {
+	var calls callCounter
+	o := newTestServer(t, Options{}, func(w http.ResponseWriter, r *http.Request) {
+		if calls.add(r) == 1 {
+			writeAPIError(w, http.StatusTooManyRequests, "Too many requests")
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	})
+
+	// A 429 rejects the call before it is processed, no check is needed
+	if err := o.client.PostWithContext(context.Background(), "/order/cart/cart-1/assign", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.get("POST /order/cart/cart-1/assign"); n != 2 {
+		t.Errorf("POST sent %d times, want 2", n)
+	}
+}
+
+func TestCheckoutAfterConnectionReset(t *testing.T) {
+	tests := []struct {
+		name     string
+		readOnly bool
+
+		wantErr   error
+		wantPosts int
+	}{
+		{name: "cart still open", wantPosts: 2},
+		{name: "cart checked out", readOnly: true, wantErr: ErrCheckoutUncertain, wantPosts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls callCounter
+			o := newTestServer(t, Options{}, func(w http.ResponseWriter, r *http.Request) {
+				n := calls.add(r)
+				switch r.Method + " " + r.URL.Path {
+				case "POST /order/cart/cart-1/checkout":
+					if n == 1 {
+						resetConnection(t, w)
+						return
+					}
+					fmt.Fprint(w, `{"orderId":123456789,"url":"https://example.com/pay","contracts":[]}`)
+				case "GET /order/cart/cart-1":
+					fmt.Fprintf(w, `{"cartId":"cart-1","readOnly":%t}`, tt.readOnly)
+				default:
+					writeAPIError(w, http.StatusNotFound, "not found")
+				}
+			})
+
+			order, err := o.checkout(context.Background(), "cart-1", false)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("err = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if order.OrderID != "123456789" {
+				t.Errorf("order ID = %q, want 123456789", order.OrderID)
+			}
+			if n := calls.get("POST /order/cart/cart-1/checkout"); n != tt.wantPosts {
+				t.Errorf("checkout sent %d times, want %d", n, tt.wantPosts)
+			}
+		})
+	}
+}
+
+func TestAddServerAfterConnectionReset(t *testing.T) {
+	var calls callCounter
+	o := newTestServer(t, Options{}, func(w http.ResponseWriter, r *http.Request) {
+		calls.add(r)
+		switch r.Method + " " + r.URL.Path {
+		case "POST /order/cart/cart-1/baremetalServers":
+			// The server is added, but the answer never arrives
+			resetConnection(t, w)
+		case "GET /order/cart/cart-1/item":
+			fmt.Fprint(w, `[42]`)
+		case "GET /order/cart/cart-1/item/42":
+			fmt.Fprint(w, `{"itemId":42,"settings":{"planCode":"24ska01","pricingMode":"default","quantity":1}}`)
+		default:
+			writeAPIError(w, http.StatusNotFound, "not found")
+		}
+	})
+
+	req := OrderRequest{Subsidiary: "FR", PlanCode: "24ska01"}.withDefaults()
+	itemID, err := o.addServer(context.Background(), "cart-1", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if itemID != 42 {
+		t.Errorf("item ID = %d, want 42", itemID)
+	}
+	if n := calls.get("POST /order/cart/cart-1/baremetalServers"); n != 1 {
+		t.Errorf("server added %d times, want 1", n)
+	}
+}
+
+func TestRetryTruncatedBody(t *testing.T) {
+	const cart = `{"cartId":"cart-1","description":"","readOnly":false}`
+	tests := []struct {
+		name string
+
+		// first answers the first attempt
+		first func(w http.ResponseWriter)
+
+		wantErr   bool
+		wantCalls int
+	}{
+		{
+			// A proxy cut the body short of its Content-Length
+			name: "truncated",
+			first: func(w http.ResponseWriter) {
+				w.Header().Set("Content-Length", strconv.Itoa(len(cart)))
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, cart[:len(cart)/2])
+			},
+			wantCalls: 2,
+		},
+		{
+			// A complete 200 which does not decode is a bug, not a
+			// transient condition
+			name: "invalid JSON",
+			first: func(w http.ResponseWriter) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, cart[:len(cart)/2])
+			},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls callCounter
+			o := newTestServer(t, Options{}, func(w http.ResponseWriter, r *http.Request) {
+				if calls.add(r) == 1 {
+					tt.first(w)
+					return
+				}
+				fmt.Fprint(w, cart)
+			})
+
+			cart, err := o.GetCart(context.Background(), "cart-1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error: %t", err, tt.wantErr)
+			}
+			if err == nil && cart.CartID != "cart-1" {
+				t.Errorf("cart ID = %q, want cart-1", cart.CartID)
+			}
+			if n := calls.get("GET /order/cart/cart-1"); n != tt.wantCalls {
+				t.Errorf("GET sent %d times, want %d", n, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCheckoutLockThenClear(t *testing.T) {
+	tests := []struct {
+		name string
+
+		// locked is the number of checkouts rejected by the lock
+		locked int
+
+		wantErr   error
+		wantPosts int
+	}{
+		{name: "released", locked: 2, wantPosts: 3},
+		{name: "never released", locked: 5, wantErr: ErrOperationInProgress, wantPosts: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls callCounter
+			clock := newFakeClock()
+			o := newTestServer(t, Options{Clock: clock, MaxAttempts: 3}, func(w http.ResponseWriter, r *http.Request) {
+				n := calls.add(r)
+				switch r.Method + " " + r.URL.Path {
+				case "POST /order/cart/cart-1/checkout":
+					if n <= tt.locked {
+						writeAPIError(w, http.StatusConflict, "Another operation is in progress on this cart")
+						return
+					}
+					fmt.Fprint(w, `{"orderId":234567890,"url":"https://example.com/pay"}`)
+				case "GET /order/cart/cart-1":
+					fmt.Fprint(w, `{"cartId":"cart-1","readOnly":false}`)
+				default:
+					writeAPIError(w, http.StatusNotFound, "not found")
+				}
+			})
+
+			order, err := o.checkout(context.Background(), "cart-1", false)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("err = %v, want %v", err, tt.wantErr)
+				}
+				// The lock is not a lack of stock
+				if errors.Is(err, ErrOutOfStock) {
+					t.Errorf("err = %v, reported as out of stock", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if order.OrderID != "234567890" {
+				t.Errorf("order ID = %q, want 234567890", order.OrderID)
+			}
+			if n := calls.get("POST /order/cart/cart-1/checkout"); n != tt.wantPosts {
+				t.Errorf("checkout sent %d times, want %d", n, tt.wantPosts)
+			}
+			// Each retry waited for the lock to be released
+			if want := time.Duration(tt.wantPosts-1) * lockBackoff; clock.waited < want {
+				t.Errorf("waited %s, want at least %s", clock.waited, want)
+			}
+			if n := calls.get("DELETE /order/cart/cart-1"); n != 0 {
+				t.Errorf("cart deleted %d times, want 0", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package orderer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// dedicatedServer is the part of a delivered server FindReusableServer reads
+type dedicatedServer struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	Datacenter  string `json:"datacenter"`
+	State       string `json:"state"`
+}
+
+// servicePlanCode returns the plan code the service of a delivered server is
+// billed for
+func (o *Orderer) servicePlanCode(ctx context.Context, serviceName string) (string, error) {
+	info, err := o.GetServiceInfo(ctx, serviceName)
+	if err != nil {
+		return "", err
+	}
+	var service struct {
+		Billing struct {
+			Plan struct {
+				Code string `json:"code"`
+			} `json:"plan"`
+		} `json:"billing"`
+	}
+	if err := o.client.GetWithContext(ctx, fmt.Sprintf("/services/%d", info.ServiceID), &service); err != nil {
+		return "", fmt.Errorf("error fetching the service of %s: %w", serviceName, err)
+	}
+	return service.Billing.Plan.Code, nil
+}
+
+// FindReusableServer returns the first delivered server, by service name,
+// whose display name is marker, whose plan is planCode and, when datacenter
+// is set, which is in this datacenter (e.g. rbx for rbx8). It returns "" when
+// no server matches. A server returned is claimed by the Orderer and never
+// returned again, so that concurrent orders do not reuse the same server.
+func (o *Orderer) FindReusableServer(ctx context.Context, planCode, datacenter, marker string) (string, error) {
+	var names []string
+	if err := o.client.GetWithContext(ctx, "/dedicated/server", &names); err != nil {
+		return "", fmt.Errorf("error listing servers: %w", err)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if o.isClaimed(name) {
+			continue
+		}
+		var server dedicatedServer
+		if err := o.client.GetWithContext(ctx, "/dedicated/server/"+name, &server); err != nil {
+			return "", fmt.Errorf("error fetching server %s: %w", name, err)
+		}
+		if server.DisplayName != marker || server.State != "ok" {
+			continue
+		}
+		if datacenter != "" && !strings.HasPrefix(strings.ToLower(server.Datacenter), strings.ToLower(datacenter)) {
+			continue
+		}
+		serverPlan, err := o.servicePlanCode(ctx, name)
+		if err != nil {
+			return "", err
+		}
+		if serverPlan != planCode {
+			o.debugf("Server %s is marked %s but has plan %s", name, marker, serverPlan)
+			continue
+		}
+		if o.claim(name) {
+			o.logger.Printf("Reusing server %s (%s in %s) instead of ordering one", name, serverPlan, server.Datacenter)
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// claim marks serviceName as reused, and reports whether it was not already
+func (o *Orderer) claim(serviceName string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.claimed[serviceName] {
+		return false
+	}
+	if o.claimed == nil {
+		o.claimed = make(map[string]bool)
+	}
+	o.claimed[serviceName] = true
+	return true
+}
+
+// isClaimed reports whether serviceName has already been reused
+func (o *Orderer) isClaimed(serviceName string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.claimed[serviceName]
+}
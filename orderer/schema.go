@@ -0,0 +1,109 @@
+package orderer
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigSchema is the JSON Schema of the configuration file, for editors and
+// linters. It is published as orderer/config.schema.json in the repository.
+//
+//go:embed config.schema.json
+var ConfigSchema string
+
+// configSchemaURL is the URL the schema is compiled under, its $id
+const configSchemaURL = "https://github.com/mediocre232/OVHAPIdedicatedserver/config.schema.json"
+
+var (
+	compiledSchemaOnce sync.Once
+	compiledSchema     *jsonschema.Schema
+	compiledSchemaErr  error
+)
+
+// SchemaViolation is a part of a configuration file that does not match
+// ConfigSchema.
+type SchemaViolation struct {
+	// Path is the JSON pointer of the offending value, such as
+	// /options/1/planCode, or / for the file itself.
+	Path    string
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// ValidateConfigSchema checks a configuration file content, YAML or JSON,
+// against ConfigSchema and returns every violation, sorted by path. The
+// error is only set when the content cannot be parsed at all.
+//
+// The schema checks the shape of the file; ParseConfig still applies the
+// checks a schema cannot express, such as the extra parameters a plan
+// accepts.
+func ValidateConfigSchema(data []byte) ([]SchemaViolation, error) {
+	compiledSchemaOnce.Do(func() {
+		compiler := jsonschema.NewCompiler()
+		if err := compiler.AddResource(configSchemaURL, strings.NewReader(ConfigSchema)); err != nil {
+			compiledSchemaErr = err
+			return
+		}
+		compiledSchema, compiledSchemaErr = compiler.Compile(configSchemaURL)
+	})
+	if compiledSchemaErr != nil {
+		return nil, fmt.Errorf("invalid config schema: %w", compiledSchemaErr)
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	// The validator only knows the types of encoding/json, so the YAML
+	// document goes through JSON; this also rejects keys that are not
+	// strings.
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	err = compiledSchema.Validate(doc)
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil, err
+	}
+	var violations []SchemaViolation
+	collectViolations(validationErr, &violations)
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Path < violations[j].Path
+	})
+	return violations, nil
+}
+
+// collectViolations appends the leaves of err, which are the actual
+// violations; the inner nodes only say which subschema failed.
+func collectViolations(err *jsonschema.ValidationError, violations *[]SchemaViolation) {
+	if len(err.Causes) == 0 {
+		path := err.InstanceLocation
+		if path == "" {
+			path = "/"
+		}
+		*violations = append(*violations, SchemaViolation{Path: path, Message: err.Message})
+		return
+	}
+	for _, cause := range err.Causes {
+		collectViolations(cause, violations)
+	}
+}
@@ -0,0 +1,268 @@
+package orderer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ErrNoPlanMatches is returned by SelectPlan when no plan meets the
+// requirements.
+var ErrNoPlanMatches = errors.New("no plan meets the requirements")
+
+// PlanSpecs are the hardware and price of a plan as delivered with its
+// default options, from the public catalog.
+type PlanSpecs struct {
+	PlanCode string
+	Name     string
+
+	CPU      string
+	Cores    int
+	MemoryGB int
+
+	// StorageTechnologies lists the technologies of the disks, e.g. NVMe.
+	StorageTechnologies []string
+
+	Regions     []string
+	Datacenters []string
+
+	// DefaultOptions are the plan codes of the default options of the
+	// families the plan requires an option of.
+	DefaultOptions []string
+
+	// MonthlyPrice is the monthly renewal price, without tax, of the plan
+	// and its default options.
+	MonthlyPrice Price
+}
+
+// PlanRequirements are the minimum specs of SelectPlan. Zero values do not
+// restrict the plans.
+type PlanRequirements struct {
+	MinMemoryGB int
+	MinCores    int
+
+	// StorageTechnology, e.g. nvme, requires a disk of this technology.
+	StorageTechnology string
+
+	// Region requires the plan to be offered in this region value, e.g.
+	// europe, or datacenter, e.g. rbx.
+	Region string
+}
+
+func (r PlanRequirements) String() string {
+	var parts []string
+	if r.MinMemoryGB > 0 {
+		parts = append(parts, fmt.Sprintf(">= %d GB RAM", r.MinMemoryGB))
+	}
+	if r.MinCores > 0 {
+		parts = append(parts, fmt.Sprintf(">= %d cores", r.MinCores))
+	}
+	if r.StorageTechnology != "" {
+		parts = append(parts, r.StorageTechnology+" storage")
+	}
+	if r.Region != "" {
+		parts = append(parts, "in "+r.Region)
+	}
+	if len(parts) == 0 {
+		return "no requirement"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// match reports whether specs meet r
+func (r PlanRequirements) match(specs PlanSpecs) bool {
+	if specs.MemoryGB < r.MinMemoryGB || specs.Cores < r.MinCores {
+		return false
+	}
+	if r.StorageTechnology != "" && !containsFold(specs.StorageTechnologies, r.StorageTechnology) {
+		return false
+	}
+	return r.Region == "" || containsFold(specs.Regions, r.Region) || containsFold(specs.Datacenters, r.Region)
+}
+
+// containsFold reports whether values contains value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// catalogPricing is a price of the public catalog, in 10^-8 of the currency
+type catalogPricing struct {
+	Capacities   []string `json:"capacities"`
+	Commitment   int      `json:"commitment"`
+	Interval     int      `json:"interval"`
+	IntervalUnit string   `json:"intervalUnit"`
+	Mode         string   `json:"mode"`
+	Price        int64    `json:"price"`
+}
+
+// monthlyRenewal returns the monthly renewal price without commitment of
+// pricings, in 10^-8 of the currency
+func monthlyRenewal(pricings []catalogPricing) (int64, bool) {
+	for _, p := range pricings {
+		if contains(p.Capacities, "renew") && p.IntervalUnit == "month" && p.Interval == 1 && p.Mode == "default" && p.Commitment == 0 {
+			return p.Price, true
+		}
+	}
+	return 0, false
+}
+
+// catalogPlan is a plan or an addon of the public catalog
+type catalogPlan struct {
+	PlanCode       string           `json:"planCode"`
+	InvoiceName    string           `json:"invoiceName"`
+	Product        string           `json:"product"`
+	Pricings       []catalogPricing `json:"pricings"`
+	Configurations []struct {
+		Name   string   `json:"name"`
+		Values []string `json:"values"`
+	} `json:"configurations"`
+	AddonFamilies []struct {
+		Name      string `json:"name"`
+		Mandatory bool   `json:"mandatory"`
+		Default   string `json:"default"`
+	} `json:"addonFamilies"`
+}
+
+// catalogProduct is a product of the public catalog, with its hardware
+type catalogProduct struct {
+	Name  string `json:"name"`
+	Blobs struct {
+		Technical struct {
+			Server struct {
+				CPU struct {
+					Brand string `json:"brand"`
+					Model string `json:"model"`
+					Cores int    `json:"cores"`
+				} `json:"cpu"`
+			} `json:"server"`
+			Memory struct {
+				Size int `json:"size"`
+			} `json:"memory"`
+			Storage struct {
+				Disks []struct {
+					Technology string `json:"technology"`
+				} `json:"disks"`
+			} `json:"storage"`
+		} `json:"technical"`
+	} `json:"blobs"`
+}
+
+// ListPlanSpecs returns the specs of the dedicated server plans offered to
+// subsidiary, read from the public catalog.
+func (o *Orderer) ListPlanSpecs(ctx context.Context, subsidiary string) ([]PlanSpecs, error) {
+	if err := o.checkSubsidiary(subsidiary); err != nil {
+		return nil, err
+	}
+	var catalog struct {
+		Locale struct {
+			CurrencyCode string `json:"currencyCode"`
+		} `json:"locale"`
+		Plans    []catalogPlan    `json:"plans"`
+		Addons   []catalogPlan    `json:"addons"`
+		Products []catalogProduct `json:"products"`
+	}
+	path := "/order/catalog/public/" + serverProduct(o.opts.Endpoint) + "?ovhSubsidiary=" + url.QueryEscape(subsidiary)
+	err := o.cached("catalog-"+subsidiary, &catalog, func() error {
+		if err := o.client.GetWithContext(ctx, path, &catalog); err != nil {
+			return fmt.Errorf("error fetching the catalog: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	products := make(map[string]catalogProduct, len(catalog.Products))
+	for _, product := range catalog.Products {
+		products[product.Name] = product
+	}
+	addons := make(map[string]catalogPlan, len(catalog.Addons))
+	for _, addon := range catalog.Addons {
+		addons[addon.PlanCode] = addon
+	}
+
+	var plans []PlanSpecs
+	for _, plan := range catalog.Plans {
+		price, ok := monthlyRenewal(plan.Pricings)
+		if !ok {
+			continue
+		}
+		specs := PlanSpecs{PlanCode: plan.PlanCode, Name: plan.InvoiceName}
+		for _, config := range plan.Configurations {
+			switch config.Name {
+			case labelRegion:
+				specs.Regions = config.Values
+			case labelDatacenter:
+				specs.Datacenters = config.Values
+			}
+		}
+
+		// The hardware is split between the plan and its default options
+		parts := []catalogProduct{products[plan.Product]}
+		for _, family := range plan.AddonFamilies {
+			addon, ok := addons[family.Default]
+			if !ok {
+				continue
+			}
+			if family.Mandatory {
+				specs.DefaultOptions = append(specs.DefaultOptions, addon.PlanCode)
+			}
+			if addonPrice, ok := monthlyRenewal(addon.Pricings); ok {
+				price += addonPrice
+			}
+			parts = append(parts, products[addon.Product])
+		}
+		for _, part := range parts {
+			technical := part.Blobs.Technical
+			if cpu := technical.Server.CPU; cpu.Cores > 0 {
+				specs.CPU = strings.TrimSpace(cpu.Brand + " " + cpu.Model)
+				specs.Cores = cpu.Cores
+			}
+			specs.MemoryGB += technical.Memory.Size
+			for _, disk := range technical.Storage.Disks {
+				if disk.Technology != "" && !containsFold(specs.StorageTechnologies, disk.Technology) {
+					specs.StorageTechnologies = append(specs.StorageTechnologies, disk.Technology)
+				}
+			}
+		}
+
+		value := new(big.Rat).SetFrac(big.NewInt(price), big.NewInt(100000000)).FloatString(2)
+		specs.MonthlyPrice = Price{
+			Value:        json.Number(value),
+			CurrencyCode: catalog.Locale.CurrencyCode,
+			Text:         value + " " + catalog.Locale.CurrencyCode,
+		}
+		plans = append(plans, specs)
+	}
+	return plans, nil
+}
+
+// SelectPlan returns the cheapest of plans meeting req, or an error wrapping
+// ErrNoPlanMatches.
+func SelectPlan(plans []PlanSpecs, req PlanRequirements) (*PlanSpecs, error) {
+	var matches []PlanSpecs
+	for _, plan := range plans {
+		if req.match(plan) {
+			matches = append(matches, plan)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w (%s) among %d plans", ErrNoPlanMatches, req, len(plans))
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, errA := matches[i].MonthlyPrice.Amount()
+		b, errB := matches[j].MonthlyPrice.Amount()
+		return errA == nil && errB == nil && a.Cmp(b) < 0
+	})
+	return &matches[0], nil
+}
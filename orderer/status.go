@@ -0,0 +1,45 @@
+package orderer
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// StatusTask is an incident or maintenance announced by OVH, as returned by
+// /status/task.
+type StatusTask struct {
+	ID        int64      `json:"id"`
+	Title     string     `json:"title"`
+	Type      string     `json:"type"`
+	Status    string     `json:"status"`
+	Impact    string     `json:"impact"`
+	Project   string     `json:"project"`
+	StartDate *time.Time `json:"startDate"`
+	EndDate   *time.Time `json:"endDate"`
+}
+
+func (t StatusTask) String() string {
+	s := fmt.Sprintf("%s %s (%s, %s impact)", t.Type, t.Title, t.Status, t.Impact)
+	if t.StartDate != nil {
+		s += " since " + t.StartDate.Format(time.RFC3339)
+	}
+	return s
+}
+
+// OngoingStatusTasks returns the incidents and maintenances announced by OVH
+// which are planned or in progress, so that a run can be postponed or at
+// least be known to happen during a maintenance window.
+func (o *Orderer) OngoingStatusTasks(ctx context.Context) ([]StatusTask, error) {
+	var tasks []StatusTask
+	if err := o.client.GetWithContext(ctx, "/status/task", &tasks); err != nil {
+		return nil, fmt.Errorf("error fetching OVH status: %w", err)
+	}
+	var ongoing []StatusTask
+	for _, task := range tasks {
+		if task.Status != "finished" {
+			ongoing = append(ongoing, task)
+		}
+	}
+	return ongoing, nil
+}
@@ -0,0 +1,215 @@
+package orderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// streamKeepAlive is the interval of the comments sent to idle streams, so
+// that proxies do not close them and disconnected clients are noticed
+const streamKeepAlive = 15 * time.Second
+
+// EventStream serves the progress events of the orders of an Orderer as
+// Server-Sent Events, for web UIs following an order live. Its OnEvent
+// method is meant for Options.OnEvent; orders are told apart by their
+// OrderRequest.RunID, and events without one are ignored.
+//
+// GET /events?run=<run ID> streams the events of the run, from the first
+// one, as they are emitted, and ends after its StepDone event. Each SSE
+// event is named after its step and carries the event as JSON; its id lets
+// a reconnecting client resume with Last-Event-ID. A client may connect
+// before the first event of the run. The stream of a run ends with its
+// first order done: the orders of OrderBulk, which share the run ID of
+// their requests, cannot be told apart.
+type EventStream struct {
+	retention time.Duration
+
+	mu   sync.Mutex
+	runs map[string]*streamRun
+}
+
+// streamRun is the events of a run
+type streamRun struct {
+	events      []streamEvent
+	done        time.Time
+	subscribers int
+
+	// updated is closed, and replaced, when an event is added
+	updated chan struct{}
+}
+
+// streamEvent is the JSON of an event in a stream
+type streamEvent struct {
+	Step              string    `json:"step"`
+	Start             time.Time `json:"start"`
+	DurationMs        int64     `json:"durationMs"`
+	RunID             string    `json:"runId"`
+	CartID            string    `json:"cartId,omitempty"`
+	OrderID           string    `json:"orderId,omitempty"`
+	ServiceName       string    `json:"serviceName,omitempty"`
+	CustomerReference string    `json:"customerReference,omitempty"`
+	Error             string    `json:"error,omitempty"`
+}
+
+// NewEventStream returns a stream keeping the events of a finished run for
+// retention (defaults to 10 minutes), so that a client connecting late still
+// gets them.
+func NewEventStream(retention time.Duration) *EventStream {
+	if retention <= 0 {
+		retention = 10 * time.Minute
+	}
+	return &EventStream{retention: retention, runs: make(map[string]*streamRun)}
+}
+
+// OnEvent records event and sends it to the clients streaming its run.
+func (s *EventStream) OnEvent(event Event) {
+	if event.RunID == "" {
+		return
+	}
+	e := streamEvent{
+		Step:              event.Step,
+		Start:             event.Start,
+		DurationMs:        event.Duration.Milliseconds(),
+		RunID:             event.RunID,
+		CartID:            event.CartID,
+		OrderID:           event.OrderID,
+		ServiceName:       event.ServiceName,
+		CustomerReference: event.CustomerReference,
+	}
+	if event.Err != nil {
+		e.Error = event.Err.Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	run := s.run(event.RunID)
+	if !run.done.IsZero() {
+		return
+	}
+	run.events = append(run.events, e)
+	if event.Step == StepDone {
+		run.done = time.Now()
+	}
+	close(run.updated)
+	run.updated = make(chan struct{})
+}
+
+// run returns the run of id, creating it if needed. s.mu must be held.
+func (s *EventStream) run(id string) *streamRun {
+	run, ok := s.runs[id]
+	if !ok {
+		run = &streamRun{updated: make(chan struct{})}
+		s.runs[id] = run
+	}
+	return run
+}
+
+// prune forgets the runs done for longer than the retention. s.mu must be
+// held.
+func (s *EventStream) prune() {
+	for id, run := range s.runs {
+		if !run.done.IsZero() && time.Since(run.done) > s.retention && run.subscribers == 0 {
+			delete(s.runs, id)
+		}
+	}
+}
+
+// next returns the events of run id from index from, whether the run is
+// done, and a channel closed when there are more
+func (s *EventStream) next(id string, from int) ([]streamEvent, bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run := s.run(id)
+	var events []streamEvent
+	if from < len(run.events) {
+		events = append(events, run.events[from:]...)
+	}
+	return events, !run.done.IsZero(), run.updated
+}
+
+// subscribe counts a client of run id, until the returned function is
+// called. A run a client waited for in vain is forgotten with its last
+// client.
+func (s *EventStream) subscribe(id string) func() {
+	s.mu.Lock()
+	s.run(id).subscribers++
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		run := s.runs[id]
+		run.subscribers--
+		if run.subscribers == 0 && len(run.events) == 0 {
+			delete(s.runs, id)
+		}
+	}
+}
+
+// ServeHTTP serves /events.
+func (s *EventStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/events" {
+		http.NotFound(w, r)
+		return
+	}
+	id := r.URL.Query().Get("run")
+	if id == "" {
+		http.Error(w, "missing run parameter", http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	from := 0
+	if last := r.Header.Get("Last-Event-ID"); last != "" {
+		n, err := strconv.Atoi(last)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		from = n
+	}
+
+	defer s.subscribe(id)()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		events, done, updated := s.next(id, from)
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			from++
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", from, event.Step, data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+		if done {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			// The client disconnected
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-updated:
+		}
+	}
+}
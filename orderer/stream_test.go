@@ -0,0 +1,156 @@
+package orderer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseEvent is an event read from a stream
+type sseEvent struct {
+	id, name, data string
+}
+
+// readEvents reads the events of an SSE stream until it ends
+func readEvents(t *testing.T, resp *http.Response) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var event sseEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if event != (sseEvent{}) {
+				events = append(events, event)
+			}
+			event = sseEvent{}
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+// getEvents starts streaming the events of run, from after lastID when set
+func getEvents(t *testing.T, ctx context.Context, url, run, lastID string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/events?run="+run, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestEventStream(t *testing.T) {
+	stream := NewEventStream(time.Minute)
+	srv := httptest.NewServer(stream)
+	defer srv.Close()
+
+	// The client connects before the first event of the run
+	resp := getEvents(t, context.Background(), srv.URL, "run-1", "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	go func() {
+		stream.OnEvent(Event{Step: StepCreateCart, RunID: "run-1", CartID: "cart-1"})
+		stream.OnEvent(Event{Step: StepCreateCart, CartID: "cart-2"}) // no run, ignored
+		stream.OnEvent(Event{Step: StepCheckout, RunID: "run-2", CartID: "cart-3"})
+		stream.OnEvent(Event{Step: StepCheckout, RunID: "run-1", CartID: "cart-1", OrderID: "234567890"})
+		stream.OnEvent(Event{Step: StepDone, RunID: "run-1", OrderID: "234567890", Err: errors.New("payment failed")})
+	}()
+
+	events := readEvents(t, resp)
+	want := []sseEvent{
+		{id: "1", name: StepCreateCart, data: `"cartId":"cart-1"`},
+		{id: "2", name: StepCheckout, data: `"orderId":"234567890"`},
+		{id: "3", name: StepDone, data: `"error":"payment failed"`},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("events = %+v, want %d", events, len(want))
+	}
+	for i, event := range events {
+		if event.id != want[i].id || event.name != want[i].name || !strings.Contains(event.data, want[i].data) {
+			t.Errorf("event %d = %+v, want %+v", i, event, want[i])
+		}
+	}
+
+	// A client reconnecting resumes after the last event it got, and a
+	// client connecting once the run is done still gets its events
+	events = readEvents(t, getEvents(t, context.Background(), srv.URL, "run-1", "2"))
+	if len(events) != 1 || events[0].id != "3" {
+		t.Errorf("events after 2 = %+v, want event 3", events)
+	}
+}
+
+func TestEventStreamDisconnect(t *testing.T) {
+	stream := NewEventStream(time.Minute)
+	srv := httptest.NewServer(stream)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	getEvents(t, ctx, srv.URL, "run-1", "")
+	cancel()
+
+	// The run the client waited for in vain is forgotten with it
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stream.mu.Lock()
+		runs := len(stream.runs)
+		stream.mu.Unlock()
+		if runs == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d runs still kept after the client disconnected", runs)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEventStreamBadRequest(t *testing.T) {
+	stream := NewEventStream(time.Minute)
+	tests := []struct {
+		name   string
+		target string
+		lastID string
+		want   int
+	}{
+		{name: "no run", target: "/events", want: http.StatusBadRequest},
+		{name: "invalid Last-Event-ID", target: "/events?run=run-1", lastID: "abc", want: http.StatusBadRequest},
+		{name: "other path", target: "/orders", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.lastID != "" {
+				r.Header.Set("Last-Event-ID", tt.lastID)
+			}
+			w := httptest.NewRecorder()
+			stream.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
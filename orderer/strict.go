@@ -0,0 +1,67 @@
+package orderer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownField is returned, wrapped, with Options.StrictJSON when a
+// response decoded strictly has a field the tool does not model.
+var ErrUnknownField = errors.New("unexpected field in API response")
+
+// strictResponse marks the responses decoded strictly with
+// Options.StrictJSON: those the tool models as a whole, the orders, their
+// payment methods and the cart summaries. Most other calls decode the few
+// fields they need into ad hoc structs, which strict decoding would reject.
+// Without Options.StrictJSON, the response is decoded into v as usual.
+type strictResponse struct {
+	v interface{}
+}
+
+// strict marks the response decoded into v as strict
+func strict(v interface{}) *strictResponse {
+	return &strictResponse{v: v}
+}
+
+func (r *strictResponse) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, r.v)
+}
+
+// strictClient decodes the responses marked with strict with
+// DisallowUnknownFields, so that a field OVH adds or renames fails the call
+// instead of being dropped silently.
+type strictClient struct {
+	Client
+}
+
+func (c *strictClient) GetWithContext(ctx context.Context, url string, resType interface{}) error {
+	r, ok := resType.(*strictResponse)
+	if !ok {
+		return c.Client.GetWithContext(ctx, url, resType)
+	}
+	var raw json.RawMessage
+	if err := c.Client.GetWithContext(ctx, url, &raw); err != nil {
+		return err
+	}
+	return decodeStrict("GET "+url, raw, r.v)
+}
+
+// decodeStrict decodes raw, the response of call, into v, failing with
+// ErrUnknownField on a field v does not have
+func decodeStrict(call string, raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	// The decoder has no error type for unknown fields
+	if err != nil && strings.HasPrefix(err.Error(), "json: unknown field") {
+		return fmt.Errorf("%w: %s: %v", ErrUnknownField, call, err)
+	}
+	return err
+}
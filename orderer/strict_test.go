@@ -0,0 +1,94 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+)
+
+// The files of testdata are responses recorded from the API, with the
+// identifiers changed
+func TestDecodeStrictRecorded(t *testing.T) {
+	t.Run("cart summary", func(t *testing.T) {
+		var summary CartSummary
+		decodeRecorded(t, "testdata/checkout_summary.json", &summary)
+		if summary.Prices.WithoutTax.Value != "14.99" || summary.Prices.Reduction.PriceInUcents != -1499000000 {
+			t.Errorf("prices = %+v", summary.Prices)
+		}
+		if len(summary.Details) != 2 || len(summary.Details[1].Reductions) != 1 {
+			t.Fatalf("details = %+v", summary.Details)
+		}
+		if detail := summary.Details[1]; detail.CartItemID != 123456789 || detail.Domain != "*" || detail.Reductions[0].Context != "promotion" {
+			t.Errorf("detail = %+v", detail)
+		}
+		if summary.OrderID != "" || summary.URL != "" {
+			t.Errorf("order = %q %q, want none", summary.OrderID, summary.URL)
+		}
+	})
+	t.Run("order", func(t *testing.T) {
+		var order OrderInfo
+		decodeRecorded(t, "testdata/order.json", &order)
+		if order.OrderID != 234567890 || order.Tax.Value != "3" || order.PDFURL == "" || !order.RetractionDate.IsZero() {
+			t.Errorf("order = %+v", order)
+		}
+	})
+}
+
+func TestDecodeStrictUnknownField(t *testing.T) {
+	var order OrderInfo
+	err := decodeStrict("GET /me/order/1", []byte(`{"orderId":1,"newField":true}`), &order)
+	if !errors.Is(err, ErrUnknownField) {
+		t.Errorf("err = %v, want ErrUnknownField", err)
+	}
+}
+
+// Only the responses marked strict fail on a new field, and only with
+// StrictJSON
+func TestStrictJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		strict bool
+
+		wantErr error
+	}{
+		{name: "lenient"},
+		{name: "strict", strict: true, wantErr: ErrUnknownField},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestServer(t, Options{StrictJSON: tt.strict}, func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method + " " + r.URL.Path {
+				case "GET /order/cart/cart-1/checkout":
+					fmt.Fprint(w, `{"details":[],"prices":{},"newField":true}`)
+				case "GET /order/cart/cart-1":
+					fmt.Fprint(w, `{"cartId":"cart-1","readOnly":false,"newField":true}`)
+				default:
+					writeAPIError(w, http.StatusNotFound, "not found")
+				}
+			})
+
+			_, err := o.summary(context.Background(), "cart-1")
+			if tt.wantErr == nil && err != nil || !errors.Is(err, tt.wantErr) {
+				t.Errorf("summary: err = %v, want %v", err, tt.wantErr)
+			}
+			// The cart is not modeled as a whole, it is never strict
+			if _, err := o.GetCart(context.Background(), "cart-1"); err != nil {
+				t.Errorf("GetCart: %v", err)
+			}
+		})
+	}
+}
+
+func decodeRecorded(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decodeStrict("GET "+name, data, v); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,32 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// TagServer sets the display name of a delivered server to tag, so it can be
+// found in the OVH manager by an external identifier. Products whose display
+// name cannot be written are skipped with a warning.
+func (o *Orderer) TagServer(ctx context.Context, serviceName, tag string) error {
+	err := o.client.PutWithContext(ctx, fmt.Sprintf("/dedicated/server/%s", serviceName), map[string]interface{}{
+		"displayName": tag,
+	}, nil)
+	var apiErr *ovh.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusMethodNotAllowed:
+			o.logger.Printf("Warning: cannot tag server %s: %s", serviceName, apiErr.Message)
+			return nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("error tagging server %s: %w", serviceName, err)
+	}
+	o.logger.Printf("Tagged server %s as %s", serviceName, tag)
+	return nil
+}
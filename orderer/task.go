@@ -0,0 +1,53 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTaskFailed is returned, wrapped, when a task of a dedicated server ends
+// in an error state.
+var ErrTaskFailed = errors.New("task failed")
+
+// Task is a task of a dedicated server, as returned by
+// /dedicated/server/{serviceName}/task/{taskId}.
+type Task struct {
+	TaskID     int64      `json:"taskId"`
+	Function   string     `json:"function"`
+	Status     string     `json:"status"`
+	Comment    string     `json:"comment"`
+	StartDate  *time.Time `json:"startDate"`
+	DoneDate   *time.Time `json:"doneDate"`
+	LastUpdate *time.Time `json:"lastUpdate"`
+}
+
+// WaitForTask polls a task of a delivered server every
+// Options.TaskPollInterval until it is done, and returns its final record.
+// Post-delivery operations such as installations, vRack and IP moves all
+// return such tasks. It fails with ErrTaskFailed when the task is cancelled
+// or ends in error, and with ErrTimeout after Options.TaskTimeout.
+func (o *Orderer) WaitForTask(ctx context.Context, serviceName string, taskID int64) (*Task, error) {
+	var task Task
+	err := o.pollUntil(ctx, poll{Interval: o.opts.TaskPollInterval, Timeout: o.opts.TaskTimeout}, func() (bool, error) {
+		task = Task{}
+		err := o.client.GetWithContext(ctx, fmt.Sprintf("/dedicated/server/%s/task/%d", serviceName, taskID), &task)
+		if err != nil {
+			return false, fmt.Errorf("error fetching task %d of %s: %w", taskID, serviceName, err)
+		}
+		switch task.Status {
+		case "done":
+			o.logger.Printf("Task %d (%s) of %s is done.", taskID, task.Function, serviceName)
+			return true, nil
+		case "cancelled", "customerError", "ovhError":
+			return false, fmt.Errorf("%w: task %d (%s) of %s ended with status %s: %s", ErrTaskFailed, taskID, task.Function, serviceName, task.Status, task.Comment)
+		}
+		o.debugf("Task %d (%s) of %s is %s", taskID, task.Function, serviceName, task.Status)
+		return false, nil
+	})
+	if err != nil {
+		return &task, fmt.Errorf("error waiting for task %d of %s: %w", taskID, serviceName, err)
+	}
+	return &task, nil
+}
@@ -0,0 +1,111 @@
+package orderer
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// terraformResource is the type of the resources written by WriteTerraform
+const terraformResource = "ovh_dedicated_server"
+
+// terraformNameInvalid matches the characters not allowed in a Terraform
+// resource name
+var terraformNameInvalid = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// TerraformName returns the name of the resource WriteTerraform writes for
+// a server displayed as tag: tag with the characters Terraform does not
+// allow replaced, or "server" without one.
+func TerraformName(tag string) string {
+	name := strings.Trim(terraformNameInvalid.ReplaceAllString(tag, "_"), "_")
+	if name == "" {
+		return "server"
+	}
+	if c := name[0]; c >= '0' && c <= '9' || c == '-' {
+		name = "server_" + name
+	}
+	return name
+}
+
+// WriteTerraform writes an ovh_dedicated_server resource of the OVH
+// Terraform provider ordering what config orders, typically the config of
+// ConfigFromResult, followed by the import block binding it to serviceName,
+// so that a server bought with the tool can be managed with Terraform from
+// then on. Without serviceName, e.g. when the order did not wait for the
+// delivery, the import is left as a comment to complete. orderID, when set,
+// is noted in a comment.
+//
+// The post-delivery settings of the config, such as its extra IPs, reverse
+// DNS or installation, have no equivalent in the resource and are not
+// written.
+func WriteTerraform(w io.Writer, config Config, orderID, serviceName string) error {
+	name := TerraformName(config.Tag)
+	var b strings.Builder
+	if orderID != "" {
+		fmt.Fprintf(&b, "# Server of OVH order %s\n", orderID)
+	}
+	fmt.Fprintf(&b, "resource %q %q {\n", terraformResource, name)
+	fmt.Fprintf(&b, "  ovh_subsidiary = %s\n", hclString(config.Subsidiary))
+	if config.Tag != "" {
+		fmt.Fprintf(&b, "  display_name   = %s\n", hclString(config.Tag))
+	}
+	b.WriteString("\n  plan = [\n    {\n")
+	fmt.Fprintf(&b, "      plan_code    = %s\n", hclString(config.Plan))
+	fmt.Fprintf(&b, "      duration     = %s\n", hclString(config.Duration))
+	fmt.Fprintf(&b, "      pricing_mode = %s\n", hclString(config.PricingMode))
+	writeTerraformLabels(&b, config.Configuration, "      ")
+	b.WriteString("    }\n  ]\n")
+	if len(config.Options) > 0 {
+		b.WriteString("\n  plan_option = [\n")
+		for _, option := range config.Options {
+			duration, pricingMode := option.Duration, option.PricingMode
+			if duration == "" {
+				duration = config.Duration
+			}
+			if pricingMode == "" {
+				pricingMode = config.PricingMode
+			}
+			b.WriteString("    {\n")
+			fmt.Fprintf(&b, "      plan_code    = %s\n", hclString(option.PlanCode))
+			fmt.Fprintf(&b, "      duration     = %s\n", hclString(duration))
+			fmt.Fprintf(&b, "      pricing_mode = %s\n", hclString(pricingMode))
+			b.WriteString("      quantity     = 1\n")
+			writeTerraformLabels(&b, option.Configuration, "      ")
+			b.WriteString("    },\n")
+		}
+		b.WriteString("  ]\n")
+	}
+	b.WriteString("}\n\n")
+
+	address := terraformResource + "." + name
+	if serviceName == "" {
+		b.WriteString("# Once the server is delivered, bind it to the resource with:\n")
+		fmt.Fprintf(&b, "#   terraform import %s <service name>\n", address)
+	} else {
+		fmt.Fprintf(&b, "import {\n  to = %s\n  id = %s\n}\n", address, hclString(serviceName))
+		fmt.Fprintf(&b, "# Or, with Terraform before 1.5: terraform import %s %s\n", address, serviceName)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeTerraformLabels writes the configuration attribute of labels, if any
+func writeTerraformLabels(b *strings.Builder, labels []ConfigLabel, indent string) {
+	if len(labels) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%sconfiguration = [\n", indent)
+	for _, label := range labels {
+		fmt.Fprintf(b, "%s  { label = %s, value = %s },\n", indent, hclString(label.Label), hclString(label.Value))
+	}
+	fmt.Fprintf(b, "%s]\n", indent)
+}
+
+// hclString quotes s as an HCL string, escaping the template sequences
+func hclString(s string) string {
+	s = strings.ReplaceAll(s, "${", "$${")
+	s = strings.ReplaceAll(s, "%{", "%%{")
+	return strconv.Quote(s)
+}
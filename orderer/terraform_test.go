@@ -0,0 +1,110 @@
+package orderer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTerraformName(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{tag: "web-1", want: "web-1"},
+		{tag: "web 1.example.com", want: "web_1_example_com"},
+		{tag: "1-web", want: "server_1-web"},
+		{tag: "-web", want: "server_-web"},
+		{tag: "", want: "server"},
+		{tag: "...", want: "server"},
+	}
+	for _, tt := range tests {
+		if got := TerraformName(tt.tag); got != tt.want {
+			t.Errorf("TerraformName(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestWriteTerraform(t *testing.T) {
+	config := Config{
+		Subsidiary:    "FR",
+		Plan:          "24rise01",
+		Duration:      "P1M",
+		PricingMode:   "default",
+		Tag:           "web-1",
+		Configuration: []ConfigLabel{{Label: labelDatacenter, Value: "gra"}},
+		Options: []ConfigOption{
+			{PlanCode: "ram-32g-ecc-2400"},
+			{PlanCode: "license-${name}", Duration: "P12M", Configuration: []ConfigLabel{{Label: "edition", Value: "std"}}},
+		},
+	}
+	tests := []struct {
+		name        string
+		orderID     string
+		serviceName string
+		want        string
+	}{
+		{
+			name:        "delivered",
+			orderID:     "234567890",
+			serviceName: "ns123.ip-1-2-3.eu",
+			want: `# Server of OVH order 234567890
+resource "ovh_dedicated_server" "web-1" {
+  ovh_subsidiary = "FR"
+  display_name   = "web-1"
+
+  plan = [
+    {
+      plan_code    = "24rise01"
+      duration     = "P1M"
+      pricing_mode = "default"
+      configuration = [
+        { label = "dedicated_datacenter", value = "gra" },
+      ]
+    }
+  ]
+
+  plan_option = [
+    {
+      plan_code    = "ram-32g-ecc-2400"
+      duration     = "P1M"
+      pricing_mode = "default"
+      quantity     = 1
+    },
+    {
+      plan_code    = "license-$${name}"
+      duration     = "P12M"
+      pricing_mode = "default"
+      quantity     = 1
+      configuration = [
+        { label = "edition", value = "std" },
+      ]
+    },
+  ]
+}
+
+import {
+  to = ovh_dedicated_server.web-1
+  id = "ns123.ip-1-2-3.eu"
+}
+# Or, with Terraform before 1.5: terraform import ovh_dedicated_server.web-1 ns123.ip-1-2-3.eu
+`,
+		},
+		{
+			name: "not delivered",
+			want: `# Once the server is delivered, bind it to the resource with:
+#   terraform import ovh_dedicated_server.web-1 <service name>
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := WriteTerraform(&b, config, tt.orderID, tt.serviceName); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); !strings.HasSuffix(got, tt.want) {
+				t.Errorf("WriteTerraform() =\n%s\nwant it to end with\n%s", got, tt.want)
+			}
+		})
+	}
+}
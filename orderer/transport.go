@@ -0,0 +1,275 @@
+package orderer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitedTransport is an http.RoundTripper waiting for a shared token
+// bucket before each request, so that the aggregate request rate stays under
+// the limit whatever the number of concurrent orders.
+type RateLimitedTransport struct {
+	Transport http.RoundTripper
+	Limiter   *rate.Limiter
+
+	// waited is the time requests spent waiting for the limiter, in
+	// nanoseconds
+	waited atomic.Int64
+}
+
+// NewRateLimitedTransport returns a transport sending requests through
+// transport (or http.DefaultTransport when nil) at the rate allowed by limiter.
+func NewRateLimitedTransport(transport http.RoundTripper, limiter *rate.Limiter) *RateLimitedTransport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &RateLimitedTransport{Transport: transport, Limiter: limiter}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	err := t.Limiter.Wait(req.Context())
+	t.waited.Add(int64(time.Since(start)))
+	if err != nil {
+		return nil, err
+	}
+	return t.Transport.RoundTrip(req)
+}
+
+// Waited returns the time requests spent waiting for the limiter so far,
+// summed over concurrent requests.
+func (t *RateLimitedTransport) Waited() time.Duration {
+	return time.Duration(t.waited.Load())
+}
+
+// RetryAfterTransport is an http.RoundTripper passing the Retry-After header
+// of rate limited and unavailable responses to the Orderer, so that waits
+// polling the API, such as WaitForDelivery, check again no sooner than OVH
+// asks. Without it they poll at their usual interval.
+type RetryAfterTransport struct {
+	Transport http.RoundTripper
+}
+
+// NewRetryAfterTransport returns a transport sending requests through
+// transport (or http.DefaultTransport when nil) and reading the Retry-After
+// header of their responses.
+func NewRetryAfterTransport(transport http.RoundTripper) *RetryAfterTransport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &RetryAfterTransport{Transport: transport}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RetryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return resp, err
+	}
+	if r, ok := req.Context().Value(retryAfterKey{}).(*retryAfter); ok {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			r.set(d)
+		}
+	}
+	return resp, nil
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, given in
+// seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := date.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+type retryAfterKey struct{}
+
+// retryAfter holds the longest Retry-After received by the calls of a wait
+// since it was last taken
+type retryAfter struct {
+	mu sync.Mutex
+	d  time.Duration
+}
+
+// withRetryAfter returns ctx collecting the Retry-After of its calls
+func withRetryAfter(ctx context.Context) (context.Context, *retryAfter) {
+	r := &retryAfter{}
+	return context.WithValue(ctx, retryAfterKey{}, r), r
+}
+
+func (r *retryAfter) set(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if d > r.d {
+		r.d = d
+	}
+}
+
+// take returns the delay received since the last call and resets it
+func (r *retryAfter) take() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := r.d
+	r.d = 0
+	return d
+}
+
+// DefaultUserAgent identifies the requests of this tool to OVH.
+const DefaultUserAgent = metadataTag + "/" + Version + " (+https://github.com/mediocre232/OVHAPIdedicatedserver)"
+
+// UserAgentTransport is an http.RoundTripper setting the User-Agent of every
+// request, so that OVH and request logs can tell the tool's traffic apart.
+type UserAgentTransport struct {
+	Transport http.RoundTripper
+	UserAgent string
+}
+
+// NewUserAgentTransport returns a transport sending requests through
+// transport (or http.DefaultTransport when nil) with userAgent (or
+// DefaultUserAgent when empty).
+func NewUserAgentTransport(transport http.RoundTripper, userAgent string) *UserAgentTransport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	return &UserAgentTransport{Transport: transport, UserAgent: userAgent}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *UserAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.UserAgent)
+	return t.Transport.RoundTrip(req)
+}
+
+// warningHeaders are the response headers through which an API announces
+// the deprecation or removal of an endpoint, or a degraded service
+var warningHeaders = []string{"Deprecation", "Sunset", "Warning"}
+
+// DeprecationTransport is an http.RoundTripper logging the Deprecation,
+// Sunset and Warning headers of the responses, so that calls to an endpoint
+// about to be removed, or made during a maintenance window, are noticed.
+// Each header is logged once per method, path and value.
+type DeprecationTransport struct {
+	Transport http.RoundTripper
+	Logger    Logger
+
+	mu     sync.Mutex
+	logged map[string]bool
+}
+
+// NewDeprecationTransport returns a transport sending requests through
+// transport (or http.DefaultTransport when nil) and logging the warning
+// headers of the responses to logger.
+func NewDeprecationTransport(transport http.RoundTripper, logger Logger) *DeprecationTransport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &DeprecationTransport{Transport: transport, Logger: logger, logged: make(map[string]bool)}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *DeprecationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	for _, header := range warningHeaders {
+		for _, value := range resp.Header.Values(header) {
+			if t.firstTime(req.Method + " " + req.URL.Path + " " + header + ": " + value) {
+				t.Logger.Printf("WARNING: %s %s answered with %s: %s", req.Method, req.URL.Path, header, value)
+			}
+		}
+	}
+	return resp, nil
+}
+
+// firstTime reports whether key is seen for the first time
+func (t *DeprecationTransport) firstTime(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.logged[key] {
+		return false
+	}
+	t.logged[key] = true
+	return true
+}
+
+// bodyReadError is the failure to read a response body, cut short by the
+// connection, as opposed to a complete body which does not decode
+type bodyReadError struct {
+	err error
+}
+
+func (e *bodyReadError) Error() string { return "reading response body: " + e.err.Error() }
+func (e *bodyReadError) Unwrap() error { return e.err }
+
+// bodyReadTransport is an http.RoundTripper returning the errors reading
+// response bodies as *bodyReadError. go-ovh returns them as is, while it
+// returns a bare io.ErrUnexpectedEOF for a truncated JSON document.
+type bodyReadTransport struct {
+	transport http.RoundTripper // http.DefaultTransport when nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *bodyReadTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	resp.Body = &bodyReader{ReadCloser: resp.Body}
+	return resp, nil
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport,
+// for http.Client.CloseIdleConnections.
+func (t *bodyReadTransport) CloseIdleConnections() {
+	transport := t.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if closer, ok := transport.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// bodyReader marks the read errors of a response body
+type bodyReader struct {
+	io.ReadCloser
+}
+
+func (r *bodyReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = &bodyReadError{err: err}
+	}
+	return n, err
+}
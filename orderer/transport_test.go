@@ -0,0 +1,67 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitedTransport(t *testing.T) {
+	var sent atomic.Int32
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		sent.Add(1)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+	})
+	send := func(transport http.RoundTripper, ctx context.Context) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://eu.api.ovh.com/1.0/me", nil)
+		resp, err := transport.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	t.Run("over the limit", func(t *testing.T) {
+		sent.Store(0)
+		transport := NewRateLimitedTransport(next, rate.NewLimiter(rate.Every(100*time.Millisecond), 1))
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			if err := send(transport, context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// The first call takes the burst, the others wait a token each
+		if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+			t.Errorf("3 calls in %s, want them to wait about 200ms", elapsed)
+		}
+		if waited := transport.Waited(); waited < 150*time.Millisecond {
+			t.Errorf("Waited() = %s, want about 200ms", waited)
+		}
+		if n := sent.Load(); n != 3 {
+			t.Errorf("%d calls sent, want 3", n)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		sent.Store(0)
+		transport := NewRateLimitedTransport(next, rate.NewLimiter(rate.Every(time.Hour), 1))
+		if err := send(transport, context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		if err := send(transport, ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want %v", err, context.Canceled)
+		}
+		if n := sent.Load(); n != 1 {
+			t.Errorf("%d calls sent, want only the first", n)
+		}
+	})
+}
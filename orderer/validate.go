@@ -0,0 +1,50 @@
+package orderer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// durationPattern matches ISO 8601 periods such as P1M, P12M, P1Y or P1Y6M.
+// Time components (PT1H) are not meaningful for billing and are rejected.
+var durationPattern = regexp.MustCompile(`^P(\d+Y)?(\d+M)?(\d+W)?(\d+D)?$`)
+
+// ValidateDuration checks that duration is an ISO 8601 period usable as a
+// billing duration.
+func ValidateDuration(duration string) error {
+	if duration == "P" || !durationPattern.MatchString(duration) {
+		return fmt.Errorf("invalid duration %q: expected an ISO 8601 period such as P1M (1 month) or P12M (12 months)", duration)
+	}
+	return nil
+}
+
+// maxDurationMonths bounds the durations durationMonths counts, far above
+// any billing period, so that huge values cannot overflow
+const maxDurationMonths = 1200
+
+// durationMonths returns the number of months of a valid duration, and false
+// when it is not a whole number of months, e.g. P1W, or is out of bounds
+func durationMonths(duration string) (int, bool) {
+	match := durationPattern.FindStringSubmatch(duration)
+	if match == nil || match[3] != "" || match[4] != "" {
+		return 0, false
+	}
+	months := 0
+	if match[1] != "" {
+		years, err := strconv.Atoi(strings.TrimSuffix(match[1], "Y"))
+		if err != nil || years > maxDurationMonths/12 {
+			return 0, false
+		}
+		months += 12 * years
+	}
+	if match[2] != "" {
+		m, err := strconv.Atoi(strings.TrimSuffix(match[2], "M"))
+		if err != nil || m > maxDurationMonths {
+			return 0, false
+		}
+		months += m
+	}
+	return months, months > 0 && months <= maxDurationMonths
+}
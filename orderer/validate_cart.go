@@ -0,0 +1,220 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCartInvalid is returned, wrapped in a CartValidationError, when the
+// cart of an order fails validation before checkout.
+var ErrCartInvalid = errors.New("cart is not ready for checkout")
+
+// CartValidationError lists the problems found in a cart before checkout.
+type CartValidationError struct {
+	CartID   string
+	Problems []string
+}
+
+func (e *CartValidationError) Error() string {
+	return fmt.Sprintf("cart %s is not ready for checkout: %s", e.CartID, strings.Join(e.Problems, "; "))
+}
+
+// Unwrap makes errors.Is(err, ErrCartInvalid) match.
+func (e *CartValidationError) Unwrap() error {
+	return ErrCartInvalid
+}
+
+// configuredLabels returns the values of the configuration labels set on a
+// cart item
+func (o *Orderer) configuredLabels(ctx context.Context, cartID string, itemID int64) (map[string]string, error) {
+	var ids []int64
+	path := fmt.Sprintf("/order/cart/%s/item/%d/configuration", cartID, itemID)
+	if err := o.client.GetWithContext(ctx, path, &ids); err != nil {
+		return nil, fmt.Errorf("error listing the configuration of item %d: %w", itemID, err)
+	}
+	labels := make(map[string]string, len(ids))
+	for _, id := range ids {
+		var config struct {
+			Label string `json:"label"`
+			Value string `json:"value"`
+		}
+		if err := o.client.GetWithContext(ctx, fmt.Sprintf("%s/%d", path, id), &config); err != nil {
+			return nil, fmt.Errorf("error fetching configuration %d of item %d: %w", id, itemID, err)
+		}
+		labels[config.Label] = config.Value
+	}
+	return labels, nil
+}
+
+// validateCart checks, without modifying the cart, that the server item and
+// its options have every required configuration label set and that every
+// item of result is in the cart. It returns a CartValidationError listing all the problems
+// found, or nil.
+func (o *Orderer) validateCart(ctx context.Context, cartID string, itemID int64, result *OrderResult) error {
+	required, err := o.requiredConfiguration(ctx, cartID, itemID)
+	if err != nil {
+		return err
+	}
+	labels, err := o.configuredLabels(ctx, cartID, itemID)
+	if err != nil {
+		return err
+	}
+	var items []int64
+	if err := o.client.GetWithContext(ctx, fmt.Sprintf("/order/cart/%s/item", cartID), &items); err != nil {
+		return fmt.Errorf("error listing the items of the cart: %w", err)
+	}
+
+	var problems []string
+	for _, config := range required {
+		if _, ok := labels[config.Label]; config.Required && !ok {
+			problems = append(problems, fmt.Sprintf("missing configuration label %s", config.Label))
+		}
+	}
+	for _, item := range result.Items {
+		if !containsItem(items, item.ItemID) {
+			problems = append(problems, fmt.Sprintf("%s %s (item %d) is not in the cart", item.Role, item.PlanCode, item.ItemID))
+			continue
+		}
+		if item.Role != ItemRoleOption {
+			continue
+		}
+		// Hardware options such as a RAID controller mode have labels of
+		// their own, set through the configuration of the option
+		missing, err := o.missingConfiguration(ctx, cartID, item.ItemID)
+		if err != nil {
+			return err
+		}
+		for _, config := range missing {
+			problem := fmt.Sprintf("missing configuration label %s of option %s (item %d)", config.Label, item.PlanCode, item.ItemID)
+			if len(config.AllowedValues) > 0 {
+				problem += ", allowed values: " + strings.Join(config.AllowedValues, ", ")
+			}
+			problems = append(problems, problem)
+		}
+	}
+	if len(problems) > 0 {
+		return &CartValidationError{CartID: cartID, Problems: problems}
+	}
+	o.logger.Printf("Cart validated: %d configuration labels set, %d items in the cart.", len(labels), len(result.Items))
+	return nil
+}
+
+// missingConfiguration returns the required configuration labels of a cart
+// item that are not set
+func (o *Orderer) missingConfiguration(ctx context.Context, cartID string, itemID int64) ([]RequiredConfiguration, error) {
+	required, err := o.requiredConfiguration(ctx, cartID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	var missing []RequiredConfiguration
+	var labels map[string]string
+	for _, config := range required {
+		if !config.Required {
+			continue
+		}
+		if labels == nil {
+			if labels, err = o.configuredLabels(ctx, cartID, itemID); err != nil {
+				return nil, err
+			}
+		}
+		if _, ok := labels[config.Label]; !ok {
+			missing = append(missing, config)
+		}
+	}
+	return missing, nil
+}
+
+// ErrCartIncomplete is returned, wrapped, when the summary of a cart about
+// to be checked out lacks items the order put in it, or is empty. The cart
+// is left as is for inspection.
+var ErrCartIncomplete = errors.New("cart summary is missing items")
+
+// ErrCartUnexpectedLines is returned, wrapped, when the summary of a cart
+// about to be checked out bills items the order did not put in it, or bills
+// a line twice. The cart is left as is for inspection.
+var ErrCartUnexpectedLines = errors.New("cart summary has unexpected lines")
+
+// checkSummaryItems verifies that summary has a line for each of items, or
+// at least one line when items is empty, e.g. for a cart built by another
+// run, and that no line is for an item other than items or repeats another
+// one: an item has a line per thing it bills, e.g. its period and its
+// installation, but never twice the same. Summaries whose lines do not
+// carry their cart item are checked on the number of lines only.
+func checkSummaryItems(cartID string, summary *CartSummary, items []CartItem) error {
+	expected := make([]string, 0, len(items))
+	for _, item := range items {
+		expected = append(expected, fmt.Sprintf("%s %s (item %d)", item.Role, item.PlanCode, item.ItemID))
+	}
+	if len(summary.Details) == 0 && len(items) == 0 {
+		return fmt.Errorf("%w: cart %s is empty", ErrCartIncomplete, cartID)
+	}
+	if len(summary.Details) == 0 {
+		return fmt.Errorf("%w: cart %s is empty, expected %s", ErrCartIncomplete, cartID, strings.Join(expected, ", "))
+	}
+
+	found := make([]string, 0, len(summary.Details))
+	lines := make(map[int64]bool, len(summary.Details))
+	type lineKey struct {
+		itemID                  int64
+		detailType, description string
+	}
+	seen := make(map[lineKey]bool, len(summary.Details))
+	var unexpected []string
+	for _, detail := range summary.Details {
+		line := fmt.Sprintf("%s (item %d)", detail.Description, detail.CartItemID)
+		found = append(found, line)
+		key := lineKey{detail.CartItemID, detail.DetailType, detail.Description}
+		if seen[key] {
+			unexpected = append(unexpected, "duplicate line "+line)
+		}
+		seen[key] = true
+		if detail.CartItemID == 0 {
+			continue
+		}
+		if !lines[detail.CartItemID] && len(items) > 0 && !hasCartItem(items, detail.CartItemID) {
+			unexpected = append(unexpected, "line "+line+" of no item of the order")
+		}
+		lines[detail.CartItemID] = true
+	}
+	if len(unexpected) > 0 {
+		return fmt.Errorf("%w: cart %s has %s; expected %s", ErrCartUnexpectedLines, cartID, strings.Join(unexpected, ", "), strings.Join(expected, ", "))
+	}
+	var missing []string
+	switch {
+	case len(lines) == 0 && len(summary.Details) < len(items):
+		missing = append(missing, fmt.Sprintf("%d lines for %d items", len(summary.Details), len(items)))
+	case len(lines) > 0:
+		for i, item := range items {
+			if !lines[item.ItemID] {
+				missing = append(missing, expected[i])
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: cart %s lacks %s; expected %s, found %s",
+			ErrCartIncomplete, cartID, strings.Join(missing, ", "), strings.Join(expected, ", "), strings.Join(found, ", "))
+	}
+	return nil
+}
+
+// hasCartItem reports whether items has the item itemID
+func hasCartItem(items []CartItem, itemID int64) bool {
+	for _, item := range items {
+		if item.ItemID == itemID {
+			return true
+		}
+	}
+	return false
+}
+
+// containsItem reports whether items contains itemID
+func containsItem(items []int64, itemID int64) bool {
+	for _, item := range items {
+		if item == itemID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package orderer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckSummaryItems(t *testing.T) {
+	items := []CartItem{
+		{ItemID: 1, Role: ItemRoleServer, PlanCode: "24ska01"},
+		{ItemID: 2, Role: ItemRoleOption, PlanCode: "ram-32g", ParentID: 1},
+	}
+	line := func(itemID int64, detailType string) SummaryDetail {
+		return SummaryDetail{CartItemID: itemID, DetailType: detailType, Description: detailType}
+	}
+	tests := []struct {
+		name    string
+		items   []CartItem
+		details []SummaryDetail
+		wantErr error
+	}{
+		{
+			name:    "every item billed",
+			items:   items,
+			details: []SummaryDetail{line(1, "DURATION"), line(1, "INSTALLATION"), line(2, "DURATION")},
+		},
+		{
+			name:    "missing item",
+			items:   items,
+			details: []SummaryDetail{line(1, "DURATION")},
+			wantErr: ErrCartIncomplete,
+		},
+		{
+			name:    "empty cart",
+			wantErr: ErrCartIncomplete,
+		},
+		{
+			name:    "item of no order item",
+			items:   items,
+			details: []SummaryDetail{line(1, "DURATION"), line(2, "DURATION"), line(3, "DURATION")},
+			wantErr: ErrCartUnexpectedLines,
+		},
+		{
+			name:    "duplicate line",
+			items:   items,
+			details: []SummaryDetail{line(1, "DURATION"), line(2, "DURATION"), line(2, "DURATION")},
+			wantErr: ErrCartUnexpectedLines,
+		},
+		{
+			name:    "cart of another run",
+			details: []SummaryDetail{line(7, "DURATION"), line(8, "DURATION")},
+		},
+		{
+			name:    "lines without items",
+			items:   items,
+			details: []SummaryDetail{{Description: "server"}, {Description: "ram"}},
+		},
+		{
+			name:    "too few lines without items",
+			items:   items,
+			details: []SummaryDetail{{Description: "server"}},
+			wantErr: ErrCartIncomplete,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSummaryItems("cart-1", &CartSummary{Details: tt.details}, tt.items)
+			if tt.wantErr == nil && err != nil || !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package orderer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// WatchedOrder is an order whose delivery is awaited by Watch.
+type WatchedOrder struct {
+	OrderID string    `json:"orderId"`
+	Added   time.Time `json:"added"`
+
+	// RunID and CustomerReference are those of the run that placed the
+	// order, passed on in its notifications.
+	RunID             string `json:"runId,omitempty"`
+	CustomerReference string `json:"customerReference,omitempty"`
+}
+
+// WatchList is a JSON file listing the orders Watch waits for. It outlives
+// the processes using it: an order run adds its order and exits, and a
+// watch started later, or restarted, resumes every order still listed.
+// Changes are made under a lock file next to it, so that several processes
+// can add orders while a watch removes the delivered ones.
+type WatchList struct {
+	Path string
+
+	// FS holds the list and its lock file. Defaults to the disk.
+	FS FS
+
+	// mu serializes the changes of this process, the lock file those of
+	// other processes
+	mu sync.Mutex
+}
+
+// watchLockStale is how old a lock file of a WatchList must be to be
+// considered left by a dead process and removed
+const watchLockStale = 30 * time.Second
+
+// Orders returns the orders of the list, none when the file does not exist.
+func (l *WatchList) Orders() ([]WatchedOrder, error) {
+	data, err := fsOrDisk(l.FS).ReadFile(l.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading watch list: %w", err)
+	}
+	var orders []WatchedOrder
+	if err := json.Unmarshal(data, &orders); err != nil {
+		return nil, fmt.Errorf("invalid watch list %s: %w", l.Path, err)
+	}
+	return orders, nil
+}
+
+// Add adds order to the list, unless it is already listed.
+func (l *WatchList) Add(order WatchedOrder) error {
+	return l.update(func(orders []WatchedOrder) []WatchedOrder {
+		for _, listed := range orders {
+			if listed.OrderID == order.OrderID {
+				return orders
+			}
+		}
+		return append(orders, order)
+	})
+}
+
+// Remove removes the order orderID from the list.
+func (l *WatchList) Remove(orderID string) error {
+	return l.update(func(orders []WatchedOrder) []WatchedOrder {
+		kept := orders[:0]
+		for _, listed := range orders {
+			if listed.OrderID != orderID {
+				kept = append(kept, listed)
+			}
+		}
+		return kept
+	})
+}
+
+// update replaces the orders of the list with those change returns
+func (l *WatchList) update(change func([]WatchedOrder) []WatchedOrder) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// The list may be the first file of its directory, e.g. the state
+	// directory of the user
+	if err := fsOrDisk(l.FS).MkdirAll(filepath.Dir(l.Path), 0o700); err != nil {
+		return fmt.Errorf("error creating the directory of the watch list: %w", err)
+	}
+	unlock, err := l.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	orders, err := l.Orders()
+	if err != nil {
+		return err
+	}
+	orders = change(orders)
+	if orders == nil {
+		orders = []WatchedOrder{}
+	}
+	data, err := json.MarshalIndent(orders, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fsOrDisk(l.FS).WriteFile(l.Path, data, 0o600); err != nil {
+		return fmt.Errorf("error writing watch list: %w", err)
+	}
+	return nil
+}
+
+// lock creates the lock file of the list, waiting for other processes to
+// release it, and returns the function releasing it
+func (l *WatchList) lock() (func(), error) {
+	fsys := fsOrDisk(l.FS)
+	path := l.Path + ".lock"
+	deadline := time.Now().Add(2 * watchLockStale)
+	for {
+		err := fsys.CreateExclusive(path, 0o600)
+		if err == nil {
+			return func() { fsys.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("error locking watch list: %w", err)
+		}
+		if info, err := fsys.Stat(path); err == nil && time.Since(info.ModTime()) > watchLockStale {
+			fsys.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("error locking watch list: %s is held by another process", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// WatchOptions configures Watch.
+type WatchOptions struct {
+	// Notifier, when set, is told of each delivery (WebhookDelivered) and of
+	// each order whose delivery cannot be awaited (WebhookFailed).
+	Notifier Notifier
+
+	// Follow keeps watching once the list is empty, for orders added later.
+	// Otherwise Watch returns once every order is delivered or failed.
+	Follow bool
+
+	// Rescan is how often the list is read again for new orders. Defaults
+	// to 1 minute.
+	Rescan time.Duration
+}
+
+// watchOutcome is the end of the wait for one order of a watch
+type watchOutcome struct {
+	order       WatchedOrder
+	serviceName string
+	err         error
+}
+
+// Watch waits, concurrently, for the delivery of every order of list,
+// including the orders added to it while it runs, bounded by
+// Options.DeliveryTimeout each. A delivered order, or one whose wait fails,
+// is notified to opts.Notifier and removed from the list. When ctx is
+// done, Watch returns and the orders still awaited stay listed, to be
+// resumed by the next watch.
+func (o *Orderer) Watch(ctx context.Context, list *WatchList, opts WatchOptions) error {
+	if opts.Rescan <= 0 {
+		opts.Rescan = time.Minute
+	}
+	outcomes := make(chan watchOutcome)
+	watching := make(map[string]bool)
+	for {
+		orders, err := list.Orders()
+		if err != nil {
+			return err
+		}
+		for _, order := range orders {
+			if watching[order.OrderID] {
+				continue
+			}
+			watching[order.OrderID] = true
+			o.logger.Printf("Watching order %s, added %s", order.OrderID, order.Added.Format(time.RFC3339))
+			go func(order WatchedOrder) {
+				serviceName, err := o.WaitForDelivery(ctx, order.OrderID)
+				outcomes <- watchOutcome{order: order, serviceName: serviceName, err: err}
+			}(order)
+		}
+		if len(watching) == 0 && !opts.Follow {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+		case outcome := <-outcomes:
+			delete(watching, outcome.order.OrderID)
+			if ctx.Err() != nil {
+				break
+			}
+			if err := o.watched(ctx, list, outcome, opts.Notifier); err != nil {
+				return err
+			}
+			continue
+		case <-o.clock.After(opts.Rescan):
+			continue
+		}
+		// The waits return with ctx, their orders stay listed
+		for range watching {
+			<-outcomes
+		}
+		return ctx.Err()
+	}
+}
+
+// watched notifies the outcome of the wait for an order and removes the
+// order from list
+func (o *Orderer) watched(ctx context.Context, list *WatchList, outcome watchOutcome, notifier Notifier) error {
+	payload := WebhookPayload{
+		OrderID:     outcome.order.OrderID,
+		ServiceName: outcome.serviceName,
+		RunID:       outcome.order.RunID,
+		Timestamp:   o.clock.Now(),
+
+		CustomerReference: outcome.order.CustomerReference,
+	}
+	if outcome.err != nil {
+		o.logger.Printf("Order %s: %v", outcome.order.OrderID, outcome.err)
+		payload.Status, payload.Step, payload.Error = WebhookFailed, StepDelivery, outcome.err.Error()
+	} else {
+		o.logger.Printf("Order %s delivered: %s", outcome.order.OrderID, outcome.serviceName)
+		payload.Status = WebhookDelivered
+	}
+	if notifier != nil {
+		if err := notifier.Notify(ctx, payload); err != nil {
+			o.logger.Printf("Error sending notification: %v", err)
+		}
+	}
+	return list.Remove(outcome.order.OrderID)
+}
@@ -0,0 +1,132 @@
+package orderer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Two processes sharing a watch list add orders concurrently
+func TestWatchListConcurrentAdds(t *testing.T) {
+	fsys := newMemFS(nil)
+	lists := []*WatchList{{Path: "/state/watch.json", FS: fsys}, {Path: "/state/watch.json", FS: fsys}}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := lists[i%2].Add(WatchedOrder{OrderID: fmt.Sprint(i)}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	orders, err := lists[0].Orders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 10 {
+		t.Errorf("%d orders listed, want 10: %+v", len(orders), orders)
+	}
+	if _, err := fsys.Stat("/state/watch.json.lock"); err == nil {
+		t.Error("lock file left behind")
+	}
+
+	// Adding an order twice lists it once
+	if err := lists[0].Add(WatchedOrder{OrderID: "3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := lists[0].Remove("4"); err != nil {
+		t.Fatal(err)
+	}
+	if orders, _ := lists[1].Orders(); len(orders) != 9 {
+		t.Errorf("%d orders listed, want 9: %+v", len(orders), orders)
+	}
+}
+
+// notifierFunc is a Notifier calling itself
+type notifierFunc func(payload WebhookPayload) error
+
+func (f notifierFunc) Notify(ctx context.Context, payload WebhookPayload) error {
+	return f(payload)
+}
+
+func TestWatch(t *testing.T) {
+	var mu sync.Mutex
+	checks := make(map[string]int)
+	client := &stubClient{answer: func(method, path string, body interface{}) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		checks[path]++
+		switch path {
+		case "/me/order/1/status":
+			if checks[path] == 1 {
+				return "delivering", nil
+			}
+			return "delivered", nil
+		case "/me/order/1/details":
+			return []int64{10, 11}, nil
+		case "/me/order/1/details/10":
+			return map[string]interface{}{"domain": "*"}, nil
+		case "/me/order/1/details/11":
+			return map[string]interface{}{"domain": "ns123.ip-1-2-3.eu"}, nil
+		case "/me/order/2/status":
+			return "cancelled", nil
+		case "/me/order/3/status":
+			return "delivering", nil
+		}
+		return nil, fmt.Errorf("unexpected call %s %s", method, path)
+	}}
+	o := New(client, Options{
+		DeliveryPollInterval:      time.Millisecond,
+		DeliveryBuildPollInterval: time.Millisecond,
+		DeliveryMaxPollInterval:   time.Millisecond,
+	})
+
+	t.Run("delivered and failed", func(t *testing.T) {
+		list := &WatchList{Path: "/state/watch.json", FS: newMemFS(nil)}
+		for _, orderID := range []string{"1", "2"} {
+			if err := list.Add(WatchedOrder{OrderID: orderID, RunID: "run-" + orderID}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		payloads := make(map[string]WebhookPayload)
+		notifier := notifierFunc(func(payload WebhookPayload) error {
+			payloads[payload.OrderID] = payload
+			return nil
+		})
+
+		if err := o.Watch(context.Background(), list, WatchOptions{Notifier: notifier, Rescan: time.Millisecond}); err != nil {
+			t.Fatal(err)
+		}
+		if p := payloads["1"]; p.Status != WebhookDelivered || p.ServiceName != "ns123.ip-1-2-3.eu" || p.RunID != "run-1" {
+			t.Errorf("order 1 notified %+v", p)
+		}
+		if p := payloads["2"]; p.Status != WebhookFailed || p.Step != StepDelivery {
+			t.Errorf("order 2 notified %+v", p)
+		}
+		if orders, _ := list.Orders(); len(orders) != 0 {
+			t.Errorf("orders still listed: %+v", orders)
+		}
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		list := &WatchList{Path: "/state/watch.json", FS: newMemFS(nil)}
+		if err := list.Add(WatchedOrder{OrderID: "3"}); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := o.Watch(ctx, list, WatchOptions{Rescan: time.Millisecond}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("err = %v, want context.DeadlineExceeded", err)
+		}
+		// The next watch resumes the order
+		if orders, _ := list.Orders(); len(orders) != 1 {
+			t.Errorf("orders listed: %+v, want order 3", orders)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package orderer
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Webhook statuses, sent when an order reaches the milestone or fails.
+const (
+	WebhookPaid      = "paid"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+)
+
+// WebhookPayload is the JSON body posted to a webhook, and the notification
+// passed to every Notifier.
+type WebhookPayload struct {
+	OrderID     string    `json:"orderId"`
+	Status      string    `json:"status"`
+	ServiceName string    `json:"serviceName,omitempty"`
+	RunID       string    `json:"runId,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+
+	// CustomerReference is that of the order, if any.
+	CustomerReference string `json:"customerReference,omitempty"`
+
+	// Step and Error are those of the failure when Status is WebhookFailed.
+	Step  string `json:"step,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Webhook posts a WebhookPayload to URL when an order is paid, delivered or
+// fails. Its OnEvent method is meant for Options.OnEvent.
+type Webhook struct {
+	URL string
+
+	// Secret, when set, signs the body with HMAC-SHA256, sent hex encoded as
+	// "X-Signature: sha256=<signature>".
+	Secret string
+
+	// Client defaults to a client giving up after DefaultNotifyTimeout.
+	Client *http.Client
+
+	// Attempts is the number of times a delivery is attempted. Defaults to 3.
+	Attempts int
+
+	// Timeout bounds the deliveries of OnEvent, attempts included. Defaults
+	// to DefaultNotifyTimeout.
+	Timeout time.Duration
+
+	// Logger receives the delivery failures, which never fail the order.
+	// Defaults to discarding them.
+	Logger Logger
+
+	// RunID, when set, identifies the run in the payloads.
+	RunID string
+}
+
+// OnEvent notifies the webhook when the payment or delivery step of an order
+// succeeds, or when a step fails.
+func (w *Webhook) OnEvent(event Event) {
+	NotifyEvents(w, w.RunID, w.Timeout, w.Logger)(event)
+}
+
+// Notify posts payload to the webhook, retrying with a doubling delay until
+// it answers with a 2xx status, the attempts are exhausted or ctx is done.
+func (w *Webhook) Notify(ctx context.Context, payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	client := w.Client
+	if client == nil {
+		client = notifyClient
+	}
+	attempts := w.Attempts
+	if attempts < 1 {
+		attempts = 3
+	}
+
+	delay := time.Second
+	for attempt := 1; ; attempt++ {
+		err = w.post(ctx, client, body)
+		if err == nil || attempt == attempts {
+			break
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("webhook %s, %d attempts: %w", w.URL, attempt, ctx.Err())
+		}
+		delay *= 2
+	}
+	if err != nil {
+		return fmt.Errorf("webhook %s, %d attempts: %w", w.URL, attempts, err)
+	}
+	return nil
+}
+
+// post makes a single delivery attempt
+func (w *Webhook) post(ctx context.Context, client *http.Client, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
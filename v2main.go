@@ -1,3 +1,5 @@
+//go:build ignore
+
 package main
 
 import (
@@ -6,6 +8,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ovh/go-ovh/ovh"
@@ -75,11 +78,37 @@ func main() {
 	fmt.Printf("Added Server to Cart with Item ID: %d\n", itemID)
 
 	// Step 4: Configure the server (dedicated_os, region, dedicated_datacenter)
+	// The "no OS" value differs between plans, so look it up in the values
+	// the plan allows for dedicated_os instead of hardcoding it
+	var requiredConfig []struct {
+		Label         string   `json:"label"`
+		AllowedValues []string `json:"allowedValues"`
+	}
+	err = client.Get(fmt.Sprintf("/order/cart/%s/item/%d/requiredConfiguration", cartID, itemID), &requiredConfig)
+	if err != nil {
+		log.Fatalf("Error fetching required configuration: %v", err)
+	}
+	noOS := ""
+	for _, config := range requiredConfig {
+		if config.Label != "dedicated_os" {
+			continue
+		}
+		for _, value := range config.AllowedValues {
+			if strings.HasPrefix(value, "none_") {
+				noOS = value
+				break
+			}
+		}
+	}
+	if noOS == "" {
+		log.Fatalf("No \"none\" value allowed for dedicated_os by this plan")
+	}
+
 	configItems := []struct {
 		Label string
 		Value string
 	}{
-		{"dedicated_os", "none_64_en"},
+		{"dedicated_os", noOS},
 		{"region", "united_states"},
 		{"dedicated_datacenter", "hil"},
 	}
@@ -1,25 +1,375 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
+	"github.com/mediocre232/OVHAPIdedicatedserver/orderer"
 	"github.com/ovh/go-ovh/ovh"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+	"gopkg.in/yaml.v3"
 )
 
 func main() {
-	// Retrieve OVH API credentials from environment variables
-	endpoint := os.Getenv("OVH_ENDPOINT")
-	appKey := os.Getenv("OVH_APPLICATION_KEY")
-	appSecret := os.Getenv("OVH_APPLICATION_SECRET")
-	consumerKey := os.Getenv("OVH_CONSUMER_KEY")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "describe-plan":
+			describePlan(os.Args[2:])
+			return
+		case "list-plans":
+			listPlans(os.Args[2:])
+			return
+		case "list-options":
+			listOptions(os.Args[2:])
+			return
+		case "list-datacenters":
+			listDatacenters(os.Args[2:])
+			return
+		case "list-orders":
+			listOrders(os.Args[2:])
+			return
+		case "report":
+			reportOrders(os.Args[2:])
+			return
+		case "list-carts":
+			listCarts(os.Args[2:])
+			return
+		case "clean-carts":
+			cleanCarts(os.Args[2:])
+			return
+		case "pay":
+			payOrder(os.Args[2:])
+			return
+		case "purchase":
+			purchaseCart(os.Args[2:])
+			return
+		case "cancel":
+			cancelOrder(os.Args[2:])
+			return
+		case "terminate":
+			terminateService(os.Args[2:])
+			return
+		case "service-info":
+			serviceInfo(os.Args[2:])
+			return
+		case "smoke":
+			smoke(os.Args[2:])
+			return
+		case "doctor":
+			doctor(os.Args[2:])
+			return
+		case "check-auth":
+			checkAuth(os.Args[2:])
+			return
+		case "validate-config":
+			validateConfig(os.Args[2:])
+			return
+		case "diff-config":
+			diffConfig(os.Args[2:])
+			return
+		case "watch":
+			watchOrders(os.Args[2:])
+			return
+		case "paths":
+			printPaths(os.Args[2:])
+			return
+		}
+	}
+
+	orderServer(os.Args[1:])
+}
+
+// Exit codes of the commands, so that pipelines and schedulers can react to
+// each class of failure. Flag parsing errors exit with exitUsage too.
+const (
+	exitOK         = 0
+	exitUsage      = 2 // invalid flags, config or request
+	exitAuth       = 3 // missing credentials, denied access, endpoint not allowed
+	exitOutOfStock = 4 // the server or an option is out of stock
+	exitPayment    = 5 // the order could not be paid, or its payment is pending
+	exitTimeout    = 6 // a wait (stock, payment, delivery, task) timed out
+	exitFailure    = 7 // API and any other errors
+)
+
+// exitCode returns the exit code for a command failing with err
+func exitCode(err error) int {
+	var apiErr *ovh.APIError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &apiErr) && (apiErr.Code == http.StatusUnauthorized || apiErr.Code == http.StatusForbidden),
+		errors.Is(err, orderer.ErrEndpointNotAllowed),
+		errors.Is(err, orderer.ErrBillingAccountMismatch),
+		errors.Is(err, orderer.ErrCartNotAssigned),
+		errors.Is(err, orderer.ErrCredentialInvalid):
+		return exitAuth
+	case errors.Is(err, orderer.ErrOutOfStock):
+		return exitOutOfStock
+	case errors.Is(err, orderer.ErrNoPaymentMethod),
+		errors.Is(err, orderer.ErrInteractivePaymentRequired),
+		errors.Is(err, orderer.ErrPaymentPending),
+		errors.Is(err, orderer.ErrPaymentFailed):
+		return exitPayment
+	case errors.Is(err, orderer.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	case errors.Is(err, orderer.ErrEngagementNotAcknowledged),
+		errors.Is(err, orderer.ErrIllegalPricing),
+		errors.Is(err, orderer.ErrPricingModeMismatch),
+		errors.Is(err, orderer.ErrOptionNotOffered),
+		errors.Is(err, orderer.ErrMissingPrerequisite),
+		errors.Is(err, orderer.ErrPolicyViolation):
+		return exitUsage
+	}
+	return exitFailure
+}
+
+// jsonErrors, when set, receives the error a command fails with as a
+// jsonError, for -output json
+var jsonErrors io.Writer
+
+// jsonError is the error object printed on failure with -output json
+type jsonError struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"`
+
+	// Step is the step of the order that failed, and QueryID the ID of the
+	// failed OVH API call, to quote to the OVH support
+	Step      string `json:"step,omitempty"`
+	QueryID   string `json:"queryId,omitempty"`
+	Retryable bool   `json:"retryable"`
+}
+
+// newJSONError returns the error object of err, exiting with code
+func newJSONError(err error, code int) jsonError {
+	e := jsonError{Error: err.Error(), Code: code, Retryable: orderer.IsRetryable(err)}
+	var stepErr *orderer.StepError
+	if errors.As(err, &stepErr) {
+		e.Step = stepErr.Step
+	}
+	var apiErr *ovh.APIError
+	if errors.As(err, &apiErr) {
+		e.QueryID = apiErr.QueryID
+	}
+	return e
+}
+
+// writeJSONError prints e to jsonErrors, if set
+func writeJSONError(e jsonError) {
+	if jsonErrors == nil {
+		return
+	}
+	if err := json.NewEncoder(jsonErrors).Encode(e); err != nil {
+		log.Printf("Error printing the error: %v", err)
+	}
+}
+
+// fatalf logs a message and exits with code
+func fatalf(code int, format string, v ...interface{}) {
+	log.Printf(format, v...)
+	writeJSONError(jsonError{Error: fmt.Sprintf(format, v...), Code: code})
+	os.Exit(code)
+}
+
+// fatalError logs a message and exits with the exit code of err
+func fatalError(err error, format string, v ...interface{}) {
+	code := exitCode(err)
+	log.Printf(format, v...)
+	writeJSONError(newJSONError(err, code))
+	os.Exit(code)
+}
+
+// strictJSON is set by -strict-json when the client is built, and applies to
+// every Orderer of the command
+var strictJSON bool
+
+// explainer narrates the decisions of the command with -explain, and is nil
+// otherwise. It is set when the client is built, and applies to every
+// Orderer of the command.
+var explainer *log.Logger
+
+// clientFlags are the command line flags shared by all commands to configure
+// the OVH client
+type clientFlags struct {
+	rate            *float64
+	burst           *int
+	simulate        *string
+	simulateLatency *time.Duration
+	userAgent       *string
+	cacheTTL        *time.Duration
+	cacheDir        *string
+	refreshCatalog  *bool
+	caCert          *string
+	insecureTLS     *bool
+	strictJSON      *bool
+	explain         *bool
+
+	// limiter is the rate limiter of the client built by newClient, if any
+	limiter *orderer.RateLimitedTransport
+
+	// The credentials, which take precedence over the OVH_* variables, are
+	// cleared once the client is built
+	endpoint    *string
+	appKey      *string
+	appSecret   *string
+	consumerKey *string
+}
+
+// registerClientFlags registers the client flags on fs
+func registerClientFlags(fs *flag.FlagSet) *clientFlags {
+	return &clientFlags{
+		rate:            fs.Float64("rate", 20, "maximum number of API requests per second, shared by all concurrent calls (0 for no limit)"),
+		burst:           fs.Int("burst", 10, "number of API requests allowed in a burst above -rate"),
+		simulate:        fs.String("simulate", "", "replay the recorded fixtures of this file or directory instead of calling the API, to watch a whole flow without credentials"),
+		simulateLatency: fs.Duration("simulate-latency", 200*time.Millisecond, "artificial latency of each API call with -simulate"),
+		userAgent:       fs.String("user-agent", orderer.DefaultUserAgent, "User-Agent sent with every API request"),
+		cacheTTL:        fs.Duration("catalog-cache-ttl", 15*time.Minute, "how long the catalog and availabilities read by discovery are cached on disk (0 disables the cache)"),
+		cacheDir:        fs.String("cache-dir", paths.CacheDir(), "directory of the catalog cache"),
+		refreshCatalog:  fs.Bool("refresh-catalog", false, "refetch the catalog and availabilities even if their cached copy is fresh"),
+		caCert:          fs.String("ca-cert", "", "PEM file of CA certificates trusted in addition to the system ones, e.g. for a TLS-intercepting proxy"),
+		insecureTLS:     fs.Bool("insecure-skip-verify", false, "do not verify the TLS certificate of the API; anyone on the path can then read the credentials and alter orders, use -ca-cert instead whenever possible"),
+		strictJSON:      fs.Bool("strict-json", false, "fail when an order, payment or cart summary response has a field the tool does not know, to catch API changes in CI or smoke tests"),
+		explain:         fs.Bool("explain", false, "print to stderr, alongside the normal output, what the tool is about to do and why before each step and API call, and where its inputs come from (flag, environment, file or default)"),
+		endpoint:        fs.String("endpoint", "", "OVH API endpoint, overriding OVH_ENDPOINT"),
+		appKey:          fs.String("app-key", "", "application key, overriding OVH_APPLICATION_KEY (flags can leak into the shell history, prefer the variable)"),
+		appSecret:       fs.String("app-secret", "", "application secret, overriding OVH_APPLICATION_SECRET (flags can leak into the shell history, prefer the variable)"),
+		consumerKey:     fs.String("consumer-key", "", "consumer key, overriding OVH_CONSUMER_KEY (flags can leak into the shell history, prefer the variable)"),
+	}
+}
+
+// credential returns the value of a credential flag, or of its environment
+// variable when the flag is not given
+func credential(flagValue *string, name string) string {
+	if *flagValue != "" {
+		return *flagValue
+	}
+	return os.Getenv(name)
+}
+
+// credentialSource returns where credential takes its value from
+func credentialSource(flagValue *string, flagName, name string) string {
+	switch {
+	case *flagValue != "":
+		return "the -" + flagName + " flag"
+	case os.Getenv(name) != "":
+		return "the " + name + " environment variable"
+	}
+	return "nowhere, it is not set"
+}
+
+// endpointValue returns the endpoint given by -endpoint or OVH_ENDPOINT
+func (cf *clientFlags) endpointValue() string {
+	return credential(cf.endpoint, "OVH_ENDPOINT")
+}
+
+// explainClient narrates where the client takes its endpoint and
+// credentials from, before they are read
+func (cf *clientFlags) explainClient() {
+	if explainer == nil {
+		return
+	}
+	if path := cf.replayPath(); path != "" {
+		explainer.Printf("API calls are answered from the recorded fixtures %s instead of the API, so no credentials are needed", path)
+		return
+	}
+	explainer.Printf("Endpoint from %s", credentialSource(cf.endpoint, "endpoint", "OVH_ENDPOINT"))
+	explainer.Printf("Application key from %s", credentialSource(cf.appKey, "app-key", "OVH_APPLICATION_KEY"))
+	explainer.Printf("Application secret from %s", credentialSource(cf.appSecret, "app-secret", "OVH_APPLICATION_SECRET"))
+	explainer.Printf("Consumer key from %s", credentialSource(cf.consumerKey, "consumer-key", "OVH_CONSUMER_KEY"))
+	if *cf.rate > 0 {
+		explainer.Printf("API calls are limited to %g per second (-rate), with bursts of %d (-burst), to stay below the rate limit of OVH", *cf.rate, *cf.burst)
+	}
+}
+
+// catalogCache returns the on-disk cache of the discovery calls, in
+// -cache-dir, or nil when it is disabled or there is no cache directory
+func (cf *clientFlags) catalogCache() *orderer.CatalogCache {
+	if *cf.cacheDir == "" || *cf.cacheTTL <= 0 {
+		return nil
+	}
+	return &orderer.CatalogCache{
+		Dir:     *cf.cacheDir,
+		TTL:     *cf.cacheTTL,
+		Refresh: *cf.refreshCatalog,
+	}
+}
+
+// replayPath returns the fixtures answering the API calls instead of the
+// API, or "" when calls go to the API
+func (cf *clientFlags) replayPath() string {
+	if *cf.simulate != "" {
+		return *cf.simulate
+	}
+	return os.Getenv("OVH_REPLAY")
+}
+
+// newClient builds an OVH client from the credential flags and the OVH_*
+// environment variables.
+// OVH_RECORD=<file> records every API call to a fixtures file and
+// OVH_REPLAY=<file> or -simulate answers the calls from such a file instead
+// of the API, in which case no credentials are needed, and
+// OVH_REPLAY_MATCH_BODIES=1 fails calls whose body differs from the recording.
+func (cf *clientFlags) newClient() *ovh.Client {
+	strictJSON = *cf.strictJSON
+	if *cf.explain {
+		explainer = log.New(os.Stderr, "explain: ", 0)
+	}
+	cf.explainClient()
+	// Retrieve OVH API credentials from the flags or environment variables
+	endpoint := cf.endpointValue()
+	appKey := credential(cf.appKey, "OVH_APPLICATION_KEY")
+	appSecret := credential(cf.appSecret, "OVH_APPLICATION_SECRET")
+	consumerKey := credential(cf.consumerKey, "OVH_CONSUMER_KEY")
+	if *cf.appKey != "" || *cf.appSecret != "" || *cf.consumerKey != "" {
+		log.Printf("Warning: credentials passed as flags can leak into the shell history and the process list, prefer the OVH_* environment variables")
+	}
+	// Only the client keeps the credentials from now on
+	*cf.appKey, *cf.appSecret, *cf.consumerKey = "", "", ""
+	replayPath := cf.replayPath()
+	recordPath := os.Getenv("OVH_RECORD")
 
+	if replayPath != "" {
+		// Recorded calls are not signed again, any credentials will do
+		if endpoint == "" {
+			endpoint = "ovh-us"
+		}
+		appKey, appSecret, consumerKey = "replay", "replay", "replay"
+	}
 	if endpoint == "" || appKey == "" || appSecret == "" || consumerKey == "" {
-		log.Fatalf("Please set OVH_ENDPOINT, OVH_APPLICATION_KEY, OVH_APPLICATION_SECRET, and OVH_CONSUMER_KEY environment variables")
+		fatalf(exitAuth, "Please set OVH_ENDPOINT, OVH_APPLICATION_KEY, OVH_APPLICATION_SECRET, and OVH_CONSUMER_KEY environment variables or pass -endpoint, -app-key, -app-secret and -consumer-key")
+	}
+	endpoint, err := orderer.NormalizeEndpoint(endpoint)
+	if err != nil {
+		fatalf(exitUsage, "Invalid OVH_ENDPOINT: %v", err)
 	}
 
 	// Create an OVH client
@@ -30,130 +380,3032 @@ func main() {
 		consumerKey,
 	)
 	if err != nil {
-		log.Fatalf("Error creating OVH client: %v", err)
+		fatalf(exitUsage, "Error creating OVH client: %v", err)
+	}
+
+	var base http.RoundTripper = http.DefaultTransport
+	if tlsConfig := cf.tlsConfig(); tlsConfig != nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = tlsConfig
+		base = t
+	}
+	var transport http.RoundTripper = orderer.NewUserAgentTransport(base, *cf.userAgent)
+	switch {
+	case replayPath != "":
+		replayer, err := orderer.LoadReplayer(files, replayPath)
+		if err != nil {
+			fatalf(exitUsage, "Error loading replay fixtures: %v", err)
+		}
+		if *cf.simulate != "" {
+			replayer.Latency = *cf.simulateLatency
+		}
+		replayer.MatchBodies = os.Getenv("OVH_REPLAY_MATCH_BODIES") == "1"
+		transport = replayer
+	case recordPath != "":
+		transport = orderer.NewRecorder(files, transport, recordPath)
+	}
+	transport = orderer.NewDeprecationTransport(transport, log.New(os.Stderr, "", 0))
+	transport = orderer.NewRetryAfterTransport(transport)
+	if *cf.rate > 0 {
+		cf.limiter = orderer.NewRateLimitedTransport(transport, rate.NewLimiter(rate.Limit(*cf.rate), *cf.burst))
+		transport = cf.limiter
+	}
+	client.Client = &http.Client{Transport: transport}
+	return client
+}
+
+// tlsConfig returns the TLS configuration of -ca-cert and
+// -insecure-skip-verify, or nil to use the default one
+func (cf *clientFlags) tlsConfig() *tls.Config {
+	if *cf.caCert == "" && !*cf.insecureTLS {
+		return nil
+	}
+	config := &tls.Config{}
+	if *cf.caCert != "" {
+		pem, err := files.ReadFile(*cf.caCert)
+		if err != nil {
+			fatalf(exitUsage, "Error reading -ca-cert: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			fatalf(exitUsage, "Invalid -ca-cert %s: no PEM certificate found", *cf.caCert)
+		}
+		config.RootCAs = pool
+	}
+	if *cf.insecureTLS {
+		log.Printf("WARNING: -insecure-skip-verify disables the verification of the API certificate: anyone on the network path can read the credentials and change or place orders on the account")
+		config.InsecureSkipVerify = true
+	}
+	return config
+}
+
+// newOrderer returns an Orderer logging its progress to stdout unless
+// opts.Logger says otherwise
+func newOrderer(client *ovh.Client, opts orderer.Options) *orderer.Orderer {
+	opts.StrictJSON = opts.StrictJSON || strictJSON
+	if opts.Explain == nil && explainer != nil {
+		opts.Explain = explainer
+	}
+	if opts.Logger == nil {
+		opts.Logger = log.New(os.Stdout, "", 0)
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint, _ = orderer.NormalizeEndpoint(client.Endpoint())
+	}
+	return orderer.New(client, opts)
+}
+
+// notifierFlags are the command line flags of the commands notifying the
+// milestones of orders
+type notifierFlags struct {
+	webhookURL      *string
+	webhookSecret   *string
+	slackWebhookURL *string
+	smtpAddr        *string
+	smtpFrom        *string
+	smtpTo          *string
+	smtpUser        *string
+	timeout         *time.Duration
+}
+
+// registerNotifierFlags registers the notification flags on fs
+func registerNotifierFlags(fs *flag.FlagSet) *notifierFlags {
+	return &notifierFlags{
+		webhookURL:      fs.String("webhook-url", "", "URL a JSON notification is posted to when the order is paid and when it is delivered"),
+		webhookSecret:   fs.String("webhook-secret", "", "secret signing the webhook notifications with HMAC-SHA256 (X-Signature header)"),
+		slackWebhookURL: fs.String("slack-webhook-url", "", "Slack incoming webhook URL a message is posted to when the order is paid, delivered or fails"),
+		smtpAddr:        fs.String("smtp-addr", "", "host:port of the SMTP server an email is sent through when the order is paid, delivered or fails"),
+		smtpFrom:        fs.String("smtp-from", "", "sender of the notification emails"),
+		smtpTo:          fs.String("smtp-to", "", "comma separated recipients of the notification emails"),
+		smtpUser:        fs.String("smtp-user", "", "SMTP user name, whose password is read from OVH_SMTP_PASSWORD"),
+		timeout:         fs.Duration("notify-timeout", orderer.DefaultNotifyTimeout, "time after which a notification is given up, retries included, so that it never holds up the order"),
+	}
+}
+
+// notifiers returns the notifiers configured by the flags, exiting when
+// they are incomplete
+func (nf *notifierFlags) notifiers(logger orderer.Logger, runID string) orderer.Notifiers {
+	var notifiers orderer.Notifiers
+	if *nf.webhookURL != "" {
+		notifiers = append(notifiers, &orderer.Webhook{URL: *nf.webhookURL, Secret: *nf.webhookSecret, Logger: logger, RunID: runID, Timeout: *nf.timeout})
+	}
+	if *nf.slackWebhookURL != "" {
+		notifiers = append(notifiers, &orderer.SlackNotifier{WebhookURL: *nf.slackWebhookURL})
+	}
+	if *nf.smtpAddr != "" {
+		if *nf.smtpFrom == "" || *nf.smtpTo == "" {
+			fatalf(exitUsage, "-smtp-addr requires -smtp-from and -smtp-to")
+		}
+		notifiers = append(notifiers, &orderer.EmailNotifier{
+			Addr:     *nf.smtpAddr,
+			From:     *nf.smtpFrom,
+			To:       strings.Split(*nf.smtpTo, ","),
+			Username: *nf.smtpUser,
+			Password: os.Getenv("OVH_SMTP_PASSWORD"),
+			Timeout:  *nf.timeout,
+		})
+	}
+	return notifiers
+}
+
+// registerSubsidiaryFlag registers the -subsidiary flag of the discovery commands
+func registerSubsidiaryFlag(fs *flag.FlagSet) *string {
+	return fs.String("subsidiary", "", "subsidiary whose catalog is queried (defaults to the first one served by the endpoint)")
+}
+
+// discoverySubsidiary returns subsidiary, normalized, or the default
+// subsidiary of the endpoint of client when it is empty
+func discoverySubsidiary(client *ovh.Client, subsidiary string) string {
+	endpoint, _ := orderer.NormalizeEndpoint(client.Endpoint())
+	if subsidiary != "" {
+		return normalizeSubsidiary(subsidiary, endpoint)
+	}
+	if subsidiary = orderer.DefaultSubsidiary(endpoint); subsidiary != "" {
+		return subsidiary
+	}
+	return "US"
+}
+
+// normalizeSubsidiary returns subsidiary in the case OVH expects, exiting
+// when it is unknown or not served by endpoint
+func normalizeSubsidiary(subsidiary, endpoint string) string {
+	normalized, err := orderer.NormalizeSubsidiary(subsidiary, endpoint)
+	if err != nil {
+		fatalf(exitUsage, "Invalid subsidiary: %v", err)
 	}
+	return normalized
+}
+
+// listPlans prints the dedicated server plans offered to a subsidiary
+func listPlans(args []string) {
+	fs := flag.NewFlagSet("list-plans", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	subsidiary := registerSubsidiaryFlag(fs)
+	format := registerFormatFlag(fs)
+	fs.Parse(args)
+	client := clientFlags.newClient()
 
-	// Step 1: Create a new cart
-	cart := make(map[string]interface{})
-	expireDate := time.Now().AddDate(0, 1, 0).Format(time.RFC3339)
-	err = client.Post("/order/cart", map[string]interface{}{
-		"ovhSubsidiary": "US",
-		"description":   "Automated Dedicated Server Order",
-		"expire":        expireDate,
-	}, &cart)
+	o := newOrderer(client, orderer.Options{CatalogCache: clientFlags.catalogCache()})
+	defer o.Close()
+	sub := discoverySubsidiary(client, *subsidiary)
+	plans, err := o.ListPlans(context.Background(), sub)
 	if err != nil {
-		log.Fatalf("Error creating cart: %v", err)
+		fatalError(err, "Error listing plans: %v", err)
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].PlanCode < plans[j].PlanCode })
+
+	type row struct {
+		Plan         string `json:"plan" yaml:"plan"`
+		Name         string `json:"name" yaml:"name"`
+		MonthlyPrice string `json:"monthlyPrice" yaml:"monthlyPrice" table:"MONTHLY PRICE"`
+	}
+	rows := []row{}
+	for _, plan := range plans {
+		price, _ := orderer.PriceFor(plan.Prices, "P1M", "default")
+		rows = append(rows, row{plan.PlanCode, plan.ProductName, displayPrice(*format, sub, price)})
+	}
+	mustRender(*format, rows)
+}
+
+// listOptions prints the options offered for a plan
+func listOptions(args []string) {
+	fs := flag.NewFlagSet("list-options", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	planCode := fs.String("plan", "", "plan code whose options are listed (e.g. 24rise01-us)")
+	kind := fs.String("kind", "", "only list the hardware options (memory, disks, RAID controller, CPU...) or the service options (bandwidth, backups, licenses...): hardware or service")
+	subsidiary := registerSubsidiaryFlag(fs)
+	format := registerFormatFlag(fs)
+	fs.Parse(args)
+	client := clientFlags.newClient()
+	if *planCode == "" {
+		fatalf(exitUsage, "Please specify a plan with -plan")
+	}
+	switch *kind {
+	case "", orderer.OptionKindHardware, orderer.OptionKindService:
+	default:
+		fatalf(exitUsage, "Invalid -kind %q: expected hardware or service", *kind)
 	}
-	cartID := cart["cartId"].(string)
-	fmt.Printf("Created Cart with ID: %s\n", cartID)
 
-	// Step 2: Assign the cart to the logged-in user
-	err = client.Post("/order/cart/"+cartID+"/assign", nil, nil)
+	o := newOrderer(client, orderer.Options{CatalogCache: clientFlags.catalogCache()})
+	defer o.Close()
+	sub := discoverySubsidiary(client, *subsidiary)
+	options, err := o.ListOptions(context.Background(), sub, *planCode)
 	if err != nil {
-		log.Fatalf("Error assigning cart: %v", err)
+		fatalError(err, "Error listing options of plan %s: %v", *planCode, err)
+	}
+
+	// Hardware options are listed first
+	type row struct {
+		Kind         string `json:"kind" yaml:"kind"`
+		Family       string `json:"family" yaml:"family"`
+		Option       string `json:"option" yaml:"option"`
+		Name         string `json:"name" yaml:"name"`
+		Mandatory    bool   `json:"mandatory" yaml:"mandatory"`
+		Delivery     bool   `json:"delivery" yaml:"delivery"`
+		MonthlyPrice string `json:"monthlyPrice" yaml:"monthlyPrice" table:"MONTHLY PRICE"`
+	}
+	rows := []row{}
+	for _, k := range []string{orderer.OptionKindHardware, orderer.OptionKindService} {
+		if *kind != "" && *kind != k {
+			continue
+		}
+		for _, family := range groupOptions(options) {
+			for _, option := range family.offers {
+				if option.Kind() != k {
+					continue
+				}
+				price, _ := orderer.PriceFor(option.Prices, "P1M", "default")
+				rows = append(rows, row{k, family.name, option.PlanCode, option.ProductName, option.Mandatory, option.IsDelivery(), displayPrice(*format, sub, price)})
+			}
+		}
+	}
+	mustRender(*format, rows)
+}
+
+// displayPrice returns price as written by subsidiary in table output, and
+// as returned by OVH in json and yaml output
+func displayPrice(format, subsidiary string, price orderer.Price) string {
+	if format == "table" {
+		return price.Format(subsidiary)
+	}
+	return price.Text
+}
+
+// registerFormatFlag registers the -format flag of the read commands
+func registerFormatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", "table", "output format: table, json or yaml")
+}
+
+// mustRender renders rows to stdout, exiting on error
+func mustRender(format string, rows interface{}) {
+	if err := render(os.Stdout, format, rows); err != nil {
+		fatalError(err, "Error printing results: %v", err)
+	}
+}
+
+// render writes rows, a slice of structs, in format: an aligned table with
+// a column per field (titled by its table tag, or its upper-cased name),
+// JSON or YAML
+func render(w io.Writer, format string, rows interface{}) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(rows); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "table":
+		return renderTable(w, rows)
+	}
+	return fmt.Errorf("unknown format %q: expected table, json or yaml", format)
+}
+
+// renderTable writes rows, a slice of structs, as an aligned table
+func renderTable(out io.Writer, rows interface{}) error {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot render %T as a table", rows)
+	}
+	t := v.Type().Elem()
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	headers := make([]string, t.NumField())
+	for i := range headers {
+		headers[i] = t.Field(i).Tag.Get("table")
+		if headers[i] == "" {
+			headers[i] = strings.ToUpper(t.Field(i).Name)
+		}
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for i := 0; i < v.Len(); i++ {
+		cells := make([]string, t.NumField())
+		for j := range cells {
+			switch value := v.Index(i).Field(j).Interface().(type) {
+			case time.Time:
+				cells[j] = value.Format(time.RFC3339)
+			case []string:
+				cells[j] = strings.Join(value, ", ")
+			default:
+				cells[j] = fmt.Sprint(value)
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
+
+// describePlan prints the configuration labels required by a plan, as a YAML
+// snippet that can be pasted into an order configuration
+func describePlan(args []string) {
+	fs := flag.NewFlagSet("describe-plan", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	planCode := fs.String("plan", "", "plan code to describe (e.g. 24rise01-us)")
+	subsidiary := registerSubsidiaryFlag(fs)
+	format := fs.String("format", "config", "output format: config (a snippet to paste in a config file), table, json or yaml")
+	fs.Parse(args)
+	client := clientFlags.newClient()
+	if *planCode == "" {
+		fatalf(exitUsage, "Please specify a plan with -plan")
 	}
-	fmt.Println("Assigned cart to the logged-in user.")
 
-	// Step 3: Add a dedicated server to the cart (using planCode "24rise01-us")
-	server := make(map[string]interface{})
-	err = client.Post("/order/cart/"+cartID+"/baremetalServers", map[string]interface{}{
-		"duration":    "P1M",
-		"planCode":    "24rise01-us",
-		"pricingMode": "default",
-		"quantity":    1,
-	}, &server)
+	o := newOrderer(client, orderer.Options{CatalogCache: clientFlags.catalogCache()})
+	defer o.Close()
+	description, err := o.DescribePlan(context.Background(), discoverySubsidiary(client, *subsidiary), *planCode)
 	if err != nil {
-		log.Fatalf("Error adding server to cart: %v", err)
+		fatalError(err, "Error describing plan %s: %v", *planCode, err)
+	}
+	if *format != "config" {
+		type row struct {
+			Label         string   `json:"label" yaml:"label"`
+			Required      bool     `json:"required" yaml:"required"`
+			AllowedValues []string `json:"allowedValues" yaml:"allowedValues" table:"ALLOWED VALUES"`
+		}
+		rows := []row{}
+		for _, config := range description.Configuration {
+			rows = append(rows, row{config.Label, config.Required, config.AllowedValues})
+		}
+		mustRender(*format, rows)
+		return
+	}
+
+	fmt.Printf("\n# Configuration for plan %s\n", *planCode)
+	fmt.Printf("version: %d\n", orderer.ConfigVersion)
+	fmt.Printf("plan: %s\n", *planCode)
+	fmt.Println("configuration:")
+	for _, config := range description.Configuration {
+		mandatory := "optional"
+		if config.Required {
+			mandatory = "mandatory"
+		}
+		value := ""
+		if len(config.AllowedValues) > 0 {
+			value = config.AllowedValues[0]
+			fmt.Printf("  # %s, allowed values: %s\n", mandatory, strings.Join(config.AllowedValues, ", "))
+		} else {
+			fmt.Printf("  # %s, free-form value\n", mandatory)
+		}
+		fmt.Printf("  - label: %s\n", config.Label)
+		fmt.Printf("    value: %q\n", value)
+	}
+	// The first option of mandatory families is selected, the others are
+	// listed commented out
+	fmt.Println("options:")
+	for _, family := range groupOptions(description.Options) {
+		mandatory := "optional"
+		if family.mandatory {
+			mandatory = "mandatory"
+		}
+		fmt.Printf("  # %s, %s %s option\n", family.name, mandatory, family.offers[0].Kind())
+		for i, option := range family.offers {
+			comment := "# "
+			if family.mandatory && i == 0 {
+				comment = ""
+			}
+			price, _ := orderer.PriceFor(option.Prices, "P1M", "default")
+			fmt.Printf("  %s- %s # %s %s\n", comment, option.PlanCode, option.ProductName, price.Format(discoverySubsidiary(client, *subsidiary)))
+		}
+	}
+	fmt.Println()
+}
+
+// optionFamily is a family of options offered for a plan, such as memory or storage
+type optionFamily struct {
+	name      string
+	mandatory bool
+	offers    []orderer.OptionOffer
+}
+
+// groupOptions groups options by family, in the order families first appear
+func groupOptions(offers []orderer.OptionOffer) []*optionFamily {
+	var families []*optionFamily
+	byName := make(map[string]*optionFamily)
+	for _, offer := range offers {
+		family := byName[offer.Family]
+		if family == nil {
+			family = &optionFamily{name: offer.Family}
+			byName[offer.Family] = family
+			families = append(families, family)
+		}
+		family.mandatory = family.mandatory || offer.Mandatory
+		family.offers = append(family.offers, offer)
+	}
+	return families
+}
+
+// listDatacenters prints the datacenters where a plan is in stock
+func listDatacenters(args []string) {
+	fs := flag.NewFlagSet("list-datacenters", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	planCode := fs.String("plan", "", "plan code to look up (e.g. 24rise01-us)")
+	format := registerFormatFlag(fs)
+	fs.Parse(args)
+	client := clientFlags.newClient()
+	if *planCode == "" {
+		fatalf(exitUsage, "Please specify a plan with -plan")
 	}
 
-	// Extract itemId as json.Number and convert it to int64
-	itemIDNum := server["itemId"].(json.Number)
-	itemID, err := strconv.ParseInt(itemIDNum.String(), 10, 64)
+	o := newOrderer(client, orderer.Options{CatalogCache: clientFlags.catalogCache()})
+	defer o.Close()
+	availabilities, err := o.Availabilities(context.Background(), *planCode)
 	if err != nil {
-		log.Fatalf("Error converting itemId to integer: %v", err)
+		fatalError(err, "Error listing datacenters: %v", err)
 	}
-	fmt.Printf("Added Server to Cart with Item ID: %d\n", itemID)
 
-	// Step 4: Set the correct `dedicated_os` value from your screenshot
-	configItems := []struct {
-		Label string
-		Value string
-	}{
-		{"dedicated_os", "none_64.en"}, // Use the correct OS value here
-		{"region", "united_states"},
-		{"dedicated_datacenter", "hil"},
+	type row struct {
+		Datacenter   string `json:"datacenter" yaml:"datacenter"`
+		Availability string `json:"availability" yaml:"availability"`
+		FQN          string `json:"fqn" yaml:"fqn" table:"CONFIGURATION"`
 	}
+	rows := []row{}
+	for _, availability := range availabilities {
+		for _, dc := range availability.Datacenters {
+			if dc.InStock() {
+				rows = append(rows, row{dc.Datacenter, dc.Availability, availability.FQN})
+			}
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Datacenter < rows[j].Datacenter })
+	if len(rows) == 0 && *format == "table" {
+		fmt.Printf("Plan %s is not in stock in any datacenter\n", *planCode)
+		return
+	}
+	mustRender(*format, rows)
+}
 
-	for _, config := range configItems {
-		configResponse := make(map[string]interface{})
-		err = client.Post(fmt.Sprintf("/order/cart/%s/item/%d/configuration", cartID, itemID), map[string]interface{}{
-			"label": config.Label,
-			"value": config.Value,
-		}, &configResponse)
+// listOrders prints the orders of the account, newest first
+func listOrders(args []string) {
+	fs := flag.NewFlagSet("list-orders", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	since := fs.String("since", "", "only list orders placed after this date (2006-01-02) or within this duration (e.g. 720h)")
+	description := fs.String("description", "", "only list orders with a detail description containing this text")
+	limit := fs.Int("limit", 0, "maximum number of orders to list (0 for all)")
+	concurrency := fs.Int("concurrency", 4, "number of orders fetched in parallel")
+	format := registerFormatFlag(fs)
+	fs.Parse(args)
+	client := clientFlags.newClient()
+
+	filter := orderer.OrderFilter{
+		DescriptionContains: *description,
+		Limit:               *limit,
+		Concurrency:         *concurrency,
+	}
+	if *since != "" {
+		t, err := parseSince(*since)
 		if err != nil {
-			log.Fatalf("Error configuring %s: %v", config.Label, err)
+			fatalf(exitUsage, "Invalid -since value: %v", err)
 		}
-		fmt.Printf("Configured %s with value %s\n", config.Label, config.Value)
+		filter.Since = t
+	}
+
+	o := newOrderer(client, orderer.Options{})
+	defer o.Close()
+	orders, err := o.ListOrders(context.Background(), filter)
+	if err != nil {
+		fatalError(err, "Error listing orders: %v", err)
+	}
+	type row struct {
+		OrderID int64     `json:"orderId" yaml:"orderId" table:"ORDER"`
+		Date    time.Time `json:"date" yaml:"date"`
+		Price   string    `json:"price" yaml:"price"`
 	}
+	rows := []row{}
+	for _, order := range orders {
+		rows = append(rows, row{order.OrderID, order.Date, displayPrice(*format, discoverySubsidiary(client, ""), order.PriceWithTax)})
+	}
+	mustRender(*format, rows)
+}
 
-	// Step 5: Add options (for vrack, storage, RAM, and bandwidth)
-	options := []string{
-		"vrack-bandwidth-1000-24rise-us",
-		"softraid-2x512nvme-24rise-us",
-		"ram-32g-ecc-3200-24rise-us",
-		"bandwidth-1000-unguaranteed-24rise-us",
+// reportOrders writes the orders placed in a period as CSV, for spend reports
+func reportOrders(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	since := fs.String("since", "", "report the orders placed after this date (2006-01-02) or within this duration (e.g. 720h)")
+	concurrency := fs.Int("concurrency", 4, "number of orders fetched in parallel")
+	fs.Parse(args)
+	if *since == "" {
+		fatalf(exitUsage, "-since is required")
 	}
+	t, err := parseSince(*since)
+	if err != nil {
+		fatalf(exitUsage, "Invalid -since value: %v", err)
+	}
+	client := clientFlags.newClient()
 
-	for _, planCode := range options {
-		optionResponse := make(map[string]interface{})
-		err = client.Post(fmt.Sprintf("/order/cart/%s/baremetalServers/options", cartID), map[string]interface{}{
-			"duration":    "P1M",
-			"itemId":      itemID, // Pass itemId as integer
-			"planCode":    planCode,
-			"pricingMode": "default",
-			"quantity":    1,
-		}, &optionResponse)
-		if err != nil {
-			log.Fatalf("Error adding option with planCode %s: %v", planCode, err)
+	o := newOrderer(client, orderer.Options{})
+	defer o.Close()
+	reports, err := o.ReportOrders(context.Background(), t, *concurrency)
+	if err != nil {
+		fatalError(err, "Error reporting orders: %v", err)
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"orderID", "date", "plan", "total", "currency", "status", "delivery"})
+	for _, report := range reports {
+		w.Write([]string{
+			strconv.FormatInt(report.OrderID, 10),
+			report.Date.Format(time.RFC3339),
+			strings.Join(report.PlanCodes, " "),
+			report.PriceWithTax.Value.String(),
+			report.PriceWithTax.CurrencyCode,
+			report.Status,
+			strings.Join(report.DeliveryCodes(), " "),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		fatalError(err, "Error writing report: %v", err)
+	}
+}
+
+// parseSince parses a date (2006-01-02), an RFC 3339 timestamp or a duration
+// counted back from now
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a date (2006-01-02), a timestamp (RFC 3339) nor a duration", value)
+	}
+	return time.Now().Add(-d), nil
+}
+
+// listCarts prints the carts of the account and the metadata of those created by this tool
+func listCarts(args []string) {
+	fs := flag.NewFlagSet("list-carts", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	all := fs.Bool("all", false, "also list carts not created by this tool")
+	format := registerFormatFlag(fs)
+	fs.Parse(args)
+	client := clientFlags.newClient()
+
+	o := newOrderer(client, orderer.Options{})
+	defer o.Close()
+	carts, err := o.ListCarts(context.Background())
+	if err != nil {
+		fatalError(err, "Error listing carts: %v", err)
+	}
+	type row struct {
+		CartID      string `json:"cartId" yaml:"cartId" table:"CART"`
+		Description string `json:"description" yaml:"description"`
+		Version     string `json:"version,omitempty" yaml:"version,omitempty"`
+		Created     string `json:"created,omitempty" yaml:"created,omitempty"`
+		RunID       string `json:"runId,omitempty" yaml:"runId,omitempty" table:"RUN"`
+		Wait        string `json:"wait,omitempty" yaml:"wait,omitempty"`
+		Customer    string `json:"customerReference,omitempty" yaml:"customerReference,omitempty"`
+		CheckedOut  bool   `json:"checkedOut" yaml:"checkedOut" table:"CHECKED OUT"`
+	}
+	rows := []row{}
+	for _, cart := range carts {
+		r := row{CartID: cart.CartID, Description: cart.Description, CheckedOut: cart.ReadOnly}
+		if cart.Metadata == nil {
+			if !*all {
+				continue
+			}
+		} else {
+			r.Version = cart.Metadata.Version
+			r.Created = cart.Metadata.Created.Format(time.RFC3339)
+			r.RunID = cart.Metadata.RunID
+			r.Wait = cart.Metadata.Wait
+			r.Customer = cart.Metadata.CustomerReference
 		}
-		fmt.Printf("Added option with planCode %s\n", planCode)
+		rows = append(rows, r)
 	}
+	mustRender(*format, rows)
+}
+
+// cleanCarts deletes the leftover carts created by this tool
+func cleanCarts(args []string) {
+	fs := flag.NewFlagSet("clean-carts", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	olderThan := fs.Duration("older-than", time.Hour, "only delete carts created more than this long ago")
+	fs.Parse(args)
+	client := clientFlags.newClient()
 
-	// Step 6: Validate the order and proceed to checkout
-	order := make(map[string]interface{})
-	err = client.Post(fmt.Sprintf("/order/cart/%s/checkout", cartID), nil, &order)
+	o := newOrderer(client, orderer.Options{})
+	defer o.Close()
+	deleted, err := o.CleanCarts(context.Background(), time.Now().Add(-*olderThan))
 	if err != nil {
-		log.Fatalf("Error validating order: %v", err)
+		fatalError(err, "Error cleaning carts: %v", err)
+	}
+	fmt.Printf("Deleted %d cart(s)\n", len(deleted))
+}
+
+// allowedEndpoints returns the endpoints listed by -allow-endpoint, which
+// purchases require unless fixtures are replayed
+func (cf *clientFlags) allowedEndpoints(value string) []string {
+	switch {
+	case value != "":
+		return strings.Split(value, ",")
+	case cf.replayPath() != "":
+		// Replayed calls cannot buy anything
+		return nil
+	}
+	fatalf(exitUsage, "Please list the endpoints orders may be placed on with -allow-endpoint (the endpoint is %s)", cf.endpointValue())
+	return nil
+}
+
+// payOrder pays an order that was checked out but not paid
+func payOrder(args []string) {
+	fs := flag.NewFlagSet("pay", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	orderID := fs.String("order", "", "ID of the order to pay")
+	paymentMethodWait := fs.Duration("payment-method-wait", 30*time.Second, "how long to keep polling for payment methods")
+	paymentSettleWait := fs.Duration("payment-settle-wait", 5*time.Minute, "how long to wait for an accepted payment to clear")
+	paymentMethodID := fs.String("payment-method-id", "", "only pay with the payment method with this ID")
+	paymentMethodType := fs.String("payment-method-type", "", "only pay with a payment method of this type (e.g. CREDIT_CARD)")
+	paymentMethodDefault := fs.Bool("payment-method-default", false, "only pay with the default payment method of the account")
+	allowEndpoints := fs.String("allow-endpoint", "", "comma-separated endpoints orders may be paid on (e.g. ovh-eu)")
+	fs.Parse(args)
+	client := clientFlags.newClient()
+	if *orderID == "" {
+		fatalf(exitUsage, "Please specify an order with -order")
 	}
-	orderID := fmt.Sprintf("%v", order["orderId"])
-	fmt.Printf("Order validated. Order ID: %s\n", orderID)
 
-	// Step 7: Fetch available payment methods for this order
-	var paymentMethods []map[string]interface{}
-	err = client.Get(fmt.Sprintf("/me/order/%s/availablePaymentMethod", orderID), &paymentMethods)
+	o := newOrderer(client, orderer.Options{
+		AllowedEndpoints:  clientFlags.allowedEndpoints(*allowEndpoints),
+		PaymentMethodWait: *paymentMethodWait,
+		PaymentSettleWait: *paymentSettleWait,
+		PaymentMethod: orderer.PaymentMethodCriteria{
+			ID:      *paymentMethodID,
+			Type:    *paymentMethodType,
+			Default: *paymentMethodDefault,
+		},
+	})
+	defer o.Close()
+	methodID, methodType, err := o.PayOrder(context.Background(), *orderID)
+	var interactivePayment *orderer.InteractivePaymentError
+	if errors.As(err, &interactivePayment) {
+		fmt.Printf("Order %s cannot be paid through the API.\n", interactivePayment.OrderID)
+		fmt.Printf("Open %s in a browser to complete the payment (e.g. 3-D Secure).\n", interactivePayment.URL)
+		os.Exit(exitCode(err))
+	}
+	if errors.Is(err, orderer.ErrPaymentPending) {
+		fmt.Printf("Payment of order %s with %s %s is still pending: check the order before paying it again.\n", *orderID, methodType, methodID)
+		os.Exit(exitCode(err))
+	}
 	if err != nil {
-		log.Fatalf("Error fetching payment methods: %v", err)
+		fatalError(err, "Error paying order: %v", err)
 	}
-	fmt.Printf("Available Payment Methods: %v\n", paymentMethods)
+	fmt.Printf("Order %s paid with %s %s.\n", *orderID, methodType, methodID)
+}
+
+// files holds the files the tool reads and writes, and paths finds its
+// default ones in the XDG base directories (see orderer.Paths)
+var (
+	files orderer.FS = orderer.OSFS{}
+	paths            = orderer.Paths{FS: files}
+)
 
-	// Example: Use the first payment method to complete the order
-	if len(paymentMethods) > 0 {
-		paymentID := paymentMethods[0]["id"].(json.Number)
-		paymentType := paymentMethods[0]["type"].(string)
+// printPaths prints the directories and files the tool uses by default
+func printPaths(args []string) {
+	fs := flag.NewFlagSet("paths", flag.ExitOnError)
+	format := registerFormatFlag(fs)
+	fs.Parse(args)
 
-		// Step 8: Pay for the order
-		paymentResponse := make(map[string]interface{})
-		err = client.Post(fmt.Sprintf("/me/order/%s/pay", orderID), map[string]interface{}{
-			"paymentMethod": map[string]interface{}{
-				"id":   paymentID,
-				"type": paymentType,
-			},
-		}, &paymentResponse)
-		if err != nil {
-			log.Fatalf("Error paying for the order: %v", err)
+	type row struct {
+		Kind     string `json:"kind" yaml:"kind"`
+		Path     string `json:"path" yaml:"path"`
+		Variable string `json:"variable" yaml:"variable"`
+		Flag     string `json:"flag" yaml:"flag"`
+	}
+	configDir, stateDir := paths.ConfigDir(), paths.StateDir()
+	join := func(dir, name string) string {
+		if dir == "" {
+			return ""
 		}
-		fmt.Println("Order has been successfully paid.")
-	} else {
-		log.Fatal("No available payment methods found.")
+		return filepath.Join(dir, name)
+	}
+	mustRender(*format, []row{
+		{"plan aliases", join(configDir, orderer.AliasesFile), orderer.XDGConfigVar, "-plan-alias"},
+		{"purchasing rules", join(configDir, orderer.PolicyFile), orderer.XDGConfigVar, "-policy"},
+		{"watch list", join(stateDir, orderer.WatchListFile), orderer.XDGStateVar, "-state"},
+		{"catalog cache", paths.CacheDir(), orderer.XDGCacheVar, "-cache-dir"},
+	})
+}
+
+// watchOrders waits for the delivery of the orders of a watch list, which
+// order -watch-file or -order add to, and notifies each delivery. Run in the
+// background, it decouples the delivery wait from the order command; the
+// list outlives it, so a restarted watch resumes the orders still listed.
+func watchOrders(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	notifierFlags := registerNotifierFlags(fs)
+	statePath := fs.String("state", paths.WatchList(), "watch list file, created if needed, listing the orders awaited")
+	orderIDs := fs.String("order", "", "comma-separated IDs of orders to add to the watch list")
+	follow := fs.Bool("follow", false, "keep running once every order is delivered, for the orders added to the list later")
+	rescan := fs.Duration("rescan", time.Minute, "interval between two reads of the watch list for new orders")
+	deliveryTimeout := fs.Duration("delivery-timeout", 72*time.Hour, "how long to wait for the delivery of each order")
+	fs.Parse(args)
+	if *statePath == "" {
+		fatalf(exitUsage, "Please specify the watch list with -state")
+	}
+	list := &orderer.WatchList{Path: *statePath}
+	if *orderIDs != "" {
+		for _, orderID := range strings.Split(*orderIDs, ",") {
+			if err := list.Add(orderer.WatchedOrder{OrderID: orderID, Added: time.Now()}); err != nil {
+				fatalError(err, "%v", err)
+			}
+		}
+	}
+	client := clientFlags.newClient()
+	logger := log.New(os.Stdout, "", log.LstdFlags)
+	o := newOrderer(client, orderer.Options{
+		DeliveryTimeout: *deliveryTimeout,
+		Logger:          logger,
+	})
+	defer o.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := o.Watch(ctx, list, orderer.WatchOptions{
+		Notifier: notifierFlags.notifiers(logger, ""),
+		Follow:   *follow,
+		Rescan:   *rescan,
+	})
+	if errors.Is(err, context.Canceled) {
+		logger.Printf("Stopped, the orders still awaited stay in %s", *statePath)
+		return
+	}
+	if err != nil {
+		fatalError(err, "%v", err)
+	}
+}
+
+// purchaseCart checks out and pays a cart built with order -build-only
+func purchaseCart(args []string) {
+	fs := flag.NewFlagSet("purchase", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	cartID := fs.String("cart", "", "ID of the cart to purchase")
+	pricingMode := fs.String("pricing-mode", "default", "pricing mode the cart was built with, to check its commitment")
+	ackEngagement := fs.Bool("ack-engagement", false, "acknowledge the commitment of a committed pricing mode; required for commitments over 1 month")
+	autoPay := fs.Bool("auto-pay-preferred", false, "let OVH charge the preferred payment method of the account instead of paying through the API")
+	maxPrice := fs.String("max-price", "", "maximum price of the cart, as a decimal amount (e.g. 129.99) compared with the price of -max-price-basis; the cart is deleted if it costs more")
+	maxPriceBasis := fs.String("max-price-basis", orderer.PriceBasisGross, "price -max-price applies to: gross (tax included) or net (without tax)")
+	paymentMethodWait := fs.Duration("payment-method-wait", 30*time.Second, "how long to keep polling for payment methods")
+	paymentSettleWait := fs.Duration("payment-settle-wait", 5*time.Minute, "how long to wait for an accepted payment to clear")
+	paymentMethodID := fs.String("payment-method-id", "", "only pay with the payment method with this ID")
+	paymentMethodType := fs.String("payment-method-type", "", "only pay with a payment method of this type (e.g. CREDIT_CARD)")
+	paymentMethodDefault := fs.Bool("payment-method-default", false, "only pay with the default payment method of the account")
+	allowEndpoints := fs.String("allow-endpoint", "", "comma-separated endpoints orders may be placed on (e.g. ovh-eu)")
+	maxStateAge := fs.Duration("max-state-age", 0, "refuse to purchase a cart built longer ago, as its prices may have changed (0 for no limit)")
+	policyPath := fs.String("policy", paths.ConfigFile(orderer.PolicyFile), "YAML file of purchasing rules checked before checkout; defaults to the policy file of the config directory when it exists, -policy= disables it")
+	priceBaselinePath := fs.String("price-baseline", "", "approved prices of the cart, written by order -build-only -save-price-baseline: the cart is deleted if a price of an item or the total drifted from them beyond -price-tolerance")
+	priceTolerance := fs.String("price-tolerance", "", "how far the prices may drift from -price-baseline: an amount (e.g. 0.50) or a percentage of each price (e.g. 2%); none by default")
+	fs.Parse(args)
+	client := clientFlags.newClient()
+	if *cartID == "" {
+		fatalf(exitUsage, "Please specify a cart with -cart")
+	}
+
+	o := newOrderer(client, orderer.Options{
+		AllowedEndpoints:  clientFlags.allowedEndpoints(*allowEndpoints),
+		MaxStateAge:       *maxStateAge,
+		CheckoutPolicy:    loadPolicy(*policyPath),
+		PaymentMethodWait: *paymentMethodWait,
+		PaymentSettleWait: *paymentSettleWait,
+		PaymentMethod: orderer.PaymentMethodCriteria{
+			ID:      *paymentMethodID,
+			Type:    *paymentMethodType,
+			Default: *paymentMethodDefault,
+		},
+	})
+	defer o.Close()
+	orderID, err := o.PurchaseCart(context.Background(), *cartID, orderer.PurchaseOptions{
+		PricingMode:   *pricingMode,
+		AckEngagement: *ackEngagement,
+		AutoPay:       *autoPay,
+		MaxPrice:      *maxPrice,
+		MaxPriceBasis: *maxPriceBasis,
+
+		PriceBaseline:  loadPriceBaseline(*priceBaselinePath),
+		PriceTolerance: *priceTolerance,
+	})
+	if errors.Is(err, orderer.ErrPriceDrift) {
+		fmt.Printf("Cart %s deleted.\n", *cartID)
+		printPriceDrift(os.Stdout, err)
+		os.Exit(exitCode(err))
+	}
+	var interactivePayment *orderer.InteractivePaymentError
+	if errors.As(err, &interactivePayment) {
+		fmt.Printf("Order %s cannot be paid through the API.\n", interactivePayment.OrderID)
+		fmt.Printf("Open %s in a browser to complete the payment (e.g. 3-D Secure).\n", interactivePayment.URL)
+		os.Exit(exitCode(err))
+	}
+	if errors.Is(err, orderer.ErrStaleCart) || errors.Is(err, orderer.ErrCartGone) {
+		fatalError(err, "%v: build a new cart with order -build-only", err)
+	}
+	if err != nil {
+		fatalError(err, "Error purchasing cart %s: %v", *cartID, err)
+	}
+	fmt.Printf("Cart %s purchased, order %s.\n", *cartID, orderID)
+}
+
+// cancelOrder cancels an order that has not been delivered yet
+func cancelOrder(args []string) {
+	fs := flag.NewFlagSet("cancel", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	orderID := fs.String("order", "", "ID of the order to cancel")
+	reason := fs.String("reason", "other", "cancellation reason (other, unused, expensive, performance, reliability, competitor, difficulty)")
+	comment := fs.String("comment", "", "optional comment sent with the cancellation")
+	yes := fs.Bool("yes", false, "confirm the cancellation")
+	fs.Parse(args)
+	client := clientFlags.newClient()
+	if *orderID == "" {
+		fatalf(exitUsage, "Please specify an order with -order")
+	}
+	if !*yes {
+		fatalf(exitUsage, "Cancelling order %s cannot be undone, add -yes to confirm", *orderID)
+	}
+
+	o := newOrderer(client, orderer.Options{})
+	defer o.Close()
+	if err := o.CancelOrder(context.Background(), *orderID, *reason, *comment); err != nil {
+		fatalError(err, "Error cancelling order: %v", err)
+	}
+}
+
+// terminateService starts, or with -token confirms, the termination of a delivered server
+func terminateService(args []string) {
+	fs := flag.NewFlagSet("terminate", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	serviceName := fs.String("service", "", "service name of the dedicated server to terminate")
+	token := fs.String("token", "", "confirmation token received by email; without it the termination is only requested")
+	reason := fs.String("reason", "OTHER", "termination reason, required with -token")
+	comment := fs.String("comment", "", "optional comment sent with the confirmation")
+	yes := fs.Bool("yes", false, "confirm the termination")
+	fs.Parse(args)
+	client := clientFlags.newClient()
+	if *serviceName == "" {
+		fatalf(exitUsage, "Please specify a server with -service")
+	}
+	if !*yes {
+		fatalf(exitUsage, "Terminating %s deletes the server and its data, add -yes to confirm", *serviceName)
+	}
+
+	o := newOrderer(client, orderer.Options{})
+	defer o.Close()
+	if *token == "" {
+		if err := o.TerminateService(context.Background(), *serviceName); err != nil {
+			fatalError(err, "Error terminating server: %v", err)
+		}
+		fmt.Printf("Run terminate -service %s -token <token> -yes with the token received by email to confirm.\n", *serviceName)
+		return
+	}
+	if err := o.ConfirmTermination(context.Background(), *serviceName, *token, *reason, *comment); err != nil {
+		fatalError(err, "Error confirming termination: %v", err)
+	}
+}
+
+// serviceInfo prints the billing information of a delivered server and,
+// with -auto-renew, turns its automatic renewal on or off
+func serviceInfo(args []string) {
+	fs := flag.NewFlagSet("service-info", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	serviceName := fs.String("service", "", "service name of the dedicated server")
+	autoRenew := fs.String("auto-renew", "", "turn the automatic renewal on or off before printing the information")
+	format := registerFormatFlag(fs)
+	fs.Parse(args)
+	client := clientFlags.newClient()
+	if *serviceName == "" {
+		fatalf(exitUsage, "Please specify a server with -service")
+	}
+
+	o := newOrderer(client, orderer.Options{})
+	defer o.Close()
+	switch *autoRenew {
+	case "":
+	case "on", "off":
+		if err := o.SetAutomaticRenewal(context.Background(), *serviceName, *autoRenew == "on"); err != nil {
+			fatalError(err, "Error changing the renewal: %v", err)
+		}
+	default:
+		fatalf(exitUsage, "Invalid -auto-renew %q: expected on or off", *autoRenew)
+	}
+	info, err := o.GetServiceInfo(context.Background(), *serviceName)
+	if err != nil {
+		fatalError(err, "Error fetching service information: %v", err)
+	}
+
+	type row struct {
+		Service       string `json:"service" yaml:"service"`
+		Status        string `json:"status" yaml:"status"`
+		Creation      string `json:"creation" yaml:"creation"`
+		Expiration    string `json:"expiration" yaml:"expiration"`
+		RenewalType   string `json:"renewalType" yaml:"renewalType" table:"RENEWAL"`
+		AutoRenew     bool   `json:"autoRenew" yaml:"autoRenew" table:"AUTO RENEW"`
+		RenewalPeriod int    `json:"renewalPeriodMonths" yaml:"renewalPeriodMonths" table:"PERIOD (MONTHS)"`
+		EngagedUpTo   string `json:"engagedUpTo,omitempty" yaml:"engagedUpTo,omitempty" table:"ENGAGED UP TO"`
+	}
+	r := row{
+		Service:     *serviceName,
+		Status:      info.Status,
+		Creation:    info.Creation,
+		Expiration:  info.Expiration,
+		RenewalType: info.RenewalType,
+		EngagedUpTo: info.EngagedUpTo,
+	}
+	if info.Renew != nil {
+		r.AutoRenew, r.RenewalPeriod = info.Renew.Automatic, info.Renew.Period
+	}
+	mustRender(*format, []row{r})
+}
+
+// checkAuth prints the status, expiration and access rules of the consumer
+// key, and exits with exitAuth if it is expired, revoked or not validated.
+// Unlike doctor, it checks nothing else.
+func checkAuth(args []string) {
+	fs := flag.NewFlagSet("check-auth", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	format := registerFormatFlag(fs)
+	fs.Parse(args)
+	client := clientFlags.newClient()
+
+	o := newOrderer(client, orderer.Options{})
+	defer o.Close()
+	credential, checkErr := o.CheckCredential(context.Background())
+	if credential == nil {
+		fatalError(checkErr, "Error checking the consumer key: %v", checkErr)
+	}
+
+	type row struct {
+		CredentialID int64  `json:"credentialId" yaml:"credentialId" table:"CREDENTIAL"`
+		Status       string `json:"status" yaml:"status"`
+		Creation     string `json:"creation,omitempty" yaml:"creation,omitempty"`
+		Expiration   string `json:"expiration,omitempty" yaml:"expiration,omitempty"`
+		Rules        string `json:"rules" yaml:"rules"`
+		MissingRules string `json:"missingRules,omitempty" yaml:"missingRules,omitempty" table:"MISSING TO ORDER"`
+	}
+	r := row{CredentialID: credential.CredentialID, Status: credential.Status, Expiration: "never"}
+	if credential.Creation != nil {
+		r.Creation = credential.Creation.Format(time.RFC3339)
+	}
+	if credential.Expiration != nil {
+		r.Expiration = fmt.Sprintf("%s (in %s)", credential.Expiration.Format(time.RFC3339), time.Until(*credential.Expiration).Round(time.Minute))
+		if checkErr != nil {
+			r.Expiration = credential.Expiration.Format(time.RFC3339)
+		}
+	}
+	var rules, missing []string
+	for _, rule := range credential.Rules {
+		rules = append(rules, rule.String())
+	}
+	for _, rule := range orderer.MissingRules(credential.Rules, orderer.RequiredAccessRules) {
+		missing = append(missing, rule.String())
+	}
+	r.Rules, r.MissingRules = strings.Join(rules, ", "), strings.Join(missing, ", ")
+	mustRender(*format, []row{r})
+	if checkErr != nil {
+		fatalError(checkErr, "%v", checkErr)
+	}
+}
+
+// validateConfig checks a configuration file against the config schema and
+// prints every violation with its path, then applies the checks of the order
+// command. It needs no credentials.
+func validateConfig(args []string) {
+	fs := flag.NewFlagSet("validate-config", flag.ExitOnError)
+	configPath := fs.String("config", "", "YAML or JSON file describing the order")
+	printSchema := fs.Bool("print-schema", false, "print the JSON Schema of config files and exit")
+	fs.Parse(args)
+
+	if *printSchema {
+		fmt.Print(orderer.ConfigSchema)
+		return
+	}
+	if *configPath == "" {
+		fatalf(exitUsage, "Please specify a config file with -config")
+	}
+	data, err := files.ReadFile(*configPath)
+	if err != nil {
+		fatalf(exitUsage, "Error reading config: %v", err)
+	}
+	violations, err := orderer.ValidateConfigSchema(data)
+	if err != nil {
+		fatalf(exitUsage, "%s: %v", *configPath, err)
+	}
+	if len(violations) > 0 {
+		for _, violation := range violations {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", *configPath, violation)
+		}
+		fatalf(exitUsage, "%s: %d schema violations", *configPath, len(violations))
+	}
+	if _, err := orderer.ParseConfig(data); err != nil {
+		fatalf(exitUsage, "%s: %v", *configPath, err)
+	}
+	fmt.Printf("%s is valid\n", *configPath)
+}
+
+// diffConfig prints the spec changes between two configs and what they cost,
+// pricing both in temporary carts, without ordering anything
+func diffConfig(args []string) {
+	fs := flag.NewFlagSet("diff-config", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	oldPath := fs.String("old", "", "config file of the current spec")
+	newPath := fs.String("new", "", "config file of the proposed spec")
+	format := fs.String("format", "text", "output format: text or json")
+	fs.Parse(args)
+	if *oldPath == "" || *newPath == "" {
+		fatalf(exitUsage, "Please specify the configs to compare with -old and -new")
+	}
+	if *format != "text" && *format != "json" {
+		fatalf(exitUsage, "Invalid -format %q: expected text or json", *format)
+	}
+	client := clientFlags.newClient()
+
+	configs := make([]*orderer.Config, 2)
+	for i, path := range []string{*oldPath, *newPath} {
+		config, err := orderer.LoadConfig(files, path)
+		if err != nil {
+			fatalf(exitUsage, "Error loading config %s: %v", path, err)
+		}
+		config.Subsidiary = discoverySubsidiary(client, config.Subsidiary)
+		configs[i] = config
+	}
+
+	o := newOrderer(client, orderer.Options{CatalogCache: clientFlags.catalogCache()})
+	defer o.Close()
+	diff, err := o.DiffConfigs(context.Background(), configs[0], configs[1])
+	if err != nil {
+		fatalError(err, "Error comparing configs: %v", err)
+	}
+	if *format == "json" {
+		printDiffJSON(os.Stdout, diff)
+		return
+	}
+
+	if len(diff.Changes) == 0 {
+		fmt.Printf("%s and %s have the same spec\n", *oldPath, *newPath)
+	} else {
+		fmt.Printf("Changes from %s to %s:\n", *oldPath, *newPath)
+		for _, change := range diff.Changes {
+			fmt.Printf("  %s: %s -> %s\n", change.Field, orNone(change.Old), orNone(change.New))
+		}
+	}
+	for i, quote := range []orderer.PlanQuote{diff.Old, diff.New} {
+		path, subsidiary := []string{*oldPath, *newPath}[i], configs[i].Subsidiary
+		if quote.Err != nil {
+			fmt.Printf("%s cannot be priced: %v\n", path, quote.Err)
+			continue
+		}
+		fmt.Printf("%s: %s for the first period, %s a month\n", path, quote.Price.Format(subsidiary), quote.Monthly.Format(subsidiary))
+		for _, skipped := range quote.SkippedOptions {
+			fmt.Printf("  without option %s: %v\n", skipped.PlanCode, skipped.Err)
+		}
+	}
+	if diff.Delta != nil && diff.MonthlyDelta != nil {
+		subsidiary := configs[1].Subsidiary
+		fmt.Printf("Price change: %s for the first period, %s a month\n", signed(*diff.Delta, subsidiary), signed(*diff.MonthlyDelta, subsidiary))
+	}
+}
+
+// orNone returns s, or "(none)" when it is empty
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// signed formats a price difference with its sign
+func signed(price orderer.Price, subsidiary string) string {
+	if amount, err := price.Amount(); err == nil && amount.Sign() > 0 {
+		return "+" + price.Format(subsidiary)
+	}
+	return price.Format(subsidiary)
+}
+
+// printDiffJSON prints the diff of two configs as a JSON object
+func printDiffJSON(w io.Writer, diff *orderer.ConfigDiff) {
+	type quote struct {
+		Plan           string         `json:"plan"`
+		Price          *orderer.Price `json:"price,omitempty"`
+		Monthly        *orderer.Price `json:"monthly,omitempty"`
+		SkippedOptions []string       `json:"skippedOptions,omitempty"`
+		Error          string         `json:"error,omitempty"`
+	}
+	convert := func(q orderer.PlanQuote) quote {
+		if q.Err != nil {
+			return quote{Plan: q.PlanCode, Error: q.Err.Error()}
+		}
+		out := quote{Plan: q.PlanCode, Price: &q.Price, Monthly: &q.Monthly}
+		for _, skipped := range q.SkippedOptions {
+			out.SkippedOptions = append(out.SkippedOptions, skipped.PlanCode)
+		}
+		return out
+	}
+	changes := diff.Changes
+	if changes == nil {
+		changes = []orderer.ConfigChange{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(struct {
+		Changes      []orderer.ConfigChange `json:"changes"`
+		Old          quote                  `json:"old"`
+		New          quote                  `json:"new"`
+		Delta        *orderer.Price         `json:"delta,omitempty"`
+		MonthlyDelta *orderer.Price         `json:"monthlyDelta,omitempty"`
+	}{changes, convert(diff.Old), convert(diff.New), diff.Delta, diff.MonthlyDelta})
+	if err != nil {
+		fatalError(err, "Error printing results: %v", err)
+	}
+}
+
+// doctor checks the environment, credentials and access rules and prints a
+// summary of each check. It exits non-zero if a critical check fails.
+func doctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	subsidiary := fs.String("subsidiary", "US", "subsidiary used for orders")
+	fs.Parse(args)
+	*subsidiary = strings.ToUpper(*subsidiary)
+
+	failed := false
+	report := func(ok, critical bool, name, detail string) {
+		switch {
+		case ok:
+			fmt.Printf("\033[32m[ OK ]\033[0m %s: %s\n", name, detail)
+		case critical:
+			failed = true
+			fmt.Printf("\033[31m[FAIL]\033[0m %s: %s\n", name, detail)
+		default:
+			fmt.Printf("\033[33m[WARN]\033[0m %s: %s\n", name, detail)
+		}
+	}
+	exit := func() {
+		if failed {
+			os.Exit(exitFailure)
+		}
+	}
+
+	// Environment variables and endpoint
+	var missing []string
+	for name, value := range map[string]*string{
+		"OVH_ENDPOINT":           clientFlags.endpoint,
+		"OVH_APPLICATION_KEY":    clientFlags.appKey,
+		"OVH_APPLICATION_SECRET": clientFlags.appSecret,
+		"OVH_CONSUMER_KEY":       clientFlags.consumerKey,
+	} {
+		if credential(value, name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) == 0 || clientFlags.replayPath() != "" {
+		report(true, true, "environment", "all credentials are set")
+	} else {
+		report(false, true, "environment", "missing "+strings.Join(missing, ", "))
+	}
+	endpoint, err := orderer.NormalizeEndpoint(clientFlags.endpointValue())
+	if err != nil {
+		report(false, true, "endpoint", err.Error())
+	} else {
+		report(true, true, "endpoint", endpoint)
+	}
+	endpointSubsidiaries := orderer.EndpointSubsidiaries(endpoint)
+	if failed {
+		exit()
+	}
+
+	client := clientFlags.newClient()
+	o := newOrderer(client, orderer.Options{})
+	defer o.Close()
+	ctx := context.Background()
+
+	// Credentials and subsidiaries
+	account, err := o.Me(ctx)
+	if err != nil {
+		report(false, true, "credentials", err.Error())
+		exit()
+	}
+	report(true, true, "credentials", fmt.Sprintf("authenticated as %s (%s)", account.Nichandle, account.Email))
+	report(contains(endpointSubsidiaries, account.Subsidiary), true, "account subsidiary",
+		fmt.Sprintf("%s on endpoint %s (serves %s)", account.Subsidiary, endpoint, strings.Join(endpointSubsidiaries, ", ")))
+	report(contains(endpointSubsidiaries, *subsidiary), true, "order subsidiary",
+		fmt.Sprintf("%s on endpoint %s", *subsidiary, endpoint))
+	report(*subsidiary == account.Subsidiary, false, "order subsidiary matches account",
+		fmt.Sprintf("orders use %s, account is %s", *subsidiary, account.Subsidiary))
+
+	// Consumer key status and access rules
+	credential, err := o.CurrentCredential(ctx)
+	if err != nil {
+		report(false, true, "consumer key", err.Error())
+		exit()
+	}
+	detail := "status " + credential.Status
+	if credential.Expiration != nil {
+		detail += ", expires " + credential.Expiration.Format(time.RFC3339)
+	}
+	report(credential.Status == "validated", true, "consumer key", detail)
+	missingRules := orderer.MissingRules(credential.Rules, orderer.RequiredAccessRules)
+	if len(missingRules) == 0 {
+		report(true, true, "access rules", "all paths needed to order are granted")
+	} else {
+		var rules []string
+		for _, rule := range missingRules {
+			rules = append(rules, rule.String())
+		}
+		report(false, true, "access rules", "missing "+strings.Join(rules, ", "))
+	}
+	exit()
+}
+
+// smoke exercises the read-only paths of the order flow against the API:
+// the account, the catalog, the availabilities and a cart deleted right
+// away. It never checks a cart out nor pays, so it is safe to run in CI,
+// and exits non-zero if any check fails.
+func smoke(args []string) {
+	fs := flag.NewFlagSet("smoke", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	subsidiary := registerSubsidiaryFlag(fs)
+	planCode := fs.String("plan", "", "plan code added to the test cart (defaults to the first plan of the catalog)")
+	fs.Parse(args)
+	client := clientFlags.newClient()
+
+	// The catalog is read from the API, not from the cache
+	o := newOrderer(client, orderer.Options{Logger: log.New(io.Discard, "", 0)})
+	defer o.Close()
+	ctx := context.Background()
+	sub := discoverySubsidiary(client, *subsidiary)
+
+	failed := false
+	check := func(name string, fn func() (string, error)) {
+		detail, err := fn()
+		if err != nil {
+			failed = true
+			fmt.Printf("\033[31m[FAIL]\033[0m %s: %v\n", name, err)
+			return
+		}
+		fmt.Printf("\033[32m[ OK ]\033[0m %s: %s\n", name, detail)
+	}
+
+	check("account", func() (string, error) {
+		account, err := o.Me(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("authenticated as %s", account.Nichandle), nil
+	})
+	check("catalog", func() (string, error) {
+		plans, err := o.ListPlans(ctx, sub)
+		if err != nil {
+			return "", err
+		}
+		if len(plans) == 0 {
+			return "", fmt.Errorf("no plan offered to %s", sub)
+		}
+		if *planCode == "" {
+			sort.Slice(plans, func(i, j int) bool { return plans[i].PlanCode < plans[j].PlanCode })
+			*planCode = plans[0].PlanCode
+		}
+		return fmt.Sprintf("%d plans offered to %s", len(plans), sub), nil
+	})
+	if *planCode == "" {
+		os.Exit(exitFailure)
+	}
+	check("availabilities", func() (string, error) {
+		availabilities, err := o.Availabilities(ctx, *planCode)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d entries for %s", len(availabilities), *planCode), nil
+	})
+	check("cart", func() (string, error) {
+		description, err := o.DescribePlan(ctx, sub, *planCode)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s added to a cart, %d configuration labels and %d options, cart deleted",
+			*planCode, len(description.Configuration), len(description.Options)), nil
+	})
+	if failed {
+		os.Exit(exitFailure)
+	}
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// orderServer runs the full order flow for the hardcoded server configuration
+func orderServer(args []string) {
+	fs := flag.NewFlagSet("order", flag.ExitOnError)
+	clientFlags := registerClientFlags(fs)
+	configPath := fs.String("config", "", "YAML file describing the order (see describe-plan); flags set explicitly override it")
+	fs.StringVar(configPath, "template", "", "alias of -config, for a base spec shared by several orders and adjusted with -set")
+	aliasPath := fs.String("plan-alias", paths.ConfigFile(orderer.AliasesFile), "YAML file mapping short names to order specs, selected with -alias; defaults to the aliases file of the config directory when it exists (see the paths command)")
+	alias := fs.String("alias", "", "name of the order spec of the -plan-alias file to order (e.g. big-storage-box), instead of -config")
+	listAliases := fs.Bool("list-aliases", false, "list the aliases of the -plan-alias file and exit")
+	var overrides overrideFlags
+	fs.Var(&overrides, "set", "override of the config, key=value or key+=value to append to options (e.g. datacenter=rbx,options+=ram-64g); may be repeated")
+	paymentMethodWait := fs.Duration("payment-method-wait", 30*time.Second, "how long to keep polling for payment methods after checkout")
+	paymentSettleWait := fs.Duration("payment-settle-wait", 5*time.Minute, "how long to wait for an accepted payment to clear before reporting it as pending")
+	autoPay := fs.Bool("auto-pay-preferred", false, "let OVH charge the preferred payment method of the account at checkout instead of paying through the API")
+	paymentMethodID := fs.String("payment-method-id", "", "only pay with the payment method with this ID")
+	paymentMethodType := fs.String("payment-method-type", "", "only pay with a payment method of this type (e.g. CREDIT_CARD)")
+	paymentMethodDefault := fs.Bool("payment-method-default", false, "only pay with the default payment method of the account")
+	waitAvailability := fs.Bool("wait-availability", false, "wait until the plan is in stock in the datacenter of the order (or any datacenter if none is configured) before ordering; without -bulk, an empty cart is held during the wait and resumed by the next run if this one stops")
+	availabilityInterval := fs.Duration("availability-interval", time.Minute, "interval between two stock checks with -wait-availability")
+	availabilityTimeout := fs.Duration("availability-timeout", 24*time.Hour, "how long to wait for stock with -wait-availability (0 for no limit)")
+	strictAvailability := fs.Bool("strict-availability", false, "fail when the stock cannot be checked with -wait-availability, instead of warning and ordering anyway")
+	maxStateAge := fs.Duration("max-state-age", 0, "with -wait-availability, do not resume a cart of an earlier wait created longer ago, start a new one instead (0 for no limit)")
+	policyPath := fs.String("policy", paths.ConfigFile(orderer.PolicyFile), "YAML file of purchasing rules (allowed datacenters and plans, forbidden options, server name pattern) checked before checkout; defaults to the policy file of the config directory when it exists (see the paths command), -policy= disables it")
+	extraIPs := fs.Int("extra-ips", 0, "number of additional IPs to order once the server is delivered")
+	extraIPsType := fs.String("extra-ips-type", orderer.ExtraIPsFailover, "type of additional IPs: failover (single IPs) or block")
+	failoverIPs := fs.Int("failover-ips", 0, "number of failover IPv4 addresses to order once the server is delivered; shorthand for -extra-ips N -extra-ips-type failover")
+	ipv6 := fs.Bool("ipv6", false, "fail unless an IPv6 block is routed to the delivered server, and print the IPs of the server")
+	billingAccount := fs.String("billing-account", "", "nichandle the order must be billed to; the order fails unless the credentials act on that account (defaults to the authenticated account)")
+	enableMonitoring := fs.Bool("enable-monitoring", false, "enable the OVH monitoring of the delivered server")
+	monitoringEmail := fs.String("monitoring-alert-email", "", "with -enable-monitoring, email address alerted when the SSH port of the server stops answering")
+	reverse := fs.String("reverse", "", "host name set as the reverse DNS of the primary IP of the delivered server (e.g. server1.example.com)")
+	deliveryTimeout := fs.Duration("delivery-timeout", 4*time.Hour, "how long to wait for the server delivery")
+	deliveryPollInterval := fs.Duration("delivery-poll-interval", 15*time.Second, "interval between two status checks of the order right after its payment")
+	deliveryBuildPollInterval := fs.Duration("delivery-build-poll-interval", time.Minute, "interval between two status checks once the server is being built, doubled after each check up to -delivery-max-poll-interval")
+	deliveryMaxPollInterval := fs.Duration("delivery-max-poll-interval", 10*time.Minute, "longest interval between two status checks of the order")
+	description := fs.String("description", "Automated Dedicated Server Order", "description of the cart")
+	runID := fs.String("run-id", "", "identifier of the run, recorded in the cart metadata and added to every log line and webhook payload (defaults to a random UUID)")
+	duration := fs.String("duration", "P1M", "ISO 8601 billing duration of the server and its options (e.g. P1M, P12M)")
+	pricingMode := fs.String("pricing-mode", "default", "pricing mode of the server and its options (e.g. default, or a commitment such as degressivity12)")
+	requirePricingMode := fs.String("require-pricing-mode", "", "abort before checkout unless the server is ordered with this pricing mode")
+	ackEngagement := fs.Bool("ack-engagement", false, "acknowledge the commitment of a committed pricing mode (e.g. degressivity12), which cannot be ended early; required for commitments over 1 month")
+	installTemplate := fs.String("install-template", "", "installation template to install once the server is delivered")
+	partitionScheme := fs.String("partition-scheme", "", "partition scheme of the installation template (defaults to the template's default scheme)")
+	hostname := fs.String("hostname", "", "custom hostname set by the installation")
+	osName := fs.String("os", "", "OS installed at delivery (dedicated_os value offered by the plan, see describe-plan); defaults to no OS")
+	maxAttempts := fs.Int("max-attempts", 4, "number of attempts of an API call failing with a transient error (503, connection reset, ...); 1 disables retries")
+	retryBudget := fs.Duration("retry-budget", 0, "total time spent waiting between retries over the whole run, after which failing calls are no longer retried (0 for no limit)")
+	debug := fs.Bool("debug", false, "print debug messages, such as the duration of each step")
+	timings := fs.Bool("timings", false, "print a summary of the duration of each step at the end")
+	output := fs.String("output", "text", "output format: text, shell to print OVH_* variables for eval, or json to print the result, or the error on failure, as a JSON object (progress then goes to stderr)")
+	tag := fs.String("tag", "", "display name set on the server once it is delivered, e.g. an inventory identifier")
+	customerReference := fs.String("customer-reference", "", "end customer the order is placed for, recorded in the cart metadata and reported in the result and notifications (up to 64 letters, digits and . _ : @ / + -)")
+	reuseExisting := fs.Bool("reuse-existing", false, "before ordering, look for a delivered server of the same plan and datacenter marked as unused by -reuse-marker and return it instead")
+	reuseMarker := fs.String("reuse-marker", "spare", "display name marking the delivered servers -reuse-existing may reuse")
+	maxPrice := fs.String("max-price", "", "maximum price of the cart, as a decimal amount (e.g. 129.99) compared with the price of -max-price-basis; the cart is deleted if it costs more")
+	maxPriceBasis := fs.String("max-price-basis", orderer.PriceBasisGross, "price -max-price applies to: gross (tax included) or net (without tax)")
+	priceBaselinePath := fs.String("price-baseline", "", "approved prices of the order, written by -build-only -save-price-baseline: the cart is deleted if a price of an item or the total drifted from them beyond -price-tolerance")
+	priceTolerance := fs.String("price-tolerance", "", "how far the prices may drift from -price-baseline: an amount (e.g. 0.50) or a percentage of each price (e.g. 2%); none by default")
+	saveBaseline := fs.String("save-price-baseline", "", "with -build-only, write the prices of the cart to this file, to approve them and check them at checkout with -price-baseline")
+	bestEffort := fs.Bool("best-effort", false, "skip the options that cannot be added instead of failing, and check out with the others")
+	deliveryPriority := fs.String("delivery-priority", "", "delivery option of the server, by plan code or a word of it (e.g. express), among the delivery options of list-options; skipped when the plan offers none")
+	allowDuplicateOptions := fs.Bool("allow-duplicate-options", false, "add an option listed several times once per listing, each charged, instead of once with a warning")
+	var optionFlags overrideFlags
+	fs.Var(&optionFlags, "option", "option added to the server, as a plan code or family=capacity (e.g. ram=32g, storage=2x512nvme); may be repeated")
+	var labelFlags overrideFlags
+	fs.Var(&labelFlags, "label", "configuration label of the server as label=value (e.g. dedicated_datacenter=rbx), replacing the value of the config; see describe-plan for the labels of a plan; may be repeated")
+	buildOnly := fs.Bool("build-only", false, "build and validate the cart without checking it out, print its ID and exit; buy it later with the purchase command")
+	noOptions := fs.Bool("no-options", false, "order the bare plan, without the options of the config or the defaults")
+	checkStatus := fs.Bool("check-status", false, "warn about the incidents and maintenances announced by OVH before ordering")
+	skipAuthCheck := fs.Bool("skip-auth-check", false, "do not check that the consumer key is valid and not expired before ordering")
+	useDefaults := fs.Bool("use-defaults", false, "order the typical build of a well-known plan when the config gives no options")
+	tfOutput := fs.String("tf-output", "", "after a successful order, write an ovh_dedicated_server Terraform resource of the server and the import binding it to this file")
+	saveConfig := fs.String("save-config", "", "after a successful order, write the config reproducing it, with the resolved plan, configuration and options, to this file (json if it ends with .json, yaml otherwise)")
+	printConfig := fs.String("print-config", "", "print the effective configuration, after merging the config file and flags, as yaml or json, and exit without ordering")
+	allowEndpoints := fs.String("allow-endpoint", "", "comma-separated endpoints orders may be placed on (e.g. ovh-eu); required unless replaying or with -build-only, so that a config cannot buy a server on the wrong account")
+	minRAM := fs.Int("min-ram", 0, "order the cheapest plan with at least this much RAM in GB, instead of the plan of the config")
+	minCores := fs.Int("min-cores", 0, "order the cheapest plan with at least this many CPU cores")
+	storageType := fs.String("storage-type", "", "order the cheapest plan with disks of this technology (e.g. nvme, ssd)")
+	inRegion := fs.String("in-region", "", "order the cheapest plan offered in this region (e.g. europe) or datacenter (e.g. rbx)")
+	notifierFlags := registerNotifierFlags(fs)
+	watchFile := fs.String("watch-file", "", "after payment, add the order to this watch list and exit instead of waiting for its delivery, which excludes the post-delivery steps (-install-template, -extra-ips, -tag...); run watch -state with the same file, or give the default watch list of watch (see the paths command), to be notified of the delivery")
+	otelEndpoint := fs.String("otel-endpoint", "", "OTLP/HTTP endpoint the trace of the order is exported to (e.g. http://localhost:4318); TRACEPARENT sets the parent trace")
+	bulk := fs.Int("bulk", 1, "number of identical servers to order, each in its own cart and order")
+	batchPath := fs.String("batch", "", "YAML or JSON file, or CSV file when named *.csv, listing distinct orders each with an id, ordered like -bulk and reported by id; every order is validated before any is placed")
+	raceDatacenters := fs.String("race-datacenters", "", "comma-separated datacenters (e.g. gra,rbx,sbg) to build a cart in concurrently, checking out the first one built that can be ordered and deleting the others; at most one order is created. Requires -ack-cart-churn")
+	ackCartChurn := fs.Bool("ack-cart-churn", false, "acknowledge that -race-datacenters creates a cart per datacenter and multiplies the API calls")
+	workers := fs.Int("workers", 4, "number of orders run in parallel with -bulk or -batch")
+	maxPerDatacenter := fs.Int("max-concurrency-per-datacenter", 0, "number of orders run in parallel for the same datacenter with -bulk or -batch (0 for no limit beyond -workers)")
+	orderTimeout := fs.Duration("timeout-per-order", 0, "how long each order of -bulk or -batch may take before it is abandoned and its cart deleted (0 for no limit)")
+	var planFlags overrideFlags
+	fs.Var(&planFlags, "plan", "plan code to order, replacing the plan of the config (e.g. 24rise01-us); with -compare-plans, may be repeated")
+	comparePlans := fs.Bool("compare-plans", false, "price each -plan with the options of the order in a temporary cart, print a comparison and exit without ordering")
+	renewal := fs.String("renewal", "", "renewal mode set on the delivered server: auto or manual (defaults to the OVH default)")
+	renewalPeriod := fs.Int("renewal-period", 0, "with -renewal auto, renewal period in months, among those the server accepts (defaults to the current period)")
+	verifyConnectivity := fs.Bool("verify-connectivity", false, "once the server is delivered and set up, check that its primary IP accepts TCP connections on -connectivity-port")
+	connectivityPort := fs.Int("connectivity-port", 22, "with -verify-connectivity, TCP port dialed on the server")
+	connectivityTimeout := fs.Duration("connectivity-timeout", 10*time.Minute, "with -verify-connectivity, how long to keep dialing the server before reporting it unreachable")
+	healthAddr := fs.String("health-addr", "", "address to serve /healthz and /readyz on during the run (e.g. :8081), reporting whether the API is reachable and accepts the credentials; off by default")
+	healthInterval := fs.Duration("health-interval", time.Minute, "interval between two checks of the API with -health-addr")
+	eventsAddr := fs.String("events-addr", "", "address to stream the progress of the order on as Server-Sent Events during the run (e.g. :8082), at /events?run=<run ID>; off by default")
+	runSummary := fs.String("run-summary", "text", "summary of the retries, backoff and rate limiting of the run printed at the end: text, json or none")
+	interactive := fs.Bool("interactive", false, "pick the plan, configuration and options from prompts, using the config and flags as defaults, and confirm the price before checkout")
+	fs.Parse(args)
+	if *output == "json" {
+		jsonErrors = os.Stdout
+	}
+
+	// Aliases are listed without credentials
+	var aliases orderer.Aliases
+	if *aliasPath != "" {
+		var err error
+		if aliases, err = orderer.LoadAliases(files, *aliasPath); err != nil {
+			fatalf(exitUsage, "Error loading aliases: %v", err)
+		}
+	}
+	if *listAliases {
+		if aliases == nil {
+			fatalf(exitUsage, "-list-aliases requires -plan-alias")
+		}
+		printAliases(os.Stdout, aliases)
+		return
+	}
+	client := clientFlags.newClient()
+
+	// In shell and json modes stdout only carries the variables or the object
+	var human io.Writer = os.Stdout
+	switch *output {
+	case "text":
+	case "shell", "json":
+		human = os.Stderr
+	default:
+		fatalf(exitUsage, "Invalid -output %q: expected text, shell or json", *output)
+	}
+	switch *runSummary {
+	case "text", "json", "none":
+	default:
+		fatalf(exitUsage, "Invalid -run-summary %q: expected text, json or none", *runSummary)
+	}
+
+	req := defaultOrderRequest()
+	var config *orderer.Config
+	if *configPath != "" || *alias != "" {
+		var err error
+		switch {
+		case *configPath != "" && *alias != "":
+			fatalf(exitUsage, "-alias cannot be combined with -config or -template")
+		case *alias != "":
+			if aliases == nil {
+				fatalf(exitUsage, "-alias requires -plan-alias")
+			}
+			config, err = aliases.Lookup(*alias)
+		default:
+			config, err = orderer.LoadConfig(files, *configPath)
+		}
+		if err != nil {
+			fatalf(exitUsage, "Error loading config: %v", err)
+		}
+		for _, override := range overrides {
+			if err := config.Set(override); err != nil {
+				fatalf(exitUsage, "Error applying -set: %v", err)
+			}
+		}
+		req = config.OrderRequest()
+	} else if len(overrides) > 0 {
+		fatalf(exitUsage, "-set requires -config, -template or -alias")
+	}
+
+	// Flags set on the command line take precedence over the config file
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	switch {
+	case *comparePlans && len(planFlags) == 0:
+		fatalf(exitUsage, "-compare-plans requires the plans to compare with -plan")
+	case len(planFlags) > 1 && !*comparePlans:
+		fatalf(exitUsage, "-plan may only be repeated with -compare-plans")
+	case len(planFlags) > 0:
+		req.PlanCode = planFlags[0]
+	}
+	if set["description"] {
+		req.Description = *description
+	}
+	if set["duration"] {
+		req.Duration = *duration
+	}
+	if set["pricing-mode"] {
+		req.PricingMode = *pricingMode
+	}
+	req.RequirePricingMode = *requirePricingMode
+	req.AckEngagement = *ackEngagement
+	if set["os"] {
+		req.OS = *osName
+	}
+	if set["install-template"] {
+		req.Install = nil
+		if *installTemplate != "" {
+			req.Install = &orderer.InstallRequest{Template: *installTemplate}
+		}
+	}
+	if set["partition-scheme"] || set["hostname"] {
+		if req.Install == nil {
+			fatalf(exitUsage, "-partition-scheme and -hostname require -install-template or an install section in the config")
+		}
+		if set["partition-scheme"] {
+			req.Install.PartitionScheme = *partitionScheme
+		}
+		if set["hostname"] {
+			req.Install.Hostname = *hostname
+		}
+	}
+	if set["extra-ips"] {
+		req.ExtraIPs = nil
+		if *extraIPs > 0 {
+			req.ExtraIPs = &orderer.ExtraIPs{Count: *extraIPs, Type: *extraIPsType}
+		}
+	}
+	if set["extra-ips-type"] && req.ExtraIPs != nil {
+		req.ExtraIPs.Type = *extraIPsType
+	}
+	if set["failover-ips"] {
+		if set["extra-ips"] || set["extra-ips-type"] {
+			fatalf(exitUsage, "-failover-ips cannot be combined with -extra-ips or -extra-ips-type")
+		}
+		req.ExtraIPs = nil
+		if *failoverIPs > 0 {
+			req.ExtraIPs = &orderer.ExtraIPs{Count: *failoverIPs, Type: orderer.ExtraIPsFailover}
+		}
+	}
+	if set["ipv6"] {
+		req.RequireIPv6 = *ipv6
+	}
+	if set["reverse"] {
+		req.Reverse = *reverse
+	}
+	if *monitoringEmail != "" && !*enableMonitoring {
+		fatalf(exitUsage, "-monitoring-alert-email requires -enable-monitoring")
+	}
+	if *enableMonitoring {
+		req.Monitoring = &orderer.MonitoringRequest{AlertEmail: *monitoringEmail}
+	}
+	switch *renewal {
+	case "":
+		if *renewalPeriod != 0 {
+			fatalf(exitUsage, "-renewal-period requires -renewal auto")
+		}
+	case "auto":
+		req.Renewal = &orderer.RenewalRequest{Automatic: true, Period: *renewalPeriod}
+	case "manual":
+		if *renewalPeriod != 0 {
+			fatalf(exitUsage, "-renewal-period requires -renewal auto")
+		}
+		req.Renewal = &orderer.RenewalRequest{}
+	default:
+		fatalf(exitUsage, "Invalid -renewal %q: expected auto or manual", *renewal)
+	}
+	if (set["connectivity-port"] || set["connectivity-timeout"]) && !*verifyConnectivity {
+		fatalf(exitUsage, "-connectivity-port and -connectivity-timeout require -verify-connectivity")
+	}
+	if *verifyConnectivity {
+		req.Connectivity = &orderer.ConnectivityRequest{Port: *connectivityPort, Timeout: *connectivityTimeout}
+	}
+	if set["billing-account"] {
+		req.BillingAccount = *billingAccount
+	}
+	for _, label := range labelFlags {
+		name, value, ok := strings.Cut(label, "=")
+		if !ok || name == "" {
+			fatalf(exitUsage, "Invalid -label %q: expected label=value", label)
+		}
+		req.Configuration = setLabel(req.Configuration, name, value)
+	}
+	if *noOptions {
+		req.Options = nil
+	}
+	for _, option := range optionFlags {
+		req.Options = append(req.Options, orderer.Option{PlanCode: option})
+	}
+	if len(req.Options) == 0 && !*noOptions {
+		if build, ok := orderer.SuggestBuild(req.PlanCode); ok {
+			if *useDefaults {
+				req.ApplyBuild(build)
+			}
+			printBuild(human, req.PlanCode, build, *useDefaults)
+		}
+	}
+	if set["best-effort"] {
+		req.BestEffort = *bestEffort
+	}
+	req.AllowDuplicateOptions = *allowDuplicateOptions
+	if set["auto-pay-preferred"] {
+		req.AutoPay = *autoPay
+	}
+	if set["tag"] {
+		req.Tag = *tag
+	}
+	if set["customer-reference"] {
+		req.CustomerReference = *customerReference
+	}
+	if set["max-price"] {
+		req.MaxPrice = *maxPrice
+	}
+	if set["max-price-basis"] {
+		req.MaxPriceBasis = *maxPriceBasis
+	}
+	if set["delivery-priority"] {
+		req.DeliveryPriority = *deliveryPriority
+	}
+	if *reuseExisting {
+		req.ReuseMarker = *reuseMarker
+	}
+	var raced []string
+	if *raceDatacenters != "" {
+		raced = strings.Split(*raceDatacenters, ",")
+		switch {
+		case len(raced) < 2:
+			fatalf(exitUsage, "-race-datacenters needs at least two datacenters")
+		case !*ackCartChurn:
+			fatalf(exitUsage, "-race-datacenters creates %d carts and multiplies the API calls: add -ack-cart-churn to race", len(raced))
+		case *bulk > 1 || *buildOnly || *waitAvailability || *reuseExisting:
+			fatalf(exitUsage, "-race-datacenters cannot be combined with -bulk, -build-only, -wait-availability or -reuse-existing")
+		}
+	}
+	if *runID == "" {
+		*runID = newRunID()
+	}
+	req.RunID = *runID
+
+	var batch []orderer.BatchEntry
+	if *batchPath != "" {
+		switch {
+		case *configPath != "" || *alias != "" || len(overrides) > 0:
+			fatalf(exitUsage, "-batch cannot be combined with -config, -template, -alias or -set: each order is described by the batch file")
+		case *bulk > 1 || raced != nil || *comparePlans || *buildOnly || *waitAvailability || *watchFile != "" || *printConfig != "":
+			fatalf(exitUsage, "-batch cannot be combined with -bulk, -race-datacenters, -compare-plans, -build-only, -wait-availability, -watch-file or -print-config")
+		case *interactive || *output != "text":
+			fatalf(exitUsage, "-batch cannot be combined with -interactive or -output shell or json")
+		}
+		batch = loadBatch(*batchPath, req, set)
+	}
+	if *priceBaselinePath != "" {
+		if batch != nil || *comparePlans || *buildOnly {
+			fatalf(exitUsage, "-price-baseline cannot be combined with -batch, -compare-plans or -build-only")
+		}
+		req.PriceBaseline = loadPriceBaseline(*priceBaselinePath)
+	}
+	if err := orderer.ValidatePriceTolerance(*priceTolerance); err != nil {
+		fatalf(exitUsage, "%v", err)
+	}
+	req.PriceTolerance = *priceTolerance
+	if *saveBaseline != "" && !*buildOnly {
+		fatalf(exitUsage, "-save-price-baseline requires -build-only")
+	}
+
+	if req.Duration != "" {
+		if err := orderer.ValidateDuration(req.Duration); err != nil {
+			fatalf(exitUsage, "Invalid duration: %v", err)
+		}
+	}
+	if req.Subsidiary != "" {
+		endpoint, _ := orderer.NormalizeEndpoint(client.Endpoint())
+		req.Subsidiary = normalizeSubsidiary(req.Subsidiary, endpoint)
+	}
+
+	requirements := orderer.PlanRequirements{
+		MinMemoryGB:       *minRAM,
+		MinCores:          *minCores,
+		StorageTechnology: *storageType,
+		Region:            *inRegion,
+	}
+	if requirements != (orderer.PlanRequirements{}) {
+		req = selectPlan(client, clientFlags.catalogCache(), req, requirements)
+	}
+	if batch == nil {
+		configSource := "the config file " + *configPath
+		if *alias != "" {
+			configSource = "the alias " + *alias + " of " + *aliasPath
+		}
+		if len(overrides) > 0 {
+			configSource += " with its -set overrides"
+		}
+		explainInputs(req, set, config, configSource)
+	}
+
+	if *printConfig != "" {
+		if err := writeConfig(os.Stdout, orderer.ConfigFromRequest(req).Redacted(), *printConfig); err != nil {
+			fatalError(err, "Error printing config: %v", err)
+		}
+		return
+	}
+
+	if *comparePlans {
+		o := newOrderer(client, orderer.Options{
+			MaxAttempts: *maxAttempts,
+			RetryBudget: *retryBudget,
+			Logger:      log.New(os.Stderr, "", 0),
+		})
+		defer o.Close()
+		quotes, err := o.ComparePlans(context.Background(), req, planFlags)
+		if err != nil {
+			fatalError(err, "Error comparing plans: %v", err)
+		}
+		printQuotes(human, req.Subsidiary, quotes)
+		return
+	}
+
+	var allowedEndpoints []string
+	if !*buildOnly || *allowEndpoints != "" {
+		// A cart built without buying it cannot buy a server on the wrong
+		// account: the purchase command asks for the endpoints
+		allowedEndpoints = clientFlags.allowedEndpoints(*allowEndpoints)
+	}
+	opts := orderer.Options{
+		AllowedEndpoints:  allowedEndpoints,
+		PaymentMethodWait: *paymentMethodWait,
+		PaymentSettleWait: *paymentSettleWait,
+		PaymentMethod: orderer.PaymentMethodCriteria{
+			ID:      *paymentMethodID,
+			Type:    *paymentMethodType,
+			Default: *paymentMethodDefault,
+		},
+		DeliveryTimeout:           *deliveryTimeout,
+		DeliveryPollInterval:      *deliveryPollInterval,
+		DeliveryBuildPollInterval: *deliveryBuildPollInterval,
+		DeliveryMaxPollInterval:   *deliveryMaxPollInterval,
+		MaxAttempts:               *maxAttempts,
+		RetryBudget:               *retryBudget,
+		StrictAvailability:        *strictAvailability,
+		MaxStateAge:               *maxStateAge,
+		CheckoutPolicy:            loadPolicy(*policyPath),
+		Debug:                     *debug,
+		Logger:                    log.New(human, "run="+*runID+" ", 0),
+	}
+	if *clientFlags.simulate != "" {
+		// Recorded status checks are answered at once, do not wait minutes between them
+		opts.DeliveryPollInterval = time.Second
+		opts.DeliveryBuildPollInterval = time.Second
+		opts.DeliveryMaxPollInterval = time.Second
+		opts.TaskPollInterval = time.Second
+	}
+	if *interactive {
+		p := &prompter{in: bufio.NewReader(os.Stdin), out: human}
+		req = p.orderRequest(context.Background(), newOrderer(client, opts), req)
+		opts.ConfirmCheckout = p.confirmCheckout
+	}
+	var handlers []func(orderer.Event)
+	var tracer *orderTracer
+	if *otelEndpoint != "" {
+		var err error
+		if tracer, err = startTracing(*otelEndpoint, req); err != nil {
+			fatalf(exitUsage, "Error setting up tracing: %v", err)
+		}
+		handlers = append(handlers, tracer.onEvent)
+	}
+	notifiers := notifierFlags.notifiers(opts.Logger, *runID)
+	if len(notifiers) > 0 {
+		handlers = append(handlers, orderer.NotifyEvents(notifiers, *runID, *notifierFlags.timeout, opts.Logger))
+	}
+	var stream *orderer.EventStream
+	if *eventsAddr != "" {
+		switch {
+		case *bulk > 1 || batch != nil:
+			fatalf(exitUsage, "-events-addr cannot be combined with -bulk or -batch, whose orders share the run ID")
+		case *buildOnly:
+			fatalf(exitUsage, "-events-addr cannot be combined with -build-only")
+		}
+		stream = orderer.NewEventStream(0)
+		handlers = append(handlers, stream.OnEvent)
+	}
+	if len(handlers) > 0 {
+		opts.OnEvent = func(event orderer.Event) {
+			for _, handler := range handlers {
+				handler(event)
+			}
+		}
+	}
+	o := newOrderer(client, opts)
+	defer o.Close()
+	opts.Logger.Printf("Starting run %s", *runID)
+	if *healthAddr != "" {
+		checker := o.NewHealthChecker(*healthInterval)
+		ctx, stop := context.WithCancel(context.Background())
+		defer stop()
+		go checker.Run(ctx)
+		server := &http.Server{Addr: *healthAddr, Handler: checker}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				opts.Logger.Printf("Error serving health checks: %v", err)
+			}
+		}()
+		defer server.Close()
+	}
+	stopStream := func() {}
+	if stream != nil {
+		server := &http.Server{Addr: *eventsAddr, Handler: stream}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				opts.Logger.Printf("Error serving progress events: %v", err)
+			}
+		}()
+		// Let the streams send the last event of the order before exiting
+		stopStream = func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(ctx)
+		}
+		opts.Logger.Printf("Streaming progress events on %s at /events?run=%s", *eventsAddr, *runID)
+	}
+	if *checkStatus {
+		tasks, err := o.OngoingStatusTasks(context.Background())
+		if err != nil {
+			opts.Logger.Printf("Warning: %v", err)
+		}
+		for _, task := range tasks {
+			opts.Logger.Printf("WARNING: OVH announces %s", task)
+		}
+	}
+	// Recorded fixtures have no credential to check
+	if !*skipAuthCheck && clientFlags.replayPath() == "" {
+		credential, err := o.CheckCredential(context.Background())
+		if err != nil {
+			fatalError(err, "Consumer key check failed, nothing was ordered: %v (see check-auth, or skip with -skip-auth-check)", err)
+		}
+		if credential.Expiration != nil && time.Until(*credential.Expiration) < *deliveryTimeout {
+			opts.Logger.Printf("Warning: the consumer key expires on %s, before the delivery may be over", credential.Expiration.Format(time.RFC3339))
+		}
+	}
+	if *debug {
+		opts.Logger.Printf("Sending API requests as %s", *clientFlags.userAgent)
+	}
+
+	if *waitAvailability {
+		var datacenters []string
+		for _, config := range req.Configuration {
+			if config.Label == "dedicated_datacenter" {
+				datacenters = append(datacenters, config.Value)
+			}
+		}
+		var datacenter string
+		var err error
+		if *bulk > 1 {
+			datacenter, err = o.WaitForAvailability(context.Background(), req, datacenters, *availabilityInterval, *availabilityTimeout)
+		} else {
+			// The cart is held during the wait, and resumed by the next run
+			// if this one dies or gives up
+			var cartID string
+			cartID, datacenter, err = o.WaitInCart(context.Background(), req, datacenters, *availabilityInterval, *availabilityTimeout)
+			if err != nil && cartID != "" {
+				err = fmt.Errorf("%w (cart %s is kept for the next run with -wait-availability)", err, cartID)
+			}
+			req.CartID = cartID
+		}
+		if err != nil {
+			fatalError(err, "%v", err)
+		}
+		if len(datacenters) == 0 && datacenter != "" {
+			req.Configuration = append(req.Configuration, orderer.Configuration{Label: "dedicated_datacenter", Value: datacenter})
+		}
+	}
+
+	if *buildOnly {
+		if *bulk > 1 {
+			fatalf(exitUsage, "-build-only cannot be combined with -bulk")
+		}
+		cartID, err := o.BuildCart(context.Background(), req)
+		if err != nil {
+			fatalError(err, "Error building the cart: %v", err)
+		}
+		fmt.Fprintf(human, "Cart %s is ready, review it then buy it with: purchase -cart %s\n", cartID, cartID)
+		if *saveBaseline != "" {
+			savePriceBaseline(human, o, cartID, *saveBaseline)
+		}
+		switch *output {
+		case "shell":
+			fmt.Fprintf(os.Stdout, "OVH_CART_ID=%s\n", shellQuote(cartID))
+		case "json":
+			json.NewEncoder(os.Stdout).Encode(map[string]string{"cartId": cartID})
+		}
+		return
+	}
+
+	if *bulk > 1 || batch != nil {
+		bulkOpts := orderer.BulkOptions{
+			Workers:          *workers,
+			MaxPerDatacenter: *maxPerDatacenter,
+			OrderTimeout:     *orderTimeout,
+		}
+		var results []orderer.BulkResult
+		var ids []string
+		if batch != nil {
+			var err error
+			results, err = o.OrderBatch(context.Background(), batch, bulkOpts)
+			if err != nil {
+				fatalf(exitUsage, "Invalid batch %s, nothing was ordered:\n%v", *batchPath, err)
+			}
+			for _, entry := range batch {
+				ids = append(ids, entry.ID)
+			}
+		} else {
+			if *interactive || *output != "text" {
+				fatalf(exitUsage, "-bulk cannot be combined with -interactive or -output shell or json")
+			}
+			reqs := make([]orderer.OrderRequest, *bulk)
+			for i := range reqs {
+				reqs[i] = req
+			}
+			results = o.OrderBulk(context.Background(), reqs, bulkOpts)
+		}
+		if tracer != nil {
+			tracer.end(nil, nil)
+		}
+		var orderResults []*orderer.OrderResult
+		for _, r := range results {
+			orderResults = append(orderResults, r.Result)
+		}
+		printRunSummary(human, *runSummary, o.RetryStats(), clientFlags.limiter, orderResults)
+		if code := printBulkResults(human, results, ids); code != exitOK {
+			os.Exit(code)
+		}
+		return
+	}
+
+	if *watchFile != "" && req.HasPostDelivery() {
+		fatalf(exitUsage, "-watch-file cannot be combined with post-delivery steps (installation, extra IPs, tag, IPv6, reverse DNS, monitoring, renewal or connectivity check), which wait for the delivery")
+	}
+
+	var result *orderer.OrderResult
+	var err error
+	if raced != nil {
+		result, err = o.RaceDatacenters(context.Background(), req, raced)
+	} else {
+		result, err = o.Order(context.Background(), req)
+	}
+	stopStream()
+	if tracer != nil {
+		tracer.end(result, err)
+	}
+	if *timings && result != nil {
+		printTimings(human, result)
+	}
+	printRunSummary(human, *runSummary, o.RetryStats(), clientFlags.limiter, []*orderer.OrderResult{result})
+	if err != nil && *output == "json" {
+		fatalError(err, "Order failed: %v", err)
+	}
+	var interactivePayment *orderer.InteractivePaymentError
+	if errors.As(err, &interactivePayment) {
+		fmt.Fprintf(human, "Order %s cannot be paid through the API.\n", interactivePayment.OrderID)
+		fmt.Fprintf(human, "Open %s in a browser to complete the payment (e.g. 3-D Secure).\n", interactivePayment.URL)
+		os.Exit(exitCode(err))
+	}
+	if errors.Is(err, orderer.ErrMaxPriceExceeded) {
+		fmt.Fprintf(human, "Order cancelled, the cart has been deleted: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+	if errors.Is(err, orderer.ErrPriceDrift) {
+		fmt.Fprintln(human, "Order cancelled, the cart has been deleted.")
+		printPriceDrift(human, err)
+		os.Exit(exitCode(err))
+	}
+	if errors.Is(err, orderer.ErrEngagementNotAcknowledged) {
+		fmt.Fprintf(human, "%s\n", orderer.ParseEngagement(req.PricingMode))
+		fatalError(err, "%v: add -ack-engagement to order it", err)
+	}
+	if errors.Is(err, orderer.ErrCheckoutDeclined) {
+		fmt.Fprintln(human, "Order cancelled, the cart has been deleted.")
+		os.Exit(exitCode(err))
+	}
+	if errors.Is(err, orderer.ErrPolicyViolation) {
+		fmt.Fprintf(human, "Order cancelled, the cart has been deleted: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+	if errors.Is(err, orderer.ErrPaymentPending) {
+		fmt.Fprintf(human, "Payment of order %s with %s payment method %s is still pending: check the order before paying it again.\n", result.OrderID, result.PaymentMethodType, result.PaymentMethodID)
+		os.Exit(exitCode(err))
+	}
+	if errors.Is(err, orderer.ErrUnreachable) {
+		// The server is delivered and set up all the same
+		printResult(human, result)
+		fatalError(err, "Connectivity check failed: %v", err)
+	}
+	if err != nil {
+		fatalError(err, "Order failed: %v", err)
+	}
+
+	printResult(human, result)
+	if *watchFile != "" && result.ServiceName == "" && !result.Reused {
+		list := &orderer.WatchList{Path: *watchFile}
+		err := list.Add(orderer.WatchedOrder{OrderID: result.OrderID, Added: time.Now(), RunID: *runID, CustomerReference: result.CustomerReference})
+		if err != nil {
+			fatalError(err, "Error adding order %s to the watch list: %v", result.OrderID, err)
+		}
+		fmt.Fprintf(human, "Order %s added to %s, run watch -state %s to be notified of its delivery\n", result.OrderID, *watchFile, *watchFile)
+	}
+	switch *output {
+	case "shell":
+		printShellVariables(os.Stdout, result)
+	case "json":
+		printJSONResult(os.Stdout, result)
+	}
+	printReproduction(human, orderer.ConfigFromResult(req, result).Redacted(), *saveConfig)
+	if *tfOutput != "" {
+		writeTerraform(human, *tfOutput, *orderer.ConfigFromResult(req, result), result)
+	}
+}
+
+// writeTerraform writes the Terraform resource of the server ordered with
+// config to path
+func writeTerraform(w io.Writer, path string, config orderer.Config, result *orderer.OrderResult) {
+	var b bytes.Buffer
+	if err := orderer.WriteTerraform(&b, config, result.OrderID, result.ServiceName); err != nil {
+		log.Printf("Error writing the Terraform resource: %v", err)
+		return
+	}
+	if err := files.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		log.Printf("Error writing the Terraform resource: %v", err)
+		return
+	}
+	fmt.Fprintf(w, "Terraform resource %s written to %s\n", orderer.TerraformName(config.Tag), path)
+	if result.ServiceName == "" {
+		fmt.Fprintln(w, "Import it once the server is delivered, see the file.")
+	}
+}
+
+// loadPolicy returns the checkout policy of the -policy file at path, or nil
+// when path is empty
+func loadPolicy(path string) orderer.CheckoutPolicy {
+	if path == "" {
+		return nil
+	}
+	policy, err := orderer.LoadPolicy(files, path)
+	if err != nil {
+		fatalf(exitUsage, "%v", err)
+	}
+	return policy.Check
+}
+
+// loadPriceBaseline returns the price baseline at path, or nil when path is
+// empty
+func loadPriceBaseline(path string) *orderer.PriceBaseline {
+	if path == "" {
+		return nil
+	}
+	baseline, err := orderer.LoadPriceBaseline(files, path)
+	if err != nil {
+		fatalf(exitUsage, "%v", err)
+	}
+	return baseline
+}
+
+// savePriceBaseline writes the prices of the cart cartID to path, as YAML
+// or, when its name ends with .json, JSON
+func savePriceBaseline(w io.Writer, o *orderer.Orderer, cartID, path string) {
+	baseline, err := o.PriceBaseline(context.Background(), cartID)
+	if err != nil {
+		fatalError(err, "Error reading the prices of cart %s: %v", cartID, err)
+	}
+	var buf bytes.Buffer
+	if strings.HasSuffix(path, ".json") {
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(baseline)
+	} else {
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err = enc.Encode(baseline); err == nil {
+			err = enc.Close()
+		}
+	}
+	if err == nil {
+		err = files.WriteFile(path, buf.Bytes(), 0o644)
+	}
+	if err != nil {
+		fatalError(err, "Error writing the price baseline: %v", err)
+	}
+	fmt.Fprintf(w, "Prices of cart %s (%s %s without tax) written to %s, check them at checkout with: purchase -cart %s -price-baseline %s\n",
+		cartID, baseline.Total, baseline.Currency, path, cartID, path)
+}
+
+// printPriceDrift prints the prices that drifted from the baseline, one per
+// line, or err when it does not list them
+func printPriceDrift(w io.Writer, err error) {
+	var drift *orderer.PriceDriftError
+	if !errors.As(err, &drift) {
+		fmt.Fprintf(w, "%v\n", err)
+		return
+	}
+	tolerance := drift.Tolerance
+	if tolerance == "" {
+		tolerance = "none"
+	}
+	fmt.Fprintf(w, "Prices drifted from the baseline (%s, tolerance %s):\n", drift.Currency, tolerance)
+	for _, d := range drift.Drifts {
+		fmt.Fprintf(w, "  %s\n", d)
+	}
+}
+
+// explainInputs narrates where the inputs of req come from: the flag setting
+// them, config, read from configSource, or the defaults
+func explainInputs(req orderer.OrderRequest, set map[string]bool, config *orderer.Config, configSource string) {
+	if explainer == nil {
+		return
+	}
+	var c orderer.Config
+	if config != nil {
+		c = *config
+	}
+	source := func(inConfig bool, flags ...string) string {
+		for _, name := range flags {
+			if set[name] {
+				return "the -" + name + " flag"
+			}
+		}
+		if inConfig {
+			return configSource
+		}
+		return "the default"
+	}
+	configDatacenter := false
+	for _, label := range c.Configuration {
+		configDatacenter = configDatacenter || label.Label == "dedicated_datacenter"
+	}
+	datacenter := "none, any datacenter with stock"
+	for _, config := range req.Configuration {
+		if config.Label == "dedicated_datacenter" {
+			datacenter = config.Value
+		}
+	}
+	options := make([]string, 0, len(req.Options))
+	for _, option := range req.Options {
+		options = append(options, option.PlanCode)
+	}
+	explainer.Printf("Subsidiary %s, from %s", req.Subsidiary, source(c.Subsidiary != ""))
+	explainer.Printf("Plan %s, from %s", req.PlanCode, source(c.Plan != "", "plan", "min-ram", "min-cores", "storage-type", "in-region"))
+	explainer.Printf("Duration %s, from %s", req.Duration, source(c.Duration != "", "duration"))
+	explainer.Printf("Pricing mode %s, from %s", req.PricingMode, source(c.PricingMode != "", "pricing-mode"))
+	explainer.Printf("Datacenter %s, from %s", datacenter, source(configDatacenter, "label", "in-region"))
+	if req.OS != "" {
+		explainer.Printf("OS %s, from %s", req.OS, source(c.OS != "", "os"))
+	} else {
+		explainer.Printf("No OS, the \"no OS\" value of the plan is looked up, as no -os is given")
+	}
+	if len(options) > 0 {
+		explainer.Printf("Options %s, from %s", strings.Join(options, ", "), source(len(c.Options) > 0, "option", "use-defaults"))
+	} else {
+		explainer.Printf("No options, from %s", source(false, "no-options"))
+	}
+	if req.MaxPrice != "" {
+		explainer.Printf("Maximum price %s (%s), from %s", req.MaxPrice, req.MaxPriceBasis, source(c.MaxPrice != "", "max-price"))
+	}
+	if req.DeliveryPriority != "" {
+		explainer.Printf("Delivery priority %s, from %s", req.DeliveryPriority, source(c.DeliveryPriority != "", "delivery-priority"))
+	}
+}
+
+// batchOrderFlags are the flags describing the server of an order, which
+// the entries of a -batch file describe instead
+var batchOrderFlags = []string{
+	"plan", "description", "duration", "pricing-mode", "os", "label", "option", "no-options", "use-defaults",
+	"install-template", "partition-scheme", "hostname", "extra-ips", "extra-ips-type", "failover-ips",
+	"ipv6", "reverse", "billing-account", "tag", "customer-reference", "max-price", "max-price-basis",
+	"best-effort", "auto-pay-preferred", "delivery-priority",
+}
+
+// loadBatch returns the orders of the -batch file at path. The settings of
+// the run set by flags on base, such as -enable-monitoring or -renewal,
+// apply to every order; the flags describing the server are rejected, as
+// each entry describes its own.
+func loadBatch(path string, base orderer.OrderRequest, set map[string]bool) []orderer.BatchEntry {
+	for _, name := range batchOrderFlags {
+		if set[name] {
+			fatalf(exitUsage, "-%s cannot be combined with -batch: set it on each order of the batch file", name)
+		}
+	}
+	batch, err := orderer.LoadBatch(files, path)
+	if err != nil {
+		fatalf(exitUsage, "Error loading batch: %v", err)
+	}
+	for i := range batch {
+		req := &batch[i].Request
+		req.RunID = base.RunID
+		req.RequirePricingMode = base.RequirePricingMode
+		req.AckEngagement = base.AckEngagement
+		req.Monitoring = base.Monitoring
+		req.Renewal = base.Renewal
+		req.Connectivity = base.Connectivity
+		req.ReuseMarker = base.ReuseMarker
+		req.AllowDuplicateOptions = base.AllowDuplicateOptions
+	}
+	return batch
+}
+
+// newRunID returns a random UUID (version 4) identifying a run
+func newRunID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		fatalError(err, "Error generating a run ID: %v", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// printReproduction prints the config reproducing an order and, when path
+// is set, writes it to path
+func printReproduction(w io.Writer, config orderer.Config, path string) {
+	fmt.Fprintln(w, "Config reproducing this order:")
+	if err := writeConfig(w, config, "yaml"); err != nil {
+		log.Printf("Error printing the config: %v", err)
+		return
+	}
+	if path == "" {
+		return
+	}
+	format := "yaml"
+	if strings.HasSuffix(path, ".json") {
+		format = "json"
+	}
+	var buf bytes.Buffer
+	if err := writeConfig(&buf, config, format); err != nil {
+		log.Printf("Error writing the config: %v", err)
+		return
+	}
+	if err := files.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		log.Printf("Error writing the config: %v", err)
+		return
+	}
+	fmt.Fprintf(w, "Config written to %s, order again with: %s -config %s\n", path, os.Args[0], path)
+}
+
+// selectPlan returns req ordering the cheapest plan meeting requirements,
+// with the default options of the plan
+func selectPlan(client *ovh.Client, cache *orderer.CatalogCache, req orderer.OrderRequest, requirements orderer.PlanRequirements) orderer.OrderRequest {
+	o := newOrderer(client, orderer.Options{CatalogCache: cache})
+	defer o.Close()
+	plans, err := o.ListPlanSpecs(context.Background(), req.Subsidiary)
+	if err != nil {
+		fatalError(err, "Error reading the catalog: %v", err)
+	}
+	plan, err := orderer.SelectPlan(plans, requirements)
+	if err != nil {
+		fatalError(err, "Error selecting a plan: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Selected plan %s (%s) at %s a month, the cheapest of the plans with %s: %s, %d cores, %d GB RAM, %s storage\n",
+		plan.PlanCode, plan.Name, plan.MonthlyPrice.Format(req.Subsidiary), requirements, plan.CPU, plan.Cores, plan.MemoryGB, strings.Join(plan.StorageTechnologies, "/"))
+
+	req.PlanCode = plan.PlanCode
+	req.Options = nil
+	for _, option := range plan.DefaultOptions {
+		req.Options = append(req.Options, orderer.Option{PlanCode: option})
+	}
+	return req
+}
+
+// orderTracer exports the steps of an order as OpenTelemetry spans, nested
+// under a root "order" span
+type orderTracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+	ctx      context.Context
+	root     trace.Span
+	planCode string
+}
+
+// startTracing starts the root span of the order of req, exported to the
+// OTLP/HTTP endpoint. The parent trace context, if any, is read from the
+// TRACEPARENT and TRACESTATE environment variables.
+func startTracing(endpoint string, req orderer.OrderRequest) (*orderTracer, error) {
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{
+		"traceparent": os.Getenv("TRACEPARENT"),
+		"tracestate":  os.Getenv("TRACESTATE"),
+	})
+	t := &orderTracer{provider: provider, tracer: provider.Tracer("github.com/mediocre232/OVHAPIdedicatedserver"), planCode: req.PlanCode}
+	t.ctx, t.root = t.tracer.Start(ctx, "order", trace.WithAttributes(attribute.String("planCode", req.PlanCode), attribute.String("runID", req.RunID)))
+	return t, nil
+}
+
+// onEvent records a completed step as a child span of the order
+func (t *orderTracer) onEvent(event orderer.Event) {
+	// The order span covers the whole order
+	if event.Step == orderer.StepDone {
+		return
+	}
+	_, span := t.tracer.Start(t.ctx, event.Step, trace.WithTimestamp(event.Start), trace.WithAttributes(
+		attribute.String("planCode", t.planCode),
+		attribute.String("cartID", event.CartID),
+		attribute.String("orderID", event.OrderID),
+	))
+	if event.Err != nil {
+		span.RecordError(event.Err)
+		span.SetStatus(codes.Error, event.Err.Error())
+	}
+	span.End(trace.WithTimestamp(event.Start.Add(event.Duration)))
+}
+
+// end ends the order span and flushes the spans to the exporter
+func (t *orderTracer) end(result *orderer.OrderResult, err error) {
+	if result != nil {
+		t.root.SetAttributes(attribute.String("cartID", result.CartID), attribute.String("orderID", result.OrderID))
+	}
+	if err != nil {
+		t.root.RecordError(err)
+		t.root.SetStatus(codes.Error, err.Error())
+	}
+	t.root.End()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := t.provider.Shutdown(ctx); err != nil {
+		log.Printf("Error exporting the trace: %v", err)
+	}
+}
+
+// writeConfig writes config to w in format, yaml or json
+func writeConfig(w io.Writer, config orderer.Config, format string) error {
+	switch format {
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(config); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(config)
+	}
+	return fmt.Errorf("unknown format %q: expected yaml or json", format)
+}
+
+// printQuotes prints the plans compared by -compare-plans, with the reason a
+// plan could not be priced
+func printQuotes(w io.Writer, subsidiary string, quotes []orderer.PlanQuote) {
+	type row struct {
+		Plan    string
+		Monthly string
+		Term    string
+		Total   string
+		Note    string
+	}
+	rows := make([]row, len(quotes))
+	for i, quote := range quotes {
+		rows[i].Plan = quote.PlanCode
+		if quote.Err != nil {
+			rows[i].Note = quote.Err.Error()
+			continue
+		}
+		rows[i].Monthly = quote.Monthly.Format(subsidiary)
+		rows[i].Term = fmt.Sprintf("%d months", quote.Months)
+		rows[i].Total = quote.Total.Format(subsidiary)
+		var skipped []string
+		for _, option := range quote.SkippedOptions {
+			skipped = append(skipped, option.PlanCode)
+		}
+		if len(skipped) > 0 {
+			rows[i].Note = "without " + strings.Join(skipped, ", ")
+		}
+	}
+	if err := renderTable(w, rows); err != nil {
+		fatalError(err, "Error printing results: %v", err)
+	}
+}
+
+// overrideFlags collects the -set overrides, which may be repeated or
+// separated by commas
+type overrideFlags []string
+
+func (f *overrideFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *overrideFlags) Set(value string) error {
+	*f = append(*f, strings.Split(value, ",")...)
+	return nil
+}
+
+// printResult prints a human readable summary of a successful order
+func printResult(w io.Writer, result *orderer.OrderResult) {
+	if result.Reused {
+		fmt.Fprintf(w, "Reused server %s, nothing was ordered\n", result.ServiceName)
+		for _, ip := range result.ExtraIPs {
+			fmt.Fprintf(w, "Additional IP: %s\n", ip)
+		}
+		return
+	}
+	if result.AutoPay {
+		fmt.Fprintf(w, "Order %s submitted for auto-payment\n", result.OrderID)
+	} else {
+		fmt.Fprintf(w, "Order %s paid with %s payment method %s\n", result.OrderID, result.PaymentMethodType, result.PaymentMethodID)
+	}
+	if result.CustomerReference != "" {
+		fmt.Fprintf(w, "Customer reference: %s\n", result.CustomerReference)
+	}
+	if result.Engagement != nil {
+		fmt.Fprintf(w, "Engagement: %s\n", result.Engagement)
+	}
+	if prices := result.Prices; prices != nil {
+		if prices.WithTax.Value == prices.WithoutTax.Value {
+			fmt.Fprintf(w, "Price: %s, no tax applies\n", prices.WithTax.Text)
+		} else {
+			fmt.Fprintf(w, "Price: %s tax included, %s without tax\n", prices.WithTax.Text, prices.WithoutTax.Text)
+		}
+	}
+	if billing := result.Billing; billing != nil {
+		fmt.Fprintf(w, "First invoice: %s, including %s of one-time fees\n", billing.FirstInvoice.Text, billing.OneTime.Text)
+		if billing.Months > 0 {
+			fmt.Fprintf(w, "Then: %s every %s (%s a month)\n", billing.Recurring.Text, billing.Duration, billing.Monthly.Text)
+		} else {
+			fmt.Fprintf(w, "Then: %s every %s\n", billing.Recurring.Text, billing.Duration)
+		}
+	}
+	if len(result.Placement) > 0 {
+		labels := make([]string, 0, len(result.Placement))
+		for label := range result.Placement {
+			labels = append(labels, label+"="+result.Placement[label])
+		}
+		sort.Strings(labels)
+		fmt.Fprintf(w, "Placement: %s\n", strings.Join(labels, ", "))
+	}
+	for _, option := range result.Options {
+		fmt.Fprintf(w, "Option %s: billed for %s with pricing mode %s\n", option.PlanCode, option.Duration, option.PricingMode)
+	}
+	if hardware := result.HardwareOptions(); len(hardware) > 0 {
+		selected := make([]string, 0, len(hardware))
+		for _, option := range hardware {
+			labels := make([]string, 0, len(option.Configuration))
+			for _, config := range option.Configuration {
+				labels = append(labels, config.Label+"="+config.Value)
+			}
+			if len(labels) > 0 {
+				selected = append(selected, fmt.Sprintf("%s (%s)", option.PlanCode, strings.Join(labels, ", ")))
+			} else {
+				selected = append(selected, option.PlanCode)
+			}
+		}
+		fmt.Fprintf(w, "Hardware options: %s\n", strings.Join(selected, ", "))
+	}
+	if included := result.IncludedOptions(); len(included) > 0 {
+		names := make([]string, 0, len(included))
+		for _, option := range included {
+			names = append(names, option.PlanCode)
+		}
+		fmt.Fprintf(w, "Included options, added at no cost: %s\n", strings.Join(names, ", "))
+	}
+	if delivery := result.Delivery; delivery != nil {
+		fmt.Fprintf(w, "Delivery option: %s (%s)\n", delivery.PlanCode, delivery.Description)
+	}
+	for _, skipped := range result.SkippedOptions {
+		fmt.Fprintf(w, "Skipped option %s: %v\n", skipped.PlanCode, skipped.Err)
+	}
+	if docs := result.Documents; docs != nil {
+		fmt.Fprintf(w, "Order details: %s\n", docs.OrderURL)
+		if docs.PDFURL != "" {
+			fmt.Fprintf(w, "Order form: %s\n", docs.PDFURL)
+		}
+		for _, contract := range docs.Contracts {
+			fmt.Fprintf(w, "Contract %s: %s\n", contract.Name, contract.URL)
+		}
+	}
+	if result.ServiceName != "" {
+		fmt.Fprintf(w, "Server %s delivered\n", result.ServiceName)
+	}
+	for _, ip := range result.ExtraIPs {
+		fmt.Fprintf(w, "Additional IP: %s\n", ip)
+	}
+	if len(result.IPs) > 0 {
+		fmt.Fprintf(w, "Server IPs: %s\n", strings.Join(result.IPs, ", "))
+	}
+	if result.Reverse != "" {
+		fmt.Fprintf(w, "Reverse DNS: %s\n", result.Reverse)
+	}
+	if m := result.Monitoring; m != nil {
+		state := "disabled"
+		if m.Enabled {
+			state = "enabled"
+		}
+		if m.AlertEmail != "" {
+			state += ", alerts emailed to " + m.AlertEmail
+		}
+		fmt.Fprintf(w, "Monitoring: %s\n", state)
+	}
+	if r := result.Renewal; r != nil {
+		fmt.Fprintf(w, "Renewal: %s\n", orderer.RenewalRequest{Automatic: r.Automatic, Period: r.Period})
+	}
+	if c := result.Connectivity; c != nil {
+		state := "unreachable"
+		if c.Reachable {
+			state = "reachable"
+		}
+		fmt.Fprintf(w, "Connectivity: %s on %s, after %d attempt(s)\n", state, net.JoinHostPort(c.IP, strconv.Itoa(c.Port)), c.Attempts)
+	}
+}
+
+// printBulkResults prints the outcome of each order of a bulk run, in the
+// order they were requested, and returns exitOK if they all succeeded, or
+// the exit code of the first order that did not. Orders are named by ids
+// when given, by their position otherwise.
+func printBulkResults(w io.Writer, results []orderer.BulkResult, ids []string) int {
+	code := exitOK
+	failed, abandoned := 0, 0
+	for _, result := range results {
+		name := strconv.Itoa(result.Index + 1)
+		if ids != nil {
+			name = ids[result.Index]
+		}
+		switch {
+		case result.Abandoned:
+			abandoned++
+			if code == exitOK {
+				code = exitTimeout
+			}
+			fmt.Fprintf(w, "Order %s abandoned: %v\n", name, result.Err)
+			continue
+		case result.Err != nil:
+			failed++
+			if code == exitOK {
+				code = exitCode(result.Err)
+			}
+			fmt.Fprintf(w, "Order %s failed: %v\n", name, result.Err)
+			continue
+		}
+		fmt.Fprintf(w, "Order %s: ", name)
+		printResult(w, result.Result)
+	}
+	fmt.Fprintf(w, "%d of %d orders succeeded", len(results)-failed-abandoned, len(results))
+	if abandoned > 0 {
+		fmt.Fprintf(w, ", %d abandoned after -timeout-per-order", abandoned)
+	}
+	fmt.Fprintln(w)
+	return code
+}
+
+// printShellVariables prints the identifiers of an order as shell variable
+// assignments, for use with eval $(... -output shell)
+func printShellVariables(w io.Writer, result *orderer.OrderResult) {
+	variables := []struct{ name, value string }{
+		{"OVH_ORDER_ID", result.OrderID},
+		{"OVH_ITEM_ID", strconv.FormatInt(result.ItemID, 10)},
+		{"OVH_CART_ID", result.CartID},
+		{"OVH_SERVICE_NAME", result.ServiceName},
+		{"OVH_EXTRA_IPS", strings.Join(result.ExtraIPs, " ")},
+		{"OVH_IPS", strings.Join(result.IPs, " ")},
+	}
+	for _, v := range variables {
+		fmt.Fprintf(w, "%s=%s\n", v.name, shellQuote(v.value))
+	}
+}
+
+// printJSONResult prints the identifiers of an order as a JSON object
+func printJSONResult(w io.Writer, result *orderer.OrderResult) {
+	out := struct {
+		OrderID           string   `json:"orderId"`
+		CartID            string   `json:"cartId"`
+		ItemID            int64    `json:"itemId"`
+		CustomerReference string   `json:"customerReference,omitempty"`
+		PaymentStatus     string   `json:"paymentStatus,omitempty"`
+		ServiceName       string   `json:"serviceName,omitempty"`
+		ExtraIPs          []string `json:"extraIps,omitempty"`
+		IPs               []string `json:"ips,omitempty"`
+		Reverse           string   `json:"reverse,omitempty"`
+		Reachable         *bool    `json:"reachable,omitempty"`
+		IncludedOptions   []string `json:"includedOptions,omitempty"`
+
+		// Delivery is the delivery option, and DeliveryDescription its name
+		// in the catalog, which gives the expected delivery time if any
+		Delivery            string `json:"delivery,omitempty"`
+		DeliveryDescription string `json:"deliveryDescription,omitempty"`
+
+		// Both prices are printed whatever -max-price-basis, equal where no
+		// tax applies
+		PriceWithTax    *orderer.Price `json:"priceWithTax,omitempty"`
+		PriceWithoutTax *orderer.Price `json:"priceWithoutTax,omitempty"`
+	}{result.OrderID, result.CartID, result.ItemID, result.CustomerReference, result.PaymentStatus, result.ServiceName, result.ExtraIPs, result.IPs, result.Reverse, reachable(result), nil, "", "", nil, nil}
+	for _, option := range result.IncludedOptions() {
+		out.IncludedOptions = append(out.IncludedOptions, option.PlanCode)
+	}
+	if result.Delivery != nil {
+		out.Delivery, out.DeliveryDescription = result.Delivery.PlanCode, result.Delivery.Description
+	}
+	if result.Prices != nil {
+		out.PriceWithTax, out.PriceWithoutTax = &result.Prices.WithTax, &result.Prices.WithoutTax
+	}
+	err := json.NewEncoder(w).Encode(out)
+	if err != nil {
+		log.Printf("Error printing the result: %v", err)
+	}
+}
+
+// reachable returns whether the server of result accepted connections, or
+// nil when its connectivity was not checked
+func reachable(result *orderer.OrderResult) *bool {
+	if result.Connectivity == nil {
+		return nil
+	}
+	return &result.Connectivity.Reachable
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// setLabel returns configs with label set to value, replacing its value if
+// it is already set
+func setLabel(configs []orderer.Configuration, label, value string) []orderer.Configuration {
+	for i := range configs {
+		if configs[i].Label == label {
+			configs[i].Value = value
+			return configs
+		}
+	}
+	return append(configs, orderer.Configuration{Label: label, Value: value})
+}
+
+// defaultOrderRequest is the order placed when no config file is given
+func defaultOrderRequest() orderer.OrderRequest {
+	req := orderer.OrderRequest{
+		Subsidiary:  "US",
+		Description: "Automated Dedicated Server Order",
+		PlanCode:    "24rise01-us",
+		Duration:    "P1M",
+		PricingMode: "default",
+		Quantity:    1,
+	}
+	// The region, datacenter and options for vrack, storage, RAM, and
+	// bandwidth of the typical build. dedicated_os is left out: the plan's
+	// "no OS" value is looked up unless -os is given
+	build, _ := orderer.SuggestBuild(req.PlanCode)
+	req.ApplyBuild(build)
+	return req
+}
+
+// printAliases prints the aliases of an alias file as a table
+func printAliases(out io.Writer, aliases orderer.Aliases) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ALIAS\tPLAN\tOPTIONS\tDESCRIPTION")
+	for _, name := range aliases.Names() {
+		config := aliases[name]
+		options := make([]string, 0, len(config.Options))
+		for _, option := range config.Options {
+			options = append(options, option.PlanCode)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, config.Plan, strings.Join(options, ","), config.Description)
+	}
+	w.Flush()
+}
+
+// printBuild prints the typical build of planCode, as applied or suggested
+func printBuild(w io.Writer, planCode string, build orderer.PlanBuild, applied bool) {
+	if applied {
+		fmt.Fprintf(w, "Ordering the typical build of plan %s:\n", planCode)
+	} else {
+		fmt.Fprintf(w, "No options given for plan %s, its typical build is (add -use-defaults to order it):\n", planCode)
+	}
+	for _, option := range build.Options {
+		fmt.Fprintf(w, "  option %s\n", option.PlanCode)
+	}
+	for _, config := range build.Configuration {
+		fmt.Fprintf(w, "  %s=%s\n", config.Label, config.Value)
+	}
+}
+
+// printTimings prints the duration of each step of an order as a table
+func printTimings(out io.Writer, result *orderer.OrderResult) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tDURATION")
+	for _, timing := range result.Timings {
+		fmt.Fprintf(w, "%s\t%s\n", timing.Step, timing.Duration.Round(time.Millisecond))
+	}
+	fmt.Fprintf(w, "total\t%s\n", result.TotalDuration.Round(time.Millisecond))
+	w.Flush()
+}
+
+// printRunSummary prints, in format, how many calls of the run were retried
+// and rate limited, in total and per step of the orders of results, and how
+// long the run waited for limiter, if any
+func printRunSummary(w io.Writer, format string, stats orderer.RetryStats, limiter *orderer.RateLimitedTransport, results []*orderer.OrderResult) {
+	type stepSummary struct {
+		Step           string  `json:"step"`
+		Retries        int     `json:"retries"`
+		BackoffSeconds float64 `json:"backoffSeconds"`
+		RateLimited    int     `json:"rateLimited"`
+	}
+	summary := struct {
+		Retries            int           `json:"retries"`
+		BackoffSeconds     float64       `json:"backoffSeconds"`
+		RateLimited        int           `json:"rateLimited"`
+		LimiterWaitSeconds float64       `json:"limiterWaitSeconds"`
+		Steps              []stepSummary `json:"steps"`
+	}{
+		Retries:        stats.Retries,
+		BackoffSeconds: stats.Backoff.Seconds(),
+		RateLimited:    stats.RateLimited,
+		Steps:          []stepSummary{},
+	}
+	var limiterWait time.Duration
+	if limiter != nil {
+		limiterWait = limiter.Waited()
+		summary.LimiterWaitSeconds = limiterWait.Seconds()
+	}
+	// Index of each step in summary.Steps, which lists them as first met
+	steps := make(map[string]int)
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		for _, timing := range result.Timings {
+			if timing.Retries == 0 && timing.RateLimited == 0 {
+				continue
+			}
+			i, ok := steps[timing.Step]
+			if !ok {
+				i = len(summary.Steps)
+				summary.Steps = append(summary.Steps, stepSummary{Step: timing.Step})
+				steps[timing.Step] = i
+			}
+			step := &summary.Steps[i]
+			step.Retries += timing.Retries
+			step.BackoffSeconds += timing.Backoff.Seconds()
+			step.RateLimited += timing.RateLimited
+		}
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		if err := enc.Encode(summary); err != nil {
+			fmt.Fprintf(os.Stderr, "Error printing the run summary: %v\n", err)
+		}
+	case "text":
+		fmt.Fprintf(w, "Run summary: %d retries, %s in backoff, %d rate limited (429) responses, %s waiting for the rate limiter\n",
+			stats.Retries, stats.Backoff.Round(time.Millisecond), stats.RateLimited, limiterWait.Round(time.Millisecond))
+		for _, step := range summary.Steps {
+			fmt.Fprintf(w, "  %s: %d retries, %s in backoff, %d rate limited\n",
+				step.Step, step.Retries, time.Duration(step.BackoffSeconds*float64(time.Second)).Round(time.Millisecond), step.RateLimited)
+		}
+	}
+}
+
+// prompter asks the questions of -interactive on the terminal
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	// subsidiary formats the prices shown
+	subsidiary string
+}
+
+// ask prints question and returns the trimmed answer, or def when the answer is empty
+func (p *prompter) ask(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil && line == "" {
+		fatalError(err, "Error reading answer: %v", err)
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer
+	}
+	return def
+}
+
+// choose lists values with their labels and returns the value picked by
+// number or by name
+func (p *prompter) choose(question string, values, labels []string, def string) string {
+	for i := range values {
+		fmt.Fprintf(p.out, "  %2d) %s\n", i+1, labels[i])
+	}
+	for {
+		answer := p.ask(question, def)
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(values) {
+			return values[n-1]
+		}
+		if contains(values, answer) {
+			return answer
+		}
+		fmt.Fprintf(p.out, "Please answer with a number between 1 and %d or one of the listed values\n", len(values))
+	}
+}
+
+// confirm asks a yes/no question, defaulting to no
+func (p *prompter) confirm(question string) bool {
+	answer := strings.ToLower(p.ask(question+" [y/N]", ""))
+	return answer == "y" || answer == "yes"
+}
+
+// planDiscovery is the description and the stock of a plan, fetched in the
+// background while the prompts are answered
+type planDiscovery struct {
+	planCode string
+	done     chan struct{}
+
+	description    *orderer.PlanDescription
+	availabilities []orderer.Availability
+	err            error
+}
+
+// discoverPlan starts fetching the description and the stock of planCode,
+// concurrently. The calls go through the client of o, so its rate limit
+// applies and the catalog calls it shares with other runs are sent once.
+func discoverPlan(ctx context.Context, o *orderer.Orderer, subsidiary, planCode string) *planDiscovery {
+	d := &planDiscovery{planCode: planCode, done: make(chan struct{})}
+	go func() {
+		defer close(d.done)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// The stock only annotates the prompts, it may be missing
+			d.availabilities, _ = o.Availabilities(ctx, planCode)
+		}()
+		d.description, d.err = o.DescribePlan(ctx, subsidiary, planCode)
+		wg.Wait()
+	}()
+	return d
+}
+
+// wait returns the discovery once it is done
+func (d *planDiscovery) wait() (*orderer.PlanDescription, []orderer.Availability, error) {
+	<-d.done
+	return d.description, d.availabilities, d.err
+}
+
+// inStockDatacenters returns the datacenters where a configuration of
+// availabilities is in stock
+func inStockDatacenters(availabilities []orderer.Availability) map[string]bool {
+	datacenters := make(map[string]bool)
+	for _, availability := range availabilities {
+		for _, dc := range availability.Datacenters {
+			if dc.InStock() {
+				datacenters[dc.Datacenter] = true
+			}
+		}
+	}
+	return datacenters
+}
+
+// orderRequest builds an order from prompts, proposing the values of req as
+// defaults. Discovery runs ahead of the prompts: the plan proposed by req is
+// described while the plans are listed, and the plan picked while the
+// billing duration is asked.
+func (p *prompter) orderRequest(ctx context.Context, o *orderer.Orderer, req orderer.OrderRequest) orderer.OrderRequest {
+	p.subsidiary = req.Subsidiary
+	if req.PricingMode == "" {
+		req.PricingMode = "default"
+	}
+	var discovery *planDiscovery
+	if req.PlanCode != "" {
+		discovery = discoverPlan(ctx, o, req.Subsidiary, req.PlanCode)
+	}
+	plans, err := o.ListPlans(ctx, req.Subsidiary)
+	if err != nil {
+		fatalError(err, "Error listing plans: %v", err)
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].PlanCode < plans[j].PlanCode })
+	var codes, labels []string
+	for _, plan := range plans {
+		price, _ := orderer.PriceFor(plan.Prices, "P1M", "default")
+		codes = append(codes, plan.PlanCode)
+		labels = append(labels, fmt.Sprintf("%-24s %s %s", plan.PlanCode, plan.ProductName, price.Format(req.Subsidiary)))
+	}
+	if len(codes) == 0 {
+		fatalf(exitFailure, "No plan is offered to subsidiary %s", req.Subsidiary)
+	}
+	def := req.PlanCode
+	if !contains(codes, def) {
+		def = ""
+	}
+	fmt.Fprintln(p.out, "Plans (monthly price):")
+	req.PlanCode = p.choose("Plan", codes, labels, def)
+	if discovery == nil || discovery.planCode != req.PlanCode {
+		discovery = discoverPlan(ctx, o, req.Subsidiary, req.PlanCode)
+	}
+
+	for {
+		req.Duration = p.ask("Billing duration", req.Duration)
+		err := orderer.ValidateDuration(req.Duration)
+		if err == nil {
+			break
+		}
+		fmt.Fprintln(p.out, err)
+	}
+
+	description, availabilities, err := discovery.wait()
+	if err != nil {
+		fatalError(err, "Error describing plan %s: %v", req.PlanCode, err)
+	}
+	inStock := inStockDatacenters(availabilities)
+
+	// Configuration, proposing the values already given for each label
+	previous := make(map[string]string)
+	for _, config := range req.Configuration {
+		previous[config.Label] = config.Value
+	}
+	if req.OS != "" {
+		previous["dedicated_os"] = req.OS
+		req.OS = ""
+	}
+	req.Configuration = nil
+	for _, config := range description.Configuration {
+		def := previous[config.Label]
+		var value string
+		if len(config.AllowedValues) == 0 {
+			value = p.ask(config.Label, def)
+		} else {
+			if !contains(config.AllowedValues, def) {
+				def = ""
+			}
+			if def == "" && config.Label == "dedicated_os" {
+				for _, allowed := range config.AllowedValues {
+					if strings.HasPrefix(allowed, "none_") {
+						def = allowed
+						break
+					}
+				}
+			}
+			if def == "" && config.Required {
+				def = config.AllowedValues[0]
+			}
+			labels := config.AllowedValues
+			if config.Label == "dedicated_datacenter" && len(availabilities) > 0 {
+				labels = make([]string, len(config.AllowedValues))
+				for i, dc := range config.AllowedValues {
+					labels[i] = dc + " (out of stock)"
+					if inStock[dc] {
+						labels[i] = dc + " (in stock)"
+					}
+				}
+			}
+			fmt.Fprintf(p.out, "Values for %s:\n", config.Label)
+			value = p.choose(config.Label, config.AllowedValues, labels, def)
+		}
+		if value != "" {
+			req.Configuration = append(req.Configuration, orderer.Configuration{Label: config.Label, Value: value})
+		}
+	}
+
+	// One option per family, proposing the options already given
+	selected := make(map[string]orderer.Option)
+	for _, option := range req.Options {
+		selected[option.PlanCode] = option
+	}
+	req.Options = nil
+	for _, family := range groupOptions(description.Options) {
+		var codes, labels []string
+		def := ""
+		if !family.mandatory {
+			codes, labels, def = append(codes, "none"), append(labels, "none"), "none"
+		}
+		for _, offer := range family.offers {
+			price, _ := orderer.PriceFor(offer.Prices, req.Duration, req.PricingMode)
+			codes = append(codes, offer.PlanCode)
+			labels = append(labels, fmt.Sprintf("%-40s %s %s", offer.PlanCode, offer.ProductName, price.Format(req.Subsidiary)))
+			if _, ok := selected[offer.PlanCode]; ok {
+				def = offer.PlanCode
+			}
+		}
+		if def == "" {
+			def = codes[0]
+		}
+		fmt.Fprintf(p.out, "Options for %s:\n", family.name)
+		code := p.choose(family.name, codes, labels, def)
+		if code == "none" {
+			continue
+		}
+		option, ok := selected[code]
+		if !ok {
+			option = orderer.Option{PlanCode: code}
+		}
+		req.Options = append(req.Options, option)
+	}
+	return req
+}
+
+// confirmCheckout shows the price of the cart and asks whether to order it
+func (p *prompter) confirmCheckout(summary *orderer.CartSummary) bool {
+	fmt.Fprintln(p.out)
+	for _, detail := range summary.Details {
+		fmt.Fprintf(p.out, "  %dx %s: %s\n", detail.Quantity, detail.Description, detail.TotalPrice.Format(p.subsidiary))
+	}
+	fmt.Fprintf(p.out, "Total: %s (%s without tax)\n", summary.Prices.WithTax.Format(p.subsidiary), summary.Prices.WithoutTax.Format(p.subsidiary))
+	if summary.Engagement != nil {
+		fmt.Fprintf(p.out, "Engagement: %s\n", summary.Engagement)
+	}
+	// The approval is tied to this total: typing it, rather than yes,
+	// means a total that changed is never approved by habit
+	total, err := summary.Prices.WithTax.Amount()
+	if err != nil {
+		fmt.Fprintf(p.out, "Cannot read the total of the cart: %v\n", err)
+		return false
+	}
+	answer := p.ask(fmt.Sprintf("Type the total %s to order and pay this cart, anything else cancels", summary.Prices.WithTax.Value), "")
+	typed, err := orderer.ParseAmount(strings.TrimSpace(strings.TrimSuffix(answer, summary.Prices.WithTax.CurrencyCode)))
+	if err != nil || typed.Cmp(total) != 0 {
+		fmt.Fprintf(p.out, "%q is not the total %s, the order is cancelled\n", answer, summary.Prices.WithTax.Value)
+		return false
 	}
+	return true
 }